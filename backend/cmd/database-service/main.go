@@ -6,8 +6,10 @@ import (
 
 	"github.com/gin-gonic/gin"
 	"github.com/wzyjerry/llm-judge/internal/api"
+	"github.com/wzyjerry/llm-judge/internal/pkg/blob"
 	"github.com/wzyjerry/llm-judge/internal/pkg/config"
 	"github.com/wzyjerry/llm-judge/internal/pkg/logger"
+	"github.com/wzyjerry/llm-judge/internal/pkg/secret"
 	"github.com/wzyjerry/llm-judge/internal/repository"
 	"go.uber.org/zap"
 )
@@ -27,6 +29,12 @@ func main() {
 
 	logger.Info("Starting LLM Judge Database Service")
 
+	// Initialize API key encryption
+	if err := secret.InitFromConfig(cfg); err != nil {
+		zap.L().Fatal("Failed to initialize API key encryption",
+			zap.Error(err))
+	}
+
 	// Initialize database
 	if err := repository.InitDB(cfg.DatabaseService.DatabaseURL); err != nil {
 		zap.L().Fatal("Failed to initialize database",
@@ -34,6 +42,12 @@ func main() {
 	}
 	defer repository.Close()
 
+	// Initialize blob storage for data file contents
+	if err := blob.Init(cfg); err != nil {
+		zap.L().Fatal("Failed to initialize blob storage",
+			zap.Error(err))
+	}
+
 	// Setup Gin router
 	gin.SetMode(gin.ReleaseMode)
 	r := gin.New()
@@ -3,13 +3,17 @@ package main
 import (
 	"fmt"
 	"log"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/wzyjerry/llm-judge/internal/api"
+	"github.com/wzyjerry/llm-judge/internal/pkg/blob"
 	"github.com/wzyjerry/llm-judge/internal/pkg/config"
 	"github.com/wzyjerry/llm-judge/internal/pkg/logger"
 	"github.com/wzyjerry/llm-judge/internal/pkg/redis"
+	"github.com/wzyjerry/llm-judge/internal/pkg/secret"
 	"github.com/wzyjerry/llm-judge/internal/repository"
+	"github.com/wzyjerry/llm-judge/internal/service"
 	"go.uber.org/zap"
 )
 
@@ -28,6 +32,12 @@ func main() {
 
 	logger.Info("Starting LLM Judge Web API")
 
+	// Initialize API key encryption
+	if err := secret.InitFromConfig(cfg); err != nil {
+		zap.L().Fatal("Failed to initialize API key encryption",
+			zap.Error(err))
+	}
+
 	// Initialize database
 	if err := repository.InitDB(cfg.DatabaseService.DatabaseURL); err != nil {
 		zap.L().Fatal("Failed to initialize database",
@@ -35,6 +45,19 @@ func main() {
 	}
 	defer repository.Close()
 
+	// Initialize blob storage for data file contents
+	if err := blob.Init(cfg); err != nil {
+		zap.L().Fatal("Failed to initialize blob storage",
+			zap.Error(err))
+	}
+	if migrated, err := service.MigrateDataContentToBlobs(); err != nil {
+		zap.L().Error("Failed to move data file contents to blob storage",
+			zap.Error(err))
+	} else if migrated > 0 {
+		zap.L().Info("Moved data file contents to blob storage",
+			zap.Int("versions", migrated))
+	}
+
 	// Initialize Redis (optional)
 	if err := redis.Init(cfg); err != nil {
 		zap.L().Warn("Redis initialization failed, rate limiting will be disabled",
@@ -43,6 +66,24 @@ func main() {
 		defer redis.Close()
 	}
 
+	// Start model endpoint health checker
+	if cfg.ModelHealth.Enabled {
+		interval := time.Duration(cfg.ModelHealth.IntervalSeconds) * time.Second
+		if interval <= 0 {
+			interval = 5 * time.Minute
+		}
+		service.StartModelHealthChecker(interval)
+	}
+
+	// Start upstream model sync
+	if cfg.ModelSync.Enabled {
+		interval := time.Duration(cfg.ModelSync.IntervalSeconds) * time.Second
+		if interval <= 0 {
+			interval = time.Hour
+		}
+		service.StartModelSync(interval)
+	}
+
 	// Set Gin mode
 	gin.SetMode(gin.ReleaseMode)
 
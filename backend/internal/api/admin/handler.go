@@ -6,6 +6,7 @@ import (
 
 	"github.com/gin-gonic/gin"
 	"github.com/wzyjerry/llm-judge/internal/repository"
+	"github.com/wzyjerry/llm-judge/internal/service"
 )
 
 // GetUsers returns all users
@@ -110,7 +111,7 @@ func DeleteUserData(c *gin.Context) {
 		return
 	}
 
-	success, err := repository.DeleteUserData(uid, did)
+	blobKeys, success, err := repository.DeleteUserData(uid, did)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"detail": err.Error()})
 		return
@@ -119,6 +120,38 @@ func DeleteUserData(c *gin.Context) {
 		c.JSON(http.StatusNotFound, gin.H{"detail": "Data file not found"})
 		return
 	}
+	service.PruneDataBlobs(c.Request.Context(), blobKeys)
 
 	c.JSON(http.StatusOK, gin.H{"message": "Data file deleted successfully"})
 }
+
+// AddDataToLibrary publishes a data file in the benchmark library, where every user can read and evaluate it
+func AddDataToLibrary(c *gin.Context) {
+	setDataPublic(c, true, "Data file added to the library")
+}
+
+// RemoveDataFromLibrary withdraws a data file from the benchmark library
+func RemoveDataFromLibrary(c *gin.Context) {
+	setDataPublic(c, false, "Data file removed from the library")
+}
+
+// setDataPublic sets whether a data file is in the benchmark library
+func setDataPublic(c *gin.Context, public bool, message string) {
+	var id int
+	if _, err := fmt.Sscanf(c.Param("data_id"), "%d", &id); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"detail": "Invalid data ID"})
+		return
+	}
+
+	success, err := repository.SetUserDataPublic(id, public)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"detail": err.Error()})
+		return
+	}
+	if !success {
+		c.JSON(http.StatusNotFound, gin.H{"detail": "Data file not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": message})
+}
@@ -1,15 +1,43 @@
 package data
 
 import (
+	"bytes"
+	"context"
 	"encoding/csv"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
+	"math"
+	"mime"
+	"mime/multipart"
 	"net/http"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/wzyjerry/llm-judge/internal/model"
+	"github.com/wzyjerry/llm-judge/internal/pkg/blob"
 	"github.com/wzyjerry/llm-judge/internal/repository"
+	"github.com/wzyjerry/llm-judge/internal/service"
+	"go.uber.org/zap"
+)
+
+const (
+	// uploadOverhead leaves room for the multipart headers and other form fields of an upload
+	uploadOverhead = 1 << 20
+	// defaultContentLimit is the page size of data content when no limit is given
+	defaultContentLimit = 50
+	// maxContentLimit is the largest page size of data content
+	maxContentLimit = 1000
+	// maxReportedParseErrors limits the parse errors listed in a data content response
+	maxReportedParseErrors = 100
+	// importPreviewCount is the number of converted items an import preview returns
+	importPreviewCount = 5
 )
 
 // GetDataFiles returns user's data files
@@ -25,9 +53,10 @@ func GetDataFiles(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"data_files": dataList})
 }
 
-// GetDataContent returns data file content
+// GetDataContent returns a page of the items of a data file, optionally searching turn texts
+// for q and keeping items with a turn of the given role. Lines that fail to parse are reported
+// with their line number and skipped.
 func GetDataContent(c *gin.Context) {
-	userID := c.GetInt("user_id")
 	dataID := c.Param("data_id")
 
 	var id int
@@ -36,44 +65,119 @@ func GetDataContent(c *gin.Context) {
 		return
 	}
 
-	data, err := repository.GetUserDataByID(userID, id)
+	offset, limit := 0, defaultContentLimit
+	if value := c.Query("offset"); value != "" {
+		if _, err := fmt.Sscanf(value, "%d", &offset); err != nil || offset < 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"detail": "Invalid offset"})
+			return
+		}
+	}
+	if value := c.Query("limit"); value != "" {
+		if _, err := fmt.Sscanf(value, "%d", &limit); err != nil || limit <= 0 || limit > maxContentLimit {
+			c.JSON(http.StatusBadRequest, gin.H{"detail": fmt.Sprintf("limit must be between 1 and %d", maxContentLimit)})
+			return
+		}
+	}
+	query := strings.ToLower(strings.TrimSpace(c.Query("q")))
+	role := c.Query("role")
+
+	data, ok := accessDataFile(c, id, model.DataPermissionRead)
+	if !ok {
+		return
+	}
+
+	content, err := service.OpenUserData(c.Request.Context(), data)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"detail": err.Error()})
 		return
 	}
-	if data == nil {
-		c.JSON(http.StatusNotFound, gin.H{"detail": "Data file not found or access denied"})
+	defer content.Close()
+
+	response := model.DataContentResponse{
+		Filename:    data.Filename,
+		Description: data.Description,
+		Offset:      offset,
+		Limit:       limit,
+		Data:        []interface{}{},
+		Indices:     []int{},
+		Errors:      []model.DataParseError{},
+	}
+	roles := make(map[string]bool)
+
+	// Stream the file so only the requested page is kept in memory
+	err = service.ForEachDataLine(content, func(line int, raw []byte) error {
+		decoder := json.NewDecoder(bytes.NewReader(raw))
+		decoder.UseNumber()
+		var item interface{}
+		if err := decoder.Decode(&item); err != nil {
+			response.ErrorCount++
+			if len(response.Errors) < maxReportedParseErrors {
+				response.Errors = append(response.Errors, model.DataParseError{Line: line, Error: err.Error()})
+			}
+			return nil
+		}
+
+		index := response.TotalCount
+		response.TotalCount++
+		collectTurnRoles(item, roles)
+		if !matchDataItem(item, query, role) {
+			return nil
+		}
+
+		if response.MatchedCount >= offset && len(response.Data) < limit {
+			response.Data = append(response.Data, item)
+			response.Indices = append(response.Indices, index)
+		}
+		response.MatchedCount++
+		return nil
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"detail": err.Error()})
 		return
 	}
 
-	// Parse JSONL content
-	lines := strings.Split(data.FileContent, "\n")
-	var jsonlData []interface{}
-	for _, line := range lines {
-		line = strings.TrimSpace(line)
-		if line == "" {
+	response.Roles = make([]string, 0, len(roles))
+	for r := range roles {
+		response.Roles = append(response.Roles, r)
+	}
+	sort.Strings(response.Roles)
+
+	c.JSON(http.StatusOK, response)
+}
+
+// matchDataItem reports whether an item has a turn of the given role, or of any role when empty,
+// whose text contains the lowercased query
+func matchDataItem(item interface{}, query, role string) bool {
+	if query == "" && role == "" {
+		return true
+	}
+
+	obj, _ := item.(map[string]interface{})
+	turns, _ := obj["turns"].([]interface{})
+	for _, raw := range turns {
+		turn, ok := raw.(map[string]interface{})
+		if !ok || (role != "" && turn["role"] != role) {
 			continue
 		}
-		var item interface{}
-		if err := json.Unmarshal([]byte(line), &item); err != nil {
-			c.JSON(http.StatusOK, model.DataContentResponse{
-				Filename:    data.Filename,
-				Description: data.Description,
-				TotalCount:  len(lines),
-				Data:        jsonlData,
-				Error:       fmt.Sprintf("Failed to parse line: %s", err.Error()),
-			})
-			return
+		text, _ := turn["text"].(string)
+		if query == "" || strings.Contains(strings.ToLower(text), query) {
+			return true
 		}
-		jsonlData = append(jsonlData, item)
 	}
+	return false
+}
 
-	c.JSON(http.StatusOK, model.DataContentResponse{
-		Filename:    data.Filename,
-		Description: data.Description,
-		TotalCount:  len(jsonlData),
-		Data:        jsonlData,
-	})
+// collectTurnRoles adds the roles of an item's turns to roles
+func collectTurnRoles(item interface{}, roles map[string]bool) {
+	obj, _ := item.(map[string]interface{})
+	turns, _ := obj["turns"].([]interface{})
+	for _, raw := range turns {
+		if turn, ok := raw.(map[string]interface{}); ok {
+			if r, ok := turn["role"].(string); ok && r != "" {
+				roles[r] = true
+			}
+		}
+	}
 }
 
 // ValidateCSV validates CSV and converts to JSONL preview
@@ -142,17 +246,7 @@ func ValidateCSV(c *gin.Context) {
 		}
 
 		// Convert to JSONL
-		jsonlItem, err := convertCSVRowToJSONL(row)
-		if err != nil {
-			validationInfo.InvalidRows = append(validationInfo.InvalidRows, map[string]interface{}{
-				"row": i + 2,
-				"data": row,
-				"error": err.Error(),
-			})
-			continue
-		}
-
-		jsonlData = append(jsonlData, jsonlItem)
+		jsonlData = append(jsonlData, service.ConvertCSVRow(row))
 		validationInfo.ValidRows++
 	}
 
@@ -180,19 +274,18 @@ func ValidateCSV(c *gin.Context) {
 	})
 }
 
-// UploadData handles data file upload
-func UploadData(c *gin.Context) {
-	userID := c.GetInt("user_id")
-
-	file, err := c.FormFile("file")
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"detail": "File is required"})
+// PreviewDataImport converts an uploaded file without storing it, returning the validation report
+// and the first converted items
+func PreviewDataImport(c *gin.Context) {
+	file, ok := formDataFile(c)
+	if !ok {
+		return
+	}
+	opts, ok := formImportOptions(c)
+	if !ok {
 		return
 	}
 
-	description := c.PostForm("description")
-
-	// Open file
 	src, err := file.Open()
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"detail": err.Error()})
@@ -200,82 +293,181 @@ func UploadData(c *gin.Context) {
 	}
 	defer src.Close()
 
-	// Read content
-	var fileContent string
-	filename := file.Filename
-
-	if strings.HasSuffix(filename, ".jsonl") {
-		// Read JSONL directly
-		content := make([]byte, file.Size)
-		if _, err := src.Read(content); err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"detail": err.Error()})
-			return
-		}
-		fileContent = string(content)
-
-		// Validate JSONL format
-		lines := strings.Split(fileContent, "\n")
-		for _, line := range lines {
-			line = strings.TrimSpace(line)
-			if line == "" {
-				continue
-			}
-			var item interface{}
-			if err := json.Unmarshal([]byte(line), &item); err != nil {
-				c.JSON(http.StatusBadRequest, gin.H{"detail": "Invalid JSONL format"})
-				return
+	previewData := []interface{}{}
+	validator := service.NewDataValidator(c.PostForm("role"))
+	err = service.ImportDataFile(c.Request.Context(), file.Filename, src, opts, validator, func(line []byte) error {
+		if len(previewData) < importPreviewCount {
+			items, err := parseDataItems(bytes.NewReader(line))
+			if err != nil {
+				return err
 			}
+			previewData = append(previewData, items[0])
 		}
+		return nil
+	})
+	var validationErr *service.DataValidationError
+	if errors.As(err, &validationErr) {
+		c.JSON(http.StatusOK, gin.H{
+			"success":      false,
+			"message":      validationErr.Error(),
+			"format":       opts.Format,
+			"validation":   validationErr.Info,
+			"preview_data": []interface{}{},
+		})
+		return
+	}
+	if err != nil {
+		writeDataError(c, err)
+		return
+	}
 
-	} else if strings.HasSuffix(filename, ".csv") {
-		// Convert CSV to JSONL
-		reader := csv.NewReader(src)
-		records, err := reader.ReadAll()
-		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"detail": err.Error()})
-			return
-		}
+	c.JSON(http.StatusOK, gin.H{
+		"success":      true,
+		"message":      "File converted successfully",
+		"format":       opts.Format,
+		"validation":   validator.Info(),
+		"preview_data": previewData,
+	})
+}
 
-		var jsonlItems []string
-		for _, row := range records[1:] {
-			if len(row) < 2 || strings.TrimSpace(row[0]) == "" {
-				continue
-			}
-			jsonlItem, err := convertCSVRowToJSONL(row)
-			if err != nil {
-				continue
-			}
-			jsonBytes, _ := json.Marshal(jsonlItem)
-			jsonlItems = append(jsonlItems, string(jsonBytes))
-		}
+// UploadData handles data file upload
+func UploadData(c *gin.Context) {
+	userID := c.GetInt("user_id")
 
-		fileContent = strings.Join(jsonlItems, "\n")
-		filename = strings.Replace(filename, ".csv", ".jsonl", 1)
+	file, ok := formDataFile(c)
+	if !ok {
+		return
+	}
 
-	} else {
-		c.JSON(http.StatusBadRequest, gin.H{"detail": "Only .jsonl and .csv files are supported"})
+	description := c.PostForm("description")
+	message := c.PostForm("message")
+	// Items are checked for the role tasks will evaluate, "assistant" unless given
+	validator := service.NewDataValidator(c.PostForm("role"))
+
+	opts, ok := formImportOptions(c)
+	if !ok {
+		return
+	}
+	if !service.IsImportableFile(file.Filename) {
+		c.JSON(http.StatusBadRequest, gin.H{"detail": service.ErrUnsupportedDataFile.Error()})
 		return
 	}
 
-	// Create database record
-	dataID, err := repository.CreateUserData(userID, filename, fileContent, description)
+	// Open file
+	src, err := file.Open()
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"detail": err.Error()})
 		return
 	}
+	defer src.Close()
+
+	// Stream content into blob storage, converting other formats to JSONL on the way
+	filename := strings.TrimSuffix(file.Filename, filepath.Ext(file.Filename)) + ".jsonl"
+	var dataBlob *model.DataBlob
+	var dataID int
+	err = service.WithDataBlobs(func() error {
+		var err error
+		if dataBlob, err = storeImportedContent(c.Request.Context(), file.Filename, src, opts, validator); err != nil {
+			return err
+		}
+
+		// Create database record
+		dataID, err = repository.CreateUserData(userID, filename, description, c.GetString("username"), message, dataBlob)
+		return err
+	})
+	if err != nil {
+		writeDataError(c, err)
+		return
+	}
 
 	c.JSON(http.StatusOK, gin.H{
 		"id": dataID,
 		"filename": filename,
-		"size": len(fileContent),
+		"size": dataBlob.Size,
+		"item_count": dataBlob.ItemCount,
 		"description": description,
+		"version": 1,
+		"validation": validator.Info(),
 		"message": "File uploaded successfully",
 	})
 }
 
+// formDataFile reads the uploaded file of a multipart form limited to the maximum upload size.
+// It writes the error response and returns false when the file is missing or too large.
+func formDataFile(c *gin.Context) (*multipart.FileHeader, bool) {
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, blob.MaxUploadSize()+uploadOverhead)
+
+	file, err := c.FormFile("file")
+	var maxBytesErr *http.MaxBytesError
+	if errors.As(err, &maxBytesErr) || (err == nil && file.Size > blob.MaxUploadSize()) {
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{"detail": service.ErrDataTooLarge.Error()})
+		return nil, false
+	}
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"detail": "File is required"})
+		return nil, false
+	}
+	return file, true
+}
+
+// formImportOptions reads the format and the JSON column mapping of an uploaded file.
+// It writes the error response and returns false when the mapping is invalid.
+func formImportOptions(c *gin.Context) (*model.DataImportOptions, bool) {
+	opts := &model.DataImportOptions{Format: c.PostForm("format")}
+	if mapping := c.PostForm("mapping"); mapping != "" {
+		if err := json.Unmarshal([]byte(mapping), &opts.Mapping); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"detail": fmt.Sprintf("Invalid column mapping: %s", err.Error())})
+			return nil, false
+		}
+	}
+	return opts, true
+}
+
+// storeImportedContent converts an uploaded file to JSONL items while streaming them into blob storage
+func storeImportedContent(ctx context.Context, filename string, src io.Reader, opts *model.DataImportOptions, validator *service.DataValidator) (*model.DataBlob, error) {
+	pr, pw := io.Pipe()
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		// A validation error closes the pipe with that error, so nothing is stored
+		pw.CloseWithError(service.ImportDataFile(ctx, filename, src, opts, validator, func(line []byte) error {
+			_, err := pw.Write(append(line, '\n'))
+			return err
+		}))
+	}()
+
+	dataBlob, err := service.StoreDataContent(ctx, pr)
+	// Closing the reader stops the conversion when storing gave up early
+	pr.CloseWithError(err)
+	<-done
+
+	return dataBlob, err
+}
+
+// writeDataError writes the response for an error reading or storing data file content
+func writeDataError(c *gin.Context, err error) {
+	var lineErr *service.DataLineError
+	var validationErr *service.DataValidationError
+	var csvErr *csv.ParseError
+	switch {
+	case errors.Is(err, service.ErrDataTooLarge):
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{"detail": err.Error()})
+	case errors.Is(err, service.ErrUnsupportedDataFile), errors.Is(err, service.ErrInvalidDataFile), errors.Is(err, service.ErrInvalidImport),
+		errors.Is(err, service.ErrInvalidDataOperation):
+		c.JSON(http.StatusBadRequest, gin.H{"detail": err.Error()})
+	case errors.As(err, &validationErr):
+		c.JSON(http.StatusBadRequest, gin.H{"detail": validationErr.Error(), "validation": validationErr.Info})
+	case errors.As(err, &lineErr):
+		c.JSON(http.StatusBadRequest, gin.H{"detail": fmt.Sprintf("Invalid JSONL format: %s", lineErr.Error())})
+	case errors.As(err, &csvErr):
+		c.JSON(http.StatusBadRequest, gin.H{"detail": fmt.Sprintf("Invalid CSV format: %s", csvErr.Error())})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"detail": err.Error()})
+	}
+}
+
 // UpdateDataInfo updates data description
 func UpdateDataInfo(c *gin.Context) {
-	userID := c.GetInt("user_id")
 	dataID := c.Param("data_id")
 
 	var id int
@@ -290,7 +482,12 @@ func UpdateDataInfo(c *gin.Context) {
 		return
 	}
 
-	success, err := repository.UpdateUserData(userID, id, req.Description)
+	data, ok := accessDataFile(c, id, model.DataPermissionWrite)
+	if !ok {
+		return
+	}
+
+	success, err := repository.UpdateUserData(data.UserID, id, req.Description)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"detail": err.Error()})
 		return
@@ -314,7 +511,7 @@ func DeleteData(c *gin.Context) {
 		return
 	}
 
-	success, err := repository.DeleteUserData(userID, id)
+	blobKeys, success, err := repository.DeleteUserData(userID, id)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"detail": err.Error()})
 		return
@@ -323,11 +520,13 @@ func DeleteData(c *gin.Context) {
 		c.JSON(http.StatusNotFound, gin.H{"detail": "Data file not found or access denied"})
 		return
 	}
+	service.PruneDataBlobs(c.Request.Context(), blobKeys)
 
 	c.JSON(http.StatusOK, gin.H{"message": "Data file deleted successfully"})
 }
 
-// GetDataFilesList returns data files list (for frontend compatibility)
+// GetDataFilesList returns the data files the user can evaluate (for frontend compatibility):
+// their own files, the files shared with them, then the benchmark library
 func GetDataFilesList(c *gin.Context) {
 	userID := c.GetInt("user_id")
 
@@ -336,49 +535,1460 @@ func GetDataFilesList(c *gin.Context) {
 		c.JSON(http.StatusInternalServerError, gin.H{"detail": err.Error()})
 		return
 	}
+	shared, err := repository.GetSharedUserDataList(userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"detail": err.Error()})
+		return
+	}
+	library, err := repository.GetPublicUserDataList(userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"detail": err.Error()})
+		return
+	}
 
 	files := []model.DataFile{}
-	for _, data := range dataList {
-		files = append(files, model.DataFile{
-			ID:          data.ID,
-			Name:        data.Filename,
-			Size:        data.FileSize,
-			Type:        "user",
-			Description: data.Description,
-		})
+	listed := make(map[int]bool)
+	for _, group := range []struct {
+		fileType string
+		dataList []model.UserData
+	}{{"user", dataList}, {"shared", shared}, {"library", library}} {
+		for _, data := range group.dataList {
+			if listed[data.ID] {
+				continue
+			}
+			listed[data.ID] = true
+			files = append(files, model.DataFile{
+				ID:          data.ID,
+				Name:        data.Filename,
+				Size:        data.FileSize,
+				Type:        group.fileType,
+				Description: data.Description,
+				Owner:       data.Owner,
+			})
+		}
 	}
 
 	c.JSON(http.StatusOK, gin.H{"data_files": files})
 }
 
-// convertCSVRowToJSONL converts a CSV row to JSONL format
-func convertCSVRowToJSONL(row []string) (map[string]interface{}, error) {
-	if len(row) < 2 {
-		return nil, fmt.Errorf("invalid row format")
+// EditDataContent edits meta_description or turn texts of one item or of every item
+func EditDataContent(c *gin.Context) {
+	id, ok := parseDataID(c)
+	if !ok {
+		return
 	}
 
-	item := map[string]interface{}{
-		"meta": map[string]interface{}{
-			"meta_description": row[1],
-		},
-		"turns": []interface{}{},
+	var req model.DataEditRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"detail": err.Error()})
+		return
+	}
+	if req.EditType == "single" && req.ItemIndex == nil {
+		c.JSON(http.StatusBadRequest, gin.H{"detail": "item_index is required for a single edit"})
+		return
+	}
+	if req.FieldType == "turn_text" && req.Role == "" && req.TurnIndex == nil {
+		c.JSON(http.StatusBadRequest, gin.H{"detail": "role or turn_index is required to edit turn_text"})
+		return
+	}
+	if req.FindText == "" && req.NewValue == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"detail": "new_value is required unless find_text is set"})
+		return
 	}
 
-	// Process pairs of Human/Assistant columns
-	turns := []interface{}{}
-	for i := 2; i+1 < len(row); i += 2 {
-		if row[i] != "" && row[i+1] != "" {
-			turns = append(turns, map[string]string{
-				"role": "Human",
-				"text": row[i],
-			})
-			turns = append(turns, map[string]string{
-				"role": "Assistant",
-				"text": row[i+1],
-			})
+	updatedCount := 0
+	items, version, ok := editDataItems(c, id, req.Message, func(items []map[string]interface{}) ([]map[string]interface{}, error) {
+		if req.EditType == "single" {
+			if *req.ItemIndex < 0 || *req.ItemIndex >= len(items) {
+				return nil, fmt.Errorf("item_index %d out of range", *req.ItemIndex)
+			}
+			updatedCount = applyDataEdit(items[*req.ItemIndex], &req)
+			return items, nil
+		}
+
+		for _, item := range items {
+			updatedCount += applyDataEdit(item, &req)
+		}
+		return items, nil
+	})
+	if !ok {
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":       "Data updated successfully",
+		"updated_count": updatedCount,
+		"total_count":   len(items),
+		"version":       version,
+	})
+}
+
+// applyDataEdit applies an edit request to one item and returns the number of fields changed
+func applyDataEdit(item map[string]interface{}, req *model.DataEditRequest) int {
+	replace := func(value string) string {
+		if req.FindText != "" {
+			return strings.ReplaceAll(value, req.FindText, req.NewValue)
+		}
+		return req.NewValue
+	}
+
+	changed := 0
+	if req.FieldType == "meta_description" {
+		meta, ok := item["meta"].(map[string]interface{})
+		if !ok {
+			meta = make(map[string]interface{})
+			item["meta"] = meta
+		}
+		old, _ := meta["meta_description"].(string)
+		if value := replace(old); value != old || meta["meta_description"] == nil {
+			meta["meta_description"] = value
+			changed++
+		}
+		return changed
+	}
+
+	turns, _ := item["turns"].([]interface{})
+	for i, raw := range turns {
+		turn, ok := raw.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		if req.Role != "" && turn["role"] != req.Role {
+			continue
 		}
+		if req.TurnIndex != nil && i != *req.TurnIndex {
+			continue
+		}
+		old, _ := turn["text"].(string)
+		if value := replace(old); value != old {
+			turn["text"] = value
+			changed++
+		}
+	}
+	return changed
+}
+
+// EditDataItem replaces an item by its edited version. Only meta_description and the
+// text of existing turns may change.
+func EditDataItem(c *gin.Context) {
+	id, ok := parseDataID(c)
+	if !ok {
+		return
+	}
+
+	var req model.SingleItemEditRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"detail": err.Error()})
+		return
+	}
+
+	edited, err := normalizeDataItem(req.EditedItem)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"detail": err.Error()})
+		return
+	}
+
+	index := *req.ItemIndex
+	_, version, ok := editDataItems(c, id, req.Message, func(items []map[string]interface{}) ([]map[string]interface{}, error) {
+		if index < 0 || index >= len(items) {
+			return nil, fmt.Errorf("item_index %d out of range", index)
+		}
+		if !onlyEditableFieldsChanged(items[index], edited) {
+			return nil, fmt.Errorf("only meta_description and turn texts can be edited")
+		}
+		items[index] = edited
+		return items, nil
+	})
+	if !ok {
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Data item updated successfully",
+		"version": version,
+	})
+}
+
+// onlyEditableFieldsChanged reports whether edited equals original once meta_description
+// and the turn texts of original are taken from edited
+func onlyEditableFieldsChanged(original, edited map[string]interface{}) bool {
+	expected, err := normalizeDataItem(original)
+	if err != nil {
+		return false
+	}
+
+	if meta, ok := expected["meta"].(map[string]interface{}); ok {
+		if editedMeta, ok := edited["meta"].(map[string]interface{}); ok {
+			if value, exists := editedMeta["meta_description"]; exists {
+				meta["meta_description"] = value
+			}
+		}
+	}
+
+	turns, _ := expected["turns"].([]interface{})
+	editedTurns, _ := edited["turns"].([]interface{})
+	if len(turns) != len(editedTurns) {
+		return false
+	}
+	for i, raw := range turns {
+		turn, ok := raw.(map[string]interface{})
+		editedTurn, editedOK := editedTurns[i].(map[string]interface{})
+		if !ok || !editedOK {
+			continue
+		}
+		if value, exists := editedTurn["text"]; exists {
+			turn["text"] = value
+		}
+	}
+
+	return reflect.DeepEqual(expected, edited)
+}
+
+// DeleteDataItem deletes one item of a data file
+func DeleteDataItem(c *gin.Context) {
+	id, ok := parseDataID(c)
+	if !ok {
+		return
+	}
+
+	var index int
+	if _, err := fmt.Sscanf(c.Param("index"), "%d", &index); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"detail": "Invalid item index"})
+		return
+	}
+
+	items, version, ok := editDataItems(c, id, c.Query("message"), func(items []map[string]interface{}) ([]map[string]interface{}, error) {
+		return deleteDataItems(items, []int{index})
+	})
+	if !ok {
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":     "Data item deleted successfully",
+		"total_count": len(items),
+		"version":     version,
+	})
+}
+
+// DeleteDataItems deletes several items of a data file at once
+func DeleteDataItems(c *gin.Context) {
+	id, ok := parseDataID(c)
+	if !ok {
+		return
+	}
+
+	var req model.BatchDeleteItemsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"detail": err.Error()})
+		return
+	}
+	if len(req.ItemIndices) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"detail": "item_indices must not be empty"})
+		return
+	}
+
+	deletedCount := 0
+	items, version, ok := editDataItems(c, id, req.Message, func(items []map[string]interface{}) ([]map[string]interface{}, error) {
+		remaining, err := deleteDataItems(items, req.ItemIndices)
+		deletedCount = len(items) - len(remaining)
+		return remaining, err
+	})
+	if !ok {
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":       "Data items deleted successfully",
+		"deleted_count": deletedCount,
+		"total_count":   len(items),
+		"version":       version,
+	})
+}
+
+// deleteDataItems removes the items at the given indices, all of which must exist
+func deleteDataItems(items []map[string]interface{}, indices []int) ([]map[string]interface{}, error) {
+	deleted := make(map[int]bool)
+	for _, index := range indices {
+		if index < 0 || index >= len(items) {
+			return nil, fmt.Errorf("item index %d out of range", index)
+		}
+		deleted[index] = true
+	}
+
+	remaining := make([]map[string]interface{}, 0, len(items)-len(deleted))
+	for i, item := range items {
+		if !deleted[i] {
+			remaining = append(remaining, item)
+		}
+	}
+	return remaining, nil
+}
+
+// AddDataItem appends one item to a data file
+func AddDataItem(c *gin.Context) {
+	id, ok := parseDataID(c)
+	if !ok {
+		return
+	}
+
+	var req model.AddItemRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"detail": err.Error()})
+		return
+	}
+
+	item, err := normalizeDataItem(req.NewItem)
+	if err == nil {
+		err = checkDataItem(item, req.Role)
+	}
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"detail": err.Error()})
+		return
+	}
+
+	items, version, ok := editDataItems(c, id, req.Message, func(items []map[string]interface{}) ([]map[string]interface{}, error) {
+		return append(items, item), nil
+	})
+	if !ok {
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":     "Data item added successfully",
+		"item_index":  len(items) - 1,
+		"total_count": len(items),
+		"version":     version,
+	})
+}
+
+// AppendData appends the items of an uploaded file in any importable format to a data file
+func AppendData(c *gin.Context) {
+	id, ok := parseDataID(c)
+	if !ok {
+		return
+	}
+
+	file, ok := formDataFile(c)
+	if !ok {
+		return
+	}
+	opts, ok := formImportOptions(c)
+	if !ok {
+		return
+	}
+
+	src, err := file.Open()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"detail": err.Error()})
+		return
+	}
+	defer src.Close()
+
+	var newItems []map[string]interface{}
+	validator := service.NewDataValidator(c.PostForm("role"))
+	err = service.ImportDataFile(c.Request.Context(), file.Filename, src, opts, validator, func(line []byte) error {
+		items, err := parseDataItems(bytes.NewReader(line))
+		newItems = append(newItems, items...)
+		return err
+	})
+	if err != nil {
+		writeDataError(c, err)
+		return
+	}
+	if len(newItems) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"detail": "No data items found in file"})
+		return
+	}
+
+	items, version, ok := editDataItems(c, id, c.PostForm("message"), func(items []map[string]interface{}) ([]map[string]interface{}, error) {
+		return append(items, newItems...), nil
+	})
+	if !ok {
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":     "Data appended successfully",
+		"added_count": len(newItems),
+		"total_count": len(items),
+		"version":     version,
+	})
+}
+
+// parseDataID reads the data_id path parameter, writing the error response when invalid
+func parseDataID(c *gin.Context) (int, bool) {
+	var id int
+	if _, err := fmt.Sscanf(c.Param("data_id"), "%d", &id); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"detail": "Invalid data ID"})
+		return 0, false
+	}
+	return id, true
+}
+
+// editDataItems applies edit to the items of a data file the current user may write and
+// stores the result as a new version with the given commit message.
+// Errors returned by edit are reported as bad requests. The content is read and stored outside the
+// database transaction, which only commits the new version when nobody changed the file meanwhile.
+// It returns the new version, or writes the error response and returns false when the edit fails.
+func editDataItems(c *gin.Context, dataID int, message string, edit func([]map[string]interface{}) ([]map[string]interface{}, error)) ([]map[string]interface{}, int, bool) {
+	data, ok := accessDataFile(c, dataID, model.DataPermissionWrite)
+	if !ok {
+		return nil, 0, false
+	}
+	ctx := c.Request.Context()
+
+	content, err := service.OpenUserData(ctx, data)
+	if err != nil {
+		writeDataError(c, err)
+		return nil, 0, false
+	}
+	parsed, err := parseDataItems(content)
+	content.Close()
+	if err != nil {
+		writeDataError(c, err)
+		return nil, 0, false
+	}
+
+	items, err := edit(parsed)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"detail": err.Error()})
+		return nil, 0, false
+	}
+	formatted, err := formatDataItems(items)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"detail": err.Error()})
+		return nil, 0, false
+	}
+
+	var version int
+	var success bool
+	err = service.WithDataBlobs(func() error {
+		dataBlob, err := service.StoreDataContent(ctx, strings.NewReader(formatted))
+		if err != nil {
+			return err
+		}
+		version, success, err = repository.UpdateUserDataContent(data.UserID, dataID, data.CurrentVersion, dataBlob,
+			c.GetString("username"), message)
+		return err
+	})
+	if errors.Is(err, repository.ErrDataVersionConflict) {
+		c.JSON(http.StatusConflict, gin.H{"detail": err.Error()})
+		return nil, 0, false
+	}
+	if err != nil {
+		writeDataError(c, err)
+		return nil, 0, false
+	}
+	if !success {
+		c.JSON(http.StatusNotFound, gin.H{"detail": "Data file not found or access denied"})
+		return nil, 0, false
+	}
+
+	return items, version, true
+}
+
+// parseDataItems parses JSONL content into items, keeping numbers as written
+func parseDataItems(r io.Reader) ([]map[string]interface{}, error) {
+	items := []map[string]interface{}{}
+	err := service.ForEachDataLine(r, func(line int, data []byte) error {
+		decoder := json.NewDecoder(bytes.NewReader(data))
+		decoder.UseNumber()
+		var item map[string]interface{}
+		if err := decoder.Decode(&item); err != nil || item == nil {
+			return &service.DataLineError{Line: line, Err: errors.New("not a JSON object")}
+		}
+		items = append(items, item)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+// parseDataVersion parses the items of a data file version
+func parseDataVersion(ctx context.Context, v *model.UserDataVersion) ([]map[string]interface{}, error) {
+	content, err := service.OpenUserDataVersion(ctx, v)
+	if err != nil {
+		return nil, err
+	}
+	defer content.Close()
+
+	return parseDataItems(content)
+}
+
+// formatDataItems serializes items as JSONL
+func formatDataItems(items []map[string]interface{}) (string, error) {
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	encoder.SetEscapeHTML(false)
+	for _, item := range items {
+		// Encode terminates each item with a newline
+		if err := encoder.Encode(item); err != nil {
+			return "", err
+		}
+	}
+	return strings.TrimSuffix(buf.String(), "\n"), nil
+}
+
+// normalizeDataItem round-trips an item through JSON so it compares equal to parsed items
+func normalizeDataItem(item map[string]interface{}) (map[string]interface{}, error) {
+	data, err := json.Marshal(item)
+	if err != nil {
+		return nil, err
+	}
+	items, err := parseDataItems(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	return items[0], nil
+}
+
+// checkDataItem validates an item against the data format for the evaluation role
+func checkDataItem(item map[string]interface{}, role string) error {
+	if errs, _ := service.ValidateDataItem(item, role); len(errs) > 0 {
+		return errors.New(strings.Join(errs, "; "))
+	}
+	return nil
+}
+
+// dataPermissionRank orders the permissions on a data file from the weakest
+var dataPermissionRank = map[string]int{
+	model.DataPermissionRead:  1,
+	model.DataPermissionWrite: 2,
+	model.DataPermissionOwner: 3,
+}
+
+// accessDataFile loads a data file the current user owns, was shared or finds in the benchmark library,
+// and checks that the user holds at least the given permission on it.
+// It writes the error response and returns false otherwise.
+func accessDataFile(c *gin.Context, dataID int, permission string) (*model.UserData, bool) {
+	data, err := repository.GetUserDataAccess(c.GetInt("user_id"), dataID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"detail": err.Error()})
+		return nil, false
+	}
+	if data == nil {
+		c.JSON(http.StatusNotFound, gin.H{"detail": "Data file not found or access denied"})
+		return nil, false
+	}
+	if dataPermissionRank[data.Permission] < dataPermissionRank[permission] {
+		c.JSON(http.StatusForbidden, gin.H{"detail": fmt.Sprintf("Data file requires %s permission", permission)})
+		return nil, false
+	}
+	return data, true
+}
+
+// DownloadData streams a data file as JSONL, CSV, OpenAI or ShareGPT records, optionally only the
+// items at the comma-separated 0-based indices
+func DownloadData(c *gin.Context) {
+	id, ok := parseDataID(c)
+	if !ok {
+		return
+	}
+
+	formatName := c.DefaultQuery("format", "jsonl")
+	format, ok := service.DataExportFormats[formatName]
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"detail": "format must be one of jsonl, csv, openai, sharegpt"})
+		return
+	}
+	var indices map[int]bool
+	if value := c.Query("indices"); value != "" {
+		list, err := parseIntList(value)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"detail": fmt.Sprintf("Invalid indices: %s", err.Error())})
+			return
+		}
+		indices = make(map[int]bool, len(list))
+		for _, index := range list {
+			indices[index] = true
+		}
+	}
+
+	data, ok := accessDataFile(c, id, model.DataPermissionRead)
+	if !ok {
+		return
+	}
+	for index := range indices {
+		if index >= data.ItemCount {
+			c.JSON(http.StatusBadRequest, gin.H{"detail": fmt.Sprintf("Invalid indices: %d is out of range, the data file has %d items", index, data.ItemCount)})
+			return
+		}
+	}
+
+	open, err := service.OpenDataForExport(c.Request.Context(), data)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"detail": err.Error()})
+		return
+	}
+	if formatName == "csv" {
+		// CSV has no column for system turns, so tell the client how many are left out
+		dropped, err := service.CSVDroppedTurns(open, indices)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"detail": err.Error()})
+			return
+		}
+		if dropped > 0 {
+			c.Header("X-Dropped-Turns", strconv.Itoa(dropped))
+		}
+	}
+
+	filename := service.ExportFileBase(data.Filename)
+	if formatName == "openai" || formatName == "sharegpt" {
+		filename += "_" + formatName
+	}
+	c.Header("Content-Type", format.ContentType)
+	c.Header("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": filename + "." + format.Extension}))
+	c.Status(http.StatusOK)
+
+	// Headers are already sent, so a failure can only be logged
+	if err := service.ExportData(c.Writer, formatName, open, indices); err != nil {
+		zap.L().Error("Failed to export data file",
+			zap.Int("data_id", id),
+			zap.String("format", formatName),
+			zap.Error(err))
+	}
+}
+
+// ExportDataBundle downloads a zip archive of the data files with the comma-separated ids, which may
+// be shared with the user, or of every data file of the user, in one format with a manifest for backup or moving between environments
+func ExportDataBundle(c *gin.Context) {
+	userID := c.GetInt("user_id")
+
+	formatName := c.DefaultQuery("format", "jsonl")
+	if _, ok := service.DataExportFormats[formatName]; !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"detail": "format must be one of jsonl, csv, openai, sharegpt"})
+		return
+	}
+
+	var ids []int
+	if value := c.Query("ids"); value != "" {
+		var err error
+		if ids, err = parseIntList(value); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"detail": fmt.Sprintf("Invalid ids: %s", err.Error())})
+			return
+		}
+	} else {
+		dataList, err := repository.GetUserDataList(userID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"detail": err.Error()})
+			return
+		}
+		for _, data := range dataList {
+			ids = append(ids, data.ID)
+		}
+	}
+
+	// The list omits the content location, so each file is loaded on its own
+	files := make([]model.UserData, 0, len(ids))
+	for _, id := range ids {
+		data, err := repository.GetUserDataAccess(userID, id)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"detail": err.Error()})
+			return
+		}
+		if data == nil {
+			c.JSON(http.StatusNotFound, gin.H{"detail": fmt.Sprintf("Data file %d not found or access denied", id)})
+			return
+		}
+		files = append(files, *data)
+	}
+
+	filename := fmt.Sprintf("datasets_%s.zip", time.Now().Format("20060102_150405"))
+	c.Header("Content-Type", "application/zip")
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"`, filename))
+	c.Status(http.StatusOK)
+
+	// Headers are already sent, so a failure can only be logged
+	if err := service.ExportDataBundle(c.Request.Context(), c.Writer, formatName, files); err != nil {
+		zap.L().Error("Failed to export data bundle",
+			zap.Ints("data_ids", ids),
+			zap.String("format", formatName),
+			zap.Error(err))
+	}
+}
+
+// parseIntList parses a comma-separated list of distinct non-negative integers, keeping their order
+func parseIntList(value string) ([]int, error) {
+	var list []int
+	seen := make(map[int]bool)
+	for _, part := range strings.Split(value, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		var n int
+		if _, err := fmt.Sscanf(part, "%d", &n); err != nil || n < 0 {
+			return nil, fmt.Errorf("%q is not a non-negative integer", part)
+		}
+		if !seen[n] {
+			seen[n] = true
+			list = append(list, n)
+		}
+	}
+	return list, nil
+}
+
+// ValidateData checks the current content of a data file against the data format for the
+// evaluation role given by the role query parameter, "assistant" by default
+func ValidateData(c *gin.Context) {
+	id, ok := parseDataID(c)
+	if !ok {
+		return
+	}
+
+	data, ok := accessDataFile(c, id, model.DataPermissionRead)
+	if !ok {
+		return
+	}
+
+	content, err := service.OpenUserData(c.Request.Context(), data)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"detail": err.Error()})
+		return
+	}
+	defer content.Close()
+
+	validator := service.NewDataValidator(c.Query("role"))
+	err = service.ForEachDataLine(content, func(line int, raw []byte) error {
+		validator.ValidateLine(line, raw)
+		return nil
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"detail": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"valid":      validator.Valid(),
+		"validation": validator.Info(),
+	})
+}
+
+// GetDataStats profiles the current content of a data file, or the given version. Statistics are
+// cached per version.
+func GetDataStats(c *gin.Context) {
+	id, ok := parseDataID(c)
+	if !ok {
+		return
+	}
+
+	data, ok := accessDataFile(c, id, model.DataPermissionRead)
+	if !ok {
+		return
+	}
+
+	ctx := c.Request.Context()
+	version := data.CurrentVersion
+	open := func() (io.ReadCloser, error) { return service.OpenUserData(ctx, data) }
+	if value := c.Query("version"); value != "" {
+		if _, err := fmt.Sscanf(value, "%d", &version); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"detail": "Invalid version"})
+			return
+		}
+		dataVersion, err := repository.GetUserDataVersion(id, version)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"detail": err.Error()})
+			return
+		}
+		if dataVersion == nil {
+			c.JSON(http.StatusNotFound, gin.H{"detail": "Data version not found"})
+			return
+		}
+		open = func() (io.ReadCloser, error) { return service.OpenUserDataVersion(ctx, dataVersion) }
+	}
+
+	statistics, err := service.GetDataStatistics(ctx, id, version, open)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"detail": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, statistics)
+}
+
+// GetDataVersions returns the version history of a data file
+func GetDataVersions(c *gin.Context) {
+	id, ok := parseDataID(c)
+	if !ok {
+		return
+	}
+	if _, ok := accessDataFile(c, id, model.DataPermissionRead); !ok {
+		return
+	}
+
+	versions, err := repository.GetUserDataVersions(id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"detail": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"versions": versions})
+}
+
+// GetDataVersion returns one version of a data file with its items
+func GetDataVersion(c *gin.Context) {
+	id, ok := parseDataID(c)
+	if !ok {
+		return
+	}
+
+	var version int
+	if _, err := fmt.Sscanf(c.Param("version"), "%d", &version); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"detail": "Invalid version"})
+		return
+	}
+	if _, ok := accessDataFile(c, id, model.DataPermissionRead); !ok {
+		return
+	}
+
+	v, err := repository.GetUserDataVersion(id, version)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"detail": err.Error()})
+		return
+	}
+	if v == nil {
+		c.JSON(http.StatusNotFound, gin.H{"detail": "Data version not found"})
+		return
+	}
+
+	items, err := parseDataVersion(c.Request.Context(), v)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"detail": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"version":     v,
+		"total_count": len(items),
+		"data":        items,
+	})
+}
+
+// DiffDataVersions lists the items added and removed between two versions of a data file
+func DiffDataVersions(c *gin.Context) {
+	id, ok := parseDataID(c)
+	if !ok {
+		return
+	}
+
+	var from, to int
+	if _, err := fmt.Sscanf(c.Query("from"), "%d", &from); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"detail": "Invalid from version"})
+		return
+	}
+	if _, err := fmt.Sscanf(c.Query("to"), "%d", &to); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"detail": "Invalid to version"})
+		return
+	}
+	if _, ok := accessDataFile(c, id, model.DataPermissionRead); !ok {
+		return
+	}
+
+	var contents [2][]map[string]interface{}
+	for i, version := range []int{from, to} {
+		v, err := repository.GetUserDataVersion(id, version)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"detail": err.Error()})
+			return
+		}
+		if v == nil {
+			c.JSON(http.StatusNotFound, gin.H{"detail": fmt.Sprintf("Data version %d not found", version)})
+			return
+		}
+		if contents[i], err = parseDataVersion(c.Request.Context(), v); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"detail": err.Error()})
+			return
+		}
+	}
+
+	diff, err := diffDataItems(contents[0], contents[1])
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"detail": err.Error()})
+		return
+	}
+	diff.From, diff.To = from, to
+
+	c.JSON(http.StatusOK, diff)
+}
+
+// diffDataItems matches identical items of two versions regardless of their position.
+// Items left unmatched were removed from the old version or added to the new one.
+func diffDataItems(from, to []map[string]interface{}) (*model.UserDataVersionDiff, error) {
+	diff := &model.UserDataVersionDiff{
+		Added:   []model.DataItemChange{},
+		Removed: []model.DataItemChange{},
+	}
+
+	// Positions of each distinct old item, consumed in order as they are matched
+	positions := make(map[string][]int)
+	for i, item := range from {
+		key, err := json.Marshal(item)
+		if err != nil {
+			return nil, err
+		}
+		positions[string(key)] = append(positions[string(key)], i)
+	}
+
+	matched := make([]bool, len(from))
+	for i, item := range to {
+		key, err := json.Marshal(item)
+		if err != nil {
+			return nil, err
+		}
+		if indices := positions[string(key)]; len(indices) > 0 {
+			matched[indices[0]] = true
+			positions[string(key)] = indices[1:]
+			diff.UnchangedCount++
+			continue
+		}
+		diff.Added = append(diff.Added, model.DataItemChange{Index: i, Item: item})
+	}
+
+	for i, item := range from {
+		if !matched[i] {
+			diff.Removed = append(diff.Removed, model.DataItemChange{Index: i, Item: item})
+		}
+	}
+
+	return diff, nil
+}
+
+// RollbackData restores a data file to an earlier version, recorded as a new version
+func RollbackData(c *gin.Context) {
+	id, ok := parseDataID(c)
+	if !ok {
+		return
+	}
+
+	var version int
+	if _, err := fmt.Sscanf(c.Param("version"), "%d", &version); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"detail": "Invalid version"})
+		return
+	}
+
+	data, ok := accessDataFile(c, id, model.DataPermissionWrite)
+	if !ok {
+		return
+	}
+
+	newVersion, found, err := repository.RollbackUserData(data.UserID, id, version, c.GetString("username"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"detail": err.Error()})
+		return
+	}
+	if !found {
+		c.JSON(http.StatusNotFound, gin.H{"detail": "Data file version not found or access denied"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":         fmt.Sprintf("Data file rolled back to version %d", version),
+		"current_version": newVersion,
+	})
+}
+
+// GetSharedDataFiles returns the data files other users shared with the current user or their teams
+func GetSharedDataFiles(c *gin.Context) {
+	dataList, err := repository.GetSharedUserDataList(c.GetInt("user_id"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"detail": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data_files": dataList})
+}
+
+// GetDataLibrary returns the data files in the benchmark library curated by admins
+func GetDataLibrary(c *gin.Context) {
+	dataList, err := repository.GetPublicUserDataList(c.GetInt("user_id"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"detail": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data_files": dataList})
+}
+
+// GetDataShares returns the users and teams a data file of the current user is shared with
+func GetDataShares(c *gin.Context) {
+	id, ok := parseDataID(c)
+	if !ok {
+		return
+	}
+	if _, ok := accessDataFile(c, id, model.DataPermissionOwner); !ok {
+		return
+	}
+
+	shares, err := repository.GetUserDataShares(id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"detail": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"shares": shares})
+}
+
+// ShareData shares a data file of the current user with a user or with a team the owner belongs to,
+// replacing the permission the user or team was granted before
+func ShareData(c *gin.Context) {
+	id, ok := parseDataID(c)
+	if !ok {
+		return
+	}
+
+	var req model.UserDataShareCreate
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"detail": err.Error()})
+		return
+	}
+	req.Username = strings.TrimSpace(req.Username)
+	byUser := req.UserID != nil || req.Username != ""
+	if byUser == (req.TeamID != nil) {
+		c.JSON(http.StatusBadRequest, gin.H{"detail": "Exactly one of user_id, username and team_id is required"})
+		return
+	}
+
+	data, ok := accessDataFile(c, id, model.DataPermissionOwner)
+	if !ok {
+		return
+	}
+
+	if byUser {
+		var user *model.User
+		var err error
+		if req.UserID != nil {
+			user, err = repository.GetUserByID(*req.UserID)
+		} else {
+			user, err = repository.GetUserByUsername(req.Username)
+		}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"detail": err.Error()})
+			return
+		}
+		if user == nil {
+			c.JSON(http.StatusNotFound, gin.H{"detail": "User not found"})
+			return
+		}
+		if user.ID == data.UserID {
+			c.JSON(http.StatusBadRequest, gin.H{"detail": "Cannot share a data file with its owner"})
+			return
+		}
+		req.UserID = &user.ID
+	} else {
+		isMember, err := repository.IsTeamMember(*req.TeamID, data.UserID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"detail": err.Error()})
+			return
+		}
+		if !isMember {
+			c.JSON(http.StatusBadRequest, gin.H{"detail": "Only members can share a data file with a team"})
+			return
+		}
+	}
+
+	shareID, err := repository.ShareUserData(id, &req, c.GetString("username"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"detail": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":  "Data file shared successfully",
+		"share_id": shareID,
+	})
+}
+
+// DeleteDataShare revokes a share of a data file of the current user
+func DeleteDataShare(c *gin.Context) {
+	id, ok := parseDataID(c)
+	if !ok {
+		return
+	}
+
+	var shareID int
+	if _, err := fmt.Sscanf(c.Param("share_id"), "%d", &shareID); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"detail": "Invalid share ID"})
+		return
+	}
+	if _, ok := accessDataFile(c, id, model.DataPermissionOwner); !ok {
+		return
+	}
+
+	success, err := repository.DeleteUserDataShare(id, shareID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"detail": err.Error()})
+		return
+	}
+	if !success {
+		c.JSON(http.StatusNotFound, gin.H{"detail": "Share not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Share revoked successfully"})
+}
+
+// dataSource is a version of a data file read as the input of a dataset operation
+type dataSource struct {
+	items []map[string]interface{}
+	ref   model.DataProvenanceSource
+}
+
+// loadDataSource reads the items of a data file the current user may read, at the given version or the
+// current one when 0. It writes the error response and returns false on failure.
+func loadDataSource(c *gin.Context, dataID, version int) (*dataSource, bool) {
+	data, ok := accessDataFile(c, dataID, model.DataPermissionRead)
+	if !ok {
+		return nil, false
+	}
+	if version == 0 {
+		version = data.CurrentVersion
+	}
+
+	dataVersion, err := repository.GetUserDataVersion(dataID, version)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"detail": err.Error()})
+		return nil, false
+	}
+	if dataVersion == nil {
+		c.JSON(http.StatusNotFound, gin.H{"detail": "Data version not found"})
+		return nil, false
+	}
+
+	items, err := parseDataVersion(c.Request.Context(), dataVersion)
+	if err != nil {
+		writeDataError(c, err)
+		return nil, false
+	}
+
+	return &dataSource{
+		items: items,
+		ref: model.DataProvenanceSource{
+			DataID:    dataID,
+			Version:   dataVersion.Version,
+			Filename:  data.Filename,
+			Checksum:  dataVersion.Checksum,
+			ItemCount: len(items),
+		},
+	}, true
+}
+
+// derivedData is a data file to create from the result of a dataset operation
+type derivedData struct {
+	filename    string
+	description string
+	part        string
+	items       []map[string]interface{}
+}
+
+// createDerivedData stores the results of a dataset operation as new data files of the current user,
+// recording where they came from, and responds with the created data files
+func createDerivedData(c *gin.Context, operation string, sources []*dataSource, params map[string]interface{}, results []derivedData) {
+	ctx := c.Request.Context()
+	userID := c.GetInt("user_id")
+	username := c.GetString("username")
+	now := time.Now().Format(time.RFC3339)
+
+	refs := make([]model.DataProvenanceSource, len(sources))
+	for i, source := range sources {
+		refs[i] = source.ref
+	}
+
+	files := make([]model.DerivedDataFile, len(results))
+	contents := make([]string, len(results))
+	for i, result := range results {
+		if len(result.items) == 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"detail": fmt.Sprintf("%s would have no items", result.filename)})
+			return
+		}
+		formatted, err := formatDataItems(result.items)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"detail": err.Error()})
+			return
+		}
+		contents[i] = formatted
+		files[i] = model.DerivedDataFile{
+			Filename:    result.filename,
+			Description: result.description,
+			Provenance: &model.DataProvenance{
+				Operation: operation,
+				Sources:   refs,
+				Params:    params,
+				Part:      result.part,
+				CreatedBy: username,
+				CreatedAt: now,
+			},
+		}
+	}
+
+	var dataIDs []int
+	err := service.WithDataBlobs(func() error {
+		for i, content := range contents {
+			dataBlob, err := service.StoreDataContent(ctx, strings.NewReader(content))
+			if err != nil {
+				return err
+			}
+			files[i].Blob = dataBlob
+		}
+
+		var err error
+		dataIDs, err = repository.CreateDerivedUserData(userID, username, files)
+		return err
+	})
+	if err != nil {
+		writeDataError(c, err)
+		return
+	}
+
+	created := make([]*model.UserData, 0, len(dataIDs))
+	for _, dataID := range dataIDs {
+		data, err := repository.GetUserDataAccess(userID, dataID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"detail": err.Error()})
+			return
+		}
+		data.FileContent = ""
+		created = append(created, data)
+	}
+
+	zap.L().Info("Derived data files",
+		zap.String("operation", operation),
+		zap.Int("user_id", userID),
+		zap.Ints("data_ids", dataIDs))
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message":    fmt.Sprintf("Created %d data file(s) by %s", len(dataIDs), operation),
+		"data_files": created,
+	})
+}
+
+// checkDerivedName checks that a requested filename or split part name is a plain name, without
+// path separators or "..". It writes the error response and returns false otherwise.
+func checkDerivedName(c *gin.Context, field, name string) bool {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return true
+	}
+	if name == "." || filepath.Base(strings.ReplaceAll(name, "\\", "/")) != name || strings.Contains(name, "..") {
+		c.JSON(http.StatusBadRequest, gin.H{"detail": fmt.Sprintf("%s must not contain path separators or '..'", field)})
+		return false
+	}
+	return true
+}
+
+// derivedFilename returns the requested name of a derived data file as .jsonl, or the fallback.
+// The name is checked by checkDerivedName.
+func derivedFilename(name, fallback string) string {
+	name = filepath.Base(strings.TrimSpace(name))
+	if name == "" || name == "." {
+		return fallback
+	}
+	return strings.TrimSuffix(name, filepath.Ext(name)) + ".jsonl"
+}
+
+// dataFileBase returns the filename of a data file without its extension
+func dataFileBase(filename string) string {
+	return strings.TrimSuffix(filename, filepath.Ext(filename))
+}
+
+// operationSeed returns the requested seed of a random dataset operation, or a new one to record.
+// New seeds stay below 2^53 so the frontend can send them back unchanged.
+func operationSeed(seed *int64) int64 {
+	if seed != nil {
+		return *seed
+	}
+	return time.Now().UnixNano() % (1 << 53)
+}
+
+// SampleData draws a random sample of a data file, optionally stratified by a meta field,
+// as a new data file
+func SampleData(c *gin.Context) {
+	id, ok := parseDataID(c)
+	if !ok {
+		return
+	}
+
+	var req model.DataSampleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"detail": err.Error()})
+		return
+	}
+	if req.Size <= 0 && (req.Fraction <= 0 || req.Fraction > 1) {
+		c.JSON(http.StatusBadRequest, gin.H{"detail": "size or a fraction between 0 and 1 is required"})
+		return
+	}
+	if !checkDerivedName(c, "filename", req.Filename) {
+		return
+	}
+
+	source, ok := loadDataSource(c, id, req.Version)
+	if !ok {
+		return
+	}
+
+	size := req.Size
+	if size <= 0 {
+		size = int(math.Round(req.Fraction * float64(len(source.items))))
+	}
+	seed := operationSeed(req.Seed)
+	items, err := service.SampleDataItems(source.items, size, req.StratifyBy, seed)
+	if err != nil {
+		writeDataError(c, err)
+		return
+	}
+
+	params := map[string]interface{}{"size": size, "seed": seed}
+	if req.Fraction > 0 && req.Size <= 0 {
+		params["fraction"] = req.Fraction
+	}
+	if req.StratifyBy != "" {
+		params["stratify_by"] = req.StratifyBy
+	}
+
+	createDerivedData(c, service.DataOperationSample, []*dataSource{source}, params, []derivedData{{
+		filename:    derivedFilename(req.Filename, fmt.Sprintf("%s_sample_%d.jsonl", dataFileBase(source.ref.Filename), size)),
+		description: req.Description,
+		items:       items,
+	}})
+}
+
+// SplitData splits a data file at random into one new data file per part, e.g. train and test,
+// optionally stratified by a meta field
+func SplitData(c *gin.Context) {
+	id, ok := parseDataID(c)
+	if !ok {
+		return
+	}
+
+	var req model.DataSplitRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"detail": err.Error()})
+		return
+	}
+	names := make(map[string]bool, len(req.Parts))
+	ratios := make([]float64, len(req.Parts))
+	for i, part := range req.Parts {
+		if !checkDerivedName(c, "part name", part.Name) {
+			return
+		}
+		if names[part.Name] {
+			c.JSON(http.StatusBadRequest, gin.H{"detail": fmt.Sprintf("Duplicate part name: %s", part.Name)})
+			return
+		}
+		names[part.Name] = true
+		ratios[i] = part.Ratio
+	}
+
+	source, ok := loadDataSource(c, id, req.Version)
+	if !ok {
+		return
+	}
+
+	seed := operationSeed(req.Seed)
+	parts, err := service.SplitDataItems(source.items, ratios, req.StratifyBy, seed)
+	if err != nil {
+		writeDataError(c, err)
+		return
+	}
+
+	params := map[string]interface{}{"parts": req.Parts, "seed": seed}
+	if req.StratifyBy != "" {
+		params["stratify_by"] = req.StratifyBy
+	}
+
+	base := dataFileBase(source.ref.Filename)
+	results := make([]derivedData, len(parts))
+	for i, items := range parts {
+		name := req.Parts[i].Name
+		results[i] = derivedData{
+			filename: fmt.Sprintf("%s_%s.jsonl", base, strings.TrimSpace(name)),
+			part:     name,
+			items:    items,
+		}
+	}
+
+	createDerivedData(c, service.DataOperationSplit, []*dataSource{source}, params, results)
+}
+
+// DedupeData copies a data file without duplicate items into a new data file
+func DedupeData(c *gin.Context) {
+	id, ok := parseDataID(c)
+	if !ok {
+		return
+	}
+
+	var req model.DataDedupeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"detail": err.Error()})
+		return
+	}
+	if req.Mode == "" {
+		req.Mode = service.DedupeExact
+	}
+	if !checkDerivedName(c, "filename", req.Filename) {
+		return
+	}
+
+	source, ok := loadDataSource(c, id, req.Version)
+	if !ok {
+		return
+	}
+
+	items, removed := service.DedupeDataItems(source.items, req.Mode)
+	createDerivedData(c, service.DataOperationDedupe, []*dataSource{source},
+		map[string]interface{}{"mode": req.Mode, "removed": removed},
+		[]derivedData{{
+			filename:    derivedFilename(req.Filename, dataFileBase(source.ref.Filename)+"_dedup.jsonl"),
+			description: req.Description,
+			items:       items,
+		}})
+}
+
+// MergeData concatenates the current versions of data files into a new data file, optionally
+// dropping duplicates across them
+func MergeData(c *gin.Context) {
+	var req model.DataMergeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"detail": err.Error()})
+		return
+	}
+
+	if !checkDerivedName(c, "filename", req.Filename) {
+		return
+	}
+	seen := make(map[int]bool, len(req.DataIDs))
+	for _, dataID := range req.DataIDs {
+		if seen[dataID] {
+			c.JSON(http.StatusBadRequest, gin.H{"detail": fmt.Sprintf("Duplicate data_id: %d", dataID)})
+			return
+		}
+		seen[dataID] = true
+	}
+
+	var items []map[string]interface{}
+	sources := make([]*dataSource, 0, len(req.DataIDs))
+	for _, dataID := range req.DataIDs {
+		source, ok := loadDataSource(c, dataID, 0)
+		if !ok {
+			return
+		}
+		sources = append(sources, source)
+		items = append(items, source.items...)
+	}
+
+	params := map[string]interface{}{}
+	if req.Dedupe != "" {
+		var removed int
+		items, removed = service.DedupeDataItems(items, req.Dedupe)
+		params["dedupe"] = req.Dedupe
+		params["removed"] = removed
 	}
-	item["turns"] = turns
 
-	return item, nil
+	createDerivedData(c, service.DataOperationMerge, sources, params, []derivedData{{
+		filename:    derivedFilename(req.Filename, dataFileBase(sources[0].ref.Filename)+"_merged.jsonl"),
+		description: req.Description,
+		items:       items,
+	}})
 }
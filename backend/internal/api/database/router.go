@@ -1,11 +1,14 @@
 package database
 
 import (
+	"errors"
+	"io"
 	"strconv"
 
 	"github.com/gin-gonic/gin"
 	"github.com/wzyjerry/llm-judge/internal/model"
 	"github.com/wzyjerry/llm-judge/internal/repository"
+	"github.com/wzyjerry/llm-judge/internal/service"
 )
 
 // SetupDatabaseRoutes configures all database service routes
@@ -44,11 +47,13 @@ func SetupDatabaseRoutes(r *gin.Engine) {
 		v1.POST("/user-reports", createUserReport)
 		v1.GET("/user-reports/list/:user_id", getUserReports)
 		v1.GET("/user-reports/:user_id/:report_id", getUserReportByID)
+		v1.GET("/user-reports/:user_id/:report_id/items", getUserReportItems)
 		v1.DELETE("/user-reports/:user_id/:report_id", deleteUserReport)
 	}
 }
 
-// getUserData retrieves user data by ID
+// getUserData retrieves user data by ID. The data file may belong to the user, be shared with them
+// or be in the benchmark library.
 func getUserData(c *gin.Context) {
 	userID := c.Param("user_id")
 	dataID := c.Param("data_id")
@@ -67,7 +72,7 @@ func getUserData(c *gin.Context) {
 	}
 
 	// Retrieve from database
-	data, err := repository.GetUserDataByID(uid, did)
+	data, err := repository.GetUserDataAccess(uid, did)
 	if err != nil {
 		c.JSON(500, gin.H{"error": err.Error()})
 		return
@@ -78,12 +83,48 @@ func getUserData(c *gin.Context) {
 		return
 	}
 
+	// Tasks read the data version they pinned
+	var content io.ReadCloser
+	version := data.CurrentVersion
+	if v := c.Query("version"); v != "" {
+		version, err = strconv.Atoi(v)
+		if err != nil {
+			c.JSON(400, gin.H{"error": "Invalid version"})
+			return
+		}
+		var dataVersion *model.UserDataVersion
+		dataVersion, err = repository.GetUserDataVersion(did, version)
+		if err != nil {
+			c.JSON(500, gin.H{"error": err.Error()})
+			return
+		}
+		if dataVersion == nil {
+			c.JSON(404, gin.H{"error": "Data version not found"})
+			return
+		}
+		content, err = service.OpenUserDataVersion(c.Request.Context(), dataVersion)
+	} else {
+		content, err = service.OpenUserData(c.Request.Context(), data)
+	}
+	if err != nil {
+		c.JSON(500, gin.H{"error": err.Error()})
+		return
+	}
+	defer content.Close()
+
+	fileContent, err := io.ReadAll(content)
+	if err != nil {
+		c.JSON(500, gin.H{"error": err.Error()})
+		return
+	}
+
 	// Map to response format
 	response := gin.H{
 		"user_id":      data.UserID,
 		"data_id":      data.ID,
 		"filename":     data.Filename,
-		"file_content": data.FileContent,
+		"file_content": string(fileContent),
+		"version":      version,
 	}
 
 	c.JSON(200, response)
@@ -253,7 +294,7 @@ func createModelConfig(c *gin.Context) {
 		return
 	}
 
-	configID, err := repository.CreateModelConfig(&config)
+	configID, err := repository.CreateModelConfig(&config, "system")
 	if err != nil {
 		c.JSON(500, gin.H{"error": err.Error()})
 		return
@@ -282,7 +323,11 @@ func updateModelConfig(c *gin.Context) {
 		return
 	}
 
-	success, err := repository.UpdateModelConfig(configIDInt, updates)
+	success, err := repository.UpdateModelConfig(configIDInt, updates, "system", "")
+	if errors.Is(err, repository.ErrInvalidModelConfigUpdate) {
+		c.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
 	if err != nil {
 		c.JSON(500, gin.H{"error": err.Error()})
 		return
@@ -329,6 +374,9 @@ func createUserReport(c *gin.Context) {
 		return
 	}
 
+	// Statistics are computed here so that every report gets them whatever its scorer
+	service.EnrichReportSummary(&report)
+
 	reportID, err := repository.CreateUserReport(&report)
 	if err != nil {
 		c.JSON(500, gin.H{"error": err.Error()})
@@ -388,6 +436,50 @@ func getUserReportByID(c *gin.Context) {
 	c.JSON(200, report)
 }
 
+// getUserReportItems retrieves the metadata and all results of a report, used to rescore it
+func getUserReportItems(c *gin.Context) {
+	userID := c.Param("user_id")
+	reportID := c.Param("report_id")
+
+	// Convert strings to int
+	uid, err := strconv.Atoi(userID)
+	if err != nil {
+		c.JSON(400, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	rid, err := strconv.Atoi(reportID)
+	if err != nil {
+		c.JSON(400, gin.H{"error": "Invalid report ID"})
+		return
+	}
+
+	report, err := repository.GetReportDetail(uid, rid)
+	if err != nil {
+		c.JSON(500, gin.H{"error": err.Error()})
+		return
+	}
+	if report == nil {
+		c.JSON(404, gin.H{"error": "Report not found"})
+		return
+	}
+
+	results := []model.ReportResult{}
+	err = repository.ForEachReportItem(rid, model.ReportItemFilter{}, func(r *model.ReportResult) error {
+		results = append(results, *r)
+		return nil
+	})
+	if err != nil {
+		c.JSON(500, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(200, gin.H{
+		"report":  report,
+		"results": results,
+	})
+}
+
 // deleteUserReport deletes a user report
 func deleteUserReport(c *gin.Context) {
 	userID := c.Param("user_id")
@@ -0,0 +1,322 @@
+package leaderboard
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/wzyjerry/llm-judge/internal/model"
+	"github.com/wzyjerry/llm-judge/internal/pkg/config"
+	"github.com/wzyjerry/llm-judge/internal/repository"
+	"github.com/wzyjerry/llm-judge/internal/service"
+)
+
+// GetLeaderboard ranks models on each dataset by the latest or best report summary.
+// Reports of the current user are used, or those of all members of team_id.
+func GetLeaderboard(c *gin.Context) {
+	filter, teamID, ok := parseLeaderboardQuery(c)
+	if !ok {
+		return
+	}
+
+	userIDs, ok := leaderboardScope(c, teamID)
+	if !ok {
+		return
+	}
+
+	leaderboard, err := service.ComputeLeaderboard(userIDs, filter)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"detail": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, leaderboard)
+}
+
+// GetLeaderboardHistory returns the score history of a model on a dataset with regressions marked
+func GetLeaderboardHistory(c *gin.Context) {
+	filter, teamID, ok := parseLeaderboardQuery(c)
+	if !ok {
+		return
+	}
+
+	modelName := c.Query("model")
+	if modelName == "" || filter.Dataset == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"detail": "model and dataset are required"})
+		return
+	}
+
+	threshold := 0.0
+	if raw := c.Query("regression_threshold"); raw != "" {
+		if _, err := fmt.Sscanf(raw, "%g", &threshold); err != nil || threshold < 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"detail": "Invalid regression_threshold"})
+			return
+		}
+	}
+
+	userIDs, ok := leaderboardScope(c, teamID)
+	if !ok {
+		return
+	}
+
+	history, err := service.ComputeLeaderboardHistory(userIDs, filter, modelName, threshold)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"detail": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, history)
+}
+
+// parseLeaderboardQuery reads the leaderboard filter and the optional team_id from the query.
+// It writes the error response and returns false when a parameter is invalid.
+func parseLeaderboardQuery(c *gin.Context) (model.LeaderboardFilter, *int, bool) {
+	filter := model.LeaderboardFilter{
+		Mode:    c.Query("mode"),
+		Metric:  c.Query("metric"),
+		Scoring: c.Query("scoring"),
+		From:    c.Query("from"),
+		To:      c.Query("to"),
+		Tags:    c.QueryArray("tag"),
+		Dataset: c.Query("dataset"),
+	}
+	if err := service.NormalizeLeaderboardFilter(&filter); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"detail": err.Error()})
+		return filter, nil, false
+	}
+
+	var teamID *int
+	if raw := c.Query("team_id"); raw != "" {
+		var id int
+		if _, err := fmt.Sscanf(raw, "%d", &id); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"detail": "Invalid team ID"})
+			return filter, nil, false
+		}
+		teamID = &id
+	}
+
+	return filter, teamID, true
+}
+
+// leaderboardScope returns the users whose reports a leaderboard covers: the current user,
+// or every member of the team, which the current user must belong to unless admin.
+// It writes the error response and returns false when the team is not accessible.
+func leaderboardScope(c *gin.Context, teamID *int) ([]int, bool) {
+	userID := c.GetInt("user_id")
+	if teamID == nil {
+		return []int{userID}, true
+	}
+
+	team, err := repository.GetTeamByID(*teamID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"detail": err.Error()})
+		return nil, false
+	}
+
+	isMember := false
+	if team != nil {
+		for _, member := range team.Members {
+			if member.UserID == userID {
+				isMember = true
+			}
+		}
+	}
+	if team == nil || (!isMember && !config.IsAdmin(c.GetString("username"))) {
+		c.JSON(http.StatusNotFound, gin.H{"detail": "Team not found or access denied"})
+		return nil, false
+	}
+
+	userIDs := make([]int, len(team.Members))
+	for i, member := range team.Members {
+		userIDs[i] = member.UserID
+	}
+	return userIDs, true
+}
+
+// GetSavedLeaderboards returns the leaderboards the current user owns or that are shared with the user's teams
+func GetSavedLeaderboards(c *gin.Context) {
+	userID := c.GetInt("user_id")
+
+	leaderboards, err := repository.GetUserLeaderboards(userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"detail": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"leaderboards": leaderboards})
+}
+
+// CreateSavedLeaderboard saves a leaderboard definition, shared with a team when team_id is given
+func CreateSavedLeaderboard(c *gin.Context) {
+	userID := c.GetInt("user_id")
+
+	req, ok := bindSavedLeaderboard(c)
+	if !ok {
+		return
+	}
+
+	id, err := repository.CreateLeaderboard(userID, req)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"detail": err.Error()})
+		return
+	}
+
+	leaderboard, err := repository.GetLeaderboardByID(id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"detail": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, leaderboard)
+}
+
+// UpdateSavedLeaderboard replaces a leaderboard definition owned by the current user
+func UpdateSavedLeaderboard(c *gin.Context) {
+	userID := c.GetInt("user_id")
+	leaderboardID := c.Param("leaderboard_id")
+
+	var id int
+	if _, err := fmt.Sscanf(leaderboardID, "%d", &id); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"detail": "Invalid leaderboard ID"})
+		return
+	}
+
+	req, ok := bindSavedLeaderboard(c)
+	if !ok {
+		return
+	}
+
+	success, err := repository.UpdateLeaderboard(id, userID, req)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"detail": err.Error()})
+		return
+	}
+	if !success {
+		c.JSON(http.StatusNotFound, gin.H{"detail": "Leaderboard not found or access denied"})
+		return
+	}
+
+	leaderboard, err := repository.GetLeaderboardByID(id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"detail": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, leaderboard)
+}
+
+// bindSavedLeaderboard reads and validates a saved leaderboard request.
+// The owner must belong to the team the leaderboard is shared with.
+func bindSavedLeaderboard(c *gin.Context) (*model.SavedLeaderboardCreate, bool) {
+	var req model.SavedLeaderboardCreate
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"detail": err.Error()})
+		return nil, false
+	}
+
+	req.Name = strings.TrimSpace(req.Name)
+	if req.Name == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"detail": "name is required"})
+		return nil, false
+	}
+	if err := service.NormalizeLeaderboardFilter(&req.Filters); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"detail": err.Error()})
+		return nil, false
+	}
+
+	if req.TeamID != nil {
+		isMember, err := repository.IsTeamMember(*req.TeamID, c.GetInt("user_id"))
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"detail": err.Error()})
+			return nil, false
+		}
+		if !isMember {
+			c.JSON(http.StatusBadRequest, gin.H{"detail": "Only members can share a leaderboard with a team"})
+			return nil, false
+		}
+	}
+
+	return &req, true
+}
+
+// GetSavedLeaderboard returns a saved leaderboard with its current ranking.
+// Shared leaderboards cover the reports of all team members, private ones those of the owner.
+func GetSavedLeaderboard(c *gin.Context) {
+	userID := c.GetInt("user_id")
+	leaderboardID := c.Param("leaderboard_id")
+
+	var id int
+	if _, err := fmt.Sscanf(leaderboardID, "%d", &id); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"detail": "Invalid leaderboard ID"})
+		return
+	}
+
+	saved, err := repository.GetLeaderboardByID(id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"detail": err.Error()})
+		return
+	}
+
+	accessible := saved != nil && (saved.OwnerID == userID || config.IsAdmin(c.GetString("username")))
+	if saved != nil && !accessible && saved.TeamID != nil {
+		accessible, err = repository.IsTeamMember(*saved.TeamID, userID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"detail": err.Error()})
+			return
+		}
+	}
+	if !accessible {
+		c.JSON(http.StatusNotFound, gin.H{"detail": "Leaderboard not found or access denied"})
+		return
+	}
+
+	userIDs := []int{saved.OwnerID}
+	if saved.TeamID != nil {
+		members, err := repository.GetTeamMembers(*saved.TeamID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"detail": err.Error()})
+			return
+		}
+		userIDs = make([]int, len(members))
+		for i, member := range members {
+			userIDs[i] = member.UserID
+		}
+	}
+
+	// Stored filters are normalized on save
+	leaderboard, err := service.ComputeLeaderboard(userIDs, saved.Filters)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"detail": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"leaderboard": saved,
+		"ranking":     leaderboard,
+	})
+}
+
+// DeleteSavedLeaderboard deletes a leaderboard owned by the current user
+func DeleteSavedLeaderboard(c *gin.Context) {
+	userID := c.GetInt("user_id")
+	leaderboardID := c.Param("leaderboard_id")
+
+	var id int
+	if _, err := fmt.Sscanf(leaderboardID, "%d", &id); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"detail": "Invalid leaderboard ID"})
+		return
+	}
+
+	success, err := repository.DeleteLeaderboard(id, userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"detail": err.Error()})
+		return
+	}
+	if !success {
+		c.JSON(http.StatusNotFound, gin.H{"detail": "Leaderboard not found or access denied"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Leaderboard deleted successfully"})
+}
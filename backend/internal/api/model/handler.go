@@ -2,15 +2,19 @@ package model
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"strings"
 
 	"github.com/gin-gonic/gin"
 	"github.com/wzyjerry/llm-judge/internal/model"
+	"github.com/wzyjerry/llm-judge/internal/pkg/secret"
 	"github.com/wzyjerry/llm-judge/internal/repository"
+	"github.com/wzyjerry/llm-judge/internal/service"
 )
 
 // GetScoringFunctions returns available scoring functions from plugin.py
@@ -136,7 +140,14 @@ func GetAvailableModels(c *gin.Context) {
 func GetModelConfigs(c *gin.Context) {
 	includeInactive := c.DefaultQuery("include_inactive", "false") == "true"
 
-	configs, err := repository.GetAllModelConfigs(includeInactive)
+	// Filter by tags (?tag=family:qwen&tag=size:7b matches configs carrying both)
+	var configs []model.ModelConfig
+	var err error
+	if tags := c.QueryArray("tag"); len(tags) > 0 {
+		configs, err = repository.GetModelConfigsByTags(tags, includeInactive)
+	} else {
+		configs, err = repository.GetAllModelConfigs(includeInactive)
+	}
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"detail": err.Error()})
 		return
@@ -149,6 +160,9 @@ func GetModelConfigs(c *gin.Context) {
 		}
 	}
 
+	attachHealth(configs)
+	attachTagsAndAliases(configs)
+
 	c.JSON(http.StatusOK, gin.H{"configs": configs})
 }
 
@@ -167,6 +181,9 @@ func AdminGetModelConfigs(c *gin.Context) {
 		}
 	}
 
+	attachHealth(configs)
+	attachTagsAndAliases(configs)
+
 	c.JSON(http.StatusOK, gin.H{"configs": configs})
 }
 
@@ -178,7 +195,7 @@ func CreateModelConfig(c *gin.Context) {
 		return
 	}
 
-	configID, err := repository.CreateModelConfig(&req)
+	configID, err := repository.CreateModelConfig(&req, c.GetString("username"))
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"detail": err.Error()})
 		return
@@ -206,6 +223,10 @@ func UpdateModelConfig(c *gin.Context) {
 		return
 	}
 
+	// The change note is stored with the version, not as a column
+	changeNote, _ := updates["change_note"].(string)
+	delete(updates, "change_note")
+
 	// Normalize types for all fields
 	for key, value := range updates {
 		switch key {
@@ -238,6 +259,11 @@ func UpdateModelConfig(c *gin.Context) {
 			if i, ok := value.(int); ok {
 				updates[key] = float64(i)
 			}
+		case "api_key":
+			// The admin UI echoes back the masked key when it was not changed
+			if str, ok := value.(string); !ok || secret.IsMasked(str) {
+				delete(updates, key)
+			}
 		case "is_active", "is_vllm":
 			// Convert to int
 			switch v := value.(type) {
@@ -258,7 +284,11 @@ func UpdateModelConfig(c *gin.Context) {
 		}
 	}
 
-	success, err := repository.UpdateModelConfig(id, updates)
+	success, err := repository.UpdateModelConfig(id, updates, c.GetString("username"), changeNote)
+	if errors.Is(err, repository.ErrInvalidModelConfigUpdate) {
+		c.JSON(http.StatusBadRequest, gin.H{"detail": err.Error()})
+		return
+	}
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"detail": err.Error()})
 		return
@@ -271,6 +301,195 @@ func UpdateModelConfig(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"message": "Model config updated successfully"})
 }
 
+// GetModelConfigVersions returns the version history of a model config (admin)
+func GetModelConfigVersions(c *gin.Context) {
+	configID := c.Param("config_id")
+
+	var id int
+	if _, err := fmt.Sscanf(configID, "%d", &id); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"detail": "Invalid config ID"})
+		return
+	}
+
+	versions, err := repository.GetModelConfigVersions(id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"detail": err.Error()})
+		return
+	}
+	if len(versions) == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"detail": "Model config not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"versions": versions})
+}
+
+// GetModelConfigVersion returns one version of a model config (admin)
+func GetModelConfigVersion(c *gin.Context) {
+	var id, version int
+	if _, err := fmt.Sscanf(c.Param("config_id"), "%d", &id); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"detail": "Invalid config ID"})
+		return
+	}
+	if _, err := fmt.Sscanf(c.Param("version"), "%d", &version); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"detail": "Invalid version"})
+		return
+	}
+
+	v, err := repository.GetModelConfigVersion(id, version)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"detail": err.Error()})
+		return
+	}
+	if v == nil {
+		c.JSON(http.StatusNotFound, gin.H{"detail": "Model config version not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, v)
+}
+
+// DiffModelConfigVersions compares two versions of a model config (admin)
+func DiffModelConfigVersions(c *gin.Context) {
+	var id, from, to int
+	if _, err := fmt.Sscanf(c.Param("config_id"), "%d", &id); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"detail": "Invalid config ID"})
+		return
+	}
+	if _, err := fmt.Sscanf(c.Query("from"), "%d", &from); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"detail": "Invalid from version"})
+		return
+	}
+	if _, err := fmt.Sscanf(c.Query("to"), "%d", &to); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"detail": "Invalid to version"})
+		return
+	}
+
+	changes, found, err := repository.DiffModelConfigVersions(id, from, to)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"detail": err.Error()})
+		return
+	}
+	if !found {
+		c.JSON(http.StatusNotFound, gin.H{"detail": "Model config version not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"config_id": id,
+		"from":      from,
+		"to":        to,
+		"changes":   changes,
+	})
+}
+
+// RollbackModelConfig restores a model config to an earlier version (admin)
+func RollbackModelConfig(c *gin.Context) {
+	var id, version int
+	if _, err := fmt.Sscanf(c.Param("config_id"), "%d", &id); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"detail": "Invalid config ID"})
+		return
+	}
+	if _, err := fmt.Sscanf(c.Param("version"), "%d", &version); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"detail": "Invalid version"})
+		return
+	}
+
+	newVersion, found, err := repository.RollbackModelConfig(id, version, c.GetString("username"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"detail": err.Error()})
+		return
+	}
+	if !found {
+		c.JSON(http.StatusNotFound, gin.H{"detail": "Model config version not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":         fmt.Sprintf("Model config rolled back to version %d", version),
+		"current_version": newVersion,
+	})
+}
+
+// TestModelConfig probes every api_url of a model config (admin)
+func TestModelConfig(c *gin.Context) {
+	configID := c.Param("config_id")
+
+	var id int
+	if _, err := fmt.Sscanf(configID, "%d", &id); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"detail": "Invalid config ID"})
+		return
+	}
+
+	modelConfig, err := repository.GetModelConfigByID(id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"detail": err.Error()})
+		return
+	}
+	if modelConfig == nil {
+		c.JSON(http.StatusNotFound, gin.H{"detail": "Model config not found"})
+		return
+	}
+
+	health, err := service.CheckModelConfig(modelConfig)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"detail": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"config_id":     modelConfig.ID,
+		"model_name":    modelConfig.ModelName,
+		"health_status": service.AggregateHealthStatus(health),
+		"health":        health,
+	})
+}
+
+// DiscoverModels lists the models served by an OpenAI-compatible endpoint (admin)
+func DiscoverModels(c *gin.Context) {
+	var req model.ModelDiscoverRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"detail": err.Error()})
+		return
+	}
+
+	models, err := service.DiscoverModels(req.APIUrl, req.APIKey)
+	if err != nil {
+		c.JSON(http.StatusBadGateway, gin.H{"detail": fmt.Sprintf("Failed to list models: %v", err)})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"api_url": req.APIUrl,
+		"models":  models,
+	})
+}
+
+// ImportDiscoveredModels creates model configs for discovered models (admin)
+func ImportDiscoveredModels(c *gin.Context) {
+	var req model.ModelDiscoverImportRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"detail": err.Error()})
+		return
+	}
+	if len(req.ModelNames) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"detail": "model_names must not be empty"})
+		return
+	}
+
+	created, skipped, err := service.ImportDiscoveredModels(&req, c.GetString("username"))
+	if err != nil {
+		c.JSON(http.StatusBadGateway, gin.H{"detail": fmt.Sprintf("Failed to list models: %v", err)})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"created": created,
+		"skipped": skipped,
+		"message": fmt.Sprintf("Created %d model configs", len(created)),
+	})
+}
+
 // DeleteModelConfig deletes a model config (admin)
 func DeleteModelConfig(c *gin.Context) {
 	configID := c.Param("config_id")
@@ -293,3 +512,157 @@ func DeleteModelConfig(c *gin.Context) {
 
 	c.JSON(http.StatusOK, gin.H{"message": "Model config deleted successfully"})
 }
+
+// attachHealth fills in the stored health results of each model config
+func attachHealth(configs []model.ModelConfig) {
+	healthByConfig, err := repository.GetAllModelEndpointHealth()
+	if err != nil {
+		return
+	}
+
+	for i := range configs {
+		configs[i].Health = healthByConfig[configs[i].ID]
+		configs[i].HealthStatus = service.AggregateHealthStatus(configs[i].Health)
+	}
+}
+
+// attachTagsAndAliases fills in the tags and aliases of each model config
+func attachTagsAndAliases(configs []model.ModelConfig) {
+	tagsByConfig, err := repository.GetAllModelConfigTags()
+	if err != nil {
+		return
+	}
+	aliasesByConfig, err := repository.GetAllModelConfigAliases()
+	if err != nil {
+		return
+	}
+
+	for i := range configs {
+		configs[i].Tags = tagsByConfig[configs[i].ID]
+		if configs[i].Tags == nil {
+			configs[i].Tags = []string{}
+		}
+		configs[i].Aliases = aliasesByConfig[configs[i].ID]
+		if configs[i].Aliases == nil {
+			configs[i].Aliases = []string{}
+		}
+	}
+}
+
+// SetModelConfigTags replaces the tags of a model config (admin)
+func SetModelConfigTags(c *gin.Context) {
+	configID := c.Param("config_id")
+
+	var id int
+	if _, err := fmt.Sscanf(configID, "%d", &id); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"detail": "Invalid config ID"})
+		return
+	}
+
+	var req model.ModelConfigTagsSet
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"detail": err.Error()})
+		return
+	}
+
+	modelConfig, err := repository.GetModelConfigByID(id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"detail": err.Error()})
+		return
+	}
+	if modelConfig == nil {
+		c.JSON(http.StatusNotFound, gin.H{"detail": "Model config not found"})
+		return
+	}
+
+	tags := make([]string, 0, len(req.Tags))
+	for _, tag := range req.Tags {
+		if tag = strings.TrimSpace(tag); tag != "" {
+			tags = append(tags, tag)
+		}
+	}
+
+	if err := repository.SetModelConfigTags(id, tags); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"detail": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Model config tags updated successfully",
+		"tags":    tags,
+	})
+}
+
+// GetModelAliases returns all model aliases
+func GetModelAliases(c *gin.Context) {
+	aliases, err := repository.GetAllModelAliases()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"detail": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"aliases": aliases})
+}
+
+// SetModelAlias creates an alias or re-points it to another model config (admin)
+func SetModelAlias(c *gin.Context) {
+	alias := strings.TrimSpace(c.Param("alias"))
+	if alias == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"detail": "alias is required"})
+		return
+	}
+
+	var req model.ModelAliasSet
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"detail": err.Error()})
+		return
+	}
+
+	// Model names take precedence when resolving, so such an alias would never be used
+	existing, err := repository.GetModelConfigByName(alias)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"detail": err.Error()})
+		return
+	}
+	if existing != nil {
+		c.JSON(http.StatusConflict, gin.H{"detail": "Alias conflicts with an existing model name"})
+		return
+	}
+
+	modelConfig, err := repository.GetModelConfigByID(req.ConfigID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"detail": err.Error()})
+		return
+	}
+	if modelConfig == nil {
+		c.JSON(http.StatusNotFound, gin.H{"detail": "Model config not found"})
+		return
+	}
+
+	if err := repository.SetModelAlias(alias, req.ConfigID, c.GetString("username")); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"detail": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":    "Model alias updated successfully",
+		"alias":      alias,
+		"config_id":  modelConfig.ID,
+		"model_name": modelConfig.ModelName,
+	})
+}
+
+// DeleteModelAlias deletes a model alias (admin)
+func DeleteModelAlias(c *gin.Context) {
+	success, err := repository.DeleteModelAlias(c.Param("alias"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"detail": err.Error()})
+		return
+	}
+	if !success {
+		c.JSON(http.StatusNotFound, gin.H{"detail": "Model alias not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Model alias deleted successfully"})
+}
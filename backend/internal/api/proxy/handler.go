@@ -6,14 +6,23 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/wzyjerry/llm-judge/internal/model"
+	"github.com/wzyjerry/llm-judge/internal/pkg/config"
 	"github.com/wzyjerry/llm-judge/internal/pkg/redis"
+	"github.com/wzyjerry/llm-judge/internal/repository"
 	"go.uber.org/zap"
 )
 
+// PurposeJudge marks model calls made by LLM-as-judge scoring functions
+const PurposeJudge = "judge"
+
+// defaultJudgeMaxConcurrency is used when redis_service.judge_max_concurrency is not set
+const defaultJudgeMaxConcurrency = 4
+
 // ModelCallWithRateLimit handles model call with rate limiting
 func ModelCallWithRateLimit(c *gin.Context) {
 	// Read body for logging before binding
@@ -31,9 +40,13 @@ func ModelCallWithRateLimit(c *gin.Context) {
 		return
 	}
 
+	// Inject the stored API key so clients never need to hold provider keys
+	injectAPIKey(&req)
+
 	zap.L().Info("Model call request received",
 		zap.String("model", req.Model),
 		zap.String("api_url", req.APIUrl),
+		zap.String("purpose", req.Purpose),
 		zap.Int("max_tokens", req.MaxTokens))
 
 	result, err := performModelCall(&req)
@@ -54,24 +67,57 @@ func ModelCallWithRateLimit(c *gin.Context) {
 	})
 }
 
+// injectAPIKey replaces the request API key with the key stored in the model config, but only when
+// the request targets one of the config's API URLs so the key is never sent to a caller-chosen host
+func injectAPIKey(req *model.ModelCallRequest) {
+	modelConfig, err := repository.GetModelConfigByName(req.Model)
+	if err != nil {
+		zap.L().Warn("Failed to load model config for API key injection",
+			zap.String("model", req.Model),
+			zap.Error(err))
+		return
+	}
+	if modelConfig == nil || modelConfig.APIKey == "" {
+		return
+	}
+	if !isConfiguredURL(req.APIUrl, modelConfig.APIUrls) {
+		zap.L().Warn("Not injecting API key for an unconfigured API URL",
+			zap.String("model", req.Model),
+			zap.String("api_url", req.APIUrl))
+		return
+	}
+	req.APIKey = modelConfig.APIKey
+}
+
+// isConfiguredURL reports whether url is one of the configured API URLs, ignoring trailing slashes
+func isConfiguredURL(url string, configured []string) bool {
+	url = strings.TrimRight(url, "/")
+	for _, candidate := range configured {
+		if url != "" && strings.TrimRight(candidate, "/") == url {
+			return true
+		}
+	}
+	return false
+}
+
 // GetModelConcurrencyStatus returns model concurrency status
 func GetModelConcurrencyStatus(c *gin.Context) {
 	modelName := c.Param("model_name")
 
-	redisKey := fmt.Sprintf("model_concurrency:%s", modelName)
+	redisKey, maxConcurrency := concurrencyBudget(&model.ModelCallRequest{
+		Model:   modelName,
+		Purpose: c.Query("purpose"),
+	})
 
 	currentCount, err := redis.GetCurrentConcurrency(redisKey)
 	if err != nil {
 		c.JSON(http.StatusOK, model.ConcurrencyStatus{
-			Model:  modelName,
-			Error:  "Redis connection failed",
+			Model: modelName,
+			Error: "Redis connection failed",
 		})
 		return
 	}
 
-	// Get max concurrency from model config or use default
-	maxConcurrency := 10 // Default
-
 	c.JSON(http.StatusOK, model.ConcurrencyStatus{
 		Model:              modelName,
 		CurrentConcurrency: currentCount,
@@ -82,10 +128,7 @@ func GetModelConcurrencyStatus(c *gin.Context) {
 
 // performModelCall performs the actual model call with rate limiting
 func performModelCall(req *model.ModelCallRequest) (string, error) {
-	redisKey := fmt.Sprintf("model_concurrency:%s", req.Model)
-
-	// Get max concurrency (default 10)
-	maxConcurrency := 10
+	redisKey, maxConcurrency := concurrencyBudget(req)
 
 	// Try to acquire slot with timeout
 	maxWaitTime := 300 * time.Second
@@ -115,6 +158,21 @@ func performModelCall(req *model.ModelCallRequest) (string, error) {
 	return "", fmt.Errorf("timeout waiting for concurrency slot")
 }
 
+// concurrencyBudget returns the redis key and slot count a call is limited by.
+// Judge calls get their own budget so scoring does not starve inference of the same model.
+func concurrencyBudget(req *model.ModelCallRequest) (string, int) {
+	if req.Purpose == PurposeJudge {
+		maxConcurrency := defaultJudgeMaxConcurrency
+		if cfg := config.Get(); cfg != nil && cfg.RedisService.JudgeMaxConcurrency > 0 {
+			maxConcurrency = cfg.RedisService.JudgeMaxConcurrency
+		}
+		return fmt.Sprintf("judge_concurrency:%s", req.Model), maxConcurrency
+	}
+
+	// Get max concurrency (default 10)
+	return fmt.Sprintf("model_concurrency:%s", req.Model), 10
+}
+
 // callModelDirect calls the model API directly
 func callModelDirect(req *model.ModelCallRequest) (string, error) {
 	if req.IsVLLM {
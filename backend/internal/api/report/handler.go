@@ -4,9 +4,25 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"strings"
 
 	"github.com/gin-gonic/gin"
+	"github.com/wzyjerry/llm-judge/internal/model"
+	"github.com/wzyjerry/llm-judge/internal/pkg/config"
 	"github.com/wzyjerry/llm-judge/internal/repository"
+	"github.com/wzyjerry/llm-judge/internal/service"
+	"go.uber.org/zap"
+)
+
+const (
+	// maxCompareReports limits how many reports one compare request can load
+	maxCompareReports = 10
+	// defaultResultsLimit is the page size of report results when no limit is given
+	defaultResultsLimit = 50
+	// maxResultsLimit is the largest page size of report results
+	maxResultsLimit = 500
+	// maxFailureCategoryLength limits the failure category of a review
+	maxFailureCategoryLength = 64
 )
 
 // GetReports returns all reports for current user
@@ -54,6 +70,552 @@ func GetReportDetail(c *gin.Context) {
 	c.JSON(http.StatusOK, reportContent)
 }
 
+// GetReport returns report metadata by ID without the per-item results.
+// Reports with reviews also carry the summary recomputed with the review overrides.
+func GetReport(c *gin.Context) {
+	reportID := c.Param("report_id")
+
+	var id int
+	if _, err := fmt.Sscanf(reportID, "%d", &id); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"detail": "Invalid report ID"})
+		return
+	}
+
+	report, ok := loadReviewableReport(c, id)
+	if !ok {
+		return
+	}
+
+	reviewed, err := repository.GetReviewedSummary(id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"detail": err.Error()})
+		return
+	}
+	if reviewed.ReviewedCount > 0 {
+		report.Reviewed = reviewed
+	}
+
+	c.JSON(http.StatusOK, report)
+}
+
+// GetReportResults returns a filtered page of report results
+func GetReportResults(c *gin.Context) {
+	reportID := c.Param("report_id")
+
+	var id int
+	if _, err := fmt.Sscanf(reportID, "%d", &id); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"detail": "Invalid report ID"})
+		return
+	}
+
+	filter, err := parseReportItemFilter(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"detail": err.Error()})
+		return
+	}
+	filter.Limit = defaultResultsLimit
+	if offset := c.Query("offset"); offset != "" {
+		if _, err := fmt.Sscanf(offset, "%d", &filter.Offset); err != nil || filter.Offset < 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"detail": "Invalid offset"})
+			return
+		}
+	}
+	if limit := c.Query("limit"); limit != "" {
+		if _, err := fmt.Sscanf(limit, "%d", &filter.Limit); err != nil || filter.Limit <= 0 || filter.Limit > maxResultsLimit {
+			c.JSON(http.StatusBadRequest, gin.H{"detail": fmt.Sprintf("limit must be between 1 and %d", maxResultsLimit)})
+			return
+		}
+	}
+
+	// Also materializes the items of reports created before the per-item store
+	if _, ok := loadReviewableReport(c, id); !ok {
+		return
+	}
+
+	results, total, err := repository.GetReportItems(id, filter)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"detail": err.Error()})
+		return
+	}
+
+	indexes := make([]int, len(results))
+	for i := range results {
+		indexes[i] = results[i].Index
+	}
+	reviews, err := repository.GetLatestReportItemReviews(id, indexes)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"detail": err.Error()})
+		return
+	}
+	for i := range results {
+		results[i].Review = reviews[results[i].Index]
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"report_id": id,
+		"total":     total,
+		"offset":    filter.Offset,
+		"limit":     filter.Limit,
+		"results":   results,
+	})
+}
+
+// ReviewReportItem overrides the score or badcase flag of a report item with the current user's review
+func ReviewReportItem(c *gin.Context) {
+	id, itemIndex, ok := parseReportItemParams(c)
+	if !ok {
+		return
+	}
+
+	var req model.ReportItemReviewSet
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"detail": err.Error()})
+		return
+	}
+	req.Comment = strings.TrimSpace(req.Comment)
+	req.FailureCategory = strings.TrimSpace(req.FailureCategory)
+	if req.Score == nil && req.IsBadcase == nil && req.Comment == "" && req.FailureCategory == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"detail": "At least one of score, is_badcase, comment and failure_category is required"})
+		return
+	}
+	if req.IsBadcase != nil && *req.IsBadcase != 0 && *req.IsBadcase != 1 {
+		c.JSON(http.StatusBadRequest, gin.H{"detail": "is_badcase must be 0 or 1"})
+		return
+	}
+	if len(req.FailureCategory) > maxFailureCategoryLength {
+		c.JSON(http.StatusBadRequest, gin.H{"detail": fmt.Sprintf("failure_category must be at most %d characters", maxFailureCategoryLength)})
+		return
+	}
+
+	if _, ok := loadReviewableReport(c, id); !ok {
+		return
+	}
+	exists, err := repository.ReportItemExists(id, itemIndex)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"detail": err.Error()})
+		return
+	}
+	if !exists {
+		c.JSON(http.StatusNotFound, gin.H{"detail": "Report item not found"})
+		return
+	}
+
+	review, err := repository.SetReportItemReview(id, itemIndex, c.GetInt("user_id"), c.GetString("username"), &req)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"detail": err.Error()})
+		return
+	}
+
+	reviewed, err := repository.GetReviewedSummary(id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"detail": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"review": review, "reviewed_summary": reviewed})
+}
+
+// DeleteReportItemReview removes the current user's review of a report item
+func DeleteReportItemReview(c *gin.Context) {
+	id, itemIndex, ok := parseReportItemParams(c)
+	if !ok {
+		return
+	}
+
+	if _, ok := loadReviewableReport(c, id); !ok {
+		return
+	}
+
+	success, err := repository.DeleteReportItemReview(id, itemIndex, c.GetInt("user_id"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"detail": err.Error()})
+		return
+	}
+	if !success {
+		c.JSON(http.StatusNotFound, gin.H{"detail": "Review not found"})
+		return
+	}
+
+	reviewed, err := repository.GetReviewedSummary(id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"detail": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Review deleted successfully", "reviewed_summary": reviewed})
+}
+
+// GetReportReviews returns all reviews of a report with the reviewed summary and agreement statistics
+func GetReportReviews(c *gin.Context) {
+	reportID := c.Param("report_id")
+
+	var id int
+	if _, err := fmt.Sscanf(reportID, "%d", &id); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"detail": "Invalid report ID"})
+		return
+	}
+
+	if _, ok := loadReviewableReport(c, id); !ok {
+		return
+	}
+
+	reviews, err := repository.GetReportItemReviews(id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"detail": err.Error()})
+		return
+	}
+	autoFlags, err := repository.GetReportItemBadcaseFlags(id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"detail": err.Error()})
+		return
+	}
+	reviewed, err := repository.GetReviewedSummary(id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"detail": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"reviews":          reviews,
+		"reviewed_summary": reviewed,
+		"agreement":        service.ComputeReviewAgreement(reviews, autoFlags),
+	})
+}
+
+// loadReviewableReport loads a report the current user owns, or any report for admins.
+// It writes the error response and returns false when the report is not accessible.
+func loadReviewableReport(c *gin.Context, reportID int) (*model.ReportDetail, bool) {
+	userID := c.GetInt("user_id")
+	if config.IsAdmin(c.GetString("username")) {
+		ownerID, err := repository.GetReportOwnerID(reportID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"detail": err.Error()})
+			return nil, false
+		}
+		if ownerID != 0 {
+			userID = ownerID
+		}
+	}
+
+	report, err := repository.GetReportDetail(userID, reportID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"detail": err.Error()})
+		return nil, false
+	}
+	if report == nil {
+		c.JSON(http.StatusNotFound, gin.H{"detail": "Report not found or access denied"})
+		return nil, false
+	}
+
+	return report, true
+}
+
+// parseReportItemParams reads the report ID and item index path parameters
+func parseReportItemParams(c *gin.Context) (int, int, bool) {
+	var id, itemIndex int
+	if _, err := fmt.Sscanf(c.Param("report_id"), "%d", &id); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"detail": "Invalid report ID"})
+		return 0, 0, false
+	}
+	if _, err := fmt.Sscanf(c.Param("index"), "%d", &itemIndex); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"detail": "Invalid item index"})
+		return 0, 0, false
+	}
+	return id, itemIndex, true
+}
+
+// GetReportAggregates returns aggregates over the report results matching the filter
+func GetReportAggregates(c *gin.Context) {
+	userID := c.GetInt("user_id")
+	reportID := c.Param("report_id")
+
+	var id int
+	if _, err := fmt.Sscanf(reportID, "%d", &id); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"detail": "Invalid report ID"})
+		return
+	}
+
+	filter, err := parseReportItemFilter(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"detail": err.Error()})
+		return
+	}
+
+	report, err := repository.GetReportDetail(userID, id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"detail": err.Error()})
+		return
+	}
+	if report == nil {
+		c.JSON(http.StatusNotFound, gin.H{"detail": "Report not found or access denied"})
+		return
+	}
+
+	aggregate, err := repository.AggregateReportItems(id, filter)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"detail": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, aggregate)
+}
+
+// GetReportItemHistory returns the results of one dataset item across the current user's reports
+func GetReportItemHistory(c *gin.Context) {
+	userID := c.GetInt("user_id")
+	dataset := c.Query("dataset")
+
+	if dataset == "" || c.Query("original_index") == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"detail": "dataset and original_index are required"})
+		return
+	}
+
+	var originalIndex, expandedIndex int
+	if _, err := fmt.Sscanf(c.Query("original_index"), "%d", &originalIndex); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"detail": "Invalid original_index"})
+		return
+	}
+	if _, err := fmt.Sscanf(c.DefaultQuery("expanded_index", "0"), "%d", &expandedIndex); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"detail": "Invalid expanded_index"})
+		return
+	}
+
+	runs, err := repository.GetReportItemHistory(userID, dataset, c.Query("model"), originalIndex, expandedIndex)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"detail": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"runs": runs})
+}
+
+// parseReportItemFilter reads the report item filter from the query string
+func parseReportItemFilter(c *gin.Context) (model.ReportItemFilter, error) {
+	filter := model.ReportItemFilter{
+		BadcaseOnly: c.DefaultQuery("badcase_only", "false") == "true",
+		ErrorOnly:   c.DefaultQuery("error_only", "false") == "true",
+		Query:       c.Query("q"),
+	}
+
+	for _, bound := range []struct {
+		name  string
+		value **float64
+	}{
+		{"min_score", &filter.MinScore},
+		{"max_score", &filter.MaxScore},
+	} {
+		raw := c.Query(bound.name)
+		if raw == "" {
+			continue
+		}
+		var score float64
+		if _, err := fmt.Sscanf(raw, "%g", &score); err != nil {
+			return filter, fmt.Errorf("Invalid %s", bound.name)
+		}
+		*bound.value = &score
+	}
+
+	return filter, nil
+}
+
+// ExportReport downloads a report as CSV, XLSX, HTML, Markdown or JSONL
+func ExportReport(c *gin.Context) {
+	userID := c.GetInt("user_id")
+	reportID := c.Param("report_id")
+
+	var id int
+	if _, err := fmt.Sscanf(reportID, "%d", &id); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"detail": "Invalid report ID"})
+		return
+	}
+
+	formatName := c.Query("format")
+	format, ok := service.ExportFormats[formatName]
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"detail": "format must be one of csv, xlsx, html, md, jsonl"})
+		return
+	}
+	badcaseOnly := c.DefaultQuery("badcase_only", "false") == "true"
+
+	report, err := repository.GetReportDetail(userID, id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"detail": err.Error()})
+		return
+	}
+	if report == nil {
+		c.JSON(http.StatusNotFound, gin.H{"detail": "Report not found or access denied"})
+		return
+	}
+
+	filename := fmt.Sprintf("report_%d", report.ID)
+	if badcaseOnly {
+		filename += "_badcases"
+	}
+	c.Header("Content-Type", format.ContentType)
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="%s.%s"`, filename, format.Extension))
+	c.Status(http.StatusOK)
+
+	// Headers are already sent, so a failure can only be logged
+	if err := service.ExportReport(c.Writer, formatName, report, badcaseOnly); err != nil {
+		zap.L().Error("Failed to export report",
+			zap.Int("report_id", id),
+			zap.String("format", formatName),
+			zap.Error(err))
+	}
+}
+
+// CompareReports compares reports of the same dataset against the first listed one
+func CompareReports(c *gin.Context) {
+	userID := c.GetInt("user_id")
+
+	var ids []int
+	seen := make(map[int]bool)
+	for _, part := range strings.Split(c.Query("ids"), ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		var id int
+		if _, err := fmt.Sscanf(part, "%d", &id); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"detail": fmt.Sprintf("Invalid report ID: %s", part)})
+			return
+		}
+		if seen[id] {
+			c.JSON(http.StatusBadRequest, gin.H{"detail": fmt.Sprintf("Duplicate report ID: %d", id)})
+			return
+		}
+		seen[id] = true
+		ids = append(ids, id)
+	}
+
+	if len(ids) < 2 {
+		c.JSON(http.StatusBadRequest, gin.H{"detail": "ids must list at least two report IDs"})
+		return
+	}
+	if len(ids) > maxCompareReports {
+		c.JSON(http.StatusBadRequest, gin.H{"detail": fmt.Sprintf("At most %d reports can be compared", maxCompareReports)})
+		return
+	}
+
+	reports := make([]*model.UserReport, 0, len(ids))
+	for _, id := range ids {
+		report, err := repository.GetUserReportByID(userID, id)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"detail": err.Error()})
+			return
+		}
+		if report == nil {
+			c.JSON(http.StatusNotFound, gin.H{"detail": fmt.Sprintf("Report %d not found or access denied", id)})
+			return
+		}
+		if len(reports) > 0 && report.Dataset != reports[0].Dataset {
+			c.JSON(http.StatusBadRequest, gin.H{"detail": "Reports must be of the same dataset"})
+			return
+		}
+		reports = append(reports, report)
+	}
+
+	comparison, err := service.CompareRuns(reports)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"detail": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, comparison)
+}
+
+// RescoreReport starts a task that scores the stored outputs of a report with another scoring
+// function or badcase threshold, producing a new report linked to the original
+func RescoreReport(c *gin.Context) {
+	userID := c.GetInt("user_id")
+	reportID := c.Param("report_id")
+
+	var id int
+	if _, err := fmt.Sscanf(reportID, "%d", &id); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"detail": "Invalid report ID"})
+		return
+	}
+
+	var config service.RescoreConfig
+	if err := c.ShouldBindJSON(&config); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"detail": err.Error()})
+		return
+	}
+	if config.Scoring == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"detail": "scoring is required"})
+		return
+	}
+	if config.MaxWorkers < 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"detail": "max_workers must be positive"})
+		return
+	}
+
+	report, err := repository.GetReportDetail(userID, id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"detail": err.Error()})
+		return
+	}
+	if report == nil {
+		c.JSON(http.StatusNotFound, gin.H{"detail": "Report not found or access denied"})
+		return
+	}
+	if report.ItemCount == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"detail": "Report has no results to rescore"})
+		return
+	}
+
+	// Keep the threshold of the original run unless a new one is given
+	if config.BadcaseThreshold == nil {
+		threshold := 1.0
+		if value, ok := report.Config["badcase_threshold"].(float64); ok {
+			threshold = value
+		}
+		config.BadcaseThreshold = &threshold
+	}
+	// Keep the judge of the original run unless another one is given; the runner only calls it
+	// from scoring functions that use a judge
+	if config.JudgeModel == "" {
+		if judgeModel, ok := report.Config["judge_model"].(string); ok && judgeModel != "" {
+			config.JudgeModel = judgeModel
+			if config.JudgePromptTemplate == "" {
+				config.JudgePromptTemplate, _ = report.Config["judge_prompt_template"].(string)
+			}
+			if scale, ok := report.Config["judge_scale"].(float64); ok && config.JudgeScale == 0 {
+				config.JudgeScale = int(scale)
+			}
+		}
+	}
+	if config.JudgeModel != "" {
+		if err := service.ResolveJudgeConfig(&config.JudgeConfig); err != nil {
+			c.JSON(service.JudgeErrorStatus(err), gin.H{"detail": err.Error()})
+			return
+		}
+	}
+
+	if config.ScoringModule == "" {
+		config.ScoringModule = service.DefaultScoringModule
+	}
+	if config.MaxWorkers == 0 {
+		config.MaxWorkers = 4
+	}
+	if config.ReportFormat == "" {
+		config.ReportFormat = "json, txt, badcases"
+	}
+
+	taskID, err := service.StartRescore(userID, report, &config)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"detail": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"task_id":          taskID,
+		"parent_report_id": report.ID,
+		"status":           "pending",
+		"message":          "Rescore task created",
+	})
+}
+
 // DeleteReport deletes a report
 func DeleteReport(c *gin.Context) {
 	userID := c.GetInt("user_id")
@@ -77,3 +639,64 @@ func DeleteReport(c *gin.Context) {
 
 	c.JSON(http.StatusOK, gin.H{"message": "Report deleted successfully"})
 }
+
+// GetComparisonReports returns all comparison reports for current user
+func GetComparisonReports(c *gin.Context) {
+	userID := c.GetInt("user_id")
+
+	reports, err := repository.GetComparisonReports(userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"detail": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"comparisons": reports})
+}
+
+// GetComparisonReport returns a comparison report with per-item side-by-side outputs
+func GetComparisonReport(c *gin.Context) {
+	userID := c.GetInt("user_id")
+	comparisonID := c.Param("comparison_id")
+
+	var id int
+	if _, err := fmt.Sscanf(comparisonID, "%d", &id); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"detail": "Invalid comparison ID"})
+		return
+	}
+
+	report, err := repository.GetComparisonReportByID(userID, id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"detail": err.Error()})
+		return
+	}
+	if report == nil {
+		c.JSON(http.StatusNotFound, gin.H{"detail": "Comparison report not found or access denied"})
+		return
+	}
+
+	c.JSON(http.StatusOK, report)
+}
+
+// DeleteComparisonReport deletes a comparison report
+func DeleteComparisonReport(c *gin.Context) {
+	userID := c.GetInt("user_id")
+	comparisonID := c.Param("comparison_id")
+
+	var id int
+	if _, err := fmt.Sscanf(comparisonID, "%d", &id); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"detail": "Invalid comparison ID"})
+		return
+	}
+
+	success, err := repository.DeleteComparisonReport(userID, id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"detail": err.Error()})
+		return
+	}
+	if !success {
+		c.JSON(http.StatusNotFound, gin.H{"detail": "Comparison report not found or access denied"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Comparison report deleted successfully"})
+}
@@ -6,10 +6,12 @@ import (
 	"github.com/wzyjerry/llm-judge/internal/api/auth"
 	"github.com/wzyjerry/llm-judge/internal/api/data"
 	databaseapi "github.com/wzyjerry/llm-judge/internal/api/database"
+	"github.com/wzyjerry/llm-judge/internal/api/leaderboard"
 	"github.com/wzyjerry/llm-judge/internal/api/model"
 	"github.com/wzyjerry/llm-judge/internal/api/proxy"
 	"github.com/wzyjerry/llm-judge/internal/api/report"
 	"github.com/wzyjerry/llm-judge/internal/api/task"
+	"github.com/wzyjerry/llm-judge/internal/api/team"
 )
 
 // SetupDatabaseRoutes configures database service routes
@@ -52,9 +54,33 @@ func SetupRouter(r *gin.Engine) {
 			dataGroup.GET("", data.GetDataFiles)
 			dataGroup.POST("", data.UploadData)
 			dataGroup.POST("/validate-csv", data.ValidateCSV)
+			dataGroup.POST("/import/preview", data.PreviewDataImport)
+			dataGroup.GET("/export", data.ExportDataBundle)
+			dataGroup.GET("/shared", data.GetSharedDataFiles)
+			dataGroup.GET("/library", data.GetDataLibrary)
+			dataGroup.POST("/merge", data.MergeData)
 			dataGroup.GET("/:data_id/content", data.GetDataContent)
+			dataGroup.GET("/:data_id/download", data.DownloadData)
 			dataGroup.PUT("/:data_id", data.UpdateDataInfo)
 			dataGroup.DELETE("/:data_id", data.DeleteData)
+			dataGroup.PUT("/:data_id/edit", data.EditDataContent)
+			dataGroup.PUT("/:data_id/edit-item", data.EditDataItem)
+			dataGroup.DELETE("/:data_id/items", data.DeleteDataItems)
+			dataGroup.DELETE("/:data_id/items/:index", data.DeleteDataItem)
+			dataGroup.POST("/:data_id/items", data.AddDataItem)
+			dataGroup.POST("/:data_id/append", data.AppendData)
+			dataGroup.GET("/:data_id/validate", data.ValidateData)
+			dataGroup.GET("/:data_id/stats", data.GetDataStats)
+			dataGroup.POST("/:data_id/sample", data.SampleData)
+			dataGroup.POST("/:data_id/split", data.SplitData)
+			dataGroup.POST("/:data_id/dedupe", data.DedupeData)
+			dataGroup.GET("/:data_id/versions", data.GetDataVersions)
+			dataGroup.GET("/:data_id/versions/diff", data.DiffDataVersions)
+			dataGroup.GET("/:data_id/versions/:version", data.GetDataVersion)
+			dataGroup.POST("/:data_id/versions/:version/rollback", data.RollbackData)
+			dataGroup.GET("/:data_id/shares", data.GetDataShares)
+			dataGroup.PUT("/:data_id/shares", data.ShareData)
+			dataGroup.DELETE("/:data_id/shares/:share_id", data.DeleteDataShare)
 		}
 
 		// Data files list (for frontend compatibility)
@@ -78,13 +104,42 @@ func SetupRouter(r *gin.Engine) {
 		{
 			reportGroup.GET("", report.GetReports)
 			reportGroup.GET("/detail", report.GetReportDetail)
+			reportGroup.GET("/compare", report.CompareReports)
+			reportGroup.GET("/:report_id", report.GetReport)
+			reportGroup.GET("/:report_id/results", report.GetReportResults)
+			reportGroup.GET("/:report_id/aggregates", report.GetReportAggregates)
+			reportGroup.GET("/:report_id/export", report.ExportReport)
+			reportGroup.POST("/:report_id/rescore", report.RescoreReport)
+			reportGroup.GET("/:report_id/reviews", report.GetReportReviews)
+			reportGroup.PUT("/:report_id/items/:index/review", report.ReviewReportItem)
+			reportGroup.DELETE("/:report_id/items/:index/review", report.DeleteReportItemReview)
+			reportGroup.GET("/items/history", report.GetReportItemHistory)
 			reportGroup.DELETE("/:report_id", report.DeleteReport)
+			reportGroup.GET("/comparisons", report.GetComparisonReports)
+			reportGroup.GET("/comparisons/:comparison_id", report.GetComparisonReport)
+			reportGroup.DELETE("/comparisons/:comparison_id", report.DeleteComparisonReport)
 		}
 
+		// Leaderboards across models and datasets
+		api.GET("/leaderboard", leaderboard.GetLeaderboard)
+		api.GET("/leaderboard/history", leaderboard.GetLeaderboardHistory)
+		leaderboardGroup := api.Group("/leaderboards")
+		{
+			leaderboardGroup.GET("", leaderboard.GetSavedLeaderboards)
+			leaderboardGroup.POST("", leaderboard.CreateSavedLeaderboard)
+			leaderboardGroup.GET("/:leaderboard_id", leaderboard.GetSavedLeaderboard)
+			leaderboardGroup.PUT("/:leaderboard_id", leaderboard.UpdateSavedLeaderboard)
+			leaderboardGroup.DELETE("/:leaderboard_id", leaderboard.DeleteSavedLeaderboard)
+		}
+
+		// Teams of the current user
+		api.GET("/teams", team.GetMyTeams)
+
 		// Model and scoring functions
 		api.GET("/scoring-functions", model.GetScoringFunctions)
 		api.GET("/models", model.GetAvailableModels)
 		api.GET("/model-configs", model.GetModelConfigs)
+		api.GET("/model-aliases", model.GetModelAliases)
 
 		// Model call proxy (with authentication)
 		api.POST("/model-call", proxy.ModelCallWithRateLimit)
@@ -100,12 +155,31 @@ func SetupRouter(r *gin.Engine) {
 			adminGroup.POST("/tasks/:task_id/terminate", admin.TerminateTask)
 			adminGroup.GET("/data", admin.GetAllData)
 			adminGroup.DELETE("/users/:user_id/data/:data_id", admin.DeleteUserData)
+			adminGroup.PUT("/data/:data_id/library", admin.AddDataToLibrary)
+			adminGroup.DELETE("/data/:data_id/library", admin.RemoveDataFromLibrary)
 
 			// Model config management (admin)
 			adminGroup.GET("/model-configs", model.AdminGetModelConfigs)
 			adminGroup.POST("/model-configs", model.CreateModelConfig)
 			adminGroup.PUT("/model-configs/:config_id", model.UpdateModelConfig)
 			adminGroup.DELETE("/model-configs/:config_id", model.DeleteModelConfig)
+			adminGroup.POST("/model-configs/:config_id/test", model.TestModelConfig)
+			adminGroup.GET("/model-configs/:config_id/versions", model.GetModelConfigVersions)
+			adminGroup.GET("/model-configs/:config_id/versions/diff", model.DiffModelConfigVersions)
+			adminGroup.GET("/model-configs/:config_id/versions/:version", model.GetModelConfigVersion)
+			adminGroup.POST("/model-configs/:config_id/versions/:version/rollback", model.RollbackModelConfig)
+			adminGroup.POST("/model-configs/discover", model.DiscoverModels)
+			adminGroup.POST("/model-configs/discover/import", model.ImportDiscoveredModels)
+			adminGroup.PUT("/model-configs/:config_id/tags", model.SetModelConfigTags)
+			adminGroup.PUT("/model-aliases/:alias", model.SetModelAlias)
+			adminGroup.DELETE("/model-aliases/:alias", model.DeleteModelAlias)
+
+			// Team management (admin)
+			adminGroup.GET("/teams", team.GetTeams)
+			adminGroup.POST("/teams", team.CreateTeam)
+			adminGroup.DELETE("/teams/:team_id", team.DeleteTeam)
+			adminGroup.POST("/teams/:team_id/members", team.AddTeamMember)
+			adminGroup.DELETE("/teams/:team_id/members/:user_id", team.RemoveTeamMember)
 		}
 	}
 }
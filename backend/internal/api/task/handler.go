@@ -1,14 +1,20 @@
 package task
 
 import (
+	"fmt"
 	"net/http"
+	"strings"
 
 	"github.com/gin-gonic/gin"
+	"github.com/wzyjerry/llm-judge/internal/model"
+	appconfig "github.com/wzyjerry/llm-judge/internal/pkg/config"
 	"github.com/wzyjerry/llm-judge/internal/repository"
 	"github.com/wzyjerry/llm-judge/internal/service"
 )
 
-// StartEvaluation starts an evaluation task
+// StartEvaluation starts an evaluation task.
+// The model may be given by name or alias; when it is empty, model_tags selects a group
+// of models and one task is started per model.
 func StartEvaluation(c *gin.Context) {
 	userID := c.GetInt("user_id")
 
@@ -19,8 +25,8 @@ func StartEvaluation(c *gin.Context) {
 	}
 
 	// Validate required fields
-	if config.Model == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"detail": "model is required"})
+	if config.Model == "" && len(config.ModelTags) == 0 && len(config.Models) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"detail": "model, models or model_tags is required"})
 		return
 	}
 	if config.DataFile == "" {
@@ -32,17 +38,239 @@ func StartEvaluation(c *gin.Context) {
 		return
 	}
 
-	// Load model config from database to get API URLs and other settings
-	modelConfig, err := repository.GetModelConfigByName(config.Model)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"detail": "Failed to load model config"})
+	// Pin the data version so later edits of the file do not change what the task evaluates
+	if status, err := pinDataVersion(userID, &config); err != nil {
+		c.JSON(status, gin.H{"detail": err.Error()})
+		return
+	}
+
+	// Resolve the judge model used by LLM-as-judge scoring functions
+	if config.JudgeModel != "" {
+		status, err := applyJudgeConfig(&config)
+		if err != nil {
+			c.JSON(status, gin.H{"detail": err.Error()})
+			return
+		}
+	}
+
+	// Compare several models in one task
+	if len(config.Models) > 0 {
+		startComparison(c, userID, config)
+		return
+	}
+
+	// Load model configs from database to get API URLs and other settings
+	var modelConfigs []model.ModelConfig
+	if config.Model != "" {
+		modelConfig, err := repository.ResolveModelConfig(config.Model)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"detail": "Failed to load model config"})
+			return
+		}
+		if modelConfig == nil {
+			c.JSON(http.StatusNotFound, gin.H{"detail": "Model config not found for: " + config.Model})
+			return
+		}
+		if modelConfig.ModelName != config.Model {
+			config.ModelAlias = config.Model
+		}
+		config.ModelTags = nil
+		modelConfigs = append(modelConfigs, *modelConfig)
+	} else {
+		configs, err := repository.GetModelConfigsByTags(config.ModelTags, false)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"detail": "Failed to load model configs"})
+			return
+		}
+		if len(configs) == 0 {
+			c.JSON(http.StatusNotFound, gin.H{"detail": "No active model config carries tags: " + strings.Join(config.ModelTags, ", ")})
+			return
+		}
+		// Per-request API URLs cannot apply to a group of different models
+		config.APIUrls = nil
+		modelConfigs = configs
+	}
+
+	var warnings []string
+	var tasks, failed []gin.H
+	for i := range modelConfigs {
+		modelConfig := &modelConfigs[i]
+		taskConfig := config
+		taskConfig.Model = modelConfig.ModelName
+
+		// Refuse or warn when every endpoint of the model failed its last health check
+		if health, err := repository.GetModelEndpointHealth(modelConfig.ID); err == nil {
+			if service.AggregateHealthStatus(health) == service.HealthDown {
+				if cfg := appconfig.Get(); cfg != nil && cfg.ModelHealth.BlockWhenDown {
+					if len(modelConfigs) == 1 {
+						c.JSON(http.StatusServiceUnavailable, gin.H{"detail": "All endpoints of model " + taskConfig.Model + " are down"})
+						return
+					}
+					warnings = append(warnings, "Skipped model "+taskConfig.Model+": all endpoints are down")
+					continue
+				}
+				warnings = append(warnings, "All endpoints of model "+taskConfig.Model+" failed the last health check")
+			}
+		}
+
+		applyModelConfig(&taskConfig, modelConfig)
+
+		// Start evaluation
+		taskID, err := service.StartEvaluation(userID, &taskConfig)
+		if err != nil {
+			if len(modelConfigs) == 1 {
+				c.JSON(http.StatusInternalServerError, gin.H{"detail": err.Error()})
+				return
+			}
+			// Tasks already started keep running, so report them with the models that failed
+			failed = append(failed, gin.H{"model": taskConfig.Model, "error": err.Error()})
+			continue
+		}
+		tasks = append(tasks, gin.H{"task_id": taskID, "model": taskConfig.Model})
+	}
+
+	if len(tasks) == 0 {
+		if len(failed) > 0 {
+			c.JSON(http.StatusInternalServerError, gin.H{"detail": "No evaluation task could be started", "failed": failed, "warnings": warnings})
+			return
+		}
+		c.JSON(http.StatusServiceUnavailable, gin.H{"detail": "All selected models are down", "warnings": warnings})
+		return
+	}
+
+	response := gin.H{
+		"task_id": tasks[0]["task_id"],
+		"status":  "pending",
+		"message": "Evaluation task created",
+	}
+	if len(config.ModelTags) > 0 {
+		response["tasks"] = tasks
+		response["message"] = fmt.Sprintf("%d evaluation tasks created", len(tasks))
+		if len(failed) > 0 {
+			response["failed"] = failed
+			response["message"] = fmt.Sprintf("%d evaluation tasks created, %d failed to start", len(tasks), len(failed))
+		}
+	}
+	if len(warnings) > 0 {
+		response["warnings"] = warnings
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// startComparison starts one task that evaluates every model in config.Models on the same data
+func startComparison(c *gin.Context, userID int, config service.EvaluationConfig) {
+	seen := make(map[string]bool)
+	var configs []*service.EvaluationConfig
+	var warnings []string
+	for _, name := range config.Models {
+		modelConfig, err := repository.ResolveModelConfig(name)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"detail": "Failed to load model config"})
+			return
+		}
+		if modelConfig == nil {
+			c.JSON(http.StatusNotFound, gin.H{"detail": "Model config not found for: " + name})
+			return
+		}
+		if seen[modelConfig.ModelName] {
+			continue
+		}
+		seen[modelConfig.ModelName] = true
+
+		// Refuse or warn when every endpoint of the model failed its last health check
+		if health, err := repository.GetModelEndpointHealth(modelConfig.ID); err == nil {
+			if service.AggregateHealthStatus(health) == service.HealthDown {
+				if cfg := appconfig.Get(); cfg != nil && cfg.ModelHealth.BlockWhenDown {
+					c.JSON(http.StatusServiceUnavailable, gin.H{"detail": "All endpoints of model " + modelConfig.ModelName + " are down"})
+					return
+				}
+				warnings = append(warnings, "All endpoints of model "+modelConfig.ModelName+" failed the last health check")
+			}
+		}
+
+		// Each model uses its own endpoints and sampling settings, everything else is shared
+		taskConfig := config
+		taskConfig.Model = modelConfig.ModelName
+		taskConfig.ModelAlias = ""
+		if modelConfig.ModelName != name {
+			taskConfig.ModelAlias = name
+		}
+		taskConfig.Models = nil
+		taskConfig.ModelTags = nil
+		taskConfig.APIUrls = nil
+		applyModelConfig(&taskConfig, modelConfig)
+		configs = append(configs, &taskConfig)
+	}
+
+	if len(configs) < 2 {
+		c.JSON(http.StatusBadRequest, gin.H{"detail": "models must contain at least two different models"})
 		return
 	}
-	if modelConfig == nil {
-		c.JSON(http.StatusNotFound, gin.H{"detail": "Model config not found for: " + config.Model})
+
+	taskID, err := service.StartComparativeEvaluation(userID, configs)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"detail": err.Error()})
 		return
 	}
 
+	response := gin.H{
+		"task_id": taskID,
+		"status":  "pending",
+		"message": fmt.Sprintf("Comparative evaluation of %d models created", len(configs)),
+	}
+	if len(warnings) > 0 {
+		response["warnings"] = warnings
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// pinDataVersion checks that the user may read the data file, their own, shared with them or in the
+// benchmark library, and sets the data version the task evaluates: the given data_version, or the
+// current version of the file.
+// On failure it returns the HTTP status to respond with.
+func pinDataVersion(userID int, config *service.EvaluationConfig) (int, error) {
+	var dataID int
+	if _, err := fmt.Sscanf(config.DataFile, "%d", &dataID); err != nil {
+		return http.StatusBadRequest, fmt.Errorf("Invalid data_file")
+	}
+
+	data, err := repository.GetUserDataAccess(userID, dataID)
+	if err != nil {
+		return http.StatusInternalServerError, fmt.Errorf("Failed to load data file")
+	}
+	if data == nil {
+		return http.StatusNotFound, fmt.Errorf("Data file not found or access denied")
+	}
+
+	if config.DataVersion == 0 {
+		config.DataVersion = data.CurrentVersion
+		return http.StatusOK, nil
+	}
+
+	version, err := repository.GetUserDataVersion(dataID, config.DataVersion)
+	if err != nil {
+		return http.StatusInternalServerError, fmt.Errorf("Failed to load data version")
+	}
+	if version == nil {
+		return http.StatusNotFound, fmt.Errorf("Data version %d not found", config.DataVersion)
+	}
+
+	return http.StatusOK, nil
+}
+
+// applyJudgeConfig resolves the judge model and fills in its endpoints and limits.
+// On failure it returns the HTTP status to respond with.
+func applyJudgeConfig(config *service.EvaluationConfig) (int, error) {
+	if err := service.ResolveJudgeConfig(&config.JudgeConfig); err != nil {
+		return service.JudgeErrorStatus(err), err
+	}
+	return http.StatusOK, nil
+}
+
+// applyModelConfig fills in the task settings that were not given explicitly from the model config
+func applyModelConfig(config *service.EvaluationConfig, modelConfig *model.ModelConfig) {
 	// Use API URLs from database config if not provided
 	if len(config.APIUrls) == 0 && len(modelConfig.APIUrls) > 0 {
 		// Convert []string to []interface{}
@@ -52,11 +280,9 @@ func StartEvaluation(c *gin.Context) {
 		}
 	}
 
-	// Use API key from database config if not provided
-	if config.APIKey == "" || config.APIKey == "sk-xxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxx" {
-		if modelConfig.APIKey != "" {
-			config.APIKey = modelConfig.APIKey
-		}
+	// The stored API key is injected by the model call proxy, never handed to the runner
+	if modelConfig.APIKey != "" {
+		config.APIKey = ""
 	}
 
 	// Set default values
@@ -76,7 +302,7 @@ func StartEvaluation(c *gin.Context) {
 		config.MaxTokens = modelConfig.MaxTokens
 	}
 	if config.ScoringModule == "" {
-		config.ScoringModule = "./function_register/plugin.py"
+		config.ScoringModule = service.DefaultScoringModule
 	}
 	if config.APIKey == "" {
 		config.APIKey = "sk-xxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxx"
@@ -92,18 +318,9 @@ func StartEvaluation(c *gin.Context) {
 	// Use is_vllm from model config (convert int to bool)
 	config.IsVLLM = modelConfig.IsVLLM == 1
 
-	// Start evaluation
-	taskID, err := service.StartEvaluation(userID, &config)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"detail": err.Error()})
-		return
-	}
-
-	c.JSON(http.StatusOK, gin.H{
-		"task_id": taskID,
-		"status":  "pending",
-		"message": "Evaluation task created",
-	})
+	// Record the exact model config version the task runs with
+	config.ModelConfigID = modelConfig.ID
+	config.ModelConfigVersion = modelConfig.CurrentVersion
 }
 
 // GetTaskStatus returns task status
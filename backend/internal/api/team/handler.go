@@ -0,0 +1,183 @@
+package team
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/wzyjerry/llm-judge/internal/model"
+	"github.com/wzyjerry/llm-judge/internal/repository"
+)
+
+// GetMyTeams returns the teams of the current user
+func GetMyTeams(c *gin.Context) {
+	userID := c.GetInt("user_id")
+
+	teams, err := repository.GetUserTeams(userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"detail": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"teams": teams})
+}
+
+// GetTeams returns all teams (admin)
+func GetTeams(c *gin.Context) {
+	teams, err := repository.GetTeams()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"detail": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"teams": teams})
+}
+
+// CreateTeam creates a team with its initial members (admin)
+func CreateTeam(c *gin.Context) {
+	var req model.TeamCreate
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"detail": err.Error()})
+		return
+	}
+
+	req.Name = strings.TrimSpace(req.Name)
+	if req.Name == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"detail": "name is required"})
+		return
+	}
+
+	exists, err := repository.TeamNameExists(req.Name)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"detail": err.Error()})
+		return
+	}
+	if exists {
+		c.JSON(http.StatusBadRequest, gin.H{"detail": "Team name already exists"})
+		return
+	}
+
+	for _, userID := range req.UserIDs {
+		user, err := repository.GetUserByID(userID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"detail": err.Error()})
+			return
+		}
+		if user == nil {
+			c.JSON(http.StatusBadRequest, gin.H{"detail": fmt.Sprintf("User %d not found", userID)})
+			return
+		}
+	}
+
+	teamID, err := repository.CreateTeam(&req, c.GetString("username"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"detail": err.Error()})
+		return
+	}
+
+	team, err := repository.GetTeamByID(teamID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"detail": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, team)
+}
+
+// DeleteTeam deletes a team (admin)
+func DeleteTeam(c *gin.Context) {
+	teamID := c.Param("team_id")
+
+	var id int
+	if _, err := fmt.Sscanf(teamID, "%d", &id); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"detail": "Invalid team ID"})
+		return
+	}
+
+	success, err := repository.DeleteTeam(id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"detail": err.Error()})
+		return
+	}
+	if !success {
+		c.JSON(http.StatusNotFound, gin.H{"detail": "Team not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Team deleted successfully"})
+}
+
+// AddTeamMember adds a user to a team (admin)
+func AddTeamMember(c *gin.Context) {
+	teamID := c.Param("team_id")
+
+	var id int
+	if _, err := fmt.Sscanf(teamID, "%d", &id); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"detail": "Invalid team ID"})
+		return
+	}
+
+	var req model.TeamMemberAdd
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"detail": err.Error()})
+		return
+	}
+
+	team, err := repository.GetTeamByID(id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"detail": err.Error()})
+		return
+	}
+	if team == nil {
+		c.JSON(http.StatusNotFound, gin.H{"detail": "Team not found"})
+		return
+	}
+
+	user, err := repository.GetUserByID(req.UserID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"detail": err.Error()})
+		return
+	}
+	if user == nil {
+		c.JSON(http.StatusNotFound, gin.H{"detail": "User not found"})
+		return
+	}
+
+	added, err := repository.AddTeamMember(id, req.UserID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"detail": err.Error()})
+		return
+	}
+	if !added {
+		c.JSON(http.StatusBadRequest, gin.H{"detail": "User is already a member of the team"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Team member added successfully"})
+}
+
+// RemoveTeamMember removes a user from a team (admin)
+func RemoveTeamMember(c *gin.Context) {
+	var id, userID int
+	if _, err := fmt.Sscanf(c.Param("team_id"), "%d", &id); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"detail": "Invalid team ID"})
+		return
+	}
+	if _, err := fmt.Sscanf(c.Param("user_id"), "%d", &userID); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"detail": "Invalid user ID"})
+		return
+	}
+
+	success, err := repository.RemoveTeamMember(id, userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"detail": err.Error()})
+		return
+	}
+	if !success {
+		c.JSON(http.StatusNotFound, gin.H{"detail": "Team member not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Team member removed successfully"})
+}
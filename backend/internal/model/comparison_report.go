@@ -0,0 +1,73 @@
+package model
+
+// ReportResult represents one evaluated item in report_content.results
+type ReportResult struct {
+	Index           int                    `json:"index"`
+	OriginalIndex   int                    `json:"original_index"`
+	ExpandedIndex   int                    `json:"expanded_index"`
+	UserInput       interface{}            `json:"user_input"`
+	ModelOutput     string                 `json:"model_output"`
+	ReferenceOutput interface{}            `json:"reference_output"`
+	InferenceTime   float64                `json:"inference_time"`
+	Score           float64                `json:"score"`
+	IsBadcase       int                    `json:"is_badcase"`
+	Details         map[string]interface{} `json:"details,omitempty"`
+	Error           string                 `json:"error,omitempty"`
+	Meta            map[string]interface{} `json:"meta,omitempty"`   // meta fields of the dataset item
+	Review          *ReportItemReview      `json:"review,omitempty"` // latest human review, not part of report_content
+}
+
+// ComparisonReport represents a side-by-side report of several models evaluated in one task
+type ComparisonReport struct {
+	ID        int               `json:"id" db:"id"`
+	UserID    int               `json:"user_id" db:"user_id"`
+	TaskID    string            `json:"task_id" db:"task_id"`
+	Dataset   string            `json:"dataset" db:"dataset"`
+	Models    []string          `json:"models" db:"models"`           // JSON array
+	Summary   ComparisonSummary `json:"summary" db:"summary"`         // JSON
+	Items     []ComparisonItem  `json:"items,omitempty" db:"content"` // JSON, omitted in lists
+	CreatedAt string            `json:"created_at" db:"created_at"`
+}
+
+// ComparisonSummary holds the per-model summaries and pairwise win/tie/loss counts
+type ComparisonSummary struct {
+	ItemCount      int                               `json:"item_count"`
+	ComparedCount  int                               `json:"compared_count"` // items evaluated by every model
+	ReportIDs      map[string]int                    `json:"report_ids"`
+	ModelSummaries map[string]map[string]interface{} `json:"model_summaries"`
+	Records        map[string]WinTieLoss             `json:"records"` // totals over all opponents
+	Pairwise       []PairwiseResult                  `json:"pairwise"`
+}
+
+// WinTieLoss counts item-level outcomes of a model
+type WinTieLoss struct {
+	Wins   int `json:"wins"`
+	Ties   int `json:"ties"`
+	Losses int `json:"losses"`
+}
+
+// PairwiseResult holds the outcomes of model A against model B
+type PairwiseResult struct {
+	ModelA string `json:"model_a"`
+	ModelB string `json:"model_b"`
+	WinTieLoss
+}
+
+// ComparisonItem holds the outputs of every model for one dataset item
+type ComparisonItem struct {
+	OriginalIndex   int                         `json:"original_index"`
+	ExpandedIndex   int                         `json:"expanded_index"`
+	UserInput       interface{}                 `json:"user_input"`
+	ReferenceOutput interface{}                 `json:"reference_output"`
+	Outputs         map[string]ComparisonOutput `json:"outputs"`
+	BestModels      []string                    `json:"best_models"` // models sharing the top score
+}
+
+// ComparisonOutput holds one model's result for a comparison item
+type ComparisonOutput struct {
+	ModelOutput   string  `json:"model_output"`
+	Score         float64 `json:"score"`
+	IsBadcase     int     `json:"is_badcase"`
+	InferenceTime float64 `json:"inference_time"`
+	Error         string  `json:"error,omitempty"`
+}
@@ -0,0 +1,78 @@
+package model
+
+import "github.com/wzyjerry/llm-judge/internal/pkg/stats"
+
+// DataStatistics profiles the items of one version of a data file. Versions never change,
+// so the statistics are computed once and cached.
+type DataStatistics struct {
+	DataID          int                            `json:"data_id"`
+	Version         int                            `json:"version"`
+	ComputedAt      string                         `json:"computed_at"`
+	ItemCount       int                            `json:"item_count"`
+	ParseErrorCount int                            `json:"parse_error_count"` // lines that are not JSON objects
+	Turns           TurnCountStatistics            `json:"turns"`
+	Roles           map[string]RoleStatistics      `json:"roles"` // per lowercased turn role
+	Duplicates      DuplicateStatistics            `json:"duplicates"`
+	Languages       map[string]int                 `json:"languages"` // items per detected language
+	MetaFields      map[string]MetaFieldStatistics `json:"meta_fields"`
+}
+
+// TurnCountStatistics describes how many turns the items have
+type TurnCountStatistics struct {
+	Mean         float64           `json:"mean"`
+	Min          int               `json:"min"`
+	Max          int               `json:"max"`
+	Distribution []TurnCountBucket `json:"distribution"` // ordered by turn count
+}
+
+// TurnCountBucket counts the items with the same number of turns
+type TurnCountBucket struct {
+	Turns int `json:"turns"`
+	Items int `json:"items"`
+}
+
+// RoleStatistics describes the text lengths of the turns of one role
+type RoleStatistics struct {
+	TurnCount  int                `json:"turn_count"`
+	Characters LengthDistribution `json:"characters"`
+	Tokens     LengthDistribution `json:"tokens"` // estimated, see service.EstimateTokens
+}
+
+// LengthDistribution describes a distribution of text lengths
+type LengthDistribution struct {
+	Mean        float64              `json:"mean"`
+	Min         float64              `json:"min"`
+	Max         float64              `json:"max"`
+	Percentiles map[string]float64   `json:"percentiles"` // p10, p25, p50, p75, p90
+	Histogram   []stats.HistogramBin `json:"histogram"`
+}
+
+// DuplicateStatistics lists items repeating an earlier item. Exact duplicates are identical items,
+// prompt duplicates share the system prompt and user turns but may differ in the other turns.
+type DuplicateStatistics struct {
+	ExactCount       int              `json:"exact_count"`   // items identical to an earlier one
+	PromptCount      int              `json:"prompt_count"`  // items with the prompt of an earlier one
+	ExactGroups      []DuplicateGroup `json:"exact_groups"`  // the first 50 groups
+	PromptGroups     []DuplicateGroup `json:"prompt_groups"` // the first 50 groups
+	ExactGroupCount  int              `json:"exact_group_count"`
+	PromptGroupCount int              `json:"prompt_group_count"`
+}
+
+// DuplicateGroup holds the 0-based indices of items that duplicate each other
+type DuplicateGroup struct {
+	Indices []int `json:"indices"`
+}
+
+// MetaFieldStatistics is the histogram of the values of one meta field
+type MetaFieldStatistics struct {
+	Present       int                  `json:"present"` // items having the field
+	DistinctCount int                  `json:"distinct_count"`
+	Values        []MetaValueCount     `json:"values"`              // the 50 most frequent values
+	Histogram     []stats.HistogramBin `json:"histogram,omitempty"` // when every value is a number
+}
+
+// MetaValueCount counts the items with one value of a meta field
+type MetaValueCount struct {
+	Value string `json:"value"`
+	Count int    `json:"count"`
+}
@@ -49,7 +49,7 @@ type Message struct {
 // ModelCallRequest represents model call request
 type ModelCallRequest struct {
 	APIUrl      string              `json:"api_url" binding:"required"`
-	APIKey      string              `json:"api_key" binding:"required"`
+	APIKey      string              `json:"api_key"` // replaced by the stored key of the model config
 	Messages    []map[string]string `json:"messages" binding:"required"`
 	Model       string              `json:"model" binding:"required"`
 	Temperature float64             `json:"temperature"`
@@ -57,6 +57,7 @@ type ModelCallRequest struct {
 	Timeout     int                 `json:"timeout"`
 	IsVLLM      bool                `json:"is_vllm"`
 	TopP        float64             `json:"top_p"`
+	Purpose     string              `json:"purpose"` // "judge" for judge calls, which have their own concurrency budget
 }
 
 // ModelCallResponse represents model call response
@@ -0,0 +1,91 @@
+package model
+
+// LeaderboardFilter selects and ranks the reports of a leaderboard
+type LeaderboardFilter struct {
+	Mode    string   `json:"mode"`              // "latest" or "best" report per model and dataset
+	Metric  string   `json:"metric"`            // summary metric to rank by, e.g. accuracy
+	Scoring string   `json:"scoring,omitempty"` // only reports scored with this function
+	From    string   `json:"from,omitempty"`    // RFC3339 or YYYY-MM-DD, inclusive
+	To      string   `json:"to,omitempty"`      // RFC3339 or YYYY-MM-DD, inclusive
+	Tags    []string `json:"tags,omitempty"`    // only models whose config carries every tag
+	Dataset string   `json:"dataset,omitempty"` // only this dataset
+}
+
+// LeaderboardReport holds the report fields a leaderboard is built from
+type LeaderboardReport struct {
+	ID        int                    `json:"id"`
+	UserID    int                    `json:"user_id"`
+	Username  string                 `json:"username"`
+	TaskID    string                 `json:"task_id"`
+	Dataset   string                 `json:"dataset"`
+	Model     string                 `json:"model"`
+	Scoring   string                 `json:"scoring"`
+	Summary   map[string]interface{} `json:"summary"`
+	CreatedAt string                 `json:"created_at"`
+}
+
+// LeaderboardEntry is the selected report of one model on one dataset
+type LeaderboardEntry struct {
+	Dataset   string                 `json:"dataset"`
+	Model     string                 `json:"model"`
+	Rank      int                    `json:"rank"` // rank of the model on the dataset, 1 is best
+	Value     float64                `json:"value"`
+	ReportID  int                    `json:"report_id"`
+	TaskID    string                 `json:"task_id"`
+	Username  string                 `json:"username"`
+	Scoring   string                 `json:"scoring"`
+	Summary   map[string]interface{} `json:"summary"`
+	CreatedAt string                 `json:"created_at"`
+	RunCount  int                    `json:"run_count"` // matching reports of the model on the dataset
+}
+
+// Leaderboard ranks models on each dataset
+type Leaderboard struct {
+	Filter   LeaderboardFilter  `json:"filter"`
+	Datasets []string           `json:"datasets"`
+	Models   []string           `json:"models"`
+	Best     map[string]string  `json:"best"` // best model of each dataset
+	Entries  []LeaderboardEntry `json:"entries"`
+}
+
+// LeaderboardPoint is one report in the score history of a model on a dataset
+type LeaderboardPoint struct {
+	ReportID   int      `json:"report_id"`
+	TaskID     string   `json:"task_id"`
+	Username   string   `json:"username"`
+	Scoring    string   `json:"scoring"`
+	CreatedAt  string   `json:"created_at"`
+	Value      float64  `json:"value"`
+	Delta      *float64 `json:"delta"` // change from the previous point, nil for the first one
+	Regression bool     `json:"regression"`
+}
+
+// LeaderboardHistory is the score history of a model on a dataset, oldest first
+type LeaderboardHistory struct {
+	Model       string             `json:"model"`
+	Dataset     string             `json:"dataset"`
+	Metric      string             `json:"metric"`
+	Threshold   float64            `json:"regression_threshold"`
+	Points      []LeaderboardPoint `json:"points"`
+	Regressions int                `json:"regressions"`
+}
+
+// SavedLeaderboard represents a stored leaderboard definition, optionally shared with a team
+type SavedLeaderboard struct {
+	ID        int               `json:"id" db:"id"`
+	Name      string            `json:"name" db:"name"`
+	OwnerID   int               `json:"owner_id" db:"owner_id"`
+	Owner     string            `json:"owner" db:"owner"`
+	TeamID    *int              `json:"team_id" db:"team_id"`
+	TeamName  string            `json:"team_name,omitempty" db:"team_name"`
+	Filters   LeaderboardFilter `json:"filters" db:"filters"` // JSON
+	CreatedAt string            `json:"created_at" db:"created_at"`
+	UpdatedAt string            `json:"updated_at" db:"updated_at"`
+}
+
+// SavedLeaderboardCreate represents create leaderboard request
+type SavedLeaderboardCreate struct {
+	Name    string            `json:"name" binding:"required"`
+	TeamID  *int              `json:"team_id"`
+	Filters LeaderboardFilter `json:"filters"`
+}
@@ -2,34 +2,45 @@ package model
 
 // ModelConfig represents a model configuration
 type ModelConfig struct {
-	ID             int       `json:"id" db:"id"`
-	ModelName      string    `json:"model_name" db:"model_name"`
-	APIUrls        []string  `json:"api_urls" db:"api_urls"` // JSON array
-	APIKey         string    `json:"api_key" db:"api_key"`
-	Temperature    float64   `json:"temperature" db:"temperature"`
-	TopP           float64   `json:"top_p" db:"top_p"`
-	MaxTokens      int       `json:"max_tokens" db:"max_tokens"`
-	Timeout        int       `json:"timeout" db:"timeout"`
-	MaxConcurrency int       `json:"max_concurrency" db:"max_concurrency"`
-	Description    string    `json:"description" db:"description"`
-	IsActive       int       `json:"is_active" db:"is_active"` // 0 or 1
-	IsVLLM         int       `json:"is_vllm" db:"is_vllm"`     // 0 or 1, default 1
-	CreatedAt      string    `json:"created_at" db:"created_at"`
-	UpdatedAt      string    `json:"updated_at" db:"updated_at"`
+	ID             int      `json:"id" db:"id"`
+	ModelName      string   `json:"model_name" db:"model_name"`
+	APIUrls        []string `json:"api_urls" db:"api_urls"` // JSON array
+	APIKey         string   `json:"-" db:"api_key"`         // decrypted, never serialized
+	MaskedAPIKey   string   `json:"api_key" db:"-"`         // masked for responses
+	Temperature    float64  `json:"temperature" db:"temperature"`
+	TopP           float64  `json:"top_p" db:"top_p"`
+	MaxTokens      int      `json:"max_tokens" db:"max_tokens"`
+	Timeout        int      `json:"timeout" db:"timeout"`
+	MaxConcurrency int      `json:"max_concurrency" db:"max_concurrency"`
+	Description    string   `json:"description" db:"description"`
+	IsActive       int      `json:"is_active" db:"is_active"` // 0 or 1
+	IsVLLM         int      `json:"is_vllm" db:"is_vllm"`     // 0 or 1, default 1
+	CreatedAt      string   `json:"created_at" db:"created_at"`
+	UpdatedAt      string   `json:"updated_at" db:"updated_at"`
+
+	CurrentVersion    int    `json:"current_version" db:"current_version"`
+	UpstreamMissing   int    `json:"upstream_missing" db:"upstream_missing"` // 1 if the model disappeared from /v1/models
+	UpstreamCheckedAt string `json:"upstream_checked_at" db:"upstream_checked_at"`
+
+	Tags    []string `json:"tags"`    // e.g. "family:qwen", "size:7b"
+	Aliases []string `json:"aliases"` // alias names that resolve to this config
+
+	HealthStatus string                `json:"health_status,omitempty"` // up, degraded, down, unknown
+	Health       []ModelEndpointHealth `json:"health,omitempty"`
 }
 
 // ModelConfigCreate represents create model config request
 type ModelConfigCreate struct {
-	ModelName      string    `json:"model_name" binding:"required"`
-	APIUrls        []string  `json:"api_urls" binding:"required"`
-	APIKey         string    `json:"api_key"`
-	Temperature    float64   `json:"temperature"`
-	TopP           float64   `json:"top_p"`
-	MaxTokens      int       `json:"max_tokens"`
-	Timeout        int       `json:"timeout"`
-	MaxConcurrency int       `json:"max_concurrency"`
-	Description    string    `json:"description"`
-	IsVLLM         int       `json:"is_vllm"` // 0 or 1, default 1
+	ModelName      string   `json:"model_name" binding:"required"`
+	APIUrls        []string `json:"api_urls" binding:"required"`
+	APIKey         string   `json:"api_key"`
+	Temperature    float64  `json:"temperature"`
+	TopP           float64  `json:"top_p"`
+	MaxTokens      int      `json:"max_tokens"`
+	Timeout        int      `json:"timeout"`
+	MaxConcurrency int      `json:"max_concurrency"`
+	Description    string   `json:"description"`
+	IsVLLM         int      `json:"is_vllm"` // 0 or 1, default 1
 }
 
 // ModelConfigUpdate represents update model config request
@@ -46,3 +57,89 @@ type ModelConfigUpdate struct {
 	IsActive       *int      `json:"is_active,omitempty"`
 	IsVLLM         *int      `json:"is_vllm,omitempty"`
 }
+
+// ModelEndpointHealth represents the latest health check result of one api_url
+type ModelEndpointHealth struct {
+	ConfigID       int    `json:"config_id" db:"config_id"`
+	APIUrl         string `json:"api_url" db:"api_url"`
+	Status         string `json:"status" db:"status"` // up, down
+	LatencyMs      int64  `json:"latency_ms" db:"latency_ms"`
+	ModelAvailable bool   `json:"model_available" db:"model_available"` // model listed by /v1/models
+	Error          string `json:"error,omitempty" db:"error"`
+	CheckedAt      string `json:"checked_at" db:"checked_at"`
+}
+
+// UpstreamModel represents a model listed by an OpenAI-compatible /v1/models endpoint
+type UpstreamModel struct {
+	ID          string `json:"id"`
+	OwnedBy     string `json:"owned_by,omitempty"`
+	MaxModelLen int    `json:"max_model_len,omitempty"` // reported by vLLM
+	Exists      bool   `json:"exists"`                  // a model config with this name already exists
+	ConfigID    int    `json:"config_id,omitempty"`
+}
+
+// ModelDiscoverRequest represents a request to list models served by an endpoint
+type ModelDiscoverRequest struct {
+	APIUrl string `json:"api_url" binding:"required"`
+	APIKey string `json:"api_key"`
+}
+
+// ModelDiscoverImportRequest represents a request to create configs for discovered models
+type ModelDiscoverImportRequest struct {
+	APIUrl      string   `json:"api_url" binding:"required"`
+	APIKey      string   `json:"api_key"`
+	ModelNames  []string `json:"model_names" binding:"required"`
+	Description string   `json:"description"`
+	IsVLLM      *int     `json:"is_vllm,omitempty"` // detected from owned_by when omitted
+}
+
+// ModelConfigSnapshot is the content of a model config recorded by one version
+type ModelConfigSnapshot struct {
+	ModelName      string   `json:"model_name"`
+	APIUrls        []string `json:"api_urls"`
+	APIKey         string   `json:"api_key"` // encrypted when stored, masked in responses
+	Temperature    float64  `json:"temperature"`
+	TopP           float64  `json:"top_p"`
+	MaxTokens      int      `json:"max_tokens"`
+	Timeout        int      `json:"timeout"`
+	MaxConcurrency int      `json:"max_concurrency"`
+	Description    string   `json:"description"`
+	IsActive       int      `json:"is_active"`
+	IsVLLM         int      `json:"is_vllm"`
+}
+
+// ModelConfigVersion represents an immutable version of a model config
+type ModelConfigVersion struct {
+	ConfigID   int                 `json:"config_id" db:"config_id"`
+	Version    int                 `json:"version" db:"version"`
+	Snapshot   ModelConfigSnapshot `json:"snapshot" db:"snapshot"` // JSON
+	Author     string              `json:"author" db:"author"`
+	ChangeNote string              `json:"change_note" db:"change_note"`
+	CreatedAt  string              `json:"created_at" db:"created_at"`
+}
+
+// ModelConfigFieldChange represents a field that differs between two versions
+type ModelConfigFieldChange struct {
+	Field string      `json:"field"`
+	From  interface{} `json:"from"`
+	To    interface{} `json:"to"`
+}
+
+// ModelAlias represents an alias name that resolves to a model config
+type ModelAlias struct {
+	Alias     string `json:"alias" db:"alias"`
+	ConfigID  int    `json:"config_id" db:"config_id"`
+	ModelName string `json:"model_name"`
+	UpdatedBy string `json:"updated_by" db:"updated_by"`
+	UpdatedAt string `json:"updated_at" db:"updated_at"`
+}
+
+// ModelAliasSet represents a request to create or re-point an alias
+type ModelAliasSet struct {
+	ConfigID int `json:"config_id" binding:"required"`
+}
+
+// ModelConfigTagsSet represents a request to replace the tags of a model config
+type ModelConfigTagsSet struct {
+	Tags []string `json:"tags"`
+}
@@ -0,0 +1,46 @@
+package model
+
+// ReportItemReview represents a reviewer's override of an automatically scored report item
+type ReportItemReview struct {
+	ID              int      `json:"id" db:"id"`
+	ReportID        int      `json:"report_id" db:"report_id"`
+	ItemIndex       int      `json:"item_index" db:"item_index"`
+	ReviewerID      int      `json:"reviewer_id" db:"reviewer_id"`
+	Reviewer        string   `json:"reviewer" db:"reviewer"`
+	Score           *float64 `json:"score" db:"score"`           // nil keeps the automatic score
+	IsBadcase       *int     `json:"is_badcase" db:"is_badcase"` // nil keeps the automatic flag
+	Comment         string   `json:"comment" db:"comment"`
+	FailureCategory string   `json:"failure_category" db:"failure_category"`
+	CreatedAt       string   `json:"created_at" db:"created_at"`
+	UpdatedAt       string   `json:"updated_at" db:"updated_at"`
+}
+
+// ReportItemReviewSet represents a review request for a report item
+type ReportItemReviewSet struct {
+	Score           *float64 `json:"score"`
+	IsBadcase       *int     `json:"is_badcase"`
+	Comment         string   `json:"comment"`
+	FailureCategory string   `json:"failure_category"`
+}
+
+// ReviewedSummary recomputes report metrics with the latest review of each item overriding the scorer
+type ReviewedSummary struct {
+	TotalCount      int     `json:"total_count"`
+	CorrectCount    int     `json:"correct_count"`
+	Accuracy        float64 `json:"accuracy"`
+	AverageScore    float64 `json:"average_score"`
+	ReviewedCount   int     `json:"reviewed_count"`   // items with at least one review
+	OverriddenCount int     `json:"overridden_count"` // reviewed items whose score or flag changed
+	FlippedCount    int     `json:"flipped_count"`    // reviewed items whose badcase flag changed
+}
+
+// ReviewAgreement holds agreement statistics of reviewers with the scorer and with each other
+type ReviewAgreement struct {
+	LabeledCount       int            `json:"labeled_count"`        // items whose latest review sets is_badcase
+	ScorerAgreement    float64        `json:"scorer_agreement"`     // share of those matching the scorer
+	ScorerKappa        *float64       `json:"scorer_kappa"`         // Cohen's kappa, nil when undefined
+	MultiReviewedCount int            `json:"multi_reviewed_count"` // items labeled by two or more reviewers
+	ReviewerPairs      int            `json:"reviewer_pairs"`
+	ReviewerAgreement  *float64       `json:"reviewer_agreement"` // share of agreeing reviewer pairs
+	FailureCategories  map[string]int `json:"failure_categories"`
+}
@@ -0,0 +1,59 @@
+package model
+
+import "github.com/wzyjerry/llm-judge/internal/pkg/stats"
+
+// ReportStatistics holds the statistics computed from the results of a report when it is stored.
+// It is saved as summary.statistics whatever the scoring function.
+type ReportStatistics struct {
+	Count          int                    `json:"count"`
+	Score          ScoreStatistics        `json:"score"`
+	Accuracy       AccuracyStatistics     `json:"accuracy"`
+	Latency        LatencyStatistics      `json:"latency"`
+	Errors         ErrorStatistics        `json:"errors"`
+	MetaBreakdowns map[string][]MetaGroup `json:"meta_breakdowns"` // per meta field, one group per value
+}
+
+// ScoreStatistics describes the score distribution of the items scored without error
+type ScoreStatistics struct {
+	Count       int                  `json:"count"`
+	Mean        float64              `json:"mean"`
+	StdDev      float64              `json:"std_dev"`
+	Min         float64              `json:"min"`
+	Max         float64              `json:"max"`
+	Percentiles map[string]float64   `json:"percentiles"` // p10, p25, p50, p75, p90
+	Histogram   []stats.HistogramBin `json:"histogram"`
+	MeanCI      stats.BootstrapCI    `json:"mean_ci"`
+}
+
+// AccuracyStatistics holds the share of items that are not badcases with its confidence interval
+type AccuracyStatistics struct {
+	Value float64           `json:"value"`
+	CI    stats.BootstrapCI `json:"ci"`
+}
+
+// LatencyStatistics describes the inference time in seconds of the items that got a model output
+type LatencyStatistics struct {
+	Count int     `json:"count"`
+	Mean  float64 `json:"mean"`
+	P50   float64 `json:"p50"`
+	P95   float64 `json:"p95"`
+	P99   float64 `json:"p99"`
+	Max   float64 `json:"max"`
+}
+
+// ErrorStatistics counts the items that failed, grouped by error type
+type ErrorStatistics struct {
+	Count  int            `json:"count"`
+	Rate   float64        `json:"rate"`
+	ByType map[string]int `json:"by_type"` // e.g. timeout, rate_limit, connection, http_error, scoring
+}
+
+// MetaGroup holds the metrics of the items sharing one value of a meta field
+type MetaGroup struct {
+	Value        string  `json:"value"`
+	Count        int     `json:"count"`
+	BadcaseCount int     `json:"badcase_count"`
+	ErrorCount   int     `json:"error_count"`
+	Accuracy     float64 `json:"accuracy"`
+	AverageScore float64 `json:"average_score"`
+}
@@ -0,0 +1,50 @@
+package model
+
+import "github.com/wzyjerry/llm-judge/internal/pkg/stats"
+
+// RunComparison compares several reports of the same dataset against the first one as baseline
+type RunComparison struct {
+	Baseline    RunInfo    `json:"baseline"`
+	Comparisons []RunDelta `json:"comparisons"`
+}
+
+// RunInfo identifies a compared report
+type RunInfo struct {
+	ReportID  int                    `json:"report_id"`
+	TaskID    string                 `json:"task_id"`
+	Dataset   string                 `json:"dataset"`
+	Model     string                 `json:"model"`
+	Timestamp string                 `json:"timestamp"`
+	ItemCount int                    `json:"item_count"`
+	Summary   map[string]interface{} `json:"summary"`
+}
+
+// RunDelta holds the differences between a report and the baseline on their aligned items
+type RunDelta struct {
+	Run          RunInfo                `json:"run"`
+	AlignedCount int                    `json:"aligned_count"` // items present in both reports
+	Metrics      map[string]MetricDelta `json:"metrics"`
+	Regressions  []RunItemChange        `json:"regressions"`  // passing in baseline, failing now
+	Improvements []RunItemChange        `json:"improvements"` // failing in baseline, passing now
+	McNemar      stats.McNemarResult    `json:"mcnemar"`      // accuracy difference
+	ScoreDiffCI  stats.BootstrapCI      `json:"score_diff_ci"`
+}
+
+// MetricDelta holds a metric of the baseline and the compared report
+type MetricDelta struct {
+	Baseline float64 `json:"baseline"`
+	Current  float64 `json:"current"`
+	Delta    float64 `json:"delta"`
+}
+
+// RunItemChange holds an item whose pass/fail outcome changed between two reports
+type RunItemChange struct {
+	OriginalIndex   int         `json:"original_index"`
+	ExpandedIndex   int         `json:"expanded_index"`
+	UserInput       interface{} `json:"user_input"`
+	ReferenceOutput interface{} `json:"reference_output"`
+	BaselineOutput  string      `json:"baseline_output"`
+	BaselineScore   float64     `json:"baseline_score"`
+	CurrentOutput   string      `json:"current_output"`
+	CurrentScore    float64     `json:"current_score"`
+}
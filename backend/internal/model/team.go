@@ -0,0 +1,30 @@
+package model
+
+// Team represents a group of users that share leaderboards
+type Team struct {
+	ID          int          `json:"id" db:"id"`
+	Name        string       `json:"name" db:"name"`
+	Description string       `json:"description" db:"description"`
+	CreatedBy   string       `json:"created_by" db:"created_by"`
+	CreatedAt   string       `json:"created_at" db:"created_at"`
+	Members     []TeamMember `json:"members"`
+}
+
+// TeamMember represents a user in a team
+type TeamMember struct {
+	UserID   int    `json:"user_id" db:"user_id"`
+	Username string `json:"username" db:"username"`
+	JoinedAt string `json:"joined_at" db:"created_at"`
+}
+
+// TeamCreate represents create team request
+type TeamCreate struct {
+	Name        string `json:"name" binding:"required"`
+	Description string `json:"description"`
+	UserIDs     []int  `json:"user_ids"` // initial members
+}
+
+// TeamMemberAdd represents a request to add a user to a team
+type TeamMemberAdd struct {
+	UserID int `json:"user_id" binding:"required"`
+}
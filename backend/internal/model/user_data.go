@@ -6,10 +6,115 @@ type UserData struct {
 	UserID      int    `json:"user_id" db:"user_id"`
 	Filename    string `json:"filename" db:"filename"`
 	Description string `json:"description" db:"description"`
-	FileContent string `json:"-" db:"file_content"` // Don't serialize large content
+	FileContent string `json:"-" db:"file_content"` // Don't serialize large content; empty once moved to blob storage
 	FileSize    int    `json:"file_size" db:"file_size"`
 	CreatedAt   string `json:"created_at" db:"created_at"`
 	UpdatedAt   string `json:"updated_at" db:"updated_at"`
+	// CurrentVersion is the version holding the current content
+	CurrentVersion int    `json:"current_version" db:"current_version"`
+	BlobKey        string `json:"-" db:"blob_key"`            // key of the content in blob storage
+	Checksum       string `json:"checksum" db:"checksum"`     // hex SHA-256 of the content
+	ItemCount      int    `json:"item_count" db:"item_count"` // non-empty JSONL lines
+	IsPublic       bool   `json:"is_public" db:"is_public"`   // listed in the benchmark library, readable by every user
+	// Owner and Permission describe a data file accessed by another user than its owner
+	Owner      string          `json:"owner,omitempty"`
+	Permission string          `json:"permission,omitempty"`                 // read, write or owner
+	Provenance *DataProvenance `json:"provenance,omitempty" db:"provenance"` // JSON, set for derived data files
+}
+
+// DataProvenance describes how a data file was derived from other data files
+type DataProvenance struct {
+	Operation string                 `json:"operation"` // sample, split, merge or dedupe
+	Sources   []DataProvenanceSource `json:"sources"`
+	Params    map[string]interface{} `json:"params"`         // operation parameters, including the seed used
+	Part      string                 `json:"part,omitempty"` // the part of a split the file holds
+	CreatedBy string                 `json:"created_by"`
+	CreatedAt string                 `json:"created_at"`
+}
+
+// DataProvenanceSource is a version of a data file a derived data file was made from
+type DataProvenanceSource struct {
+	DataID    int    `json:"data_id"`
+	Version   int    `json:"version"`
+	Filename  string `json:"filename"`
+	Checksum  string `json:"checksum"`
+	ItemCount int    `json:"item_count"`
+}
+
+// DerivedDataFile is a data file produced by a dataset operation, stored in blob storage
+type DerivedDataFile struct {
+	Filename    string
+	Description string
+	Blob        *DataBlob
+	Provenance  *DataProvenance
+}
+
+// Permissions of a user on a data file. Readers may view, download and evaluate it, writers may
+// also change its content and description, and only the owner may delete or share it.
+const (
+	DataPermissionRead  = "read"
+	DataPermissionWrite = "write"
+	DataPermissionOwner = "owner"
+)
+
+// UserDataShare grants a user, or every member of a team, a permission on a data file
+type UserDataShare struct {
+	ID         int    `json:"id" db:"id"`
+	DataID     int    `json:"data_id" db:"data_id"`
+	UserID     *int   `json:"user_id" db:"user_id"`
+	Username   string `json:"username,omitempty"`
+	TeamID     *int   `json:"team_id" db:"team_id"`
+	TeamName   string `json:"team_name,omitempty"`
+	Permission string `json:"permission" db:"permission"` // read or write
+	CreatedBy  string `json:"created_by" db:"created_by"`
+	CreatedAt  string `json:"created_at" db:"created_at"`
+}
+
+// UserDataShareCreate represents a request to share a data file with either a user, given by
+// user_id or username, or a team. Sharing again with the same user or team replaces its permission.
+type UserDataShareCreate struct {
+	UserID     *int   `json:"user_id"`
+	Username   string `json:"username"`
+	TeamID     *int   `json:"team_id"`
+	Permission string `json:"permission" binding:"required,oneof=read write"`
+}
+
+// DataBlob locates a data file content stored in blob storage
+type DataBlob struct {
+	Key       string
+	Size      int
+	Checksum  string // hex SHA-256 of the content
+	ItemCount int
+}
+
+// UserDataVersion represents an immutable version of a data file's content
+type UserDataVersion struct {
+	DataID      int    `json:"data_id" db:"data_id"`
+	Version     int    `json:"version" db:"version"`
+	FileContent string `json:"-" db:"file_content"` // empty once moved to blob storage
+	BlobKey     string `json:"-" db:"blob_key"`
+	Checksum    string `json:"checksum" db:"checksum"`
+	FileSize    int    `json:"file_size" db:"file_size"`
+	ItemCount   int    `json:"item_count" db:"item_count"`
+	Author      string `json:"author" db:"author"`
+	Message     string `json:"message" db:"message"` // optional commit message
+	CreatedAt   string `json:"created_at" db:"created_at"`
+}
+
+// DataItemChange represents an item that was added or removed between two versions
+type DataItemChange struct {
+	Index int         `json:"index"` // position in the version the item belongs to
+	Item  interface{} `json:"item"`
+}
+
+// UserDataVersionDiff represents the items that differ between two versions of a data file.
+// An edited item shows up as removed from the old version and added to the new one.
+type UserDataVersionDiff struct {
+	From           int              `json:"from"`
+	To             int              `json:"to"`
+	Added          []DataItemChange `json:"added"`
+	Removed        []DataItemChange `json:"removed"`
+	UnchangedCount int              `json:"unchanged_count"`
 }
 
 // UserDataCreate represents create user data request
@@ -30,30 +135,84 @@ type UserDataContentUpdate struct {
 	FileContent string `json:"file_content" binding:"required"`
 }
 
-// DataEditRequest represents data edit request
+// DataEditRequest represents data edit request.
+// A single edit changes the item at item_index, a batch edit every item. turn_text edits
+// the turns matching role and turn_index. When find_text is set, its occurrences are
+// replaced by new_value instead of overwriting the whole field, otherwise new_value is required.
 type DataEditRequest struct {
-	EditType  string  `json:"edit_type" binding:"required,oneof=single batch"`
-	ItemIndex *int    `json:"item_index,omitempty"`
-	FieldType string  `json:"field_type" binding:"required"`
-	Role      string  `json:"role,omitempty"`
-	TurnIndex *int    `json:"turn_index,omitempty"`
-	NewValue  string  `json:"new_value" binding:"required"`
+	EditType  string `json:"edit_type" binding:"required,oneof=single batch"`
+	ItemIndex *int   `json:"item_index,omitempty"`
+	FieldType string `json:"field_type" binding:"required,oneof=meta_description turn_text"`
+	Role      string `json:"role,omitempty"`
+	TurnIndex *int   `json:"turn_index,omitempty"`
+	FindText  string `json:"find_text,omitempty"`
+	NewValue  string `json:"new_value"`
+	Message   string `json:"message,omitempty"` // commit message of the new version
 }
 
 // SingleItemEditRequest represents single item edit request
 type SingleItemEditRequest struct {
-	ItemIndex  int                    `json:"item_index" binding:"required"`
+	ItemIndex  *int                   `json:"item_index" binding:"required"`
 	EditedItem map[string]interface{} `json:"edited_item" binding:"required"`
+	Message    string                 `json:"message,omitempty"`
 }
 
 // BatchDeleteItemsRequest represents batch delete items request
 type BatchDeleteItemsRequest struct {
-	ItemIndices []int `json:"item_indices" binding:"required"`
+	ItemIndices []int  `json:"item_indices" binding:"required"`
+	Message     string `json:"message,omitempty"`
 }
 
-// AddItemRequest represents add single item request
+// AddItemRequest represents add single item request.
+// Role is the evaluation role the item is validated for, "assistant" when empty.
 type AddItemRequest struct {
 	NewItem map[string]interface{} `json:"new_item" binding:"required"`
+	Role    string                 `json:"role,omitempty"`
+	Message string                 `json:"message,omitempty"`
+}
+
+// DataSampleRequest draws a random sample of a data file as a new data file. With stratify_by,
+// every value of that meta field keeps its share of the items.
+type DataSampleRequest struct {
+	Size        int     `json:"size"`        // items to draw
+	Fraction    float64 `json:"fraction"`    // share of the items to draw when size is not given
+	StratifyBy  string  `json:"stratify_by"` // meta field
+	Seed        *int64  `json:"seed"`        // random when not given
+	Version     int     `json:"version"`     // source version, the current one when 0
+	Filename    string  `json:"filename"`
+	Description string  `json:"description"`
+}
+
+// DataSplitRequest splits a data file into new data files, one per part, sized by the part ratios.
+// With stratify_by, every value of that meta field is split in the same ratios.
+type DataSplitRequest struct {
+	Parts      []DataSplitPart `json:"parts" binding:"required,min=2,dive"`
+	StratifyBy string          `json:"stratify_by"`
+	Seed       *int64          `json:"seed"`
+	Version    int             `json:"version"`
+}
+
+// DataSplitPart is a part of a split, e.g. train or test
+type DataSplitPart struct {
+	Name  string  `json:"name" binding:"required"`
+	Ratio float64 `json:"ratio" binding:"gt=0"` // relative size; ratios need not add up to 1
+}
+
+// DataMergeRequest concatenates the current versions of data files into a new data file
+type DataMergeRequest struct {
+	DataIDs     []int  `json:"data_ids" binding:"required,min=2"`
+	Dedupe      string `json:"dedupe" binding:"omitempty,oneof=exact prompt"` // drop duplicates across the files
+	Filename    string `json:"filename"`
+	Description string `json:"description"`
+}
+
+// DataDedupeRequest copies a data file into a new data file without duplicate items,
+// keeping the first item of each group
+type DataDedupeRequest struct {
+	Mode        string `json:"mode" binding:"omitempty,oneof=exact prompt"` // exact (default) or prompt
+	Version     int    `json:"version"`
+	Filename    string `json:"filename"`
+	Description string `json:"description"`
 }
 
 // DataFile represents a data file info
@@ -61,17 +220,87 @@ type DataFile struct {
 	ID          int    `json:"id"`
 	Name        string `json:"name"`
 	Size        int    `json:"size"`
-	Type        string `json:"type"`
+	Type        string `json:"type"` // user, shared or library
 	Description string `json:"description"`
+	Owner       string `json:"owner,omitempty"` // owner of a shared or library file
 }
 
-// DataContentResponse represents data content response
+// DataContentResponse represents a page of data content.
+// Items are numbered by their position among the parsed items of the file.
 type DataContentResponse struct {
-	Filename    string        `json:"filename"`
-	Description string        `json:"description"`
-	TotalCount  int           `json:"total_count"`
-	Data        []interface{} `json:"data"`
-	Error       string        `json:"error,omitempty"`
+	Filename     string           `json:"filename"`
+	Description  string           `json:"description"`
+	TotalCount   int              `json:"total_count"`   // parsed items in the file
+	MatchedCount int              `json:"matched_count"` // items matching q and role
+	Offset       int              `json:"offset"`
+	Limit        int              `json:"limit"`
+	Data         []interface{}    `json:"data"`
+	Indices      []int            `json:"indices"` // item index of each entry of data
+	Roles        []string         `json:"roles"`   // turn roles used in the file
+	Errors       []DataParseError `json:"errors"`
+	ErrorCount   int              `json:"error_count"`
+}
+
+// DataParseError reports a line of a data file that could not be parsed
+type DataParseError struct {
+	Line  int    `json:"line"`
+	Error string `json:"error"`
+}
+
+// DataValidationInfo reports how the lines of JSONL data match the meta/turns format
+type DataValidationInfo struct {
+	Role         string          `json:"role"`        // evaluation role the data was checked for
+	TotalLines   int             `json:"total_lines"` // non-empty lines
+	ValidItems   int             `json:"valid_items"`
+	InvalidCount int             `json:"invalid_count"`
+	WarningCount int             `json:"warning_count"`
+	InvalidLines []DataLineIssue `json:"invalid_lines"` // the first 100 invalid lines
+	Warnings     []DataLineIssue `json:"warnings"`      // the first 100 lines with warnings
+}
+
+// DataLineIssue lists the problems found on one line of JSONL data
+type DataLineIssue struct {
+	Line     int      `json:"line"`
+	Messages []string `json:"messages"`
+}
+
+// DataBundleManifest describes the data files of an export bundle
+type DataBundleManifest struct {
+	ExportedAt string            `json:"exported_at"`
+	Format     string            `json:"format"`
+	Datasets   []DataBundleEntry `json:"datasets"`
+}
+
+// DataBundleEntry is a data file in an export bundle
+type DataBundleEntry struct {
+	ID           int    `json:"id"`
+	Filename     string `json:"filename"`
+	Description  string `json:"description"`
+	Version      int    `json:"version"`
+	ItemCount    int    `json:"item_count"`
+	Checksum     string `json:"checksum"`
+	Path         string `json:"path,omitempty"`          // path of the exported file in the archive
+	DroppedTurns int    `json:"dropped_turns,omitempty"` // system turns the format cannot hold
+	Error        string `json:"error,omitempty"`         // why the file is missing from the archive
+}
+
+// DataImportOptions selects how the records of an uploaded file are converted into meta/turns items
+type DataImportOptions struct {
+	Format  string             `json:"format"`  // auto (default), native, openai, sharegpt, alpaca or columns
+	Mapping *DataColumnMapping `json:"mapping"` // required by the columns format
+}
+
+// DataColumnMapping maps the columns of flat records, such as CSV rows, to an item
+type DataColumnMapping struct {
+	System string           `json:"system,omitempty"` // column holding meta_description
+	Turns  []DataColumnTurn `json:"turns"`            // columns holding the turns, in order
+	Meta   []string         `json:"meta,omitempty"`   // columns copied into meta
+}
+
+// DataColumnTurn maps a column to a turn of the given role; empty cells add no turn
+type DataColumnTurn struct {
+	Column string `json:"column"`
+	Role   string `json:"role"`
 }
 
 // CSVValidationInfo represents CSV validation info
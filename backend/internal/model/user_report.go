@@ -22,6 +22,10 @@ type UserReportCreate struct {
 	ReportContent string                 `json:"report_content" binding:"required"`
 	Timestamp     string                 `json:"timestamp" binding:"required"`
 	Summary       map[string]interface{} `json:"summary" binding:"required"`
+
+	// Set for reports derived from another report, e.g. by rescoring
+	ParentReportID *int                   `json:"parent_report_id"`
+	Derivation     map[string]interface{} `json:"derivation"`
 }
 
 // ReportSummary represents report summary
@@ -32,3 +36,63 @@ type ReportSummary struct {
 	Timestamp  string                 `json:"timestamp"`
 	Summary   map[string]interface{} `json:"summary"`
 }
+
+// ReportDetail represents report metadata without the per-item results
+type ReportDetail struct {
+	ID           int                    `json:"id" db:"id"`
+	UserID       int                    `json:"user_id" db:"user_id"`
+	TaskID       string                 `json:"task_id" db:"task_id"`
+	Dataset      string                 `json:"dataset" db:"dataset"`
+	Model        string                 `json:"model" db:"model"`
+	Timestamp    string                 `json:"timestamp" db:"timestamp"`
+	Config       map[string]interface{} `json:"config" db:"report_config"` // JSON
+	Summary      map[string]interface{} `json:"summary" db:"summary"`      // JSON
+	ItemCount    int                    `json:"item_count" db:"item_count"`
+	BadcaseCount int                    `json:"badcase_count" db:"badcase_count"`
+	CreatedAt    string                 `json:"created_at" db:"created_at"`
+	Reviewed     *ReviewedSummary       `json:"reviewed_summary,omitempty"`
+
+	// ParentReportID and Derivation describe how a derived report was produced from its parent
+	ParentReportID *int                   `json:"parent_report_id,omitempty" db:"parent_report_id"`
+	Derivation     map[string]interface{} `json:"derivation,omitempty" db:"derivation"` // JSON
+	DerivedReports []int                  `json:"derived_reports,omitempty"`
+}
+
+// ReportItemFilter selects a page of report items
+type ReportItemFilter struct {
+	Offset      int
+	Limit       int
+	BadcaseOnly bool
+	ErrorOnly   bool
+	MinScore    *float64
+	MaxScore    *float64
+	Query       string // substring of user_input, model_output or reference_output
+}
+
+// ReportItemAggregate holds aggregates over the report items matching a filter
+type ReportItemAggregate struct {
+	Count                int           `json:"count"`
+	BadcaseCount         int           `json:"badcase_count"`
+	ErrorCount           int           `json:"error_count"`
+	Accuracy             float64       `json:"accuracy"`
+	AverageScore         float64       `json:"average_score"`
+	MinScore             float64       `json:"min_score"`
+	MaxScore             float64       `json:"max_score"`
+	AverageInferenceTime float64       `json:"average_inference_time"`
+	ScoreDistribution    []ScoreBucket `json:"score_distribution"`
+}
+
+// ScoreBucket counts the items with one score
+type ScoreBucket struct {
+	Score float64 `json:"score"`
+	Count int     `json:"count"`
+}
+
+// ReportItemRun holds the result of one dataset item in one report
+type ReportItemRun struct {
+	ReportID  int    `json:"report_id"`
+	TaskID    string `json:"task_id"`
+	Model     string `json:"model"`
+	Timestamp string `json:"timestamp"`
+	ReportResult
+}
@@ -0,0 +1,97 @@
+package blob
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/wzyjerry/llm-judge/internal/pkg/config"
+	"go.uber.org/zap"
+)
+
+const (
+	defaultLocalDir    = "data/blobs"
+	defaultMaxUploadMB = 512
+)
+
+var (
+	store       Store
+	maxUploadMB = defaultMaxUploadMB
+
+	ErrNotFound       = errors.New("blob: object not found")
+	ErrNotInitialized = errors.New("blob: store not initialized")
+	ErrInvalidKey     = errors.New("blob: invalid key")
+)
+
+// Store keeps large objects such as data file contents outside the database
+type Store interface {
+	// Put stores size bytes read from r under key, replacing any existing object
+	Put(ctx context.Context, key string, r io.Reader, size int64) error
+	// Get opens the object stored under key; it returns ErrNotFound when there is none
+	Get(ctx context.Context, key string) (io.ReadCloser, error)
+	// Delete removes the object stored under key; deleting a missing object is not an error
+	Delete(ctx context.Context, key string) error
+}
+
+// Init creates the store selected by the storage config
+func Init(cfg *config.Config) error {
+	storageCfg := cfg.Storage
+	if storageCfg.MaxUploadMB > 0 {
+		maxUploadMB = storageCfg.MaxUploadMB
+	}
+
+	switch storageCfg.Type {
+	case "", "local":
+		dir := storageCfg.LocalDir
+		if dir == "" {
+			dir = defaultLocalDir
+		}
+		local, err := NewLocalStore(dir)
+		if err != nil {
+			return err
+		}
+		store = local
+		zap.L().Info("Using local blob storage", zap.String("dir", dir))
+	case "s3":
+		s3, err := NewS3Store(storageCfg.S3)
+		if err != nil {
+			return err
+		}
+		store = s3
+		zap.L().Info("Using S3 blob storage",
+			zap.String("endpoint", storageCfg.S3.Endpoint),
+			zap.String("bucket", storageCfg.S3.Bucket))
+	default:
+		return fmt.Errorf("blob: unknown storage type %q", storageCfg.Type)
+	}
+
+	return nil
+}
+
+// GetStore returns the store created by Init
+func GetStore() (Store, error) {
+	if store == nil {
+		return nil, ErrNotInitialized
+	}
+	return store, nil
+}
+
+// MaxUploadSize returns the largest accepted upload in bytes
+func MaxUploadSize() int64 {
+	return int64(maxUploadMB) << 20
+}
+
+// validateKey rejects keys that could escape the store, such as absolute paths or ".." segments
+func validateKey(key string) error {
+	if key == "" || strings.HasPrefix(key, "/") || strings.Contains(key, "\\") {
+		return ErrInvalidKey
+	}
+	for _, segment := range strings.Split(key, "/") {
+		if segment == "" || segment == "." || segment == ".." {
+			return ErrInvalidKey
+		}
+	}
+	return nil
+}
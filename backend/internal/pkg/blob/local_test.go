@@ -0,0 +1,143 @@
+package blob
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestValidateKey(t *testing.T) {
+	tests := []struct {
+		key   string
+		valid bool
+	}{
+		{"datasets/abc.jsonl", true},
+		{"a", true},
+		{"a/b/c", true},
+		{"..a/b..", true},
+		{"", false},
+		{"/etc/passwd", false},
+		{"../escape", false},
+		{"a/../../escape", false},
+		{"a/./b", false},
+		{"a//b", false},
+		{"a/", false},
+		{`a\..\b`, false},
+	}
+	for _, tt := range tests {
+		err := validateKey(tt.key)
+		if tt.valid && err != nil {
+			t.Errorf("validateKey(%q) = %v, want nil", tt.key, err)
+		}
+		if !tt.valid && !errors.Is(err, ErrInvalidKey) {
+			t.Errorf("validateKey(%q) = %v, want ErrInvalidKey", tt.key, err)
+		}
+	}
+}
+
+func TestLocalStoreRoundTrip(t *testing.T) {
+	store, err := NewLocalStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("NewLocalStore: %v", err)
+	}
+	ctx := context.Background()
+
+	if err := store.Put(ctx, "datasets/a.jsonl", strings.NewReader("hello"), 5); err != nil {
+		t.Fatalf("Put: %v", err)
+	}
+	r, err := store.Get(ctx, "datasets/a.jsonl")
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	got, _ := io.ReadAll(r)
+	r.Close()
+	if string(got) != "hello" {
+		t.Fatalf("Get returned %q, want %q", got, "hello")
+	}
+
+	if err := store.Delete(ctx, "datasets/a.jsonl"); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	if _, err := store.Get(ctx, "datasets/a.jsonl"); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("Get after Delete returned %v, want ErrNotFound", err)
+	}
+	if err := store.Delete(ctx, "datasets/a.jsonl"); err != nil {
+		t.Fatalf("Delete of a missing object: %v", err)
+	}
+}
+
+// failingReader returns some data and then an error, like an upload cut off midway
+type failingReader struct {
+	data string
+	done bool
+}
+
+func (r *failingReader) Read(p []byte) (int, error) {
+	if r.done {
+		return 0, errors.New("connection reset")
+	}
+	r.done = true
+	return copy(p, r.data), nil
+}
+
+func TestLocalStorePutIsAtomic(t *testing.T) {
+	dir := t.TempDir()
+	store, err := NewLocalStore(dir)
+	if err != nil {
+		t.Fatalf("NewLocalStore: %v", err)
+	}
+	ctx := context.Background()
+	if err := store.Put(ctx, "datasets/a.jsonl", strings.NewReader("old"), 3); err != nil {
+		t.Fatalf("Put: %v", err)
+	}
+
+	// A failed read and a short write both keep the previous object and leave no temporary files
+	if err := store.Put(ctx, "datasets/a.jsonl", &failingReader{data: "new partial"}, 100); err == nil {
+		t.Fatal("Put with a failing reader succeeded")
+	}
+	if err := store.Put(ctx, "datasets/a.jsonl", strings.NewReader("short"), 100); err == nil {
+		t.Fatal("Put with fewer bytes than the size succeeded")
+	}
+
+	got, err := os.ReadFile(filepath.Join(dir, "datasets", "a.jsonl"))
+	if err != nil || string(got) != "old" {
+		t.Fatalf("object is %q, %v after failed writes, want %q", got, err, "old")
+	}
+	entries, _ := os.ReadDir(filepath.Join(dir, "datasets"))
+	if len(entries) != 1 {
+		names := make([]string, len(entries))
+		for i, entry := range entries {
+			names[i] = entry.Name()
+		}
+		t.Fatalf("directory holds %v, want only a.jsonl", names)
+	}
+}
+
+func TestLocalStoreRejectsTraversal(t *testing.T) {
+	parent := t.TempDir()
+	root := filepath.Join(parent, "blobs")
+	store, err := NewLocalStore(root)
+	if err != nil {
+		t.Fatalf("NewLocalStore: %v", err)
+	}
+	ctx := context.Background()
+
+	for _, key := range []string{"../escape", "datasets/../../escape", "/tmp/escape"} {
+		if err := store.Put(ctx, key, strings.NewReader("x"), 1); !errors.Is(err, ErrInvalidKey) {
+			t.Errorf("Put(%q) returned %v, want ErrInvalidKey", key, err)
+		}
+		if _, err := store.Get(ctx, key); !errors.Is(err, ErrInvalidKey) {
+			t.Errorf("Get(%q) returned %v, want ErrInvalidKey", key, err)
+		}
+		if err := store.Delete(ctx, key); !errors.Is(err, ErrInvalidKey) {
+			t.Errorf("Delete(%q) returned %v, want ErrInvalidKey", key, err)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(parent, "escape")); !os.IsNotExist(err) {
+		t.Fatal("a key escaped the store root")
+	}
+}
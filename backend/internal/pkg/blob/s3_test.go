@@ -0,0 +1,276 @@
+package blob
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/wzyjerry/llm-judge/internal/pkg/config"
+)
+
+const (
+	testAccessKey = "AKIDEXAMPLE"
+	testSecretKey = "wJalrXUtnFEMI/K7MDENG+bPxRfiCYEXAMPLEKEY"
+	testRegion    = "us-west-2"
+	testBucket    = "llm-judge"
+)
+
+// s3StandIn is an in-memory S3 bucket that verifies the Signature V4 of every request
+type s3StandIn struct {
+	mu      sync.Mutex
+	objects map[string][]byte
+}
+
+func (s *s3StandIn) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if err := verifySignature(r); err != nil {
+		http.Error(w, "<Error><Code>SignatureDoesNotMatch</Code><Message>"+err.Error()+"</Message></Error>", http.StatusForbidden)
+		return
+	}
+
+	prefix := "/" + testBucket + "/"
+	if !strings.HasPrefix(r.URL.Path, prefix) {
+		http.Error(w, "<Error><Code>NoSuchBucket</Code></Error>", http.StatusNotFound)
+		return
+	}
+	key := strings.TrimPrefix(r.URL.Path, prefix)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	switch r.Method {
+	case http.MethodPut:
+		body, err := io.ReadAll(r.Body)
+		if err != nil || int64(len(body)) != r.ContentLength {
+			http.Error(w, "<Error><Code>IncompleteBody</Code></Error>", http.StatusBadRequest)
+			return
+		}
+		s.objects[key] = body
+	case http.MethodGet:
+		body, ok := s.objects[key]
+		if !ok {
+			http.Error(w, "<Error><Code>NoSuchKey</Code></Error>", http.StatusNotFound)
+			return
+		}
+		w.Write(body)
+	case http.MethodDelete:
+		delete(s.objects, key)
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+// verifySignature recomputes the Signature V4 of a request from what arrived on the wire
+func verifySignature(r *http.Request) error {
+	auth := r.Header.Get("Authorization")
+	if !strings.HasPrefix(auth, "AWS4-HMAC-SHA256 ") {
+		return errors.New("missing AWS4-HMAC-SHA256 authorization")
+	}
+	fields := map[string]string{}
+	for _, part := range strings.Split(strings.TrimPrefix(auth, "AWS4-HMAC-SHA256 "), ",") {
+		name, value, _ := strings.Cut(strings.TrimSpace(part), "=")
+		fields[name] = value
+	}
+
+	credential := strings.Split(fields["Credential"], "/")
+	if len(credential) != 5 || credential[0] != testAccessKey || credential[2] != testRegion ||
+		credential[3] != "s3" || credential[4] != "aws4_request" {
+		return errors.New("bad credential scope")
+	}
+	amzDate := r.Header.Get("X-Amz-Date")
+	if !strings.HasPrefix(amzDate, credential[1]) {
+		return errors.New("date does not match the credential scope")
+	}
+	payloadHash := r.Header.Get("X-Amz-Content-Sha256")
+	if payloadHash == "" {
+		return errors.New("missing payload hash")
+	}
+
+	signedHeaders := strings.Split(fields["SignedHeaders"], ";")
+	if !sort.StringsAreSorted(signedHeaders) {
+		return errors.New("signed headers are not sorted")
+	}
+	var canonicalHeaders strings.Builder
+	for _, name := range signedHeaders {
+		value := r.Header.Get(name)
+		if name == "host" {
+			value = r.Host
+		}
+		canonicalHeaders.WriteString(name + ":" + strings.TrimSpace(value) + "\n")
+	}
+	for _, required := range []string{"host", "x-amz-content-sha256", "x-amz-date"} {
+		if !strings.Contains(fields["SignedHeaders"], required) {
+			return errors.New(required + " is not signed")
+		}
+	}
+
+	canonicalRequest := strings.Join([]string{
+		r.Method,
+		awsPathEncode(r.URL.Path),
+		r.URL.RawQuery,
+		canonicalHeaders.String(),
+		fields["SignedHeaders"],
+		payloadHash,
+	}, "\n")
+	hash := sha256.Sum256([]byte(canonicalRequest))
+	stringToSign := strings.Join([]string{
+		"AWS4-HMAC-SHA256",
+		amzDate,
+		strings.Join(credential[1:], "/"),
+		hex.EncodeToString(hash[:]),
+	}, "\n")
+
+	key := []byte("AWS4" + testSecretKey)
+	for _, part := range append(credential[1:4], "aws4_request") {
+		mac := hmac.New(sha256.New, key)
+		mac.Write([]byte(part))
+		key = mac.Sum(nil)
+	}
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(stringToSign))
+	if !hmac.Equal([]byte(hex.EncodeToString(mac.Sum(nil))), []byte(fields["Signature"])) {
+		return errors.New("signature mismatch")
+	}
+	return nil
+}
+
+// awsPathEncode encodes each segment of a decoded path as Signature V4 requires: every byte except
+// A-Z a-z 0-9 - _ . ~ as %XX
+func awsPathEncode(path string) string {
+	const hexDigits = "0123456789ABCDEF"
+	var b strings.Builder
+	for i := 0; i < len(path); i++ {
+		c := path[i]
+		switch {
+		case c == '/', c == '-', c == '_', c == '.', c == '~',
+			'A' <= c && c <= 'Z', 'a' <= c && c <= 'z', '0' <= c && c <= '9':
+			b.WriteByte(c)
+		default:
+			b.WriteByte('%')
+			b.WriteByte(hexDigits[c>>4])
+			b.WriteByte(hexDigits[c&15])
+		}
+	}
+	return b.String()
+}
+
+func newTestS3Store(t *testing.T, secretKey string) (*S3Store, *s3StandIn) {
+	t.Helper()
+	standIn := &s3StandIn{objects: map[string][]byte{}}
+	server := httptest.NewServer(standIn)
+	t.Cleanup(server.Close)
+
+	store, err := NewS3Store(config.S3Config{
+		Endpoint:     server.URL,
+		Region:       testRegion,
+		Bucket:       testBucket,
+		AccessKey:    testAccessKey,
+		SecretKey:    secretKey,
+		UsePathStyle: true,
+	})
+	if err != nil {
+		t.Fatalf("NewS3Store: %v", err)
+	}
+	return store, standIn
+}
+
+func TestS3StorePutGetDelete(t *testing.T) {
+	store, standIn := newTestS3Store(t, testSecretKey)
+	ctx := context.Background()
+	key := "datasets/abc 1.jsonl"
+	content := []byte("{\"a\":1}\n{\"b\":2}\n")
+
+	if err := store.Put(ctx, key, bytes.NewReader(content), int64(len(content))); err != nil {
+		t.Fatalf("Put: %v", err)
+	}
+	if !bytes.Equal(standIn.objects[key], content) {
+		t.Fatalf("stored %q, want %q", standIn.objects[key], content)
+	}
+
+	r, err := store.Get(ctx, key)
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	got, _ := io.ReadAll(r)
+	r.Close()
+	if !bytes.Equal(got, content) {
+		t.Fatalf("Get returned %q, want %q", got, content)
+	}
+
+	if err := store.Delete(ctx, key); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	if _, ok := standIn.objects[key]; ok {
+		t.Fatal("object still stored after Delete")
+	}
+	if err := store.Delete(ctx, key); err != nil {
+		t.Fatalf("Delete of a missing object: %v", err)
+	}
+}
+
+func TestS3StoreEmptyObject(t *testing.T) {
+	store, standIn := newTestS3Store(t, testSecretKey)
+	if err := store.Put(context.Background(), "empty", strings.NewReader(""), 0); err != nil {
+		t.Fatalf("Put: %v", err)
+	}
+	if got, ok := standIn.objects["empty"]; !ok || len(got) != 0 {
+		t.Fatalf("stored %q, %v; want an empty object", got, ok)
+	}
+}
+
+func TestS3StoreMissingKey(t *testing.T) {
+	store, _ := newTestS3Store(t, testSecretKey)
+	if _, err := store.Get(context.Background(), "datasets/missing.jsonl"); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("Get of a missing key returned %v, want ErrNotFound", err)
+	}
+}
+
+func TestS3StoreWrongSecret(t *testing.T) {
+	store, _ := newTestS3Store(t, "not-the-secret")
+	err := store.Put(context.Background(), "k", strings.NewReader("x"), 1)
+	if err == nil || !strings.Contains(err.Error(), "403") {
+		t.Fatalf("Put with a wrong secret returned %v, want a 403 error", err)
+	}
+}
+
+func TestS3StoreRejectsInvalidKeys(t *testing.T) {
+	store, standIn := newTestS3Store(t, testSecretKey)
+	ctx := context.Background()
+	for _, key := range []string{"", "../escape", "/abs", "a//b"} {
+		if err := store.Put(ctx, key, strings.NewReader("x"), 1); !errors.Is(err, ErrInvalidKey) {
+			t.Errorf("Put(%q) returned %v, want ErrInvalidKey", key, err)
+		}
+		if _, err := store.Get(ctx, key); !errors.Is(err, ErrInvalidKey) {
+			t.Errorf("Get(%q) returned %v, want ErrInvalidKey", key, err)
+		}
+	}
+	if len(standIn.objects) != 0 {
+		t.Fatalf("invalid keys reached the server: %v", standIn.objects)
+	}
+}
+
+func TestS3StoreObjectURL(t *testing.T) {
+	store, err := NewS3Store(config.S3Config{
+		Region: "eu-west-1", Bucket: "b", AccessKey: "a", SecretKey: "s",
+	})
+	if err != nil {
+		t.Fatalf("NewS3Store: %v", err)
+	}
+	if got, want := store.objectURL("datasets/x.jsonl").String(), "https://b.s3.eu-west-1.amazonaws.com/datasets/x.jsonl"; got != want {
+		t.Fatalf("virtual-hosted URL %s, want %s", got, want)
+	}
+
+	store.usePathStyle = true
+	if got, want := store.objectURL("datasets/x.jsonl").String(), "https://s3.eu-west-1.amazonaws.com/b/datasets/x.jsonl"; got != want {
+		t.Fatalf("path-style URL %s, want %s", got, want)
+	}
+}
@@ -16,6 +16,10 @@ type Config struct {
 	JWT             JWTConfig             `mapstructure:"jwt"`
 	Log             LogConfig             `mapstructure:"log"`
 	Admin           AdminConfig           `mapstructure:"admin"`
+	ModelHealth     ModelHealthConfig     `mapstructure:"model_health"`
+	ModelSync       ModelSyncConfig       `mapstructure:"model_sync"`
+	Security        SecurityConfig        `mapstructure:"security"`
+	Storage         StorageConfig         `mapstructure:"storage"`
 }
 
 type DatabaseServiceConfig struct {
@@ -40,6 +44,10 @@ type RedisServiceConfig struct {
 	Port        int    `mapstructure:"port"`
 	DB          int    `mapstructure:"db"`
 	MaxWaitTime int    `mapstructure:"max_wait_time"`
+
+	// JudgeMaxConcurrency is the concurrency budget of judge calls per judge model,
+	// separate from the budget of the evaluated model
+	JudgeMaxConcurrency int `mapstructure:"judge_max_concurrency"`
 }
 
 type JWTConfig struct {
@@ -58,6 +66,40 @@ type AdminConfig struct {
 	PasswordHash string `mapstructure:"password_hash"`
 }
 
+type ModelHealthConfig struct {
+	Enabled         bool `mapstructure:"enabled"`
+	IntervalSeconds int  `mapstructure:"interval_seconds"`
+	TimeoutSeconds  int  `mapstructure:"timeout_seconds"`
+	BlockWhenDown   bool `mapstructure:"block_when_down"`
+}
+
+type ModelSyncConfig struct {
+	Enabled         bool `mapstructure:"enabled"`
+	IntervalSeconds int  `mapstructure:"interval_seconds"`
+}
+
+type SecurityConfig struct {
+	MasterKey string `mapstructure:"master_key"` // encrypts model API keys at rest
+}
+
+// StorageConfig selects where data file contents are stored
+type StorageConfig struct {
+	Type        string   `mapstructure:"type"`          // "local" (default) or "s3"
+	LocalDir    string   `mapstructure:"local_dir"`     // root directory of the local store
+	MaxUploadMB int      `mapstructure:"max_upload_mb"` // largest accepted data file
+	S3          S3Config `mapstructure:"s3"`
+}
+
+// S3Config configures an S3-compatible object store such as AWS S3 or MinIO
+type S3Config struct {
+	Endpoint     string `mapstructure:"endpoint"` // e.g. http://localhost:9000, AWS when empty
+	Region       string `mapstructure:"region"`
+	Bucket       string `mapstructure:"bucket"`
+	AccessKey    string `mapstructure:"access_key"`
+	SecretKey    string `mapstructure:"secret_key"`
+	UsePathStyle bool   `mapstructure:"use_path_style"` // bucket in the path instead of the host name
+}
+
 var cfg *Config
 
 // Load loads the configuration from config.yaml
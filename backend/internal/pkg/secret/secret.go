@@ -0,0 +1,127 @@
+package secret
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/wzyjerry/llm-judge/internal/pkg/config"
+)
+
+// MasterKeyEnv overrides security.master_key, so the key need not be kept in config.yaml
+const MasterKeyEnv = "LLM_JUDGE_MASTER_KEY"
+
+// encryptedPrefix marks values produced by Encrypt
+const encryptedPrefix = "enc:v1:"
+
+var (
+	gcm cipher.AEAD
+
+	ErrNotInitialized = errors.New("secret: master key not initialized")
+)
+
+// Init derives the AES-256 key from the master key
+func Init(masterKey string) error {
+	if masterKey == "" {
+		return errors.New("secret: master key is empty")
+	}
+
+	key := sha256.Sum256([]byte(masterKey))
+	block, err := aes.NewCipher(key[:])
+	if err != nil {
+		return fmt.Errorf("secret: failed to create cipher: %w", err)
+	}
+
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return fmt.Errorf("secret: failed to create GCM: %w", err)
+	}
+
+	gcm = aead
+	return nil
+}
+
+// InitFromConfig initializes encryption with the master key from MasterKeyEnv or security.master_key.
+// There is no fallback: without a master key the services must not start.
+func InitFromConfig(cfg *config.Config) error {
+	masterKey := os.Getenv(MasterKeyEnv)
+	if masterKey == "" {
+		masterKey = cfg.Security.MasterKey
+	}
+	if masterKey == "" {
+		return fmt.Errorf("secret: security.master_key (or %s) must be set", MasterKeyEnv)
+	}
+	return Init(masterKey)
+}
+
+// IsEncrypted reports whether a value was produced by Encrypt
+func IsEncrypted(value string) bool {
+	return strings.HasPrefix(value, encryptedPrefix)
+}
+
+// Encrypt encrypts a value; empty and already encrypted values are returned unchanged
+func Encrypt(plaintext string) (string, error) {
+	if plaintext == "" || IsEncrypted(plaintext) {
+		return plaintext, nil
+	}
+	if gcm == nil {
+		return "", ErrNotInitialized
+	}
+
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return "", fmt.Errorf("secret: failed to generate nonce: %w", err)
+	}
+
+	sealed := gcm.Seal(nonce, nonce, []byte(plaintext), nil)
+	return encryptedPrefix + base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+// Decrypt decrypts a value produced by Encrypt; legacy plaintext values are returned unchanged
+func Decrypt(value string) (string, error) {
+	if !IsEncrypted(value) {
+		return value, nil
+	}
+	if gcm == nil {
+		return "", ErrNotInitialized
+	}
+
+	sealed, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(value, encryptedPrefix))
+	if err != nil {
+		return "", fmt.Errorf("secret: invalid encoding: %w", err)
+	}
+	if len(sealed) < gcm.NonceSize() {
+		return "", errors.New("secret: ciphertext too short")
+	}
+
+	nonce, ciphertext := sealed[:gcm.NonceSize()], sealed[gcm.NonceSize():]
+	plaintext, err := gcm.Open(nil, nonce, ciphertext, nil)
+	if err != nil {
+		return "", fmt.Errorf("secret: failed to decrypt: %w", err)
+	}
+
+	return string(plaintext), nil
+}
+
+// Mask hides all but the first three and last four characters of a key
+func Mask(key string) string {
+	if key == "" {
+		return ""
+	}
+	if len(key) <= 8 {
+		return strings.Repeat("*", len(key))
+	}
+	return key[:3] + "****" + key[len(key)-4:]
+}
+
+// IsMasked reports whether a value looks like the output of Mask
+func IsMasked(value string) bool {
+	return (value != "" && strings.Trim(value, "*") == "") || strings.Contains(value, "****")
+}
@@ -0,0 +1,155 @@
+package secret
+
+import (
+	"encoding/base64"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/wzyjerry/llm-judge/internal/pkg/config"
+)
+
+// initTestKey initializes encryption with a master key and resets it after the test
+func initTestKey(t *testing.T, masterKey string) {
+	t.Helper()
+	if err := Init(masterKey); err != nil {
+		t.Fatalf("Init: %v", err)
+	}
+	t.Cleanup(func() { gcm = nil })
+}
+
+func TestEncryptDecryptRoundTrip(t *testing.T) {
+	initTestKey(t, "master key")
+
+	for _, plaintext := range []string{"sk-abcdef0123456789", "k1", "密钥 with spaces\n"} {
+		encrypted, err := Encrypt(plaintext)
+		if err != nil {
+			t.Fatalf("Encrypt(%q): %v", plaintext, err)
+		}
+		// Short plaintexts may turn up in the base64 text by chance
+		if !IsEncrypted(encrypted) || (len(plaintext) >= 8 && strings.Contains(encrypted, plaintext)) {
+			t.Errorf("Encrypt(%q) = %q", plaintext, encrypted)
+		}
+		again, _ := Encrypt(plaintext)
+		if again == encrypted {
+			t.Errorf("Encrypt(%q) reused a nonce", plaintext)
+		}
+
+		decrypted, err := Decrypt(encrypted)
+		if err != nil || decrypted != plaintext {
+			t.Errorf("Decrypt(Encrypt(%q)) = %q, %v", plaintext, decrypted, err)
+		}
+	}
+}
+
+func TestEncryptDecryptPassThrough(t *testing.T) {
+	initTestKey(t, "master key")
+
+	encrypted, _ := Encrypt("sk-1")
+	tests := []struct {
+		name, value string
+	}{
+		{"empty", ""},
+		{"already encrypted", encrypted},
+	}
+	for _, tt := range tests {
+		if got, err := Encrypt(tt.value); err != nil || got != tt.value {
+			t.Errorf("Encrypt of %s value = %q, %v; want it unchanged", tt.name, got, err)
+		}
+	}
+
+	// Keys stored before encryption are read as they are
+	if got, err := Decrypt("sk-legacy"); err != nil || got != "sk-legacy" {
+		t.Errorf("Decrypt of a plaintext value = %q, %v", got, err)
+	}
+}
+
+func TestDecryptRejectsInvalidValues(t *testing.T) {
+	initTestKey(t, "master key")
+	encrypted, _ := Encrypt("sk-abcdef")
+	sealed, _ := base64.StdEncoding.DecodeString(strings.TrimPrefix(encrypted, encryptedPrefix))
+	sealed[len(sealed)-1] ^= 1
+
+	tests := []struct {
+		name, value string
+	}{
+		{"bad encoding", encryptedPrefix + "not base64!"},
+		{"too short", encryptedPrefix + base64.StdEncoding.EncodeToString([]byte("short"))},
+		{"tampered", encryptedPrefix + base64.StdEncoding.EncodeToString(sealed)},
+	}
+	for _, tt := range tests {
+		if _, err := Decrypt(tt.value); err == nil {
+			t.Errorf("Decrypt of a %s value succeeded", tt.name)
+		}
+	}
+
+	// A value encrypted with another master key does not decrypt
+	initTestKey(t, "other key")
+	if _, err := Decrypt(encrypted); err == nil {
+		t.Error("Decrypt with another master key succeeded")
+	}
+}
+
+func TestNotInitialized(t *testing.T) {
+	gcm = nil
+	if _, err := Encrypt("sk-1"); !errors.Is(err, ErrNotInitialized) {
+		t.Errorf("Encrypt returned %v, want ErrNotInitialized", err)
+	}
+	if _, err := Decrypt(encryptedPrefix + "AAAA"); !errors.Is(err, ErrNotInitialized) {
+		t.Errorf("Decrypt returned %v, want ErrNotInitialized", err)
+	}
+	if err := Init(""); err == nil {
+		t.Error("Init accepted an empty master key")
+	}
+}
+
+func TestInitFromConfig(t *testing.T) {
+	t.Cleanup(func() { gcm = nil })
+	cfg := &config.Config{}
+
+	t.Setenv(MasterKeyEnv, "")
+	if err := InitFromConfig(cfg); err == nil {
+		t.Fatal("InitFromConfig succeeded without a master key")
+	}
+
+	cfg.Security.MasterKey = "config key"
+	if err := InitFromConfig(cfg); err != nil {
+		t.Fatalf("InitFromConfig: %v", err)
+	}
+	fromConfig, _ := Encrypt("sk-1")
+
+	// The environment variable takes precedence over the config
+	t.Setenv(MasterKeyEnv, "env key")
+	if err := InitFromConfig(cfg); err != nil {
+		t.Fatalf("InitFromConfig: %v", err)
+	}
+	if _, err := Decrypt(fromConfig); err == nil {
+		t.Error("the config master key was used although the environment sets one")
+	}
+}
+
+func TestMask(t *testing.T) {
+	tests := []struct {
+		key, masked string
+	}{
+		{"", ""},
+		{"abc", "***"},
+		{"12345678", "********"},
+		{"sk-abcdef0123456789", "sk-****6789"},
+	}
+	for _, tt := range tests {
+		got := Mask(tt.key)
+		if got != tt.masked {
+			t.Errorf("Mask(%q) = %q, want %q", tt.key, got, tt.masked)
+		}
+		if tt.key != "" && !IsMasked(got) {
+			t.Errorf("IsMasked(%q) = false", got)
+		}
+	}
+
+	for _, value := range []string{"", "sk-abcdef0123456789", "a*b"} {
+		if IsMasked(value) {
+			t.Errorf("IsMasked(%q) = true", value)
+		}
+	}
+}
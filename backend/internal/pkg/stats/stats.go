@@ -0,0 +1,201 @@
+package stats
+
+import (
+	"math"
+	"math/rand"
+	"sort"
+)
+
+// exactMcNemarLimit is the largest discordant pair count tested with the exact binomial test
+const exactMcNemarLimit = 25
+
+// McNemarResult holds the outcome of McNemar's test on paired pass/fail outcomes
+type McNemarResult struct {
+	OnlyA     int     `json:"only_a"` // pairs passing in A only
+	OnlyB     int     `json:"only_b"` // pairs passing in B only
+	Statistic float64 `json:"statistic"`
+	PValue    float64 `json:"p_value"`
+	Exact     bool    `json:"exact"` // binomial test instead of chi-square
+}
+
+// McNemar tests whether two paired pass/fail outcomes differ, given the discordant pair counts.
+// Small samples use the exact two-sided binomial test, larger ones the chi-square test with continuity correction.
+func McNemar(onlyA, onlyB int) McNemarResult {
+	result := McNemarResult{OnlyA: onlyA, OnlyB: onlyB, PValue: 1}
+
+	n := onlyA + onlyB
+	if n == 0 {
+		return result
+	}
+
+	if n <= exactMcNemarLimit {
+		k := onlyA
+		if onlyB < k {
+			k = onlyB
+		}
+		p := 0.0
+		for i := 0; i <= k; i++ {
+			p += binomialPMF(n, i)
+		}
+		result.Exact = true
+		result.Statistic = float64(k)
+		result.PValue = math.Min(1, 2*p)
+		return result
+	}
+
+	// The continuity correction must not turn equal counts into a difference
+	diff := math.Max(0, math.Abs(float64(onlyA-onlyB))-1)
+	result.Statistic = diff * diff / float64(n)
+	result.PValue = math.Erfc(math.Sqrt(result.Statistic / 2))
+	return result
+}
+
+// binomialPMF returns P(X = k) for X ~ Binomial(n, 0.5)
+func binomialPMF(n, k int) float64 {
+	lgN, _ := math.Lgamma(float64(n + 1))
+	lgK, _ := math.Lgamma(float64(k + 1))
+	lgNK, _ := math.Lgamma(float64(n - k + 1))
+	return math.Exp(lgN - lgK - lgNK - float64(n)*math.Ln2)
+}
+
+// BootstrapCI holds a percentile bootstrap confidence interval of a mean
+type BootstrapCI struct {
+	Mean       float64 `json:"mean"`
+	Lower      float64 `json:"lower"`
+	Upper      float64 `json:"upper"`
+	Confidence float64 `json:"confidence"`
+	Iterations int     `json:"iterations"`
+}
+
+// BootstrapMeanCI estimates a percentile confidence interval for the mean of values.
+// The seed makes the interval reproducible for the same input.
+func BootstrapMeanCI(values []float64, iterations int, confidence float64, seed int64) BootstrapCI {
+	ci := BootstrapCI{Confidence: confidence, Iterations: iterations}
+	if len(values) == 0 || iterations <= 0 {
+		return ci
+	}
+
+	ci.Mean = Mean(values)
+
+	rng := rand.New(rand.NewSource(seed))
+	means := make([]float64, iterations)
+	for i := range means {
+		sum := 0.0
+		for range values {
+			sum += values[rng.Intn(len(values))]
+		}
+		means[i] = sum / float64(len(values))
+	}
+	sort.Float64s(means)
+
+	alpha := (1 - confidence) / 2
+	ci.Lower = percentile(means, alpha)
+	ci.Upper = percentile(means, 1-alpha)
+	return ci
+}
+
+// Mean returns the arithmetic mean of values, or 0 when empty
+func Mean(values []float64) float64 {
+	if len(values) == 0 {
+		return 0
+	}
+	sum := 0.0
+	for _, v := range values {
+		sum += v
+	}
+	return sum / float64(len(values))
+}
+
+// Percentile returns the q-th quantile (0 <= q <= 1) of values using linear interpolation, or 0 when empty
+func Percentile(values []float64, q float64) float64 {
+	if len(values) == 0 {
+		return 0
+	}
+	sorted := append([]float64(nil), values...)
+	sort.Float64s(sorted)
+	return percentile(sorted, q)
+}
+
+// HistogramBin counts the values in [Lower, Upper), the last bin also includes Upper
+type HistogramBin struct {
+	Lower float64 `json:"lower"`
+	Upper float64 `json:"upper"`
+	Count int     `json:"count"`
+}
+
+// Histogram splits the range of values into equal-width bins.
+// All values fall into a single bin when they are equal.
+func Histogram(values []float64, bins int) []HistogramBin {
+	if len(values) == 0 || bins <= 0 {
+		return []HistogramBin{}
+	}
+
+	min, max := values[0], values[0]
+	for _, v := range values {
+		min = math.Min(min, v)
+		max = math.Max(max, v)
+	}
+	if min == max {
+		return []HistogramBin{{Lower: min, Upper: max, Count: len(values)}}
+	}
+
+	width := (max - min) / float64(bins)
+	histogram := make([]HistogramBin, bins)
+	for i := range histogram {
+		histogram[i].Lower = min + width*float64(i)
+		histogram[i].Upper = min + width*float64(i+1)
+	}
+	histogram[bins-1].Upper = max
+
+	for _, v := range values {
+		i := int((v - min) / width)
+		if i >= bins {
+			i = bins - 1
+		}
+		histogram[i].Count++
+	}
+	return histogram
+}
+
+// percentile returns the q-th quantile of sorted values using linear interpolation
+func percentile(sorted []float64, q float64) float64 {
+	if len(sorted) == 1 {
+		return sorted[0]
+	}
+	pos := q * float64(len(sorted)-1)
+	lower := int(math.Floor(pos))
+	upper := int(math.Ceil(pos))
+	frac := pos - float64(lower)
+	return sorted[lower] + (sorted[upper]-sorted[lower])*frac
+}
+
+// CohenKappa measures agreement of two raters on paired binary labels beyond chance.
+// The returned bool is false when kappa is undefined, i.e. there are no labels or chance agreement is certain.
+func CohenKappa(a, b []bool) (float64, bool) {
+	n := len(a)
+	if n == 0 || n != len(b) {
+		return 0, false
+	}
+
+	agree, positiveA, positiveB := 0, 0, 0
+	for i := range a {
+		if a[i] == b[i] {
+			agree++
+		}
+		if a[i] {
+			positiveA++
+		}
+		if b[i] {
+			positiveB++
+		}
+	}
+
+	observed := float64(agree) / float64(n)
+	pa := float64(positiveA) / float64(n)
+	pb := float64(positiveB) / float64(n)
+	expected := pa*pb + (1-pa)*(1-pb)
+	if expected == 1 {
+		return 0, false
+	}
+	return (observed - expected) / (1 - expected), true
+}
@@ -0,0 +1,149 @@
+package stats
+
+import (
+	"math"
+	"testing"
+)
+
+func approx(a, b float64) bool {
+	return math.Abs(a-b) < 1e-9
+}
+
+func TestMcNemar(t *testing.T) {
+	tests := []struct {
+		onlyA, onlyB int
+		want         McNemarResult
+	}{
+		{0, 0, McNemarResult{PValue: 1}},
+		// Exact: P(X <= 1) for Binomial(10, 0.5) is 11/1024, doubled
+		{1, 9, McNemarResult{OnlyA: 1, OnlyB: 9, Statistic: 1, PValue: 22.0 / 1024, Exact: true}},
+		{9, 1, McNemarResult{OnlyA: 9, OnlyB: 1, Statistic: 1, PValue: 22.0 / 1024, Exact: true}},
+		{5, 5, McNemarResult{OnlyA: 5, OnlyB: 5, Statistic: 5, PValue: 1, Exact: true}},
+		{0, 3, McNemarResult{OnlyA: 0, OnlyB: 3, Statistic: 0, PValue: 0.25, Exact: true}},
+		// Chi-square with continuity correction: (|b-c|-1)^2 / (b+c)
+		{10, 30, McNemarResult{OnlyA: 10, OnlyB: 30, Statistic: 9.025, PValue: 0.002663119259138554}},
+		{40, 20, McNemarResult{OnlyA: 40, OnlyB: 20, Statistic: 361.0 / 60, PValue: 0.01417138825401233}},
+		{12, 14, McNemarResult{OnlyA: 12, OnlyB: 14, Statistic: 1.0 / 26, PValue: 0.84451926747294}},
+		{13, 13, McNemarResult{OnlyA: 13, OnlyB: 13, Statistic: 0, PValue: 1}},
+	}
+	for _, tt := range tests {
+		got := McNemar(tt.onlyA, tt.onlyB)
+		if got.OnlyA != tt.want.OnlyA || got.OnlyB != tt.want.OnlyB || got.Exact != tt.want.Exact ||
+			!approx(got.Statistic, tt.want.Statistic) || !approx(got.PValue, tt.want.PValue) {
+			t.Errorf("McNemar(%d, %d) = %+v, want %+v", tt.onlyA, tt.onlyB, got, tt.want)
+		}
+	}
+}
+
+func TestBootstrapMeanCI(t *testing.T) {
+	if ci := BootstrapMeanCI(nil, 1000, 0.95, 1); ci.Mean != 0 || ci.Lower != 0 || ci.Upper != 0 {
+		t.Errorf("empty values gave %+v, want a zero interval", ci)
+	}
+	if ci := BootstrapMeanCI([]float64{2, 2, 2}, 500, 0.9, 1); ci.Mean != 2 || ci.Lower != 2 || ci.Upper != 2 {
+		t.Errorf("constant values gave %+v, want the interval [2, 2]", ci)
+	}
+
+	// 100 pass/fail values at 50%: the standard error is 0.05, so the 95% interval is about 0.5 ± 0.098
+	values := make([]float64, 100)
+	for i := 0; i < 50; i++ {
+		values[i] = 1
+	}
+	ci := BootstrapMeanCI(values, 2000, 0.95, 42)
+	if ci.Mean != 0.5 || ci.Confidence != 0.95 || ci.Iterations != 2000 {
+		t.Errorf("got %+v, want mean 0.5 at 95%% over 2000 iterations", ci)
+	}
+	if ci.Lower < 0.38 || ci.Lower > 0.42 || ci.Upper < 0.58 || ci.Upper > 0.62 {
+		t.Errorf("interval [%v, %v], want about [0.40, 0.60]", ci.Lower, ci.Upper)
+	}
+	if again := BootstrapMeanCI(values, 2000, 0.95, 42); again != ci {
+		t.Errorf("the same seed gave %+v and %+v", ci, again)
+	}
+}
+
+func TestCohenKappa(t *testing.T) {
+	// 20 both yes, 5 only a, 10 only b, 15 both no: po = 0.7, pe = 0.5*0.6 + 0.5*0.4 = 0.5, kappa = 0.4
+	var a, b []bool
+	add := func(n int, x, y bool) {
+		for i := 0; i < n; i++ {
+			a, b = append(a, x), append(b, y)
+		}
+	}
+	add(20, true, true)
+	add(5, true, false)
+	add(10, false, true)
+	add(15, false, false)
+
+	tests := []struct {
+		name   string
+		a, b   []bool
+		kappa  float64
+		define bool
+	}{
+		{"textbook", a, b, 0.4, true},
+		{"perfect", []bool{true, false, true}, []bool{true, false, true}, 1, true},
+		{"opposite", []bool{true, false}, []bool{false, true}, -1, true},
+		{"chance", []bool{true, true, false, false}, []bool{true, false, true, false}, 0, true},
+		{"one label", []bool{true, true}, []bool{true, true}, 0, false},
+		{"empty", nil, nil, 0, false},
+		{"length mismatch", []bool{true}, []bool{true, false}, 0, false},
+	}
+	for _, tt := range tests {
+		kappa, ok := CohenKappa(tt.a, tt.b)
+		if ok != tt.define || !approx(kappa, tt.kappa) {
+			t.Errorf("%s: CohenKappa = %v, %v; want %v, %v", tt.name, kappa, ok, tt.kappa, tt.define)
+		}
+	}
+}
+
+func TestHistogram(t *testing.T) {
+	tests := []struct {
+		name   string
+		values []float64
+		bins   int
+		want   []HistogramBin
+	}{
+		{"empty", nil, 3, []HistogramBin{}},
+		{"no bins", []float64{1}, 0, []HistogramBin{}},
+		{"equal values", []float64{3, 3, 3}, 4, []HistogramBin{{3, 3, 3}}},
+		{"max in last bin", []float64{0, 1, 2, 3, 4}, 2, []HistogramBin{{0, 2, 2}, {2, 4, 3}}},
+		{"unsorted", []float64{1, 0, 0.5, 0.25, 0.75, 1}, 4, []HistogramBin{{0, 0.25, 1}, {0.25, 0.5, 1}, {0.5, 0.75, 1}, {0.75, 1, 3}}},
+	}
+	for _, tt := range tests {
+		got := Histogram(tt.values, tt.bins)
+		if len(got) != len(tt.want) {
+			t.Errorf("%s: Histogram = %+v, want %+v", tt.name, got, tt.want)
+			continue
+		}
+		for i := range got {
+			if !approx(got[i].Lower, tt.want[i].Lower) || !approx(got[i].Upper, tt.want[i].Upper) || got[i].Count != tt.want[i].Count {
+				t.Errorf("%s: Histogram = %+v, want %+v", tt.name, got, tt.want)
+				break
+			}
+		}
+	}
+}
+
+func TestPercentile(t *testing.T) {
+	values := []float64{4, 1, 3, 2}
+	tests := []struct {
+		values []float64
+		q      float64
+		want   float64
+	}{
+		{values, 0, 1},
+		{values, 0.25, 1.75},
+		{values, 0.5, 2.5},
+		{values, 0.9, 3.7},
+		{values, 1, 4},
+		{[]float64{7}, 0.3, 7},
+		{nil, 0.5, 0},
+	}
+	for _, tt := range tests {
+		if got := Percentile(tt.values, tt.q); !approx(got, tt.want) {
+			t.Errorf("Percentile(%v, %v) = %v, want %v", tt.values, tt.q, got, tt.want)
+		}
+	}
+	if values[0] != 4 {
+		t.Errorf("Percentile sorted its input: %v", values)
+	}
+}
@@ -0,0 +1,120 @@
+package repository
+
+import (
+	"database/sql"
+	"encoding/json"
+	"time"
+
+	"github.com/wzyjerry/llm-judge/internal/model"
+)
+
+// CreateComparisonReport stores a comparison report
+func CreateComparisonReport(report *model.ComparisonReport) (int, error) {
+	now := time.Now().Format(time.RFC3339)
+	modelsJSON, _ := json.Marshal(report.Models)
+	summaryJSON, _ := json.Marshal(report.Summary)
+	contentJSON, err := json.Marshal(report.Items)
+	if err != nil {
+		return 0, err
+	}
+
+	query := `
+		INSERT INTO comparison_reports (user_id, task_id, dataset, models, summary, content, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?)
+	`
+
+	result, err := db.Exec(query,
+		report.UserID, report.TaskID, report.Dataset,
+		string(modelsJSON), string(summaryJSON), string(contentJSON), now)
+	if err != nil {
+		return 0, err
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return 0, err
+	}
+
+	return int(id), nil
+}
+
+// GetComparisonReports returns all comparison reports of a user without their items
+func GetComparisonReports(userID int) ([]model.ComparisonReport, error) {
+	query := `
+		SELECT id, user_id, task_id, dataset, models, summary, created_at
+		FROM comparison_reports WHERE user_id = ? ORDER BY created_at DESC
+	`
+
+	rows, err := db.Query(query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	reports := []model.ComparisonReport{}
+	for rows.Next() {
+		var report model.ComparisonReport
+		var modelsStr, summaryStr string
+
+		err := rows.Scan(
+			&report.ID, &report.UserID, &report.TaskID, &report.Dataset,
+			&modelsStr, &summaryStr, &report.CreatedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		// Parse JSON fields
+		json.Unmarshal([]byte(modelsStr), &report.Models)
+		json.Unmarshal([]byte(summaryStr), &report.Summary)
+
+		reports = append(reports, report)
+	}
+
+	return reports, nil
+}
+
+// GetComparisonReportByID returns a comparison report with its items
+func GetComparisonReportByID(userID, reportID int) (*model.ComparisonReport, error) {
+	query := `
+		SELECT id, user_id, task_id, dataset, models, summary, content, created_at
+		FROM comparison_reports WHERE id = ? AND user_id = ?
+	`
+
+	report := &model.ComparisonReport{}
+	var modelsStr, summaryStr, contentStr string
+
+	err := db.QueryRow(query, reportID, userID).Scan(
+		&report.ID, &report.UserID, &report.TaskID, &report.Dataset,
+		&modelsStr, &summaryStr, &contentStr, &report.CreatedAt,
+	)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// Parse JSON fields
+	json.Unmarshal([]byte(modelsStr), &report.Models)
+	json.Unmarshal([]byte(summaryStr), &report.Summary)
+	json.Unmarshal([]byte(contentStr), &report.Items)
+
+	return report, nil
+}
+
+// DeleteComparisonReport deletes a comparison report
+func DeleteComparisonReport(userID, reportID int) (bool, error) {
+	query := `DELETE FROM comparison_reports WHERE id = ? AND user_id = ?`
+	result, err := db.Exec(query, reportID, userID)
+	if err != nil {
+		return false, err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+
+	return rowsAffected > 0, nil
+}
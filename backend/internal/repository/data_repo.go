@@ -2,22 +2,86 @@ package repository
 
 import (
 	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
 	"time"
 
 	"github.com/wzyjerry/llm-judge/internal/model"
 )
 
-// CreateUserData creates a new user data record
-func CreateUserData(userID int, filename, fileContent, description string) (int, error) {
+// CreateUserData creates a new user data record whose content, already in blob storage, becomes version 1
+func CreateUserData(userID int, filename, description, author, message string, blob *model.DataBlob) (int, error) {
+	var dataID int
+	err := WithTx(func(tx *sql.Tx) error {
+		var err error
+		dataID, err = createUserData(tx, userID, filename, description, author, message, blob, nil)
+		return err
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	return dataID, nil
+}
+
+// CreateDerivedUserData creates the data files produced by a dataset operation in one transaction,
+// returning their IDs in order. Each first version is described by the provenance of its file.
+func CreateDerivedUserData(userID int, author string, files []model.DerivedDataFile) ([]int, error) {
+	dataIDs := make([]int, 0, len(files))
+	err := WithTx(func(tx *sql.Tx) error {
+		for _, file := range files {
+			message := describeDataProvenance(file.Provenance)
+			dataID, err := createUserData(tx, userID, file.Filename, file.Description, author, message, file.Blob, file.Provenance)
+			if err != nil {
+				return err
+			}
+			dataIDs = append(dataIDs, dataID)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return dataIDs, nil
+}
+
+// describeDataProvenance summarizes a provenance as a version message, e.g. "split of d.jsonl v2 (train)"
+func describeDataProvenance(p *model.DataProvenance) string {
+	sources := make([]string, len(p.Sources))
+	for i, source := range p.Sources {
+		sources[i] = fmt.Sprintf("%s v%d", source.Filename, source.Version)
+	}
+	description := fmt.Sprintf("%s of %s", p.Operation, strings.Join(sources, ", "))
+	if p.Part != "" {
+		description += fmt.Sprintf(" (%s)", p.Part)
+	}
+	return description
+}
+
+// createUserData creates a user data record within tx, recording its content as version 1
+func createUserData(tx *sql.Tx, userID int, filename, description, author, message string, blob *model.DataBlob, provenance *model.DataProvenance) (int, error) {
 	now := time.Now().Format(time.RFC3339)
-	fileSize := len(fileContent)
+
+	var provenanceJSON sql.NullString
+	if provenance != nil {
+		data, err := json.Marshal(provenance)
+		if err != nil {
+			return 0, err
+		}
+		provenanceJSON = sql.NullString{String: string(data), Valid: true}
+	}
 
 	query := `
-		INSERT INTO user_data (user_id, filename, description, file_content, file_size, created_at, updated_at)
-		VALUES (?, ?, ?, ?, ?, ?, ?)
+		INSERT INTO user_data (user_id, filename, description, file_content, file_size, created_at, updated_at,
+			blob_key, checksum, item_count, provenance)
+		VALUES (?, ?, ?, '', ?, ?, ?, ?, ?, ?, ?)
 	`
 
-	result, err := db.Exec(query, userID, filename, description, fileContent, fileSize, now, now)
+	result, err := tx.Exec(query, userID, filename, description, blob.Size, now, now,
+		blob.Key, blob.Checksum, blob.ItemCount, provenanceJSON)
 	if err != nil {
 		return 0, err
 	}
@@ -27,13 +91,29 @@ func CreateUserData(userID int, filename, fileContent, description string) (int,
 		return 0, err
 	}
 
+	if _, err := recordUserDataVersion(tx, int(id), author, message); err != nil {
+		return 0, err
+	}
 	return int(id), nil
 }
 
+// parseDataProvenance decodes the provenance column of a data file, nil for uploaded files
+func parseDataProvenance(value sql.NullString) *model.DataProvenance {
+	if !value.Valid || value.String == "" {
+		return nil
+	}
+	var provenance model.DataProvenance
+	if err := json.Unmarshal([]byte(value.String), &provenance); err != nil {
+		return nil
+	}
+	return &provenance
+}
+
 // GetUserDataList returns all data files for a user
 func GetUserDataList(userID int) ([]model.UserData, error) {
 	query := `
-		SELECT id, user_id, filename, description, file_size, created_at, updated_at
+		SELECT id, user_id, filename, description, file_size, created_at, updated_at, COALESCE(current_version, 0),
+			COALESCE(checksum, ''), COALESCE(item_count, 0), COALESCE(is_public, 0), provenance
 		FROM user_data WHERE user_id = ? ORDER BY created_at DESC
 	`
 
@@ -46,13 +126,16 @@ func GetUserDataList(userID int) ([]model.UserData, error) {
 	var dataList []model.UserData
 	for rows.Next() {
 		var data model.UserData
+		var provenance sql.NullString
 		err := rows.Scan(
 			&data.ID, &data.UserID, &data.Filename, &data.Description,
-			&data.FileSize, &data.CreatedAt, &data.UpdatedAt,
+			&data.FileSize, &data.CreatedAt, &data.UpdatedAt, &data.CurrentVersion,
+			&data.Checksum, &data.ItemCount, &data.IsPublic, &provenance,
 		)
 		if err != nil {
 			return nil, err
 		}
+		data.Provenance = parseDataProvenance(provenance)
 		dataList = append(dataList, data)
 	}
 
@@ -62,14 +145,16 @@ func GetUserDataList(userID int) ([]model.UserData, error) {
 // GetUserDataAdapterByID returns user data by ID (without file content)
 func GetUserDataAdapterByID(userID, dataID int) (*model.UserData, error) {
 	query := `
-		SELECT id, user_id, filename, description, file_size, created_at, updated_at
+		SELECT id, user_id, filename, description, file_size, created_at, updated_at, COALESCE(current_version, 0),
+			COALESCE(checksum, ''), COALESCE(item_count, 0), COALESCE(is_public, 0)
 		FROM user_data WHERE id = ? AND user_id = ?
 	`
 
 	data := &model.UserData{}
 	err := db.QueryRow(query, dataID, userID).Scan(
 		&data.ID, &data.UserID, &data.Filename, &data.Description,
-		&data.FileSize, &data.CreatedAt, &data.UpdatedAt,
+		&data.FileSize, &data.CreatedAt, &data.UpdatedAt, &data.CurrentVersion,
+		&data.Checksum, &data.ItemCount, &data.IsPublic,
 	)
 
 	if err == sql.ErrNoRows {
@@ -82,17 +167,19 @@ func GetUserDataAdapterByID(userID, dataID int) (*model.UserData, error) {
 	return data, nil
 }
 
-// GetUserDataByID returns user data by ID (with file content)
+// GetUserDataByID returns user data by ID (with file content or its blob key)
 func GetUserDataByID(userID, dataID int) (*model.UserData, error) {
 	query := `
-		SELECT id, user_id, filename, description, file_content, file_size, created_at, updated_at
+		SELECT id, user_id, filename, description, file_content, file_size, created_at, updated_at, COALESCE(current_version, 0),
+			COALESCE(blob_key, ''), COALESCE(checksum, ''), COALESCE(item_count, 0), COALESCE(is_public, 0)
 		FROM user_data WHERE id = ? AND user_id = ?
 	`
 
 	data := &model.UserData{}
 	err := db.QueryRow(query, dataID, userID).Scan(
 		&data.ID, &data.UserID, &data.Filename, &data.Description,
-		&data.FileContent, &data.FileSize, &data.CreatedAt, &data.UpdatedAt,
+		&data.FileContent, &data.FileSize, &data.CreatedAt, &data.UpdatedAt, &data.CurrentVersion,
+		&data.BlobKey, &data.Checksum, &data.ItemCount, &data.IsPublic,
 	)
 
 	if err == sql.ErrNoRows {
@@ -123,45 +210,125 @@ func UpdateUserData(userID, dataID int, description string) (bool, error) {
 	return rowsAffected > 0, nil
 }
 
-// UpdateUserDataContent updates user data file content
-func UpdateUserDataContent(userID, dataID int, fileContent string) (bool, error) {
-	now := time.Now().Format(time.RFC3339)
-	fileSize := len(fileContent)
-	query := `UPDATE user_data SET file_content = ?, file_size = ?, updated_at = ? WHERE id = ? AND user_id = ?`
+// ErrDataVersionConflict is returned when a data file changed after the version an edit was based on
+var ErrDataVersionConflict = errors.New("data file was changed concurrently, reload it and try again")
+
+// UpdateUserDataContent points a data file at new content in blob storage, recorded as a new version.
+// The content must be derived from baseVersion: when the data file moved on since, nothing changes and
+// ErrDataVersionConflict is returned. Blob reads and writes happen before calling it, so the
+// transaction stays short. It returns the new version, and false when the data file does not exist
+// or belongs to another user.
+func UpdateUserDataContent(userID, dataID, baseVersion int, blob *model.DataBlob, author, message string) (int, bool, error) {
+	var version int
+	var success bool
+	err := WithTx(func(tx *sql.Tx) error {
+		now := time.Now().Format(time.RFC3339)
+		result, err := tx.Exec(`
+			UPDATE user_data SET file_content = '', blob_key = ?, checksum = ?, file_size = ?, item_count = ?, updated_at = ?
+			WHERE id = ? AND user_id = ? AND COALESCE(current_version, 0) = ?
+		`, blob.Key, blob.Checksum, blob.Size, blob.ItemCount, now, dataID, userID, baseVersion)
+		if err != nil {
+			return err
+		}
 
-	result, err := db.Exec(query, fileContent, fileSize, now, dataID, userID)
-	if err != nil {
-		return false, err
-	}
+		rowsAffected, err := result.RowsAffected()
+		if err != nil {
+			return err
+		}
+		if rowsAffected == 0 {
+			var exists bool
+			err := tx.QueryRow(`SELECT EXISTS(SELECT 1 FROM user_data WHERE id = ? AND user_id = ?)`, dataID, userID).Scan(&exists)
+			if err != nil {
+				return err
+			}
+			if exists {
+				return ErrDataVersionConflict
+			}
+			return nil
+		}
 
-	rowsAffected, err := result.RowsAffected()
+		success = true
+		version, err = recordUserDataVersion(tx, dataID, author, message)
+		return err
+	})
 	if err != nil {
-		return false, err
+		return 0, false, err
 	}
 
-	return rowsAffected > 0, nil
+	return version, success, nil
 }
 
-// DeleteUserData deletes user data
-func DeleteUserData(userID, dataID int) (bool, error) {
-	query := `DELETE FROM user_data WHERE id = ? AND user_id = ?`
-	result, err := db.Exec(query, dataID, userID)
-	if err != nil {
-		return false, err
+// DeleteUserData deletes user data with all its versions.
+// It returns the blob keys the data file used, which other data files may still reference.
+func DeleteUserData(userID, dataID int) ([]string, bool, error) {
+	var keys []string
+	var success bool
+	err := WithTx(func(tx *sql.Tx) error {
+		rows, err := tx.Query(`
+			SELECT blob_key FROM user_data_versions WHERE data_id = ? AND blob_key IS NOT NULL
+			UNION
+			SELECT blob_key FROM user_data WHERE id = ? AND user_id = ? AND blob_key IS NOT NULL
+		`, dataID, dataID, userID)
+		if err != nil {
+			return err
+		}
+		for rows.Next() {
+			var key string
+			if err := rows.Scan(&key); err != nil {
+				rows.Close()
+				return err
+			}
+			keys = append(keys, key)
+		}
+		rows.Close()
+
+		result, err := tx.Exec(`DELETE FROM user_data WHERE id = ? AND user_id = ?`, dataID, userID)
+		if err != nil {
+			return err
+		}
+
+		rowsAffected, err := result.RowsAffected()
+		if err != nil {
+			return err
+		}
+		success = rowsAffected > 0
+		if !success {
+			return nil
+		}
+
+		if _, err := tx.Exec(`DELETE FROM user_data_stats WHERE data_id = ?`, dataID); err != nil {
+			return err
+		}
+		if _, err := tx.Exec(`DELETE FROM user_data_shares WHERE data_id = ?`, dataID); err != nil {
+			return err
+		}
+		_, err = tx.Exec(`DELETE FROM user_data_versions WHERE data_id = ?`, dataID)
+		return err
+	})
+	if err != nil || !success {
+		return nil, false, err
 	}
 
-	rowsAffected, err := result.RowsAffected()
+	return keys, true, nil
+}
+
+// IsDataBlobReferenced reports whether any data file or version still uses the blob key
+func IsDataBlobReferenced(key string) (bool, error) {
+	var count int
+	err := db.QueryRow(`
+		SELECT (SELECT COUNT(*) FROM user_data WHERE blob_key = ?) + (SELECT COUNT(*) FROM user_data_versions WHERE blob_key = ?)
+	`, key, key).Scan(&count)
 	if err != nil {
 		return false, err
 	}
-
-	return rowsAffected > 0, nil
+	return count > 0, nil
 }
 
 // GetAllDataGlobal returns all data files (admin)
 func GetAllDataGlobal() ([]model.UserData, error) {
 	query := `
-		SELECT id, user_id, filename, description, file_size, created_at, updated_at
+		SELECT id, user_id, filename, description, file_size, created_at, updated_at, COALESCE(current_version, 0),
+			COALESCE(checksum, ''), COALESCE(item_count, 0), COALESCE(is_public, 0)
 		FROM user_data ORDER BY created_at DESC
 	`
 
@@ -176,7 +343,8 @@ func GetAllDataGlobal() ([]model.UserData, error) {
 		var data model.UserData
 		err := rows.Scan(
 			&data.ID, &data.UserID, &data.Filename, &data.Description,
-			&data.FileSize, &data.CreatedAt, &data.UpdatedAt,
+			&data.FileSize, &data.CreatedAt, &data.UpdatedAt, &data.CurrentVersion,
+			&data.Checksum, &data.ItemCount, &data.IsPublic,
 		)
 		if err != nil {
 			return nil, err
@@ -0,0 +1,42 @@
+package repository
+
+import (
+	"testing"
+
+	"github.com/wzyjerry/llm-judge/internal/model"
+)
+
+func TestCreateDerivedUserDataDescribesEachFile(t *testing.T) {
+	userID := createTestUser(t)
+	sources := []model.DataProvenanceSource{{DataID: 1, Version: 2, Filename: "d.jsonl"}}
+	blob := &model.DataBlob{Key: "datasets/x.jsonl", Checksum: "x", Size: 10, ItemCount: 1}
+	files := []model.DerivedDataFile{
+		{Filename: "d_train.jsonl", Blob: blob, Provenance: &model.DataProvenance{Operation: "split", Sources: sources, Part: "train"}},
+		{Filename: "d_test.jsonl", Blob: blob, Provenance: &model.DataProvenance{Operation: "split", Sources: sources, Part: "test"}},
+	}
+
+	dataIDs, err := CreateDerivedUserData(userID, "alice", files)
+	if err != nil {
+		t.Fatalf("CreateDerivedUserData: %v", err)
+	}
+	want := []string{"split of d.jsonl v2 (train)", "split of d.jsonl v2 (test)"}
+	for i, dataID := range dataIDs {
+		version, err := GetUserDataVersion(dataID, 1)
+		if err != nil || version == nil {
+			t.Fatalf("GetUserDataVersion(%d, 1) = %v, %v", dataID, version, err)
+		}
+		if version.Message != want[i] || version.Author != "alice" {
+			t.Errorf("file %d version 1 by %q with message %q, want alice and %q", i, version.Author, version.Message, want[i])
+		}
+	}
+}
+
+func TestDescribeDataProvenance(t *testing.T) {
+	merge := &model.DataProvenance{
+		Operation: "merge",
+		Sources:   []model.DataProvenanceSource{{Filename: "a.jsonl", Version: 1}, {Filename: "b.jsonl", Version: 3}},
+	}
+	if got, want := describeDataProvenance(merge), "merge of a.jsonl v1, b.jsonl v3"; got != want {
+		t.Errorf("describeDataProvenance = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,206 @@
+package repository
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/wzyjerry/llm-judge/internal/model"
+)
+
+// dataPermission computes the permission of the user bound to its three parameters on the data file d:
+// owner, the strongest permission shared with the user or one of their teams ("write" sorts after "read"),
+// read when the file is in the benchmark library, or empty.
+const dataPermission = `
+	CASE WHEN d.user_id = ? THEN 'owner' ELSE COALESCE(
+		(SELECT MAX(s.permission) FROM user_data_shares s
+		 WHERE s.data_id = d.id AND (s.user_id = ? OR s.team_id IN (SELECT team_id FROM team_members WHERE user_id = ?))),
+		CASE WHEN d.is_public = 1 THEN 'read' END,
+		''
+	) END
+`
+
+// GetUserDataAccess returns a data file (with file content or its blob key) together with the permission
+// of the user on it, or nil when the file does not exist or the user cannot access it
+func GetUserDataAccess(userID, dataID int) (*model.UserData, error) {
+	query := `
+		SELECT d.id, d.user_id, u.username, d.filename, d.description, d.file_content, d.file_size, d.created_at, d.updated_at,
+			COALESCE(d.current_version, 0), COALESCE(d.blob_key, ''), COALESCE(d.checksum, ''), COALESCE(d.item_count, 0),
+			COALESCE(d.is_public, 0), d.provenance, ` + dataPermission + `
+		FROM user_data d JOIN users u ON u.id = d.user_id
+		WHERE d.id = ?
+	`
+
+	data := &model.UserData{}
+	var provenance sql.NullString
+	err := db.QueryRow(query, userID, userID, userID, dataID).Scan(
+		&data.ID, &data.UserID, &data.Owner, &data.Filename, &data.Description,
+		&data.FileContent, &data.FileSize, &data.CreatedAt, &data.UpdatedAt, &data.CurrentVersion,
+		&data.BlobKey, &data.Checksum, &data.ItemCount, &data.IsPublic, &provenance, &data.Permission,
+	)
+
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if data.Permission == "" {
+		return nil, nil
+	}
+	data.Provenance = parseDataProvenance(provenance)
+
+	return data, nil
+}
+
+// GetSharedUserDataList returns the data files other users shared with a user or their teams
+func GetSharedUserDataList(userID int) ([]model.UserData, error) {
+	return queryAccessibleUserData(userID, `
+		d.user_id != ? AND d.id IN (
+			SELECT data_id FROM user_data_shares
+			WHERE user_id = ? OR team_id IN (SELECT team_id FROM team_members WHERE user_id = ?)
+		)
+	`, userID, userID, userID)
+}
+
+// GetPublicUserDataList returns the data files in the benchmark library, with the permission of a user on them
+func GetPublicUserDataList(userID int) ([]model.UserData, error) {
+	return queryAccessibleUserData(userID, `d.is_public = 1`)
+}
+
+// queryAccessibleUserData lists the data files matching where (without file content), with their owner
+// and the permission of the user on them
+func queryAccessibleUserData(userID int, where string, args ...interface{}) ([]model.UserData, error) {
+	query := `
+		SELECT d.id, d.user_id, u.username, d.filename, d.description, d.file_size, d.created_at, d.updated_at,
+			COALESCE(d.current_version, 0), COALESCE(d.checksum, ''), COALESCE(d.item_count, 0),
+			COALESCE(d.is_public, 0), d.provenance, ` + dataPermission + `
+		FROM user_data d JOIN users u ON u.id = d.user_id
+		WHERE ` + where + ` ORDER BY d.created_at DESC
+	`
+
+	rows, err := db.Query(query, append([]interface{}{userID, userID, userID}, args...)...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	dataList := []model.UserData{}
+	for rows.Next() {
+		var data model.UserData
+		var provenance sql.NullString
+		err := rows.Scan(
+			&data.ID, &data.UserID, &data.Owner, &data.Filename, &data.Description,
+			&data.FileSize, &data.CreatedAt, &data.UpdatedAt, &data.CurrentVersion,
+			&data.Checksum, &data.ItemCount, &data.IsPublic, &provenance, &data.Permission,
+		)
+		if err != nil {
+			return nil, err
+		}
+		data.Provenance = parseDataProvenance(provenance)
+		dataList = append(dataList, data)
+	}
+
+	return dataList, nil
+}
+
+// GetUserDataShares returns the shares of a data file, users first
+func GetUserDataShares(dataID int) ([]model.UserDataShare, error) {
+	query := `
+		SELECT s.id, s.data_id, s.user_id, COALESCE(u.username, ''), s.team_id, COALESCE(t.name, ''),
+			s.permission, COALESCE(s.created_by, ''), s.created_at
+		FROM user_data_shares s
+		LEFT JOIN users u ON u.id = s.user_id
+		LEFT JOIN teams t ON t.id = s.team_id
+		WHERE s.data_id = ? ORDER BY s.team_id IS NOT NULL, u.username, t.name
+	`
+
+	rows, err := db.Query(query, dataID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	shares := []model.UserDataShare{}
+	for rows.Next() {
+		var share model.UserDataShare
+		var userID, teamID sql.NullInt64
+		err := rows.Scan(&share.ID, &share.DataID, &userID, &share.Username, &teamID, &share.TeamName,
+			&share.Permission, &share.CreatedBy, &share.CreatedAt)
+		if err != nil {
+			return nil, err
+		}
+		if userID.Valid {
+			id := int(userID.Int64)
+			share.UserID = &id
+		}
+		if teamID.Valid {
+			id := int(teamID.Int64)
+			share.TeamID = &id
+		}
+		shares = append(shares, share)
+	}
+
+	return shares, nil
+}
+
+// ShareUserData grants a user or a team a permission on a data file, replacing the permission
+// they were granted before. It returns the ID of the share.
+func ShareUserData(dataID int, share *model.UserDataShareCreate, createdBy string) (int, error) {
+	now := time.Now().Format(time.RFC3339)
+
+	var shareID int
+	err := WithTx(func(tx *sql.Tx) error {
+		_, err := tx.Exec(`DELETE FROM user_data_shares WHERE data_id = ? AND (user_id = ? OR team_id = ?)`,
+			dataID, share.UserID, share.TeamID)
+		if err != nil {
+			return err
+		}
+
+		result, err := tx.Exec(`
+			INSERT INTO user_data_shares (data_id, user_id, team_id, permission, created_by, created_at)
+			VALUES (?, ?, ?, ?, ?, ?)
+		`, dataID, share.UserID, share.TeamID, share.Permission, createdBy, now)
+		if err != nil {
+			return err
+		}
+
+		id, err := result.LastInsertId()
+		shareID = int(id)
+		return err
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	return shareID, nil
+}
+
+// DeleteUserDataShare revokes a share of a data file
+func DeleteUserDataShare(dataID, shareID int) (bool, error) {
+	result, err := db.Exec(`DELETE FROM user_data_shares WHERE id = ? AND data_id = ?`, shareID, dataID)
+	if err != nil {
+		return false, err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+
+	return rowsAffected > 0, nil
+}
+
+// SetUserDataPublic adds a data file to the benchmark library or removes it (admin)
+func SetUserDataPublic(dataID int, public bool) (bool, error) {
+	result, err := db.Exec(`UPDATE user_data SET is_public = ? WHERE id = ?`, public, dataID)
+	if err != nil {
+		return false, err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+
+	return rowsAffected > 0, nil
+}
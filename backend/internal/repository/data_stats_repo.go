@@ -0,0 +1,42 @@
+package repository
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/wzyjerry/llm-judge/internal/model"
+)
+
+// GetDataStatistics returns the cached statistics of a data file version, or nil when not computed yet
+func GetDataStatistics(dataID, version int) (*model.DataStatistics, error) {
+	var content string
+	err := db.QueryRow(`SELECT statistics FROM user_data_stats WHERE data_id = ? AND version = ?`, dataID, version).Scan(&content)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var statistics model.DataStatistics
+	if err := json.Unmarshal([]byte(content), &statistics); err != nil {
+		return nil, fmt.Errorf("failed to parse cached data statistics: %w", err)
+	}
+	return &statistics, nil
+}
+
+// SaveDataStatistics caches the statistics of a data file version
+func SaveDataStatistics(statistics *model.DataStatistics) error {
+	content, err := json.Marshal(statistics)
+	if err != nil {
+		return err
+	}
+
+	_, err = db.Exec(`
+		INSERT OR REPLACE INTO user_data_stats (data_id, version, statistics, created_at)
+		VALUES (?, ?, ?, ?)
+	`, statistics.DataID, statistics.Version, string(content), time.Now().Format(time.RFC3339))
+	return err
+}
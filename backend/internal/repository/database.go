@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
 	"sync"
 	"time"
 
@@ -54,6 +55,39 @@ func InitDB(dbPath string) error {
 		return fmt.Errorf("failed to create tables: %w", err)
 	}
 
+	// Add columns introduced after the initial schema
+	if err := migrateTables(); err != nil {
+		return fmt.Errorf("failed to migrate tables: %w", err)
+	}
+
+	// Encrypt API keys left in plaintext by older versions
+	if count, err := EncryptPlaintextAPIKeys(); err != nil {
+		return fmt.Errorf("failed to encrypt model API keys: %w", err)
+	} else if count > 0 {
+		zap.L().Info("Encrypted plaintext model API keys", zap.Int("count", count))
+	}
+
+	// Record an initial version for model configs created before versioning
+	if count, err := BackfillModelConfigVersions(); err != nil {
+		return fmt.Errorf("failed to backfill model config versions: %w", err)
+	} else if count > 0 {
+		zap.L().Info("Recorded initial model config versions", zap.Int("count", count))
+	}
+
+	// Record an initial version for data files uploaded before versioning
+	if count, err := BackfillUserDataVersions(); err != nil {
+		return fmt.Errorf("failed to backfill data versions: %w", err)
+	} else if count > 0 {
+		zap.L().Info("Recorded initial data versions", zap.Int("count", count))
+	}
+
+	// Split reports created before the per-item store into report items
+	if count, err := BackfillReportItems(); err != nil {
+		return fmt.Errorf("failed to backfill report items: %w", err)
+	} else if count > 0 {
+		zap.L().Info("Backfilled report items", zap.Int("reports", count))
+	}
+
 	// Initialize admin user
 	if err := initializeAdminUser(); err != nil {
 		zap.L().Error("Failed to initialize admin user", zap.Error(err))
@@ -184,6 +218,56 @@ func createTables() error {
 		`CREATE INDEX IF NOT EXISTS idx_user_reports_user_id ON user_reports(user_id)`,
 		`CREATE INDEX IF NOT EXISTS idx_user_reports_task_id ON user_reports(task_id)`,
 
+		`CREATE TABLE IF NOT EXISTS report_items (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			report_id INTEGER NOT NULL,
+			item_index INTEGER NOT NULL,
+			original_index INTEGER NOT NULL,
+			expanded_index INTEGER NOT NULL,
+			user_input TEXT,
+			model_output TEXT,
+			reference_output TEXT,
+			inference_time REAL DEFAULT 0,
+			score REAL DEFAULT 0,
+			is_badcase INTEGER DEFAULT 0,
+			details TEXT,
+			error TEXT,
+			FOREIGN KEY (report_id) REFERENCES user_reports(id) ON DELETE CASCADE
+		)`,
+		`CREATE INDEX IF NOT EXISTS idx_report_items_report_id ON report_items(report_id, item_index)`,
+		`CREATE INDEX IF NOT EXISTS idx_report_items_badcase ON report_items(report_id, is_badcase)`,
+		`CREATE INDEX IF NOT EXISTS idx_report_items_original_index ON report_items(report_id, original_index, expanded_index)`,
+
+		`CREATE TABLE IF NOT EXISTS report_item_reviews (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			report_id INTEGER NOT NULL,
+			item_index INTEGER NOT NULL,
+			reviewer_id INTEGER NOT NULL,
+			reviewer TEXT NOT NULL,
+			score REAL,
+			is_badcase INTEGER,
+			comment TEXT,
+			failure_category TEXT,
+			created_at TEXT NOT NULL,
+			updated_at TEXT NOT NULL,
+			UNIQUE(report_id, item_index, reviewer_id),
+			FOREIGN KEY (report_id) REFERENCES user_reports(id) ON DELETE CASCADE
+		)`,
+
+		`CREATE TABLE IF NOT EXISTS comparison_reports (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			user_id INTEGER NOT NULL,
+			task_id TEXT NOT NULL,
+			dataset TEXT NOT NULL,
+			models TEXT NOT NULL,
+			summary TEXT NOT NULL,
+			content TEXT NOT NULL,
+			created_at TEXT NOT NULL,
+			FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+			FOREIGN KEY (task_id) REFERENCES user_tasks(task_id) ON DELETE CASCADE
+		)`,
+		`CREATE INDEX IF NOT EXISTS idx_comparison_reports_user_id ON comparison_reports(user_id)`,
+
 		`CREATE TABLE IF NOT EXISTS model_configs (
 			id INTEGER PRIMARY KEY AUTOINCREMENT,
 			model_name TEXT UNIQUE NOT NULL,
@@ -202,6 +286,121 @@ func createTables() error {
 		)`,
 		`CREATE INDEX IF NOT EXISTS idx_model_configs_model_name ON model_configs(model_name)`,
 		`CREATE INDEX IF NOT EXISTS idx_model_configs_is_active ON model_configs(is_active)`,
+
+		`CREATE TABLE IF NOT EXISTS model_endpoint_health (
+			config_id INTEGER NOT NULL,
+			api_url TEXT NOT NULL,
+			status TEXT NOT NULL,
+			latency_ms INTEGER DEFAULT 0,
+			model_available INTEGER DEFAULT 0,
+			error TEXT,
+			checked_at TEXT NOT NULL,
+			PRIMARY KEY (config_id, api_url),
+			FOREIGN KEY (config_id) REFERENCES model_configs(id) ON DELETE CASCADE
+		)`,
+
+		// Versions are kept after the config is deleted so old tasks stay reproducible
+		`CREATE TABLE IF NOT EXISTS model_config_versions (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			config_id INTEGER NOT NULL,
+			version INTEGER NOT NULL,
+			snapshot TEXT NOT NULL,
+			author TEXT,
+			change_note TEXT,
+			created_at TEXT NOT NULL,
+			UNIQUE(config_id, version)
+		)`,
+
+		`CREATE TABLE IF NOT EXISTS model_aliases (
+			alias TEXT PRIMARY KEY,
+			config_id INTEGER NOT NULL,
+			updated_by TEXT,
+			updated_at TEXT NOT NULL,
+			FOREIGN KEY (config_id) REFERENCES model_configs(id) ON DELETE CASCADE
+		)`,
+
+		`CREATE TABLE IF NOT EXISTS model_config_tags (
+			config_id INTEGER NOT NULL,
+			tag TEXT NOT NULL,
+			PRIMARY KEY (config_id, tag),
+			FOREIGN KEY (config_id) REFERENCES model_configs(id) ON DELETE CASCADE
+		)`,
+		`CREATE INDEX IF NOT EXISTS idx_model_config_tags_tag ON model_config_tags(tag)`,
+
+		`CREATE TABLE IF NOT EXISTS teams (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			name TEXT UNIQUE NOT NULL,
+			description TEXT,
+			created_by TEXT,
+			created_at TEXT NOT NULL
+		)`,
+
+		`CREATE TABLE IF NOT EXISTS team_members (
+			team_id INTEGER NOT NULL,
+			user_id INTEGER NOT NULL,
+			created_at TEXT NOT NULL,
+			PRIMARY KEY (team_id, user_id),
+			FOREIGN KEY (team_id) REFERENCES teams(id) ON DELETE CASCADE,
+			FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+		)`,
+		`CREATE INDEX IF NOT EXISTS idx_team_members_user_id ON team_members(user_id)`,
+
+		`CREATE TABLE IF NOT EXISTS leaderboards (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			name TEXT NOT NULL,
+			owner_id INTEGER NOT NULL,
+			team_id INTEGER,
+			filters TEXT NOT NULL,
+			created_at TEXT NOT NULL,
+			updated_at TEXT NOT NULL,
+			FOREIGN KEY (owner_id) REFERENCES users(id) ON DELETE CASCADE,
+			FOREIGN KEY (team_id) REFERENCES teams(id) ON DELETE SET NULL
+		)`,
+		`CREATE INDEX IF NOT EXISTS idx_leaderboards_owner_id ON leaderboards(owner_id)`,
+		`CREATE INDEX IF NOT EXISTS idx_leaderboards_team_id ON leaderboards(team_id)`,
+
+		// Tasks pin a version so the data they evaluated can be read back after later edits
+		`CREATE TABLE IF NOT EXISTS user_data_versions (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			data_id INTEGER NOT NULL,
+			version INTEGER NOT NULL,
+			file_content TEXT NOT NULL,
+			file_size INTEGER,
+			item_count INTEGER DEFAULT 0,
+			author TEXT,
+			message TEXT,
+			created_at TEXT NOT NULL,
+			UNIQUE(data_id, version),
+			FOREIGN KEY (data_id) REFERENCES user_data(id) ON DELETE CASCADE
+		)`,
+
+		// Statistics are cached per version since versions never change
+		`CREATE TABLE IF NOT EXISTS user_data_stats (
+			data_id INTEGER NOT NULL,
+			version INTEGER NOT NULL,
+			statistics TEXT NOT NULL,
+			created_at TEXT NOT NULL,
+			PRIMARY KEY (data_id, version),
+			FOREIGN KEY (data_id) REFERENCES user_data(id) ON DELETE CASCADE
+		)`,
+
+		// Each share grants either a user or the members of a team access to a data file
+		`CREATE TABLE IF NOT EXISTS user_data_shares (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			data_id INTEGER NOT NULL,
+			user_id INTEGER,
+			team_id INTEGER,
+			permission TEXT NOT NULL,
+			created_by TEXT,
+			created_at TEXT NOT NULL,
+			FOREIGN KEY (data_id) REFERENCES user_data(id) ON DELETE CASCADE,
+			FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+			FOREIGN KEY (team_id) REFERENCES teams(id) ON DELETE CASCADE
+		)`,
+		`CREATE UNIQUE INDEX IF NOT EXISTS idx_user_data_shares_user ON user_data_shares(data_id, user_id) WHERE user_id IS NOT NULL`,
+		`CREATE UNIQUE INDEX IF NOT EXISTS idx_user_data_shares_team ON user_data_shares(data_id, team_id) WHERE team_id IS NOT NULL`,
+		`CREATE INDEX IF NOT EXISTS idx_user_data_shares_user_id ON user_data_shares(user_id)`,
+		`CREATE INDEX IF NOT EXISTS idx_user_data_shares_team_id ON user_data_shares(team_id)`,
 	}
 
 	for _, table := range tables {
@@ -213,6 +412,76 @@ func createTables() error {
 	return nil
 }
 
+// migrateTables adds columns that are missing from databases created by older versions
+func migrateTables() error {
+	columns := []struct {
+		table      string
+		column     string
+		definition string
+	}{
+		{"model_configs", "upstream_missing", "INTEGER DEFAULT 0"},
+		{"model_configs", "upstream_checked_at", "TEXT"},
+		{"model_configs", "current_version", "INTEGER DEFAULT 0"},
+		{"user_reports", "items_materialized", "INTEGER DEFAULT 0"},
+		{"user_reports", "report_config", "TEXT"},
+		{"user_reports", "item_count", "INTEGER DEFAULT 0"},
+		{"user_reports", "badcase_count", "INTEGER DEFAULT 0"},
+		{"user_reports", "parent_report_id", "INTEGER"},
+		{"user_reports", "derivation", "TEXT"},
+		{"report_items", "meta", "TEXT"},
+		{"user_data", "current_version", "INTEGER DEFAULT 0"},
+		{"user_data", "blob_key", "TEXT"},
+		{"user_data", "checksum", "TEXT"},
+		{"user_data", "item_count", "INTEGER DEFAULT 0"},
+		{"user_data", "is_public", "INTEGER DEFAULT 0"},
+		{"user_data", "provenance", "TEXT"},
+		{"user_data_versions", "blob_key", "TEXT"},
+		{"user_data_versions", "checksum", "TEXT"},
+	}
+
+	for _, col := range columns {
+		if err := addColumnIfNotExists(col.table, col.column, col.definition); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// addColumnIfNotExists adds a column to a table unless it already exists
+func addColumnIfNotExists(table, column, definition string) error {
+	rows, err := db.Query(fmt.Sprintf("PRAGMA table_info(%s)", table))
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var cid, notNull, pk int
+		var name, colType string
+		var defaultValue sql.NullString
+		if err := rows.Scan(&cid, &name, &colType, &notNull, &defaultValue, &pk); err != nil {
+			return err
+		}
+		if name == column {
+			return nil
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	query := fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s %s", table, column, definition)
+	if _, err := db.Exec(query); err != nil {
+		// The other service may have added the column since it was checked
+		if strings.Contains(err.Error(), "duplicate column name") {
+			return nil
+		}
+		return fmt.Errorf("failed to execute SQL: %s, error: %w", query, err)
+	}
+	return nil
+}
+
 // WithTx executes a function within a transaction
 func WithTx(fn func(*sql.Tx) error) error {
 	tx, err := db.Begin()
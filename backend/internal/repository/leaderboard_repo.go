@@ -0,0 +1,209 @@
+package repository
+
+import (
+	"database/sql"
+	"encoding/json"
+	"strings"
+	"time"
+
+	"github.com/wzyjerry/llm-judge/internal/model"
+)
+
+// GetLeaderboardReports returns the reports of the given users, oldest first.
+// An empty dataset matches every dataset and nil models match every model.
+func GetLeaderboardReports(userIDs []int, dataset string, models []string) ([]model.LeaderboardReport, error) {
+	reports := []model.LeaderboardReport{}
+	if len(userIDs) == 0 || (models != nil && len(models) == 0) {
+		return reports, nil
+	}
+
+	query := `
+		SELECT r.id, r.user_id, u.username, r.task_id, r.dataset, r.model, r.report_config, r.summary, r.created_at
+		FROM user_reports r JOIN users u ON u.id = r.user_id
+		WHERE r.user_id IN (` + placeholders(len(userIDs)) + `)`
+	args := []interface{}{}
+	for _, id := range userIDs {
+		args = append(args, id)
+	}
+
+	if dataset != "" {
+		query += " AND r.dataset = ?"
+		args = append(args, dataset)
+	}
+	if models != nil {
+		query += " AND r.model IN (" + placeholders(len(models)) + ")"
+		for _, name := range models {
+			args = append(args, name)
+		}
+	}
+	query += " ORDER BY r.created_at, r.id"
+
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var report model.LeaderboardReport
+		var configStr, summaryStr sql.NullString
+
+		err := rows.Scan(
+			&report.ID, &report.UserID, &report.Username, &report.TaskID, &report.Dataset,
+			&report.Model, &configStr, &summaryStr, &report.CreatedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		// Parse JSON fields
+		if configStr.Valid {
+			var config struct {
+				Scoring string `json:"scoring"`
+			}
+			json.Unmarshal([]byte(configStr.String), &config)
+			report.Scoring = config.Scoring
+		}
+		if summaryStr.Valid {
+			json.Unmarshal([]byte(summaryStr.String), &report.Summary)
+		}
+
+		reports = append(reports, report)
+	}
+
+	return reports, nil
+}
+
+// placeholders returns n comma separated SQL placeholders
+func placeholders(n int) string {
+	return strings.TrimSuffix(strings.Repeat("?, ", n), ", ")
+}
+
+// CreateLeaderboard saves a leaderboard definition
+func CreateLeaderboard(ownerID int, leaderboard *model.SavedLeaderboardCreate) (int, error) {
+	now := time.Now().Format(time.RFC3339)
+	filtersJSON, _ := json.Marshal(leaderboard.Filters)
+
+	query := `
+		INSERT INTO leaderboards (name, owner_id, team_id, filters, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?)
+	`
+
+	result, err := db.Exec(query, leaderboard.Name, ownerID, leaderboard.TeamID, string(filtersJSON), now, now)
+	if err != nil {
+		return 0, err
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return 0, err
+	}
+
+	return int(id), nil
+}
+
+// UpdateLeaderboard replaces the definition of a leaderboard owned by the user
+func UpdateLeaderboard(leaderboardID, ownerID int, leaderboard *model.SavedLeaderboardCreate) (bool, error) {
+	now := time.Now().Format(time.RFC3339)
+	filtersJSON, _ := json.Marshal(leaderboard.Filters)
+
+	query := `
+		UPDATE leaderboards SET name = ?, team_id = ?, filters = ?, updated_at = ?
+		WHERE id = ? AND owner_id = ?
+	`
+
+	result, err := db.Exec(query, leaderboard.Name, leaderboard.TeamID, string(filtersJSON), now, leaderboardID, ownerID)
+	if err != nil {
+		return false, err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+
+	return rowsAffected > 0, nil
+}
+
+// savedLeaderboardSelect selects saved leaderboards (aliased l) in scanSavedLeaderboard order
+const savedLeaderboardSelect = `
+	SELECT l.id, l.name, l.owner_id, u.username, l.team_id, t.name, l.filters, l.created_at, l.updated_at
+	FROM leaderboards l
+	JOIN users u ON u.id = l.owner_id
+	LEFT JOIN teams t ON t.id = l.team_id
+`
+
+// scanSavedLeaderboard reads a leaderboard selected with savedLeaderboardSelect
+func scanSavedLeaderboard(scanner interface{ Scan(...interface{}) error }) (*model.SavedLeaderboard, error) {
+	leaderboard := &model.SavedLeaderboard{}
+	var teamID sql.NullInt64
+	var teamName sql.NullString
+	var filtersStr string
+
+	err := scanner.Scan(
+		&leaderboard.ID, &leaderboard.Name, &leaderboard.OwnerID, &leaderboard.Owner,
+		&teamID, &teamName, &filtersStr, &leaderboard.CreatedAt, &leaderboard.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	if teamID.Valid {
+		id := int(teamID.Int64)
+		leaderboard.TeamID = &id
+	}
+	leaderboard.TeamName = teamName.String
+	json.Unmarshal([]byte(filtersStr), &leaderboard.Filters)
+
+	return leaderboard, nil
+}
+
+// GetUserLeaderboards returns the leaderboards a user owns or that are shared with one of the user's teams
+func GetUserLeaderboards(userID int) ([]model.SavedLeaderboard, error) {
+	query := savedLeaderboardSelect + `
+		WHERE l.owner_id = ? OR l.team_id IN (SELECT team_id FROM team_members WHERE user_id = ?)
+		ORDER BY l.name, l.id
+	`
+
+	rows, err := db.Query(query, userID, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	leaderboards := []model.SavedLeaderboard{}
+	for rows.Next() {
+		leaderboard, err := scanSavedLeaderboard(rows)
+		if err != nil {
+			return nil, err
+		}
+		leaderboards = append(leaderboards, *leaderboard)
+	}
+
+	return leaderboards, nil
+}
+
+// GetLeaderboardByID returns a saved leaderboard, or nil when it does not exist
+func GetLeaderboardByID(leaderboardID int) (*model.SavedLeaderboard, error) {
+	row := db.QueryRow(savedLeaderboardSelect+` WHERE l.id = ?`, leaderboardID)
+	leaderboard, err := scanSavedLeaderboard(row)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return leaderboard, err
+}
+
+// DeleteLeaderboard deletes a leaderboard owned by the user
+func DeleteLeaderboard(leaderboardID, ownerID int) (bool, error) {
+	result, err := db.Exec(`DELETE FROM leaderboards WHERE id = ? AND owner_id = ?`, leaderboardID, ownerID)
+	if err != nil {
+		return false, err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+
+	return rowsAffected > 0, nil
+}
@@ -0,0 +1,206 @@
+package repository
+
+import (
+	"database/sql"
+	"strings"
+	"time"
+
+	"github.com/wzyjerry/llm-judge/internal/model"
+)
+
+// SetModelAlias points an alias at a model config, replacing its previous target atomically
+func SetModelAlias(alias string, configID int, updatedBy string) error {
+	query := `
+		INSERT INTO model_aliases (alias, config_id, updated_by, updated_at)
+		VALUES (?, ?, ?, ?)
+		ON CONFLICT(alias) DO UPDATE SET
+			config_id = excluded.config_id,
+			updated_by = excluded.updated_by,
+			updated_at = excluded.updated_at
+	`
+	_, err := db.Exec(query, alias, configID, updatedBy, time.Now().Format(time.RFC3339))
+	return err
+}
+
+// DeleteModelAlias deletes an alias
+func DeleteModelAlias(alias string) (bool, error) {
+	result, err := db.Exec(`DELETE FROM model_aliases WHERE alias = ?`, alias)
+	if err != nil {
+		return false, err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+
+	return rowsAffected > 0, nil
+}
+
+// GetAllModelAliases returns all aliases with the model name they resolve to
+func GetAllModelAliases() ([]model.ModelAlias, error) {
+	query := `
+		SELECT a.alias, a.config_id, m.model_name, a.updated_by, a.updated_at
+		FROM model_aliases a
+		JOIN model_configs m ON m.id = a.config_id
+		ORDER BY a.alias
+	`
+
+	rows, err := db.Query(query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	aliases := []model.ModelAlias{}
+	for rows.Next() {
+		var a model.ModelAlias
+		var updatedBy sql.NullString
+		if err := rows.Scan(&a.Alias, &a.ConfigID, &a.ModelName, &updatedBy, &a.UpdatedAt); err != nil {
+			return nil, err
+		}
+		a.UpdatedBy = updatedBy.String
+		aliases = append(aliases, a)
+	}
+
+	return aliases, nil
+}
+
+// GetModelConfigByAlias returns the model config an alias points to
+func GetModelConfigByAlias(alias string) (*model.ModelConfig, error) {
+	query := `SELECT ` + modelConfigColumns + ` FROM model_configs WHERE id = (SELECT config_id FROM model_aliases WHERE alias = ?)`
+
+	config, err := scanModelConfig(db.QueryRow(query, alias))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return config, nil
+}
+
+// ResolveModelConfig returns the model config for a model name or an alias.
+// Model names take precedence over aliases.
+func ResolveModelConfig(nameOrAlias string) (*model.ModelConfig, error) {
+	config, err := GetModelConfigByName(nameOrAlias)
+	if err != nil || config != nil {
+		return config, err
+	}
+	return GetModelConfigByAlias(nameOrAlias)
+}
+
+// SetModelConfigTags replaces the tags of a model config
+func SetModelConfigTags(configID int, tags []string) error {
+	return WithTx(func(tx *sql.Tx) error {
+		if _, err := tx.Exec(`DELETE FROM model_config_tags WHERE config_id = ?`, configID); err != nil {
+			return err
+		}
+
+		for _, tag := range tags {
+			if _, err := tx.Exec(`INSERT OR IGNORE INTO model_config_tags (config_id, tag) VALUES (?, ?)`, configID, tag); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// GetAllModelConfigTags returns the tags of every model config grouped by config ID
+func GetAllModelConfigTags() (map[int][]string, error) {
+	rows, err := db.Query(`SELECT config_id, tag FROM model_config_tags ORDER BY config_id, tag`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	result := make(map[int][]string)
+	for rows.Next() {
+		var configID int
+		var tag string
+		if err := rows.Scan(&configID, &tag); err != nil {
+			return nil, err
+		}
+		result[configID] = append(result[configID], tag)
+	}
+
+	return result, nil
+}
+
+// GetAllModelConfigAliases returns the aliases of every model config grouped by config ID
+func GetAllModelConfigAliases() (map[int][]string, error) {
+	rows, err := db.Query(`SELECT config_id, alias FROM model_aliases ORDER BY config_id, alias`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	result := make(map[int][]string)
+	for rows.Next() {
+		var configID int
+		var alias string
+		if err := rows.Scan(&configID, &alias); err != nil {
+			return nil, err
+		}
+		result[configID] = append(result[configID], alias)
+	}
+
+	return result, nil
+}
+
+// GetModelConfigsByTags returns the model configs that carry every given tag
+func GetModelConfigsByTags(tags []string, includeInactive bool) ([]model.ModelConfig, error) {
+	query := `SELECT ` + modelConfigColumns + ` FROM model_configs WHERE 1 = 1`
+	args := []interface{}{}
+
+	if !includeInactive {
+		query += " AND is_active = 1"
+	}
+	if len(tags) > 0 {
+		tags = uniqueStrings(tags)
+		placeholders := strings.TrimSuffix(strings.Repeat("?, ", len(tags)), ", ")
+		query += ` AND id IN (
+			SELECT config_id FROM model_config_tags
+			WHERE tag IN (` + placeholders + `)
+			GROUP BY config_id
+			HAVING COUNT(DISTINCT tag) = ?
+		)`
+		for _, tag := range tags {
+			args = append(args, tag)
+		}
+		args = append(args, len(tags))
+	}
+
+	query += " ORDER BY created_at DESC"
+
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	configs := []model.ModelConfig{}
+	for rows.Next() {
+		config, err := scanModelConfig(rows)
+		if err != nil {
+			return nil, err
+		}
+		configs = append(configs, *config)
+	}
+
+	return configs, nil
+}
+
+// uniqueStrings returns the distinct values in their original order
+func uniqueStrings(values []string) []string {
+	seen := make(map[string]bool, len(values))
+	result := make([]string, 0, len(values))
+	for _, v := range values {
+		if !seen[v] {
+			seen[v] = true
+			result = append(result, v)
+		}
+	}
+	return result
+}
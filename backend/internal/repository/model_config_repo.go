@@ -3,14 +3,16 @@ package repository
 import (
 	"database/sql"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"time"
 
 	"github.com/wzyjerry/llm-judge/internal/model"
+	"github.com/wzyjerry/llm-judge/internal/pkg/secret"
 )
 
-// CreateModelConfig creates a new model config
-func CreateModelConfig(config *model.ModelConfigCreate) (int, error) {
+// CreateModelConfig creates a new model config and records it as version 1
+func CreateModelConfig(config *model.ModelConfigCreate, author string) (int, error) {
 	now := time.Now().Format(time.RFC3339)
 	apiUrlsJSON, _ := json.Marshal(config.APIUrls)
 
@@ -20,33 +22,92 @@ func CreateModelConfig(config *model.ModelConfigCreate) (int, error) {
 		isVLLM = 1
 	}
 
+	// Encrypt API key at rest
+	encryptedKey, err := secret.Encrypt(config.APIKey)
+	if err != nil {
+		return 0, fmt.Errorf("failed to encrypt api_key: %w", err)
+	}
+
 	query := `
 		INSERT INTO model_configs (model_name, api_urls, api_key, temperature, top_p, max_tokens, timeout, max_concurrency, description, is_active, is_vllm, created_at, updated_at)
 		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, 1, ?, ?, ?)
 	`
 
-	result, err := db.Exec(query,
-		config.ModelName, string(apiUrlsJSON), config.APIKey,
-		config.Temperature, config.TopP, config.MaxTokens,
-		config.Timeout, config.MaxConcurrency, config.Description, isVLLM, now, now)
+	var configID int
+	err = WithTx(func(tx *sql.Tx) error {
+		result, err := tx.Exec(query,
+			config.ModelName, string(apiUrlsJSON), encryptedKey,
+			config.Temperature, config.TopP, config.MaxTokens,
+			config.Timeout, config.MaxConcurrency, config.Description, isVLLM, now, now)
+		if err != nil {
+			return err
+		}
+
+		id, err := result.LastInsertId()
+		if err != nil {
+			return err
+		}
+		configID = int(id)
+
+		_, err = recordModelConfigVersion(tx, configID, author, "Created")
+		return err
+	})
 	if err != nil {
 		return 0, err
 	}
 
-	id, err := result.LastInsertId()
+	return configID, nil
+}
+
+// modelConfigColumns lists the model_configs columns read by scanModelConfig
+const modelConfigColumns = `id, model_name, api_urls, api_key, temperature, top_p, max_tokens, timeout, max_concurrency, description, is_active, is_vllm, created_at, updated_at, current_version, upstream_missing, upstream_checked_at`
+
+// rowScanner is implemented by *sql.Row and *sql.Rows
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+// scanModelConfig scans a row selected with modelConfigColumns
+func scanModelConfig(row rowScanner) (*model.ModelConfig, error) {
+	config := &model.ModelConfig{}
+	var apiUrlsStr, apiKeyStr, upstreamCheckedAt sql.NullString
+	var currentVersion, upstreamMissing sql.NullInt64
+
+	err := row.Scan(
+		&config.ID, &config.ModelName, &apiUrlsStr, &apiKeyStr,
+		&config.Temperature, &config.TopP, &config.MaxTokens,
+		&config.Timeout, &config.MaxConcurrency, &config.Description,
+		&config.IsActive, &config.IsVLLM, &config.CreatedAt, &config.UpdatedAt,
+		&currentVersion, &upstreamMissing, &upstreamCheckedAt,
+	)
 	if err != nil {
-		return 0, err
+		return nil, err
+	}
+
+	// Parse JSON fields
+	if apiUrlsStr.Valid {
+		json.Unmarshal([]byte(apiUrlsStr.String), &config.APIUrls)
+	}
+	if apiKeyStr.Valid {
+		apiKey, err := secret.Decrypt(apiKeyStr.String)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decrypt api_key of model config %d: %w", config.ID, err)
+		}
+		config.APIKey = apiKey
+	} else {
+		config.APIKey = ""
 	}
+	config.MaskedAPIKey = secret.Mask(config.APIKey)
+	config.CurrentVersion = int(currentVersion.Int64)
+	config.UpstreamMissing = int(upstreamMissing.Int64)
+	config.UpstreamCheckedAt = upstreamCheckedAt.String
 
-	return int(id), nil
+	return config, nil
 }
 
 // GetAllModelConfigs returns all model configs
 func GetAllModelConfigs(includeInactive bool) ([]model.ModelConfig, error) {
-	query := `
-		SELECT id, model_name, api_urls, api_key, temperature, top_p, max_tokens, timeout, max_concurrency, description, is_active, is_vllm, created_at, updated_at
-		FROM model_configs
-	`
+	query := `SELECT ` + modelConfigColumns + ` FROM model_configs`
 
 	if !includeInactive {
 		query += " WHERE is_active = 1"
@@ -62,30 +123,11 @@ func GetAllModelConfigs(includeInactive bool) ([]model.ModelConfig, error) {
 
 	var configs []model.ModelConfig
 	for rows.Next() {
-		var config model.ModelConfig
-		var apiUrlsStr, apiKeyStr sql.NullString
-
-		err := rows.Scan(
-			&config.ID, &config.ModelName, &apiUrlsStr, &apiKeyStr,
-			&config.Temperature, &config.TopP, &config.MaxTokens,
-			&config.Timeout, &config.MaxConcurrency, &config.Description,
-			&config.IsActive, &config.IsVLLM, &config.CreatedAt, &config.UpdatedAt,
-		)
+		config, err := scanModelConfig(rows)
 		if err != nil {
 			return nil, err
 		}
-
-		// Parse JSON fields
-		if apiUrlsStr.Valid {
-			json.Unmarshal([]byte(apiUrlsStr.String), &config.APIUrls)
-		}
-		if apiKeyStr.Valid {
-			config.APIKey = apiKeyStr.String
-		} else {
-			config.APIKey = ""
-		}
-
-		configs = append(configs, config)
+		configs = append(configs, *config)
 	}
 
 	return configs, nil
@@ -93,21 +135,9 @@ func GetAllModelConfigs(includeInactive bool) ([]model.ModelConfig, error) {
 
 // GetModelConfigByID returns a model config by ID
 func GetModelConfigByID(configID int) (*model.ModelConfig, error) {
-	query := `
-		SELECT id, model_name, api_urls, api_key, temperature, top_p, max_tokens, timeout, max_concurrency, description, is_active, is_vllm, created_at, updated_at
-		FROM model_configs WHERE id = ?
-	`
-
-	config := &model.ModelConfig{}
-	var apiUrlsStr, apiKeyStr sql.NullString
-
-	err := db.QueryRow(query, configID).Scan(
-		&config.ID, &config.ModelName, &apiUrlsStr, &apiKeyStr,
-		&config.Temperature, &config.TopP, &config.MaxTokens,
-		&config.Timeout, &config.MaxConcurrency, &config.Description,
-		&config.IsActive, &config.IsVLLM, &config.CreatedAt, &config.UpdatedAt,
-	)
+	query := `SELECT ` + modelConfigColumns + ` FROM model_configs WHERE id = ?`
 
+	config, err := scanModelConfig(db.QueryRow(query, configID))
 	if err == sql.ErrNoRows {
 		return nil, nil
 	}
@@ -115,36 +145,14 @@ func GetModelConfigByID(configID int) (*model.ModelConfig, error) {
 		return nil, err
 	}
 
-	// Parse JSON fields
-	if apiUrlsStr.Valid {
-		json.Unmarshal([]byte(apiUrlsStr.String), &config.APIUrls)
-	}
-	if apiKeyStr.Valid {
-		config.APIKey = apiKeyStr.String
-	} else {
-		config.APIKey = ""
-	}
-
 	return config, nil
 }
 
 // GetModelConfigByName returns a model config by name
 func GetModelConfigByName(modelName string) (*model.ModelConfig, error) {
-	query := `
-		SELECT id, model_name, api_urls, api_key, temperature, top_p, max_tokens, timeout, max_concurrency, description, is_active, is_vllm, created_at, updated_at
-		FROM model_configs WHERE model_name = ?
-	`
-
-	config := &model.ModelConfig{}
-	var apiUrlsStr, apiKeyStr sql.NullString
-
-	err := db.QueryRow(query, modelName).Scan(
-		&config.ID, &config.ModelName, &apiUrlsStr, &apiKeyStr,
-		&config.Temperature, &config.TopP, &config.MaxTokens,
-		&config.Timeout, &config.MaxConcurrency, &config.Description,
-		&config.IsActive, &config.IsVLLM, &config.CreatedAt, &config.UpdatedAt,
-	)
+	query := `SELECT ` + modelConfigColumns + ` FROM model_configs WHERE model_name = ?`
 
+	config, err := scanModelConfig(db.QueryRow(query, modelName))
 	if err == sql.ErrNoRows {
 		return nil, nil
 	}
@@ -152,21 +160,43 @@ func GetModelConfigByName(modelName string) (*model.ModelConfig, error) {
 		return nil, err
 	}
 
-	// Parse JSON fields
-	if apiUrlsStr.Valid {
-		json.Unmarshal([]byte(apiUrlsStr.String), &config.APIUrls)
-	}
-	if apiKeyStr.Valid {
-		config.APIKey = apiKeyStr.String
-	} else {
-		config.APIKey = ""
+	return config, nil
+}
+
+// SetModelConfigUpstreamStatus records whether the model is still listed by its endpoints
+func SetModelConfigUpstreamStatus(configID int, missing bool) error {
+	upstreamMissing := 0
+	if missing {
+		upstreamMissing = 1
 	}
 
-	return config, nil
+	query := `UPDATE model_configs SET upstream_missing = ?, upstream_checked_at = ? WHERE id = ?`
+	_, err := db.Exec(query, upstreamMissing, time.Now().Format(time.RFC3339), configID)
+	return err
+}
+
+// ErrInvalidModelConfigUpdate is returned when an update sets a field that is not editable or
+// gives a field a value of the wrong kind
+var ErrInvalidModelConfigUpdate = errors.New("invalid model config update")
+
+// editableModelConfigColumns are the columns UpdateModelConfig may set. The version pointer and the
+// upstream check are kept by the repository itself.
+var editableModelConfigColumns = map[string]bool{
+	"model_name":      true,
+	"api_urls":        true,
+	"api_key":         true,
+	"temperature":     true,
+	"top_p":           true,
+	"max_tokens":      true,
+	"timeout":         true,
+	"max_concurrency": true,
+	"description":     true,
+	"is_active":       true,
+	"is_vllm":         true,
 }
 
-// UpdateModelConfig updates a model config
-func UpdateModelConfig(configID int, updates map[string]interface{}) (bool, error) {
+// UpdateModelConfig updates a model config and records the result as a new version
+func UpdateModelConfig(configID int, updates map[string]interface{}, author, changeNote string) (bool, error) {
 	now := time.Now().Format(time.RFC3339)
 
 	// Build update query dynamically
@@ -174,6 +204,10 @@ func UpdateModelConfig(configID int, updates map[string]interface{}) (bool, erro
 	args := []interface{}{now}
 
 	for key, value := range updates {
+		if !editableModelConfigColumns[key] {
+			return false, fmt.Errorf("%w: %s is not an editable field", ErrInvalidModelConfigUpdate, key)
+		}
+
 		// Handle array types
 		if key == "api_urls" {
 			var urls []string
@@ -205,14 +239,24 @@ func UpdateModelConfig(configID int, updates map[string]interface{}) (bool, erro
 			continue
 		}
 
-		// Skip any slice/array types that aren't api_urls to prevent SQL errors
-		// This handles edge cases where complex types might slip through
-		switch value.(type) {
-		case []interface{}, []string:
-			// Skip unexpected array types
+		// Encrypt API key at rest
+		if key == "api_key" {
+			apiKey, _ := value.(string)
+			encryptedKey, err := secret.Encrypt(apiKey)
+			if err != nil {
+				return false, fmt.Errorf("failed to encrypt api_key: %w", err)
+			}
+			setClause += ", api_key = ?"
+			args = append(args, encryptedKey)
 			continue
 		}
 
+		// The other columns hold single values
+		switch value.(type) {
+		case []interface{}, []string, map[string]interface{}:
+			return false, fmt.Errorf("%w: %s must be a single value", ErrInvalidModelConfigUpdate, key)
+		}
+
 		setClause += ", " + key + " = ?"
 		args = append(args, value)
 	}
@@ -220,17 +264,31 @@ func UpdateModelConfig(configID int, updates map[string]interface{}) (bool, erro
 	args = append(args, configID)
 
 	query := "UPDATE model_configs SET " + setClause + " WHERE id = ?"
-	result, err := db.Exec(query, args...)
-	if err != nil {
-		return false, err
-	}
 
-	rowsAffected, err := result.RowsAffected()
+	var updated bool
+	err := WithTx(func(tx *sql.Tx) error {
+		result, err := tx.Exec(query, args...)
+		if err != nil {
+			return err
+		}
+
+		rowsAffected, err := result.RowsAffected()
+		if err != nil {
+			return err
+		}
+		if rowsAffected == 0 {
+			return nil
+		}
+		updated = true
+
+		_, err = recordModelConfigVersion(tx, configID, author, changeNote)
+		return err
+	})
 	if err != nil {
 		return false, err
 	}
 
-	return rowsAffected > 0, nil
+	return updated, nil
 }
 
 // DeleteModelConfig deletes a model config
@@ -248,3 +306,37 @@ func DeleteModelConfig(configID int) (bool, error) {
 
 	return rowsAffected > 0, nil
 }
+
+// EncryptPlaintextAPIKeys encrypts API keys stored by versions that kept them in plaintext
+func EncryptPlaintextAPIKeys() (int, error) {
+	rows, err := db.Query(`SELECT id, api_key FROM model_configs WHERE api_key IS NOT NULL AND api_key != ''`)
+	if err != nil {
+		return 0, err
+	}
+
+	plaintext := make(map[int]string)
+	for rows.Next() {
+		var id int
+		var apiKey string
+		if err := rows.Scan(&id, &apiKey); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		if !secret.IsEncrypted(apiKey) {
+			plaintext[id] = apiKey
+		}
+	}
+	rows.Close()
+
+	for id, apiKey := range plaintext {
+		encryptedKey, err := secret.Encrypt(apiKey)
+		if err != nil {
+			return 0, err
+		}
+		if _, err := db.Exec(`UPDATE model_configs SET api_key = ? WHERE id = ?`, encryptedKey, id); err != nil {
+			return 0, err
+		}
+	}
+
+	return len(plaintext), nil
+}
@@ -0,0 +1,40 @@
+package repository
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestUpdateModelConfigRejectsNonEditableFields(t *testing.T) {
+	configID := mustExec(t, `INSERT INTO model_configs (model_name, api_urls, api_key, created_at, updated_at)
+		VALUES (?, '["http://localhost"]', '', '', '')`, t.Name())
+	if _, err := UpdateModelConfig(configID, map[string]interface{}{"description": "first"}, "admin", ""); err != nil {
+		t.Fatalf("UpdateModelConfig: %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		updates map[string]interface{}
+	}{
+		{"version pointer", map[string]interface{}{"current_version": 5}},
+		{"unknown column", map[string]interface{}{"description = 'x', model_name": "y"}},
+		{"object value", map[string]interface{}{"description": map[string]interface{}{"a": 1}}},
+		{"list value", map[string]interface{}{"max_tokens": []interface{}{1}}},
+		{"with an editable field", map[string]interface{}{"description": "second", "is_vllm": map[string]interface{}{}}},
+	}
+	for _, tt := range tests {
+		if _, err := UpdateModelConfig(configID, tt.updates, "admin", ""); !errors.Is(err, ErrInvalidModelConfigUpdate) {
+			t.Errorf("%s: UpdateModelConfig returned %v, want ErrInvalidModelConfigUpdate", tt.name, err)
+		}
+	}
+
+	// Rejected updates change nothing
+	var description string
+	var version int
+	if err := db.QueryRow(`SELECT description, current_version FROM model_configs WHERE id = ?`, configID).Scan(&description, &version); err != nil {
+		t.Fatalf("read model config: %v", err)
+	}
+	if description != "first" || version != 1 {
+		t.Errorf("model config has description %q at version %d, want \"first\" at 1", description, version)
+	}
+}
@@ -0,0 +1,331 @@
+package repository
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"time"
+
+	"github.com/wzyjerry/llm-judge/internal/model"
+	"github.com/wzyjerry/llm-judge/internal/pkg/secret"
+)
+
+// readModelConfigSnapshot reads the current state of a model config, keeping the API key encrypted
+func readModelConfigSnapshot(tx *sql.Tx, configID int) (*model.ModelConfigSnapshot, error) {
+	query := `
+		SELECT model_name, api_urls, api_key, temperature, top_p, max_tokens, timeout, max_concurrency, description, is_active, is_vllm
+		FROM model_configs WHERE id = ?
+	`
+
+	snapshot := &model.ModelConfigSnapshot{}
+	var apiUrlsStr, apiKeyStr, description sql.NullString
+
+	err := tx.QueryRow(query, configID).Scan(
+		&snapshot.ModelName, &apiUrlsStr, &apiKeyStr,
+		&snapshot.Temperature, &snapshot.TopP, &snapshot.MaxTokens,
+		&snapshot.Timeout, &snapshot.MaxConcurrency, &description,
+		&snapshot.IsActive, &snapshot.IsVLLM,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	if apiUrlsStr.Valid {
+		json.Unmarshal([]byte(apiUrlsStr.String), &snapshot.APIUrls)
+	}
+	snapshot.APIKey = apiKeyStr.String
+	snapshot.Description = description.String
+
+	return snapshot, nil
+}
+
+// recordModelConfigVersion stores the current state of a model config as a new version.
+// Nothing is recorded when the state matches the latest version, whose number is returned instead.
+func recordModelConfigVersion(tx *sql.Tx, configID int, author, changeNote string) (int, error) {
+	snapshot, err := readModelConfigSnapshot(tx, configID)
+	if err != nil {
+		return 0, err
+	}
+
+	latest, err := queryModelConfigVersion(tx, `WHERE config_id = ? ORDER BY version DESC LIMIT 1`, configID)
+	if err != nil && err != sql.ErrNoRows {
+		return 0, err
+	}
+
+	version := 1
+	if latest != nil {
+		changes, err := DiffModelConfigSnapshots(&latest.Snapshot, snapshot)
+		if err != nil {
+			return 0, err
+		}
+		if len(changes) == 0 {
+			return latest.Version, nil
+		}
+		version = latest.Version + 1
+	}
+
+	snapshotJSON, err := json.Marshal(snapshot)
+	if err != nil {
+		return 0, fmt.Errorf("failed to marshal snapshot: %w", err)
+	}
+
+	query := `
+		INSERT INTO model_config_versions (config_id, version, snapshot, author, change_note, created_at)
+		VALUES (?, ?, ?, ?, ?, ?)
+	`
+	if _, err := tx.Exec(query, configID, version, string(snapshotJSON), author, changeNote, time.Now().Format(time.RFC3339)); err != nil {
+		return 0, err
+	}
+
+	if _, err := tx.Exec(`UPDATE model_configs SET current_version = ? WHERE id = ?`, version, configID); err != nil {
+		return 0, err
+	}
+
+	return version, nil
+}
+
+// queryModelConfigVersion reads a single version with the given WHERE clause
+func queryModelConfigVersion(tx *sql.Tx, where string, args ...interface{}) (*model.ModelConfigVersion, error) {
+	query := `SELECT config_id, version, snapshot, author, change_note, created_at FROM model_config_versions ` + where
+
+	v := &model.ModelConfigVersion{}
+	var snapshotStr string
+	var author, changeNote sql.NullString
+
+	err := tx.QueryRow(query, args...).Scan(&v.ConfigID, &v.Version, &snapshotStr, &author, &changeNote, &v.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := json.Unmarshal([]byte(snapshotStr), &v.Snapshot); err != nil {
+		return nil, fmt.Errorf("failed to parse snapshot of version %d: %w", v.Version, err)
+	}
+	v.Author = author.String
+	v.ChangeNote = changeNote.String
+
+	return v, nil
+}
+
+// maskSnapshotAPIKey replaces the encrypted API key of a snapshot with its masked form
+func maskSnapshotAPIKey(snapshot *model.ModelConfigSnapshot) error {
+	apiKey, err := secret.Decrypt(snapshot.APIKey)
+	if err != nil {
+		return err
+	}
+	snapshot.APIKey = secret.Mask(apiKey)
+	return nil
+}
+
+// GetModelConfigVersions returns all versions of a model config, newest first
+func GetModelConfigVersions(configID int) ([]model.ModelConfigVersion, error) {
+	query := `
+		SELECT config_id, version, snapshot, author, change_note, created_at
+		FROM model_config_versions
+		WHERE config_id = ?
+		ORDER BY version DESC
+	`
+
+	rows, err := db.Query(query, configID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	versions := []model.ModelConfigVersion{}
+	for rows.Next() {
+		var v model.ModelConfigVersion
+		var snapshotStr string
+		var author, changeNote sql.NullString
+
+		if err := rows.Scan(&v.ConfigID, &v.Version, &snapshotStr, &author, &changeNote, &v.CreatedAt); err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal([]byte(snapshotStr), &v.Snapshot); err != nil {
+			return nil, fmt.Errorf("failed to parse snapshot of version %d: %w", v.Version, err)
+		}
+		if err := maskSnapshotAPIKey(&v.Snapshot); err != nil {
+			return nil, err
+		}
+		v.Author = author.String
+		v.ChangeNote = changeNote.String
+
+		versions = append(versions, v)
+	}
+
+	return versions, nil
+}
+
+// GetModelConfigVersion returns one version of a model config with the API key masked
+func GetModelConfigVersion(configID, version int) (*model.ModelConfigVersion, error) {
+	var v *model.ModelConfigVersion
+	err := WithTx(func(tx *sql.Tx) error {
+		var err error
+		v, err = queryModelConfigVersion(tx, `WHERE config_id = ? AND version = ?`, configID, version)
+		return err
+	})
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if err := maskSnapshotAPIKey(&v.Snapshot); err != nil {
+		return nil, err
+	}
+	return v, nil
+}
+
+// DiffModelConfigVersions returns the fields that changed between two versions.
+// The returned bool is false when either version does not exist.
+func DiffModelConfigVersions(configID, fromVersion, toVersion int) ([]model.ModelConfigFieldChange, bool, error) {
+	var changes []model.ModelConfigFieldChange
+	err := WithTx(func(tx *sql.Tx) error {
+		from, err := queryModelConfigVersion(tx, `WHERE config_id = ? AND version = ?`, configID, fromVersion)
+		if err != nil {
+			return err
+		}
+		to, err := queryModelConfigVersion(tx, `WHERE config_id = ? AND version = ?`, configID, toVersion)
+		if err != nil {
+			return err
+		}
+
+		changes, err = DiffModelConfigSnapshots(&from.Snapshot, &to.Snapshot)
+		return err
+	})
+	if err == sql.ErrNoRows {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+
+	return changes, true, nil
+}
+
+// DiffModelConfigSnapshots compares two stored snapshots field by field.
+// API keys are compared decrypted and reported masked.
+func DiffModelConfigSnapshots(from, to *model.ModelConfigSnapshot) ([]model.ModelConfigFieldChange, error) {
+	fromKey, err := secret.Decrypt(from.APIKey)
+	if err != nil {
+		return nil, err
+	}
+	toKey, err := secret.Decrypt(to.APIKey)
+	if err != nil {
+		return nil, err
+	}
+
+	fields := []struct {
+		name     string
+		from, to interface{}
+	}{
+		{"model_name", from.ModelName, to.ModelName},
+		{"api_urls", from.APIUrls, to.APIUrls},
+		{"api_key", secret.Mask(fromKey), secret.Mask(toKey)},
+		{"temperature", from.Temperature, to.Temperature},
+		{"top_p", from.TopP, to.TopP},
+		{"max_tokens", from.MaxTokens, to.MaxTokens},
+		{"timeout", from.Timeout, to.Timeout},
+		{"max_concurrency", from.MaxConcurrency, to.MaxConcurrency},
+		{"description", from.Description, to.Description},
+		{"is_active", from.IsActive, to.IsActive},
+		{"is_vllm", from.IsVLLM, to.IsVLLM},
+	}
+
+	changes := []model.ModelConfigFieldChange{}
+	for _, f := range fields {
+		changed := !reflect.DeepEqual(f.from, f.to)
+		if f.name == "api_key" {
+			// Different keys can share the same mask
+			changed = fromKey != toKey
+		}
+		if changed {
+			changes = append(changes, model.ModelConfigFieldChange{Field: f.name, From: f.from, To: f.to})
+		}
+	}
+
+	return changes, nil
+}
+
+// RollbackModelConfig restores a model config to an earlier version, recorded as a new version.
+// The returned bool is false when the config or version does not exist.
+func RollbackModelConfig(configID, version int, author string) (int, bool, error) {
+	var newVersion int
+	err := WithTx(func(tx *sql.Tx) error {
+		target, err := queryModelConfigVersion(tx, `WHERE config_id = ? AND version = ?`, configID, version)
+		if err != nil {
+			return err
+		}
+
+		s := target.Snapshot
+		apiUrlsJSON, err := json.Marshal(s.APIUrls)
+		if err != nil {
+			return fmt.Errorf("failed to marshal api_urls: %w", err)
+		}
+
+		query := `
+			UPDATE model_configs
+			SET model_name = ?, api_urls = ?, api_key = ?, temperature = ?, top_p = ?, max_tokens = ?,
+				timeout = ?, max_concurrency = ?, description = ?, is_active = ?, is_vllm = ?, updated_at = ?
+			WHERE id = ?
+		`
+		result, err := tx.Exec(query,
+			s.ModelName, string(apiUrlsJSON), s.APIKey, s.Temperature, s.TopP, s.MaxTokens,
+			s.Timeout, s.MaxConcurrency, s.Description, s.IsActive, s.IsVLLM,
+			time.Now().Format(time.RFC3339), configID)
+		if err != nil {
+			return err
+		}
+
+		rowsAffected, err := result.RowsAffected()
+		if err != nil {
+			return err
+		}
+		if rowsAffected == 0 {
+			return sql.ErrNoRows
+		}
+
+		newVersion, err = recordModelConfigVersion(tx, configID, author, fmt.Sprintf("Rollback to version %d", version))
+		return err
+	})
+	if err == sql.ErrNoRows {
+		return 0, false, nil
+	}
+	if err != nil {
+		return 0, false, err
+	}
+
+	return newVersion, true, nil
+}
+
+// BackfillModelConfigVersions records version 1 for model configs that have no versions yet
+func BackfillModelConfigVersions() (int, error) {
+	rows, err := db.Query(`SELECT id FROM model_configs WHERE id NOT IN (SELECT config_id FROM model_config_versions)`)
+	if err != nil {
+		return 0, err
+	}
+
+	var configIDs []int
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		configIDs = append(configIDs, id)
+	}
+	rows.Close()
+
+	for _, id := range configIDs {
+		err := WithTx(func(tx *sql.Tx) error {
+			_, err := recordModelConfigVersion(tx, id, "system", "Initial version")
+			return err
+		})
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	return len(configIDs), nil
+}
@@ -0,0 +1,78 @@
+package repository
+
+import (
+	"database/sql"
+
+	"github.com/wzyjerry/llm-judge/internal/model"
+)
+
+// ReplaceModelEndpointHealth replaces the stored health results of a model config
+func ReplaceModelEndpointHealth(configID int, health []model.ModelEndpointHealth) error {
+	return WithTx(func(tx *sql.Tx) error {
+		if _, err := tx.Exec(`DELETE FROM model_endpoint_health WHERE config_id = ?`, configID); err != nil {
+			return err
+		}
+
+		query := `
+			INSERT INTO model_endpoint_health (config_id, api_url, status, latency_ms, model_available, error, checked_at)
+			VALUES (?, ?, ?, ?, ?, ?, ?)
+		`
+		for _, h := range health {
+			modelAvailable := 0
+			if h.ModelAvailable {
+				modelAvailable = 1
+			}
+			if _, err := tx.Exec(query, configID, h.APIUrl, h.Status, h.LatencyMs, modelAvailable, h.Error, h.CheckedAt); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// GetModelEndpointHealth returns the stored health results of a model config
+func GetModelEndpointHealth(configID int) ([]model.ModelEndpointHealth, error) {
+	all, err := queryModelEndpointHealth(`WHERE config_id = ?`, configID)
+	if err != nil {
+		return nil, err
+	}
+	return all[configID], nil
+}
+
+// GetAllModelEndpointHealth returns the stored health results grouped by config ID
+func GetAllModelEndpointHealth() (map[int][]model.ModelEndpointHealth, error) {
+	return queryModelEndpointHealth("")
+}
+
+// queryModelEndpointHealth runs a health query with an optional WHERE clause
+func queryModelEndpointHealth(where string, args ...interface{}) (map[int][]model.ModelEndpointHealth, error) {
+	query := `
+		SELECT config_id, api_url, status, latency_ms, model_available, error, checked_at
+		FROM model_endpoint_health
+	` + where + ` ORDER BY config_id, api_url`
+
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	result := make(map[int][]model.ModelEndpointHealth)
+	for rows.Next() {
+		var h model.ModelEndpointHealth
+		var modelAvailable int
+		var errStr sql.NullString
+
+		if err := rows.Scan(&h.ConfigID, &h.APIUrl, &h.Status, &h.LatencyMs, &modelAvailable, &errStr, &h.CheckedAt); err != nil {
+			return nil, err
+		}
+		h.ModelAvailable = modelAvailable == 1
+		if errStr.Valid {
+			h.Error = errStr.String
+		}
+
+		result[h.ConfigID] = append(result[h.ConfigID], h)
+	}
+
+	return result, nil
+}
@@ -0,0 +1,503 @@
+package repository
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/wzyjerry/llm-judge/internal/model"
+	"go.uber.org/zap"
+)
+
+// storedReportContent is the part of report_content kept outside the per-item store
+type storedReportContent struct {
+	Config  map[string]interface{} `json:"config"`
+	Results []model.ReportResult   `json:"results"`
+}
+
+// marshalJSONText encodes a value as JSON without escaping HTML characters, so it stays searchable
+func marshalJSONText(v interface{}) (string, error) {
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	encoder.SetEscapeHTML(false)
+	if err := encoder.Encode(v); err != nil {
+		return "", err
+	}
+	return strings.TrimRight(buf.String(), "\n"), nil
+}
+
+// materializeReportItems splits report_content into report_items rows and records the report config and counts.
+// The content is cleared once split, since the items, config and summary hold all of it. Content that is not a
+// JSON report, as stored by early versions, is kept as it is and the report gets no items.
+func materializeReportItems(tx *sql.Tx, reportID int, reportContent string) error {
+	var content storedReportContent
+	if err := json.Unmarshal([]byte(reportContent), &content); err != nil {
+		query := `
+			UPDATE user_reports
+			SET items_materialized = 1, report_content = ?, item_count = 0, badcase_count = 0
+			WHERE id = ?
+		`
+		_, err = tx.Exec(query, reportContent, reportID)
+		return err
+	}
+
+	if _, err := tx.Exec(`DELETE FROM report_items WHERE report_id = ?`, reportID); err != nil {
+		return err
+	}
+
+	stmt, err := tx.Prepare(`
+		INSERT INTO report_items (report_id, item_index, original_index, expanded_index, user_input, model_output,
+			reference_output, inference_time, score, is_badcase, details, error, meta)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	badcaseCount := 0
+	for _, r := range content.Results {
+		userInput, err := marshalJSONText(r.UserInput)
+		if err != nil {
+			return fmt.Errorf("failed to marshal user_input of item %d: %w", r.Index, err)
+		}
+		referenceOutput, err := marshalJSONText(r.ReferenceOutput)
+		if err != nil {
+			return fmt.Errorf("failed to marshal reference_output of item %d: %w", r.Index, err)
+		}
+		details, err := marshalJSONText(r.Details)
+		if err != nil {
+			return fmt.Errorf("failed to marshal details of item %d: %w", r.Index, err)
+		}
+		var meta sql.NullString
+		if r.Meta != nil {
+			meta.String, err = marshalJSONText(r.Meta)
+			if err != nil {
+				return fmt.Errorf("failed to marshal meta of item %d: %w", r.Index, err)
+			}
+			meta.Valid = true
+		}
+
+		if r.IsBadcase != 0 {
+			badcaseCount++
+		}
+
+		_, err = stmt.Exec(reportID, r.Index, r.OriginalIndex, r.ExpandedIndex, userInput, r.ModelOutput,
+			referenceOutput, r.InferenceTime, r.Score, r.IsBadcase, details, r.Error, meta)
+		if err != nil {
+			return err
+		}
+	}
+
+	configJSON, err := json.Marshal(content.Config)
+	if err != nil {
+		return fmt.Errorf("failed to marshal config of report %d: %w", reportID, err)
+	}
+
+	query := `
+		UPDATE user_reports
+		SET items_materialized = 1, report_content = '', report_config = ?, item_count = ?, badcase_count = ?
+		WHERE id = ?
+	`
+	_, err = tx.Exec(query, string(configJSON), len(content.Results), badcaseCount, reportID)
+	return err
+}
+
+// fillReportContent rebuilds the report_content of a report whose content was split into report items
+func fillReportContent(report *model.UserReport) error {
+	if report.ReportContent != "" {
+		return nil
+	}
+
+	var configStr sql.NullString
+	if err := db.QueryRow(`SELECT report_config FROM user_reports WHERE id = ?`, report.ID).Scan(&configStr); err != nil {
+		return err
+	}
+
+	content := struct {
+		Timestamp string                 `json:"timestamp"`
+		Config    map[string]interface{} `json:"config"`
+		Summary   map[string]interface{} `json:"summary"`
+		Badcases  []model.ReportResult   `json:"badcases"`
+		Results   []model.ReportResult   `json:"results"`
+	}{
+		Timestamp: report.Timestamp,
+		Summary:   report.Summary,
+		Badcases:  []model.ReportResult{},
+		Results:   []model.ReportResult{},
+	}
+	if configStr.Valid {
+		json.Unmarshal([]byte(configStr.String), &content.Config)
+	}
+
+	err := ForEachReportItem(report.ID, model.ReportItemFilter{}, func(r *model.ReportResult) error {
+		content.Results = append(content.Results, *r)
+		if r.IsBadcase != 0 {
+			content.Badcases = append(content.Badcases, *r)
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to load items of report %d: %w", report.ID, err)
+	}
+
+	report.ReportContent, err = marshalJSONText(content)
+	return err
+}
+
+// ensureReportItems materializes the items of a report created before the per-item store existed
+func ensureReportItems(reportID int) error {
+	var materialized int
+	err := db.QueryRow(`SELECT COALESCE(items_materialized, 0) FROM user_reports WHERE id = ?`, reportID).Scan(&materialized)
+	if err != nil {
+		return err
+	}
+	if materialized == 1 {
+		return nil
+	}
+
+	return materializeStoredReport(reportID)
+}
+
+// materializeStoredReport splits the stored content of a report into report items.
+// The report is claimed first, so a report materialized concurrently is left alone.
+func materializeStoredReport(reportID int) error {
+	return WithTx(func(tx *sql.Tx) error {
+		result, err := tx.Exec(`UPDATE user_reports SET items_materialized = 1 WHERE id = ? AND COALESCE(items_materialized, 0) = 0`, reportID)
+		if err != nil {
+			return err
+		}
+		rowsAffected, err := result.RowsAffected()
+		if err != nil || rowsAffected == 0 {
+			return err
+		}
+
+		var reportContent string
+		if err := tx.QueryRow(`SELECT report_content FROM user_reports WHERE id = ?`, reportID).Scan(&reportContent); err != nil {
+			return err
+		}
+		return materializeReportItems(tx, reportID, reportContent)
+	})
+}
+
+// BackfillReportItems materializes the items of all reports created before the per-item store existed.
+// Reports that fail are logged and left for lazy materialization.
+func BackfillReportItems() (int, error) {
+	rows, err := db.Query(`SELECT id FROM user_reports WHERE COALESCE(items_materialized, 0) = 0`)
+	if err != nil {
+		return 0, err
+	}
+
+	var reportIDs []int
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		reportIDs = append(reportIDs, id)
+	}
+	rows.Close()
+
+	count := 0
+	for _, id := range reportIDs {
+		if err := materializeStoredReport(id); err != nil {
+			zap.L().Warn("Failed to backfill report items",
+				zap.Int("report_id", id),
+				zap.Error(err))
+			continue
+		}
+		count++
+	}
+
+	return count, nil
+}
+
+// GetReportDetail returns the metadata of a report without loading its results
+func GetReportDetail(userID, reportID int) (*model.ReportDetail, error) {
+	var ownerID int
+	err := db.QueryRow(`SELECT user_id FROM user_reports WHERE id = ?`, reportID).Scan(&ownerID)
+	if err == sql.ErrNoRows || (err == nil && ownerID != userID) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if err := ensureReportItems(reportID); err != nil {
+		return nil, err
+	}
+
+	query := `
+		SELECT id, user_id, task_id, dataset, model, timestamp, report_config, summary, item_count, badcase_count, created_at,
+			parent_report_id, derivation
+		FROM user_reports WHERE id = ?
+	`
+
+	report := &model.ReportDetail{}
+	var configStr, summaryStr, derivationStr sql.NullString
+	var parentReportID sql.NullInt64
+
+	err = db.QueryRow(query, reportID).Scan(
+		&report.ID, &report.UserID, &report.TaskID, &report.Dataset, &report.Model,
+		&report.Timestamp, &configStr, &summaryStr, &report.ItemCount, &report.BadcaseCount,
+		&report.CreatedAt, &parentReportID, &derivationStr,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	// Parse JSON fields
+	if configStr.Valid {
+		json.Unmarshal([]byte(configStr.String), &report.Config)
+	}
+	if summaryStr.Valid {
+		json.Unmarshal([]byte(summaryStr.String), &report.Summary)
+	}
+	if derivationStr.Valid {
+		json.Unmarshal([]byte(derivationStr.String), &report.Derivation)
+	}
+	if parentReportID.Valid {
+		parentID := int(parentReportID.Int64)
+		report.ParentReportID = &parentID
+	}
+
+	report.DerivedReports, err = getDerivedReportIDs(reportID)
+	if err != nil {
+		return nil, err
+	}
+
+	return report, nil
+}
+
+// getDerivedReportIDs returns the IDs of the reports derived from a report, oldest first
+func getDerivedReportIDs(reportID int) ([]int, error) {
+	rows, err := db.Query(`SELECT id FROM user_reports WHERE parent_report_id = ? ORDER BY id`, reportID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var ids []int
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+
+	return ids, rows.Err()
+}
+
+// reportItemWhere builds the WHERE clause selecting the items of a report that match a filter
+func reportItemWhere(reportID int, filter model.ReportItemFilter) (string, []interface{}) {
+	where := []string{"report_id = ?"}
+	args := []interface{}{reportID}
+
+	if filter.BadcaseOnly {
+		where = append(where, "is_badcase != 0")
+	}
+	if filter.ErrorOnly {
+		where = append(where, "COALESCE(error, '') != ''")
+	}
+	if filter.MinScore != nil {
+		where = append(where, "score >= ?")
+		args = append(args, *filter.MinScore)
+	}
+	if filter.MaxScore != nil {
+		where = append(where, "score <= ?")
+		args = append(args, *filter.MaxScore)
+	}
+	if filter.Query != "" {
+		pattern := "%" + escapeLike(filter.Query) + "%"
+		where = append(where, `(user_input LIKE ? ESCAPE '\' OR model_output LIKE ? ESCAPE '\' OR reference_output LIKE ? ESCAPE '\')`)
+		args = append(args, pattern, pattern, pattern)
+	}
+
+	return strings.Join(where, " AND "), args
+}
+
+// GetReportItems returns a page of report items matching the filter and the total number of matches.
+// The report must already be materialized, see GetReportDetail.
+func GetReportItems(reportID int, filter model.ReportItemFilter) ([]model.ReportResult, int, error) {
+	whereClause, args := reportItemWhere(reportID, filter)
+
+	var total int
+	if err := db.QueryRow(`SELECT COUNT(*) FROM report_items WHERE `+whereClause, args...).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+
+	query := `
+		SELECT item_index, original_index, expanded_index, user_input, model_output, reference_output,
+			inference_time, score, is_badcase, details, error, meta
+		FROM report_items WHERE ` + whereClause + `
+		ORDER BY item_index, id
+		LIMIT ? OFFSET ?
+	`
+
+	rows, err := db.Query(query, append(args, filter.Limit, filter.Offset)...)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	items := []model.ReportResult{}
+	for rows.Next() {
+		r, err := scanReportItem(rows)
+		if err != nil {
+			return nil, 0, err
+		}
+		items = append(items, *r)
+	}
+
+	return items, total, nil
+}
+
+// scanReportItem reads a report item selected as item_index, original_index, expanded_index, user_input,
+// model_output, reference_output, inference_time, score, is_badcase, details, error, meta, followed by extra columns
+func scanReportItem(rows *sql.Rows, extra ...interface{}) (*model.ReportResult, error) {
+	r := &model.ReportResult{}
+	var userInput, modelOutput, referenceOutput, details, errorStr, meta sql.NullString
+
+	dest := []interface{}{
+		&r.Index, &r.OriginalIndex, &r.ExpandedIndex, &userInput, &modelOutput, &referenceOutput,
+		&r.InferenceTime, &r.Score, &r.IsBadcase, &details, &errorStr, &meta,
+	}
+	if err := rows.Scan(append(dest, extra...)...); err != nil {
+		return nil, err
+	}
+
+	// Parse JSON fields
+	if userInput.Valid {
+		json.Unmarshal([]byte(userInput.String), &r.UserInput)
+	}
+	if referenceOutput.Valid {
+		json.Unmarshal([]byte(referenceOutput.String), &r.ReferenceOutput)
+	}
+	if details.Valid {
+		json.Unmarshal([]byte(details.String), &r.Details)
+	}
+	if meta.Valid {
+		json.Unmarshal([]byte(meta.String), &r.Meta)
+	}
+	r.ModelOutput = modelOutput.String
+	r.Error = errorStr.String
+
+	return r, nil
+}
+
+// AggregateReportItems computes aggregates over the report items matching the filter.
+// The report must already be materialized, see GetReportDetail.
+func AggregateReportItems(reportID int, filter model.ReportItemFilter) (*model.ReportItemAggregate, error) {
+	whereClause, args := reportItemWhere(reportID, filter)
+
+	query := `
+		SELECT COUNT(*),
+			COALESCE(SUM(CASE WHEN is_badcase != 0 THEN 1 ELSE 0 END), 0),
+			COALESCE(SUM(CASE WHEN COALESCE(error, '') != '' THEN 1 ELSE 0 END), 0),
+			COALESCE(AVG(score), 0), COALESCE(MIN(score), 0), COALESCE(MAX(score), 0),
+			COALESCE(AVG(inference_time), 0)
+		FROM report_items WHERE ` + whereClause
+
+	aggregate := &model.ReportItemAggregate{ScoreDistribution: []model.ScoreBucket{}}
+	err := db.QueryRow(query, args...).Scan(
+		&aggregate.Count, &aggregate.BadcaseCount, &aggregate.ErrorCount,
+		&aggregate.AverageScore, &aggregate.MinScore, &aggregate.MaxScore,
+		&aggregate.AverageInferenceTime,
+	)
+	if err != nil {
+		return nil, err
+	}
+	if aggregate.Count > 0 {
+		aggregate.Accuracy = float64(aggregate.Count-aggregate.BadcaseCount) / float64(aggregate.Count)
+	}
+
+	rows, err := db.Query(`SELECT score, COUNT(*) FROM report_items WHERE `+whereClause+` GROUP BY score ORDER BY score`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var bucket model.ScoreBucket
+		if err := rows.Scan(&bucket.Score, &bucket.Count); err != nil {
+			return nil, err
+		}
+		aggregate.ScoreDistribution = append(aggregate.ScoreDistribution, bucket)
+	}
+
+	return aggregate, nil
+}
+
+// GetReportItemHistory returns the results of one dataset item across a user's reports, newest first.
+// An empty modelName matches every model.
+func GetReportItemHistory(userID int, dataset, modelName string, originalIndex, expandedIndex int) ([]model.ReportItemRun, error) {
+	query := `
+		SELECT i.item_index, i.original_index, i.expanded_index, i.user_input, i.model_output, i.reference_output,
+			i.inference_time, i.score, i.is_badcase, i.details, i.error, i.meta,
+			r.id, r.task_id, r.model, r.timestamp
+		FROM report_items i
+		JOIN user_reports r ON r.id = i.report_id
+		WHERE r.user_id = ? AND r.dataset = ? AND (? = '' OR r.model = ?)
+			AND i.original_index = ? AND i.expanded_index = ?
+		ORDER BY r.created_at DESC, r.id DESC
+	`
+
+	rows, err := db.Query(query, userID, dataset, modelName, modelName, originalIndex, expandedIndex)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	runs := []model.ReportItemRun{}
+	for rows.Next() {
+		var run model.ReportItemRun
+		r, err := scanReportItem(rows, &run.ReportID, &run.TaskID, &run.Model, &run.Timestamp)
+		if err != nil {
+			return nil, err
+		}
+		run.ReportResult = *r
+		runs = append(runs, run)
+	}
+
+	return runs, nil
+}
+
+// escapeLike escapes the LIKE wildcards of a search term
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(s)
+}
+
+// ForEachReportItem calls fn for every report item matching the filter in report order, ignoring offset and limit.
+// The report must already be materialized, see GetReportDetail.
+func ForEachReportItem(reportID int, filter model.ReportItemFilter, fn func(*model.ReportResult) error) error {
+	whereClause, args := reportItemWhere(reportID, filter)
+
+	query := `
+		SELECT item_index, original_index, expanded_index, user_input, model_output, reference_output,
+			inference_time, score, is_badcase, details, error, meta
+		FROM report_items WHERE ` + whereClause + `
+		ORDER BY item_index, id
+	`
+
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		r, err := scanReportItem(rows)
+		if err != nil {
+			return err
+		}
+		if err := fn(r); err != nil {
+			return err
+		}
+	}
+
+	return rows.Err()
+}
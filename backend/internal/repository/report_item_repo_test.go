@@ -0,0 +1,119 @@
+package repository
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/wzyjerry/llm-judge/internal/model"
+)
+
+const reportItemTestContent = `{"timestamp":"20260101_000000","config":{"scoring":"exact_match"},"summary":{"total":2},
+"badcases":[{"index":1,"original_index":1,"expanded_index":0,"user_input":[{"role":"user","content":"b"}],"model_output":"x","reference_output":"y","inference_time":0.5,"score":0,"is_badcase":1}],
+"results":[{"index":0,"original_index":0,"expanded_index":0,"user_input":[{"role":"user","content":"a"}],"model_output":"a","reference_output":"a","inference_time":0.25,"score":1,"is_badcase":0,"meta":{"category":"c"}},
+{"index":1,"original_index":1,"expanded_index":0,"user_input":[{"role":"user","content":"b"}],"model_output":"x","reference_output":"y","inference_time":0.5,"score":0,"is_badcase":1}]}`
+
+// createTestUserReport creates a user and task and stores a report with the given content
+func createTestUserReport(t *testing.T, content string) (int, int) {
+	t.Helper()
+	userID := createTestUser(t)
+	mustExec(t, `INSERT INTO user_tasks (user_id, task_id, status, created_at, updated_at) VALUES (?, ?, 'completed', '', '')`, userID, t.Name())
+	reportID, err := CreateUserReport(&model.UserReportCreate{
+		UserID: userID, TaskID: t.Name(), Dataset: "d", Model: "m",
+		ReportContent: content, Timestamp: "2026-01-01 00:00:00", Summary: map[string]interface{}{"total": 2},
+	})
+	if err != nil {
+		t.Fatalf("CreateUserReport: %v", err)
+	}
+	return userID, reportID
+}
+
+// storedContent returns the report_content column of a report
+func storedContent(t *testing.T, reportID int) string {
+	t.Helper()
+	var content string
+	if err := db.QueryRow(`SELECT report_content FROM user_reports WHERE id = ?`, reportID).Scan(&content); err != nil {
+		t.Fatalf("select report_content: %v", err)
+	}
+	return content
+}
+
+// reportResults returns the results of report content
+func reportResults(t *testing.T, content string) []model.ReportResult {
+	t.Helper()
+	var parsed struct {
+		Results []model.ReportResult `json:"results"`
+	}
+	if err := json.Unmarshal([]byte(content), &parsed); err != nil {
+		t.Fatalf("parse report content %q: %v", content, err)
+	}
+	return parsed.Results
+}
+
+func TestCreateUserReportSplitsContent(t *testing.T) {
+	userID, reportID := createTestUserReport(t, reportItemTestContent)
+
+	if content := storedContent(t, reportID); content != "" {
+		t.Errorf("report_content stored next to the items: %q", content)
+	}
+
+	detail, err := GetReportDetail(userID, reportID)
+	if err != nil || detail == nil {
+		t.Fatalf("GetReportDetail: %+v, %v", detail, err)
+	}
+	if detail.ItemCount != 2 || detail.BadcaseCount != 1 || detail.Config["scoring"] != "exact_match" {
+		t.Errorf("detail has %d items, %d badcases and config %v", detail.ItemCount, detail.BadcaseCount, detail.Config)
+	}
+
+	report, err := GetUserReportByID(userID, reportID)
+	if err != nil || report == nil {
+		t.Fatalf("GetUserReportByID: %+v, %v", report, err)
+	}
+	want := reportResults(t, reportItemTestContent)
+	got := reportResults(t, report.ReportContent)
+	if len(got) != len(want) {
+		t.Fatalf("rebuilt content has %d results, want %d", len(got), len(want))
+	}
+	for i := range want {
+		wantJSON, _ := json.Marshal(want[i])
+		gotJSON, _ := json.Marshal(got[i])
+		if string(gotJSON) != string(wantJSON) {
+			t.Errorf("result %d rebuilt as %s, want %s", i, gotJSON, wantJSON)
+		}
+	}
+}
+
+func TestCreateUserReportKeepsLegacyContent(t *testing.T) {
+	const legacy = "Accuracy: 50%\nnot a JSON report"
+	userID, reportID := createTestUserReport(t, legacy)
+
+	if content := storedContent(t, reportID); content != legacy {
+		t.Errorf("report_content stored as %q, want %q", content, legacy)
+	}
+	report, err := GetUserReportByID(userID, reportID)
+	if err != nil || report == nil || report.ReportContent != legacy {
+		t.Fatalf("GetUserReportByID: %+v, %v", report, err)
+	}
+	detail, err := GetReportDetail(userID, reportID)
+	if err != nil || detail == nil || detail.ItemCount != 0 {
+		t.Fatalf("GetReportDetail: %+v, %v", detail, err)
+	}
+}
+
+func TestEnsureReportItemsClearsSplitContent(t *testing.T) {
+	// A report stored before the per-item store existed
+	userID := createTestUser(t)
+	mustExec(t, `INSERT INTO user_tasks (user_id, task_id, status, created_at, updated_at) VALUES (?, ?, 'completed', '', '')`, userID, t.Name())
+	reportID := mustExec(t, `INSERT INTO user_reports (user_id, task_id, dataset, model, report_content, timestamp, created_at)
+		VALUES (?, ?, 'd', 'm', ?, '', '')`, userID, t.Name(), reportItemTestContent)
+
+	if err := ensureReportItems(reportID); err != nil {
+		t.Fatalf("ensureReportItems: %v", err)
+	}
+	if content := storedContent(t, reportID); content != "" {
+		t.Errorf("report_content kept after splitting: %q", content)
+	}
+	items, total, err := GetReportItems(reportID, model.ReportItemFilter{Limit: 10})
+	if err != nil || total != 2 || len(items) != 2 {
+		t.Fatalf("GetReportItems: %d of %d items, %v", len(items), total, err)
+	}
+}
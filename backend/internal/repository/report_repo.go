@@ -8,32 +8,51 @@ import (
 	"github.com/wzyjerry/llm-judge/internal/model"
 )
 
-// CreateUserReport creates a new user report
+// CreateUserReport creates a new user report and stores its results as report items.
+// The content itself is only stored when it cannot be split, see materializeReportItems.
 func CreateUserReport(report *model.UserReportCreate) (int, error) {
 	now := time.Now().Format(time.RFC3339)
 	summaryJSON, _ := json.Marshal(report.Summary)
 
+	var derivation sql.NullString
+	if report.Derivation != nil {
+		derivationJSON, _ := json.Marshal(report.Derivation)
+		derivation = sql.NullString{String: string(derivationJSON), Valid: true}
+	}
+
 	query := `
-		INSERT INTO user_reports (user_id, task_id, dataset, model, report_content, timestamp, summary, created_at)
-		VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+		INSERT INTO user_reports (user_id, task_id, dataset, model, report_content, timestamp, summary, created_at,
+			parent_report_id, derivation)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
 	`
 
-	result, err := db.Exec(query,
-		report.UserID, report.TaskID, report.Dataset, report.Model,
-		report.ReportContent, report.Timestamp, string(summaryJSON), now)
-	if err != nil {
-		return 0, err
-	}
+	var reportID int
+	err := WithTx(func(tx *sql.Tx) error {
+		result, err := tx.Exec(query,
+			report.UserID, report.TaskID, report.Dataset, report.Model,
+			"", report.Timestamp, string(summaryJSON), now,
+			report.ParentReportID, derivation)
+		if err != nil {
+			return err
+		}
+
+		id, err := result.LastInsertId()
+		if err != nil {
+			return err
+		}
+		reportID = int(id)
 
-	id, err := result.LastInsertId()
+		return materializeReportItems(tx, reportID, report.ReportContent)
+	})
 	if err != nil {
 		return 0, err
 	}
 
-	return int(id), nil
+	return reportID, nil
 }
 
-// GetUserReports returns all reports for a user
+// GetUserReports returns all reports for a user.
+// The content of reports split into report items is left empty, see GetUserReportByID.
 func GetUserReports(userID int) ([]model.UserReport, error) {
 	query := `
 		SELECT id, user_id, task_id, dataset, model, report_content, timestamp, summary, created_at
@@ -99,9 +118,58 @@ func GetUserReportByID(userID int, reportID int) (*model.UserReport, error) {
 		json.Unmarshal([]byte(summaryStr.String), &report.Summary)
 	}
 
+	if err := fillReportContent(report); err != nil {
+		return nil, err
+	}
+
 	return report, nil
 }
 
+// GetUserReportsByTaskID returns all reports produced by a task, oldest first
+func GetUserReportsByTaskID(taskID string) ([]model.UserReport, error) {
+	query := `
+		SELECT id, user_id, task_id, dataset, model, report_content, timestamp, summary, created_at
+		FROM user_reports WHERE task_id = ? ORDER BY id
+	`
+
+	rows, err := db.Query(query, taskID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var reports []model.UserReport
+	for rows.Next() {
+		var report model.UserReport
+		var summaryStr sql.NullString
+
+		err := rows.Scan(
+			&report.ID, &report.UserID, &report.TaskID, &report.Dataset,
+			&report.Model, &report.ReportContent, &report.Timestamp,
+			&summaryStr, &report.CreatedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		// Parse JSON field
+		if summaryStr.Valid {
+			json.Unmarshal([]byte(summaryStr.String), &report.Summary)
+		}
+
+		reports = append(reports, report)
+	}
+	rows.Close()
+
+	for i := range reports {
+		if err := fillReportContent(&reports[i]); err != nil {
+			return nil, err
+		}
+	}
+
+	return reports, nil
+}
+
 // GetUserReportByPath returns a report by dataset and model
 func GetUserReportByPath(userID int, dataset, modelName string) (*model.UserReport, error) {
 	query := `
@@ -131,18 +199,33 @@ func GetUserReportByPath(userID int, dataset, modelName string) (*model.UserRepo
 		json.Unmarshal([]byte(summaryStr.String), &report.Summary)
 	}
 
+	if err := fillReportContent(report); err != nil {
+		return nil, err
+	}
+
 	return report, nil
 }
 
-// DeleteUserReport deletes a report
+// DeleteUserReport deletes a report with its items and reviews
 func DeleteUserReport(userID int, reportID int) (bool, error) {
-	query := `DELETE FROM user_reports WHERE id = ? AND user_id = ?`
-	result, err := db.Exec(query, reportID, userID)
-	if err != nil {
-		return false, err
-	}
+	var rowsAffected int64
+	err := WithTx(func(tx *sql.Tx) error {
+		result, err := tx.Exec(`DELETE FROM user_reports WHERE id = ? AND user_id = ?`, reportID, userID)
+		if err != nil {
+			return err
+		}
 
-	rowsAffected, err := result.RowsAffected()
+		rowsAffected, err = result.RowsAffected()
+		if err != nil || rowsAffected == 0 {
+			return err
+		}
+
+		if _, err := tx.Exec(`DELETE FROM report_items WHERE report_id = ?`, reportID); err != nil {
+			return err
+		}
+		_, err = tx.Exec(`DELETE FROM report_item_reviews WHERE report_id = ?`, reportID)
+		return err
+	})
 	if err != nil {
 		return false, err
 	}
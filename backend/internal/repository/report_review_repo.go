@@ -0,0 +1,213 @@
+package repository
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/wzyjerry/llm-judge/internal/model"
+)
+
+// reviewedItems selects each item of a report (bound as the only parameter) with its reviewed
+// score and badcase flag. Each is taken from the latest review setting it, so a later comment-only
+// review keeps an earlier override. reviewed is 1 for items with any review.
+const reviewedItems = `
+	SELECT i.score, i.is_badcase,
+		(SELECT score FROM report_item_reviews
+			WHERE report_id = i.report_id AND item_index = i.item_index AND score IS NOT NULL
+			ORDER BY updated_at DESC, id DESC LIMIT 1) AS review_score,
+		(SELECT is_badcase FROM report_item_reviews
+			WHERE report_id = i.report_id AND item_index = i.item_index AND is_badcase IS NOT NULL
+			ORDER BY updated_at DESC, id DESC LIMIT 1) AS review_badcase,
+		EXISTS (SELECT 1 FROM report_item_reviews
+			WHERE report_id = i.report_id AND item_index = i.item_index) AS reviewed
+	FROM report_items i
+	WHERE i.report_id = ?
+`
+
+// reviewColumns are the selected columns of a review, in scanReview order
+const reviewColumns = `id, report_id, item_index, reviewer_id, reviewer, score, is_badcase, comment, failure_category, created_at, updated_at`
+
+// scanReview reads a review selected as reviewColumns
+func scanReview(scanner interface{ Scan(...interface{}) error }) (*model.ReportItemReview, error) {
+	review := &model.ReportItemReview{}
+	var score sql.NullFloat64
+	var isBadcase sql.NullInt64
+	var comment, failureCategory sql.NullString
+
+	err := scanner.Scan(
+		&review.ID, &review.ReportID, &review.ItemIndex, &review.ReviewerID, &review.Reviewer,
+		&score, &isBadcase, &comment, &failureCategory, &review.CreatedAt, &review.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	if score.Valid {
+		review.Score = &score.Float64
+	}
+	if isBadcase.Valid {
+		flag := int(isBadcase.Int64)
+		review.IsBadcase = &flag
+	}
+	review.Comment = comment.String
+	review.FailureCategory = failureCategory.String
+
+	return review, nil
+}
+
+// GetReportOwnerID returns the user that owns a report, or 0 when the report does not exist
+func GetReportOwnerID(reportID int) (int, error) {
+	var ownerID int
+	err := db.QueryRow(`SELECT user_id FROM user_reports WHERE id = ?`, reportID).Scan(&ownerID)
+	if err == sql.ErrNoRows {
+		return 0, nil
+	}
+	return ownerID, err
+}
+
+// ReportItemExists reports whether a materialized report has an item with the given index
+func ReportItemExists(reportID, itemIndex int) (bool, error) {
+	var count int
+	err := db.QueryRow(`SELECT COUNT(*) FROM report_items WHERE report_id = ? AND item_index = ?`, reportID, itemIndex).Scan(&count)
+	return count > 0, err
+}
+
+// SetReportItemReview creates or replaces a reviewer's review of a report item
+func SetReportItemReview(reportID, itemIndex, reviewerID int, reviewer string, review *model.ReportItemReviewSet) (*model.ReportItemReview, error) {
+	now := time.Now().Format(time.RFC3339)
+
+	query := `
+		INSERT INTO report_item_reviews (report_id, item_index, reviewer_id, reviewer, score, is_badcase, comment, failure_category, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT(report_id, item_index, reviewer_id) DO UPDATE SET
+			reviewer = excluded.reviewer, score = excluded.score, is_badcase = excluded.is_badcase,
+			comment = excluded.comment, failure_category = excluded.failure_category, updated_at = excluded.updated_at
+	`
+
+	_, err := db.Exec(query, reportID, itemIndex, reviewerID, reviewer, review.Score, review.IsBadcase,
+		review.Comment, review.FailureCategory, now, now)
+	if err != nil {
+		return nil, err
+	}
+
+	row := db.QueryRow(`SELECT `+reviewColumns+` FROM report_item_reviews WHERE report_id = ? AND item_index = ? AND reviewer_id = ?`,
+		reportID, itemIndex, reviewerID)
+	return scanReview(row)
+}
+
+// DeleteReportItemReview deletes a reviewer's review of a report item
+func DeleteReportItemReview(reportID, itemIndex, reviewerID int) (bool, error) {
+	result, err := db.Exec(`DELETE FROM report_item_reviews WHERE report_id = ? AND item_index = ? AND reviewer_id = ?`,
+		reportID, itemIndex, reviewerID)
+	if err != nil {
+		return false, err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+
+	return rowsAffected > 0, nil
+}
+
+// GetReportItemReviews returns all reviews of a report ordered by item, newest review first
+func GetReportItemReviews(reportID int) ([]model.ReportItemReview, error) {
+	rows, err := db.Query(`SELECT `+reviewColumns+` FROM report_item_reviews WHERE report_id = ? ORDER BY item_index, updated_at DESC, id DESC`, reportID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	reviews := []model.ReportItemReview{}
+	for rows.Next() {
+		review, err := scanReview(rows)
+		if err != nil {
+			return nil, err
+		}
+		reviews = append(reviews, *review)
+	}
+
+	return reviews, nil
+}
+
+// GetLatestReportItemReviews returns the latest review of each reviewed item among the given indexes
+func GetLatestReportItemReviews(reportID int, itemIndexes []int) (map[int]*model.ReportItemReview, error) {
+	latest := make(map[int]*model.ReportItemReview)
+	if len(itemIndexes) == 0 {
+		return latest, nil
+	}
+
+	wanted := make(map[int]bool, len(itemIndexes))
+	for _, index := range itemIndexes {
+		wanted[index] = true
+	}
+
+	reviews, err := GetReportItemReviews(reportID)
+	if err != nil {
+		return nil, err
+	}
+	for i := range reviews {
+		review := &reviews[i]
+		// Reviews of an item are ordered newest first
+		if wanted[review.ItemIndex] && latest[review.ItemIndex] == nil {
+			latest[review.ItemIndex] = review
+		}
+	}
+
+	return latest, nil
+}
+
+// GetReviewedSummary recomputes report metrics with the latest reviewed score and badcase flag of
+// each item overriding the scorer
+func GetReviewedSummary(reportID int) (*model.ReviewedSummary, error) {
+	query := `
+		SELECT COUNT(*),
+			COALESCE(SUM(CASE WHEN COALESCE(r.review_badcase, r.is_badcase) = 0 THEN 1 ELSE 0 END), 0),
+			COALESCE(AVG(COALESCE(r.review_score, r.score)), 0),
+			COALESCE(SUM(r.reviewed), 0),
+			COALESCE(SUM(CASE WHEN COALESCE(r.review_score, r.score) != r.score
+				OR COALESCE(r.review_badcase, r.is_badcase) != r.is_badcase THEN 1 ELSE 0 END), 0),
+			COALESCE(SUM(CASE WHEN COALESCE(r.review_badcase, r.is_badcase) != r.is_badcase THEN 1 ELSE 0 END), 0)
+		FROM (` + reviewedItems + `) r
+	`
+
+	summary := &model.ReviewedSummary{}
+	err := db.QueryRow(query, reportID).Scan(
+		&summary.TotalCount, &summary.CorrectCount, &summary.AverageScore,
+		&summary.ReviewedCount, &summary.OverriddenCount, &summary.FlippedCount,
+	)
+	if err != nil {
+		return nil, err
+	}
+	if summary.TotalCount > 0 {
+		summary.Accuracy = float64(summary.CorrectCount) / float64(summary.TotalCount)
+	}
+
+	return summary, nil
+}
+
+// GetReportItemBadcaseFlags returns the automatic badcase flag of each reviewed item of a report
+func GetReportItemBadcaseFlags(reportID int) (map[int]int, error) {
+	query := `
+		SELECT item_index, is_badcase FROM report_items
+		WHERE report_id = ? AND item_index IN (SELECT item_index FROM report_item_reviews WHERE report_id = ?)
+	`
+
+	rows, err := db.Query(query, reportID, reportID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	flags := make(map[int]int)
+	for rows.Next() {
+		var index, flag int
+		if err := rows.Scan(&index, &flag); err != nil {
+			return nil, err
+		}
+		flags[index] = flag
+	}
+
+	return flags, nil
+}
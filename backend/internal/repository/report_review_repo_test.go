@@ -0,0 +1,56 @@
+package repository
+
+import (
+	"math"
+	"testing"
+
+	"github.com/wzyjerry/llm-judge/internal/model"
+)
+
+func TestGetReviewedSummaryKeepsEarlierOverrides(t *testing.T) {
+	// Two items the scorer marked correct with score 1
+	reportID := createTestReport(t, [][2]float64{{1, 0}, {1, 0}})
+	score := func(v float64) *float64 { return &v }
+	flag := func(v int) *int { return &v }
+
+	reviews := []struct {
+		itemIndex, reviewerID int
+		updatedAt             string
+		set                   model.ReportItemReviewSet
+	}{
+		// Item 0 is overridden to a badcase with score 0, then a later review only comments
+		{0, 1, "2026-01-01T00:00:00Z", model.ReportItemReviewSet{Score: score(0), IsBadcase: flag(1)}},
+		{0, 2, "2026-01-02T00:00:00Z", model.ReportItemReviewSet{Comment: "looks wrong"}},
+		// Item 1 gets a score override, then a later flag that keeps it correct
+		{1, 1, "2026-01-01T00:00:00Z", model.ReportItemReviewSet{Score: score(0.5)}},
+		{1, 2, "2026-01-02T00:00:00Z", model.ReportItemReviewSet{IsBadcase: flag(0)}},
+	}
+	for _, r := range reviews {
+		if _, err := SetReportItemReview(reportID, r.itemIndex, r.reviewerID, "reviewer", &r.set); err != nil {
+			t.Fatalf("SetReportItemReview: %v", err)
+		}
+		mustExec(t, `UPDATE report_item_reviews SET updated_at = ? WHERE report_id = ? AND item_index = ? AND reviewer_id = ?`,
+			r.updatedAt, reportID, r.itemIndex, r.reviewerID)
+	}
+
+	summary, err := GetReviewedSummary(reportID)
+	if err != nil {
+		t.Fatalf("GetReviewedSummary: %v", err)
+	}
+	want := model.ReviewedSummary{
+		TotalCount:      2,
+		CorrectCount:    1,
+		Accuracy:        0.5,
+		AverageScore:    0.25,
+		ReviewedCount:   2,
+		OverriddenCount: 2,
+		FlippedCount:    1,
+	}
+	if math.Abs(summary.AverageScore-want.AverageScore) > 1e-9 {
+		t.Errorf("average score %v, want %v", summary.AverageScore, want.AverageScore)
+	}
+	summary.AverageScore = want.AverageScore
+	if *summary != want {
+		t.Errorf("summary %+v, want %+v", *summary, want)
+	}
+}
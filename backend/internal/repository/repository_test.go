@@ -0,0 +1,58 @@
+package repository
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// TestMain opens a fresh database for the package, since InitDB connects only once
+func TestMain(m *testing.M) {
+	dir, err := os.MkdirTemp("", "llm-judge-repository-")
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	if err := InitDB(filepath.Join(dir, "test.db")); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.RemoveAll(dir)
+		os.Exit(1)
+	}
+	code := m.Run()
+	db.Close()
+	os.RemoveAll(dir)
+	os.Exit(code)
+}
+
+// mustExec runs a statement and returns the id of the inserted row
+func mustExec(t *testing.T, query string, args ...interface{}) int {
+	t.Helper()
+	result, err := db.Exec(query, args...)
+	if err != nil {
+		t.Fatalf("%s: %v", query, err)
+	}
+	id, _ := result.LastInsertId()
+	return int(id)
+}
+
+// createTestUser creates a user named after the test
+func createTestUser(t *testing.T) int {
+	t.Helper()
+	return mustExec(t, `INSERT INTO users (username, password_hash, created_at, updated_at) VALUES (?, 'x', '', '')`, t.Name())
+}
+
+// createTestReport creates a user, task and report with items of the given score and badcase flag
+func createTestReport(t *testing.T, items [][2]float64) int {
+	t.Helper()
+	name := t.Name()
+	userID := createTestUser(t)
+	mustExec(t, `INSERT INTO user_tasks (user_id, task_id, status, created_at, updated_at) VALUES (?, ?, 'completed', '', '')`, userID, name)
+	reportID := mustExec(t, `INSERT INTO user_reports (user_id, task_id, dataset, model, report_content, timestamp, created_at)
+		VALUES (?, ?, 'd', 'm', '{}', '', '')`, userID, name)
+	for i, item := range items {
+		mustExec(t, `INSERT INTO report_items (report_id, item_index, original_index, expanded_index, score, is_badcase)
+			VALUES (?, ?, ?, 0, ?, ?)`, reportID, i, i, item[0], int(item[1]))
+	}
+	return reportID
+}
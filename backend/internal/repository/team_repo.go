@@ -0,0 +1,198 @@
+package repository
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/wzyjerry/llm-judge/internal/model"
+)
+
+// CreateTeam creates a team with its initial members
+func CreateTeam(team *model.TeamCreate, createdBy string) (int, error) {
+	now := time.Now().Format(time.RFC3339)
+
+	var teamID int
+	err := WithTx(func(tx *sql.Tx) error {
+		result, err := tx.Exec(`INSERT INTO teams (name, description, created_by, created_at) VALUES (?, ?, ?, ?)`,
+			team.Name, team.Description, createdBy, now)
+		if err != nil {
+			return err
+		}
+
+		id, err := result.LastInsertId()
+		if err != nil {
+			return err
+		}
+		teamID = int(id)
+
+		for _, userID := range team.UserIDs {
+			_, err := tx.Exec(`INSERT OR IGNORE INTO team_members (team_id, user_id, created_at) VALUES (?, ?, ?)`,
+				teamID, userID, now)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	return teamID, nil
+}
+
+// TeamNameExists checks if a team exists by name
+func TeamNameExists(name string) (bool, error) {
+	var count int
+	err := db.QueryRow(`SELECT COUNT(*) FROM teams WHERE name = ?`, name).Scan(&count)
+	return count > 0, err
+}
+
+// GetTeams returns all teams with their members
+func GetTeams() ([]model.Team, error) {
+	return queryTeams(`SELECT id, name, description, created_by, created_at FROM teams ORDER BY name`)
+}
+
+// GetUserTeams returns the teams a user belongs to, with their members
+func GetUserTeams(userID int) ([]model.Team, error) {
+	query := `
+		SELECT t.id, t.name, t.description, t.created_by, t.created_at
+		FROM teams t JOIN team_members m ON m.team_id = t.id
+		WHERE m.user_id = ? ORDER BY t.name
+	`
+	return queryTeams(query, userID)
+}
+
+// GetTeamByID returns a team with its members
+func GetTeamByID(teamID int) (*model.Team, error) {
+	teams, err := queryTeams(`SELECT id, name, description, created_by, created_at FROM teams WHERE id = ?`, teamID)
+	if err != nil || len(teams) == 0 {
+		return nil, err
+	}
+	return &teams[0], nil
+}
+
+// queryTeams runs a team query and loads the members of each team
+func queryTeams(query string, args ...interface{}) ([]model.Team, error) {
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	teams := []model.Team{}
+	for rows.Next() {
+		var team model.Team
+		var description, createdBy sql.NullString
+		if err := rows.Scan(&team.ID, &team.Name, &description, &createdBy, &team.CreatedAt); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		team.Description = description.String
+		team.CreatedBy = createdBy.String
+		teams = append(teams, team)
+	}
+	rows.Close()
+
+	for i := range teams {
+		teams[i].Members, err = GetTeamMembers(teams[i].ID)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return teams, nil
+}
+
+// GetTeamMembers returns the members of a team ordered by username
+func GetTeamMembers(teamID int) ([]model.TeamMember, error) {
+	query := `
+		SELECT m.user_id, u.username, m.created_at
+		FROM team_members m JOIN users u ON u.id = m.user_id
+		WHERE m.team_id = ? ORDER BY u.username
+	`
+
+	rows, err := db.Query(query, teamID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	members := []model.TeamMember{}
+	for rows.Next() {
+		var member model.TeamMember
+		if err := rows.Scan(&member.UserID, &member.Username, &member.JoinedAt); err != nil {
+			return nil, err
+		}
+		members = append(members, member)
+	}
+
+	return members, nil
+}
+
+// IsTeamMember reports whether a user belongs to a team
+func IsTeamMember(teamID, userID int) (bool, error) {
+	var count int
+	err := db.QueryRow(`SELECT COUNT(*) FROM team_members WHERE team_id = ? AND user_id = ?`, teamID, userID).Scan(&count)
+	return count > 0, err
+}
+
+// AddTeamMember adds a user to a team, returning false when the user already belongs to it
+func AddTeamMember(teamID, userID int) (bool, error) {
+	now := time.Now().Format(time.RFC3339)
+	result, err := db.Exec(`INSERT OR IGNORE INTO team_members (team_id, user_id, created_at) VALUES (?, ?, ?)`,
+		teamID, userID, now)
+	if err != nil {
+		return false, err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+
+	return rowsAffected > 0, nil
+}
+
+// RemoveTeamMember removes a user from a team
+func RemoveTeamMember(teamID, userID int) (bool, error) {
+	result, err := db.Exec(`DELETE FROM team_members WHERE team_id = ? AND user_id = ?`, teamID, userID)
+	if err != nil {
+		return false, err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+
+	return rowsAffected > 0, nil
+}
+
+// DeleteTeam deletes a team and its memberships. Leaderboards shared with the team become private to their owners,
+// data files shared with the team are no longer shared with its members.
+func DeleteTeam(teamID int) (bool, error) {
+	var rowsAffected int64
+	err := WithTx(func(tx *sql.Tx) error {
+		if _, err := tx.Exec(`UPDATE leaderboards SET team_id = NULL WHERE team_id = ?`, teamID); err != nil {
+			return err
+		}
+		if _, err := tx.Exec(`DELETE FROM user_data_shares WHERE team_id = ?`, teamID); err != nil {
+			return err
+		}
+		if _, err := tx.Exec(`DELETE FROM team_members WHERE team_id = ?`, teamID); err != nil {
+			return err
+		}
+
+		result, err := tx.Exec(`DELETE FROM teams WHERE id = ?`, teamID)
+		if err != nil {
+			return err
+		}
+		rowsAffected, err = result.RowsAffected()
+		return err
+	})
+	if err != nil {
+		return false, err
+	}
+
+	return rowsAffected > 0, nil
+}
@@ -0,0 +1,253 @@
+package repository
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/wzyjerry/llm-judge/internal/model"
+)
+
+// recordUserDataVersion stores the current content of a data file as a new version.
+// Nothing is recorded when the content matches the latest version, whose number is returned instead.
+func recordUserDataVersion(tx *sql.Tx, dataID int, author, message string) (int, error) {
+	current := &model.UserDataVersion{DataID: dataID}
+	var fileSize sql.NullInt64
+	err := tx.QueryRow(`
+		SELECT file_content, COALESCE(blob_key, ''), COALESCE(checksum, ''), file_size, COALESCE(item_count, 0)
+		FROM user_data WHERE id = ?
+	`, dataID).Scan(&current.FileContent, &current.BlobKey, &current.Checksum, &fileSize, &current.ItemCount)
+	if err != nil {
+		return 0, err
+	}
+	current.FileSize = int(fileSize.Int64)
+
+	latest, err := queryUserDataVersion(tx, `WHERE data_id = ? ORDER BY version DESC LIMIT 1`, dataID)
+	if err != nil && err != sql.ErrNoRows {
+		return 0, err
+	}
+
+	version := 1
+	if latest != nil {
+		// Contents in blob storage compare by checksum, contents left in the database by value
+		if latest.Checksum == current.Checksum && latest.FileContent == current.FileContent {
+			return latest.Version, nil
+		}
+		version = latest.Version + 1
+	}
+
+	query := `
+		INSERT INTO user_data_versions (data_id, version, file_content, blob_key, checksum, file_size, item_count,
+			author, message, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`
+	_, err = tx.Exec(query, dataID, version, current.FileContent, nullString(current.BlobKey), nullString(current.Checksum),
+		current.FileSize, current.ItemCount, author, message, time.Now().Format(time.RFC3339))
+	if err != nil {
+		return 0, err
+	}
+
+	if _, err := tx.Exec(`UPDATE user_data SET current_version = ? WHERE id = ?`, version, dataID); err != nil {
+		return 0, err
+	}
+
+	return version, nil
+}
+
+// nullString stores empty strings as NULL
+func nullString(s string) sql.NullString {
+	return sql.NullString{String: s, Valid: s != ""}
+}
+
+// queryUserDataVersion reads a single version with its content using the given WHERE clause
+func queryUserDataVersion(tx *sql.Tx, where string, args ...interface{}) (*model.UserDataVersion, error) {
+	query := `
+		SELECT data_id, version, file_content, COALESCE(blob_key, ''), COALESCE(checksum, ''), file_size, item_count,
+			author, message, created_at
+		FROM user_data_versions ` + where
+
+	v := &model.UserDataVersion{}
+	var fileSize, itemCount sql.NullInt64
+	var author, message sql.NullString
+
+	err := tx.QueryRow(query, args...).Scan(
+		&v.DataID, &v.Version, &v.FileContent, &v.BlobKey, &v.Checksum, &fileSize, &itemCount,
+		&author, &message, &v.CreatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	v.FileSize = int(fileSize.Int64)
+	v.ItemCount = int(itemCount.Int64)
+	v.Author = author.String
+	v.Message = message.String
+
+	return v, nil
+}
+
+// GetUserDataVersions returns all versions of a data file without their content, newest first
+func GetUserDataVersions(dataID int) ([]model.UserDataVersion, error) {
+	query := `
+		SELECT data_id, version, COALESCE(checksum, ''), file_size, item_count, author, message, created_at
+		FROM user_data_versions
+		WHERE data_id = ?
+		ORDER BY version DESC
+	`
+
+	rows, err := db.Query(query, dataID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	versions := []model.UserDataVersion{}
+	for rows.Next() {
+		var v model.UserDataVersion
+		var fileSize, itemCount sql.NullInt64
+		var author, message sql.NullString
+
+		if err := rows.Scan(&v.DataID, &v.Version, &v.Checksum, &fileSize, &itemCount, &author, &message, &v.CreatedAt); err != nil {
+			return nil, err
+		}
+		v.FileSize = int(fileSize.Int64)
+		v.ItemCount = int(itemCount.Int64)
+		v.Author = author.String
+		v.Message = message.String
+
+		versions = append(versions, v)
+	}
+
+	return versions, nil
+}
+
+// GetUserDataVersion returns one version of a data file with its content
+func GetUserDataVersion(dataID, version int) (*model.UserDataVersion, error) {
+	var v *model.UserDataVersion
+	err := WithTx(func(tx *sql.Tx) error {
+		var err error
+		v, err = queryUserDataVersion(tx, `WHERE data_id = ? AND version = ?`, dataID, version)
+		return err
+	})
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return v, nil
+}
+
+// RollbackUserData restores a data file to an earlier version, recorded as a new version.
+// The returned bool is false when the data file or version does not exist.
+func RollbackUserData(userID, dataID, version int, author string) (int, bool, error) {
+	var newVersion int
+	err := WithTx(func(tx *sql.Tx) error {
+		target, err := queryUserDataVersion(tx, `WHERE data_id = ? AND version = ?`, dataID, version)
+		if err != nil {
+			return err
+		}
+
+		// Versions recorded before blob storage keep their content inline until migrated
+		query := `
+			UPDATE user_data SET file_content = ?, blob_key = ?, checksum = ?, file_size = ?, item_count = ?, updated_at = ?
+			WHERE id = ? AND user_id = ?
+		`
+		result, err := tx.Exec(query, target.FileContent, nullString(target.BlobKey), nullString(target.Checksum),
+			target.FileSize, target.ItemCount, time.Now().Format(time.RFC3339), dataID, userID)
+		if err != nil {
+			return err
+		}
+
+		rowsAffected, err := result.RowsAffected()
+		if err != nil {
+			return err
+		}
+		if rowsAffected == 0 {
+			return sql.ErrNoRows
+		}
+
+		newVersion, err = recordUserDataVersion(tx, dataID, author, fmt.Sprintf("Rollback to version %d", version))
+		return err
+	})
+	if err == sql.ErrNoRows {
+		return 0, false, nil
+	}
+	if err != nil {
+		return 0, false, err
+	}
+
+	return newVersion, true, nil
+}
+
+// BackfillUserDataVersions records version 1 for data files that have no versions yet
+func BackfillUserDataVersions() (int, error) {
+	rows, err := db.Query(`SELECT id FROM user_data WHERE id NOT IN (SELECT data_id FROM user_data_versions)`)
+	if err != nil {
+		return 0, err
+	}
+
+	var dataIDs []int
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		dataIDs = append(dataIDs, id)
+	}
+	rows.Close()
+
+	for _, id := range dataIDs {
+		err := WithTx(func(tx *sql.Tx) error {
+			_, err := recordUserDataVersion(tx, id, "system", "Initial version")
+			return err
+		})
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	return len(dataIDs), nil
+}
+
+// GetInlineDataVersions returns the versions whose content is still stored in the database, without content
+func GetInlineDataVersions() ([]model.UserDataVersion, error) {
+	rows, err := db.Query(`SELECT data_id, version FROM user_data_versions WHERE blob_key IS NULL ORDER BY data_id, version`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var versions []model.UserDataVersion
+	for rows.Next() {
+		var v model.UserDataVersion
+		if err := rows.Scan(&v.DataID, &v.Version); err != nil {
+			return nil, err
+		}
+		versions = append(versions, v)
+	}
+
+	return versions, nil
+}
+
+// MoveDataVersionToBlob replaces the inline content of a version by its blob storage location.
+// A data file whose current version it is is moved along.
+func MoveDataVersionToBlob(dataID, version int, blob *model.DataBlob) error {
+	return WithTx(func(tx *sql.Tx) error {
+		_, err := tx.Exec(`
+			UPDATE user_data_versions SET file_content = '', blob_key = ?, checksum = ?, file_size = ?, item_count = ?
+			WHERE data_id = ? AND version = ?
+		`, blob.Key, blob.Checksum, blob.Size, blob.ItemCount, dataID, version)
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.Exec(`
+			UPDATE user_data SET file_content = '', blob_key = ?, checksum = ?, file_size = ?, item_count = ?
+			WHERE id = ? AND current_version = ? AND blob_key IS NULL
+		`, blob.Key, blob.Checksum, blob.Size, blob.ItemCount, dataID, version)
+		return err
+	})
+}
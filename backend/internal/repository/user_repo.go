@@ -67,15 +67,24 @@ func VerifyUser(username, password string) (*model.User, error) {
 
 // GetUserByID returns a user by ID
 func GetUserByID(userID int) (*model.User, error) {
+	return queryUser(`WHERE id = ?`, userID)
+}
+
+// GetUserByUsername returns a user by username
+func GetUserByUsername(username string) (*model.User, error) {
+	return queryUser(`WHERE username = ?`, username)
+}
+
+// queryUser returns the user matching where, or nil
+func queryUser(where string, args ...interface{}) (*model.User, error) {
 	query := `
 		SELECT id, username, password_hash, email, created_at, updated_at
-		FROM users WHERE id = ?
-	`
+		FROM users ` + where
 
 	user := &model.User{}
 	var email sql.NullString
 
-	err := db.QueryRow(query, userID).Scan(
+	err := db.QueryRow(query, args...).Scan(
 		&user.ID, &user.Username, &user.Password, &email,
 		&user.CreatedAt, &user.UpdatedAt,
 	)
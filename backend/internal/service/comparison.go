@@ -0,0 +1,159 @@
+package service
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+
+	"github.com/wzyjerry/llm-judge/internal/model"
+)
+
+// scoreTolerance is the largest score difference still counted as a tie
+const scoreTolerance = 1e-9
+
+// reportContent is the part of report_content needed to compare reports
+type reportContent struct {
+	Summary map[string]interface{} `json:"summary"`
+	Results []model.ReportResult   `json:"results"`
+}
+
+// itemKey identifies a dataset item independently of the order results were produced in
+type itemKey struct {
+	originalIndex int
+	expandedIndex int
+}
+
+// BuildComparison aligns the reports of several models by dataset item and counts
+// pairwise win/tie/loss outcomes. Models are compared in the given order.
+func BuildComparison(models []string, reports map[string]*model.UserReport) (*model.ComparisonReport, error) {
+	comparison := &model.ComparisonReport{
+		Models: models,
+		Summary: model.ComparisonSummary{
+			ReportIDs:      make(map[string]int),
+			ModelSummaries: make(map[string]map[string]interface{}),
+			Records:        make(map[string]model.WinTieLoss),
+			Pairwise:       []model.PairwiseResult{},
+		},
+	}
+
+	items := make(map[itemKey]*model.ComparisonItem)
+	for _, name := range models {
+		report, ok := reports[name]
+		if !ok {
+			return nil, fmt.Errorf("no report for model %s", name)
+		}
+
+		var content reportContent
+		if err := json.Unmarshal([]byte(report.ReportContent), &content); err != nil {
+			return nil, fmt.Errorf("failed to parse report of model %s: %w", name, err)
+		}
+
+		comparison.Dataset = report.Dataset
+		comparison.Summary.ReportIDs[name] = report.ID
+		comparison.Summary.ModelSummaries[name] = content.Summary
+		comparison.Summary.Records[name] = model.WinTieLoss{}
+
+		for _, r := range content.Results {
+			key := itemKey{r.OriginalIndex, r.ExpandedIndex}
+			item, ok := items[key]
+			if !ok {
+				item = &model.ComparisonItem{
+					OriginalIndex:   r.OriginalIndex,
+					ExpandedIndex:   r.ExpandedIndex,
+					UserInput:       r.UserInput,
+					ReferenceOutput: r.ReferenceOutput,
+					Outputs:         make(map[string]model.ComparisonOutput),
+				}
+				items[key] = item
+			}
+			item.Outputs[name] = model.ComparisonOutput{
+				ModelOutput:   r.ModelOutput,
+				Score:         r.Score,
+				IsBadcase:     r.IsBadcase,
+				InferenceTime: r.InferenceTime,
+				Error:         r.Error,
+			}
+		}
+	}
+
+	pairwise := make([]model.PairwiseResult, 0, len(models)*(len(models)-1)/2)
+	for i := 0; i < len(models); i++ {
+		for j := i + 1; j < len(models); j++ {
+			pairwise = append(pairwise, model.PairwiseResult{ModelA: models[i], ModelB: models[j]})
+		}
+	}
+
+	comparison.Items = make([]model.ComparisonItem, 0, len(items))
+	for _, item := range items {
+		item.BestModels = bestModels(models, item.Outputs)
+
+		if len(item.Outputs) == len(models) {
+			comparison.Summary.ComparedCount++
+			for p := range pairwise {
+				a := item.Outputs[pairwise[p].ModelA].Score
+				b := item.Outputs[pairwise[p].ModelB].Score
+				recordOutcome(&pairwise[p].WinTieLoss, a, b)
+			}
+		}
+
+		comparison.Items = append(comparison.Items, *item)
+	}
+
+	for _, p := range pairwise {
+		recordA := comparison.Summary.Records[p.ModelA]
+		recordA.Wins += p.Wins
+		recordA.Ties += p.Ties
+		recordA.Losses += p.Losses
+		comparison.Summary.Records[p.ModelA] = recordA
+
+		recordB := comparison.Summary.Records[p.ModelB]
+		recordB.Wins += p.Losses
+		recordB.Ties += p.Ties
+		recordB.Losses += p.Wins
+		comparison.Summary.Records[p.ModelB] = recordB
+	}
+	comparison.Summary.Pairwise = pairwise
+
+	sort.Slice(comparison.Items, func(i, j int) bool {
+		if comparison.Items[i].OriginalIndex != comparison.Items[j].OriginalIndex {
+			return comparison.Items[i].OriginalIndex < comparison.Items[j].OriginalIndex
+		}
+		return comparison.Items[i].ExpandedIndex < comparison.Items[j].ExpandedIndex
+	})
+	comparison.Summary.ItemCount = len(comparison.Items)
+
+	return comparison, nil
+}
+
+// recordOutcome adds the outcome of score a against score b
+func recordOutcome(record *model.WinTieLoss, a, b float64) {
+	switch {
+	case math.Abs(a-b) <= scoreTolerance:
+		record.Ties++
+	case a > b:
+		record.Wins++
+	default:
+		record.Losses++
+	}
+}
+
+// bestModels returns the models sharing the top score of an item
+func bestModels(models []string, outputs map[string]model.ComparisonOutput) []string {
+	best := []string{}
+	var top float64
+	for _, name := range models {
+		output, ok := outputs[name]
+		if !ok {
+			continue
+		}
+		switch {
+		case len(best) == 0 || output.Score > top+scoreTolerance:
+			best = []string{name}
+			top = output.Score
+		case math.Abs(output.Score-top) <= scoreTolerance:
+			best = append(best, name)
+		}
+	}
+	return best
+}
@@ -0,0 +1,374 @@
+package service
+
+import (
+	"archive/zip"
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/wzyjerry/llm-judge/internal/model"
+	"go.uber.org/zap"
+)
+
+// DataExportFormats lists the supported data export formats by name
+var DataExportFormats = map[string]ExportFormat{
+	"jsonl":    {"application/x-ndjson", "jsonl"},
+	"csv":      {"text/csv; charset=utf-8", "csv"},
+	"openai":   {"application/x-ndjson", "jsonl"},
+	"sharegpt": {"application/x-ndjson", "jsonl"},
+}
+
+// openAIRoles and shareGPTExportRoles name the sides of a conversation in the exported formats
+var (
+	openAIRoles         = map[string]string{turnSideSystem: "system", turnSideUser: "user", turnSideModel: "assistant"}
+	shareGPTExportRoles = map[string]string{turnSideSystem: "system", turnSideUser: "human", turnSideModel: "gpt"}
+)
+
+// ExportData writes the items of a data file in the given format. open is called for each pass
+// over the content, and CSV needs two to size its columns. When indices is not nil only the items
+// at those 0-based indices are written. Lines that fail to parse are skipped and not counted,
+// like in content listings. System turns have no column in CSV, CSVDroppedTurns counts them.
+func ExportData(w io.Writer, format string, open func() (io.ReadCloser, error), indices map[int]bool) error {
+	_, err := exportData(w, format, open, indices)
+	return err
+}
+
+// exportData is ExportData returning the number of turns the format could not hold
+func exportData(w io.Writer, format string, open func() (io.ReadCloser, error), indices map[int]bool) (int, error) {
+	buf := bufio.NewWriter(w)
+	var dropped int
+	var err error
+	switch format {
+	case "jsonl":
+		err = forEachExportItem(open, indices, func(raw []byte, _ map[string]interface{}) error {
+			buf.Write(raw)
+			return buf.WriteByte('\n')
+		})
+	case "openai", "sharegpt":
+		encoder := json.NewEncoder(buf)
+		encoder.SetEscapeHTML(false)
+		convert := openAIRecord
+		if format == "sharegpt" {
+			convert = shareGPTRecord
+		}
+		err = forEachExportItem(open, indices, func(_ []byte, item map[string]interface{}) error {
+			if item == nil {
+				return nil
+			}
+			// Encode terminates each record with a newline
+			return encoder.Encode(convert(item))
+		})
+	case "csv":
+		dropped, err = exportDataCSV(buf, open, indices)
+	default:
+		return 0, fmt.Errorf("unsupported export format: %s", format)
+	}
+	if err != nil {
+		return 0, err
+	}
+	return dropped, buf.Flush()
+}
+
+// OpenDataForExport returns a function opening the current content of a data file for each pass
+// of ExportData. The content is opened once up front, so a storage failure is returned before
+// anything is written.
+func OpenDataForExport(ctx context.Context, data *model.UserData) (func() (io.ReadCloser, error), error) {
+	first, err := OpenUserData(ctx, data)
+	if err != nil {
+		return nil, err
+	}
+	return func() (io.ReadCloser, error) {
+		if content := first; content != nil {
+			first = nil
+			return content, nil
+		}
+		return OpenUserData(ctx, data)
+	}, nil
+}
+
+// ExportDataBundle writes a zip archive holding each data file in the given format and a
+// manifest.json describing them. Files whose content cannot be read are listed in the manifest
+// with the error instead.
+func ExportDataBundle(ctx context.Context, w io.Writer, format string, files []model.UserData) error {
+	extension := DataExportFormats[format].Extension
+	archive := zip.NewWriter(w)
+
+	manifest := model.DataBundleManifest{
+		ExportedAt: time.Now().Format(time.RFC3339),
+		Format:     format,
+		Datasets:   []model.DataBundleEntry{},
+	}
+	for i := range files {
+		data := &files[i]
+		bundleEntry := model.DataBundleEntry{
+			ID:          data.ID,
+			Filename:    data.Filename,
+			Description: data.Description,
+			Version:     data.CurrentVersion,
+			ItemCount:   data.ItemCount,
+			Checksum:    data.Checksum,
+		}
+
+		open, err := OpenDataForExport(ctx, data)
+		if err != nil {
+			zap.L().Warn("Skipping unreadable data file in export bundle", zap.Int("data_id", data.ID), zap.Error(err))
+			bundleEntry.Error = err.Error()
+			manifest.Datasets = append(manifest.Datasets, bundleEntry)
+			continue
+		}
+
+		bundleEntry.Path = fmt.Sprintf("datasets/%d_%s.%s", data.ID, ExportFileBase(data.Filename), extension)
+		entry, err := archive.Create(bundleEntry.Path)
+		if err != nil {
+			return err
+		}
+		dropped, err := exportData(entry, format, open, nil)
+		if err != nil {
+			return fmt.Errorf("data file %d: %w", data.ID, err)
+		}
+		bundleEntry.DroppedTurns = dropped
+		manifest.Datasets = append(manifest.Datasets, bundleEntry)
+	}
+
+	entry, err := archive.Create("manifest.json")
+	if err != nil {
+		return err
+	}
+	encoder := json.NewEncoder(entry)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(manifest); err != nil {
+		return err
+	}
+	return archive.Close()
+}
+
+// ExportFileBase returns a filename without its extension, reduced to a single path element so it
+// is safe to use in archive entries and download names
+func ExportFileBase(filename string) string {
+	base := path.Base(strings.ReplaceAll(filename, "\\", "/"))
+	base = strings.TrimSuffix(base, path.Ext(base))
+	base = strings.ReplaceAll(base, "..", "_")
+	if base == "" || base == "." || base == "/" {
+		return "data"
+	}
+	return base
+}
+
+// forEachExportItem calls fn with the raw line and the object of each selected item.
+// The object is nil for items that are valid JSON but not objects.
+func forEachExportItem(open func() (io.ReadCloser, error), indices map[int]bool, fn func(raw []byte, item map[string]interface{}) error) error {
+	content, err := open()
+	if err != nil {
+		return err
+	}
+	defer content.Close()
+
+	index := 0
+	return ForEachDataLine(content, func(line int, raw []byte) error {
+		decoder := json.NewDecoder(bytes.NewReader(raw))
+		decoder.UseNumber()
+		var value interface{}
+		if err := decoder.Decode(&value); err != nil {
+			return nil
+		}
+		selected := indices == nil || indices[index]
+		index++
+		if !selected {
+			return nil
+		}
+		item, _ := value.(map[string]interface{})
+		return fn(raw, item)
+	})
+}
+
+// turnSide returns the side of a turn role. Unknown roles are custom evaluation roles, so they
+// count as the model side.
+func turnSide(role string) string {
+	if side, known := knownTurnRoles[strings.ToLower(role)]; known {
+		return side
+	}
+	return turnSideModel
+}
+
+// exportedTurns returns the role and text of each turn of an item
+func exportedTurns(item map[string]interface{}) [][2]string {
+	turns, _ := item["turns"].([]interface{})
+	result := make([][2]string, 0, len(turns))
+	for _, raw := range turns {
+		turn, _ := raw.(map[string]interface{})
+		role, _ := turn["role"].(string)
+		text, _ := turn["text"].(string)
+		result = append(result, [2]string{role, text})
+	}
+	return result
+}
+
+// splitExportMeta returns the meta_description of an item and its other meta fields
+func splitExportMeta(item map[string]interface{}) (string, map[string]interface{}) {
+	meta, _ := item["meta"].(map[string]interface{})
+	description, _ := meta["meta_description"].(string)
+	rest := make(map[string]interface{}, len(meta))
+	for k, v := range meta {
+		if k != "meta_description" {
+			rest[k] = v
+		}
+	}
+	return description, rest
+}
+
+// openAIRecord converts an item to chat messages; meta_description becomes the system message
+// and the other meta fields are kept under meta, so the record imports back unchanged
+func openAIRecord(item map[string]interface{}) map[string]interface{} {
+	description, meta := splitExportMeta(item)
+	messages := []interface{}{}
+	if description != "" {
+		messages = append(messages, map[string]interface{}{"role": "system", "content": description})
+	}
+	for _, turn := range exportedTurns(item) {
+		messages = append(messages, map[string]interface{}{"role": openAIRoles[turnSide(turn[0])], "content": turn[1]})
+	}
+
+	record := map[string]interface{}{"messages": messages}
+	if len(meta) > 0 {
+		record["meta"] = meta
+	}
+	return record
+}
+
+// shareGPTRecord converts an item to a ShareGPT conversation; meta_description becomes the
+// system field and the other meta fields are kept under meta
+func shareGPTRecord(item map[string]interface{}) map[string]interface{} {
+	description, meta := splitExportMeta(item)
+	conversations := []interface{}{}
+	for _, turn := range exportedTurns(item) {
+		conversations = append(conversations, map[string]interface{}{
+			"from":  shareGPTExportRoles[turnSide(turn[0])],
+			"value": turn[1],
+		})
+	}
+
+	record := map[string]interface{}{"conversations": conversations}
+	if description != "" {
+		record["system"] = description
+	}
+	if len(meta) > 0 {
+		record["meta"] = meta
+	}
+	return record
+}
+
+// csvTurnPairs groups the user and model turns of an item into [Human, Assistant] pairs and
+// returns the number of system turns, which the layout cannot hold
+func csvTurnPairs(item map[string]interface{}) ([][2]string, int) {
+	var pairs [][2]string
+	system := 0
+	for _, turn := range exportedTurns(item) {
+		switch turnSide(turn[0]) {
+		case turnSideSystem:
+			system++
+		case turnSideUser:
+			pairs = append(pairs, [2]string{turn[1], ""})
+		case turnSideModel:
+			if n := len(pairs); n > 0 && pairs[n-1][1] == "" {
+				pairs[n-1][1] = turn[1]
+			} else {
+				pairs = append(pairs, [2]string{"", turn[1]})
+			}
+		}
+	}
+	return pairs, system
+}
+
+// CSVDroppedTurns returns the number of system turns a CSV export of the selected items leaves out
+func CSVDroppedTurns(open func() (io.ReadCloser, error), indices map[int]bool) (int, error) {
+	dropped := 0
+	err := forEachExportItem(open, indices, func(_ []byte, item map[string]interface{}) error {
+		_, system := csvTurnPairs(item)
+		dropped += system
+		return nil
+	})
+	return dropped, err
+}
+
+// csvFormulaPrefixes are the first characters spreadsheets read as the start of a formula
+const csvFormulaPrefixes = "=+-@\t\r"
+
+// csvSafeCell prefixes a cell a spreadsheet would evaluate as a formula with a quote. Cells already
+// starting with quotes before such a character get one more, so unescapeCSVCell restores any text.
+func csvSafeCell(cell string) string {
+	rest := strings.TrimLeft(cell, "'")
+	if rest != "" && strings.ContainsRune(csvFormulaPrefixes, rune(rest[0])) {
+		return "'" + cell
+	}
+	return cell
+}
+
+// unescapeCSVCell reverses csvSafeCell
+func unescapeCSVCell(cell string) string {
+	rest := strings.TrimLeft(cell, "'")
+	if rest != cell && rest != "" && strings.ContainsRune(csvFormulaPrefixes, rune(rest[0])) {
+		return cell[1:]
+	}
+	return cell
+}
+
+// exportDataCSV writes items in the CSV layout the importer reads: meta, meta_description,
+// Human/Assistant column pairs, as many as the longest item needs, and the other meta fields as a
+// JSON object in a last meta_json column. It returns the number of system turns left out.
+func exportDataCSV(w io.Writer, open func() (io.ReadCloser, error), indices map[int]bool) (int, error) {
+	width, dropped := 1, 0
+	err := forEachExportItem(open, indices, func(_ []byte, item map[string]interface{}) error {
+		pairs, system := csvTurnPairs(item)
+		if len(pairs) > width {
+			width = len(pairs)
+		}
+		dropped += system
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	writer := csv.NewWriter(w)
+	header := []string{"meta", "meta_description"}
+	for i := 0; i < width; i++ {
+		header = append(header, "Human", "Assistant")
+	}
+	header = append(header, csvMetaColumn)
+	if err := writer.Write(header); err != nil {
+		return 0, err
+	}
+
+	err = forEachExportItem(open, indices, func(_ []byte, item map[string]interface{}) error {
+		if item == nil {
+			return nil
+		}
+		description, meta := splitExportMeta(item)
+		row := make([]string, len(header))
+		row[0], row[1] = "meta", csvSafeCell(description)
+		pairs, _ := csvTurnPairs(item)
+		for i, pair := range pairs {
+			row[2+2*i], row[3+2*i] = csvSafeCell(pair[0]), csvSafeCell(pair[1])
+		}
+		if len(meta) > 0 {
+			encoded, err := json.Marshal(meta)
+			if err != nil {
+				return err
+			}
+			row[len(row)-1] = csvSafeCell(string(encoded))
+		}
+		return writer.Write(row)
+	})
+	if err != nil {
+		return 0, err
+	}
+	writer.Flush()
+	return dropped, writer.Error()
+}
@@ -0,0 +1,206 @@
+package service
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/wzyjerry/llm-judge/internal/model"
+)
+
+const exportTestContent = `{"meta":{"meta_description":"=SUM(A1:A2)","category":"math","level":2},"turns":[{"role":"Human","text":"-1 + 1?"},{"role":"Assistant","text":"@0"},{"role":"Human","text":"'=quoted"},{"role":"Assistant","text":"plain"}]}
+{"meta":{"meta_description":""},"turns":[{"role":"user","text":"hi, \"there\"\nnext line"},{"role":"assistant","text":"héllo"}]}
+`
+
+func openString(content string) func() (io.ReadCloser, error) {
+	return func() (io.ReadCloser, error) {
+		return io.NopCloser(strings.NewReader(content)), nil
+	}
+}
+
+// comparableItem reduces an item to what every export format keeps: meta and the side and text
+// of each turn
+func comparableItem(t *testing.T, item map[string]interface{}) string {
+	t.Helper()
+	meta, _ := item["meta"].(map[string]interface{})
+	if meta == nil {
+		meta = map[string]interface{}{}
+	}
+	if _, ok := meta["meta_description"]; !ok {
+		meta["meta_description"] = ""
+	}
+	var turns [][2]string
+	for _, turn := range exportedTurns(item) {
+		turns = append(turns, [2]string{turnSide(turn[0]), turn[1]})
+	}
+	encoded, err := json.Marshal(map[string]interface{}{"meta": meta, "turns": turns})
+	if err != nil {
+		t.Fatalf("marshal: %v", err)
+	}
+	return string(encoded)
+}
+
+func comparableItems(t *testing.T, lines []string) []string {
+	t.Helper()
+	var items []string
+	for _, line := range lines {
+		item, err := decodeRecord([]byte(line))
+		if err != nil {
+			t.Fatalf("decode %q: %v", line, err)
+		}
+		items = append(items, comparableItem(t, item))
+	}
+	return items
+}
+
+func TestExportImportRoundTrip(t *testing.T) {
+	want := comparableItems(t, strings.Split(strings.TrimSpace(exportTestContent), "\n"))
+
+	for _, format := range []string{"jsonl", "openai", "sharegpt", "csv"} {
+		t.Run(format, func(t *testing.T) {
+			var exported bytes.Buffer
+			if err := ExportData(&exported, format, openString(exportTestContent), nil); err != nil {
+				t.Fatalf("ExportData: %v", err)
+			}
+
+			var lines []string
+			filename := "export." + DataExportFormats[format].Extension
+			err := ImportDataFile(context.Background(), filename, &exported, &model.DataImportOptions{}, NewDataValidator(""), func(line []byte) error {
+				lines = append(lines, string(line))
+				return nil
+			})
+			if err != nil {
+				t.Fatalf("ImportDataFile: %v", err)
+			}
+
+			got := comparableItems(t, lines)
+			if len(got) != len(want) {
+				t.Fatalf("imported %d items, want %d", len(got), len(want))
+			}
+			for i := range want {
+				if got[i] != want[i] {
+					t.Errorf("item %d imported as %s, want %s", i, got[i], want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestExportDataCSVNeutralisesFormulas(t *testing.T) {
+	var exported bytes.Buffer
+	if err := ExportData(&exported, "csv", openString(exportTestContent), nil); err != nil {
+		t.Fatalf("ExportData: %v", err)
+	}
+	rows, err := csv.NewReader(&exported).ReadAll()
+	if err != nil {
+		t.Fatalf("read CSV: %v", err)
+	}
+
+	wantHeader := []string{"meta", "meta_description", "Human", "Assistant", "Human", "Assistant", "meta_json"}
+	if strings.Join(rows[0], ",") != strings.Join(wantHeader, ",") {
+		t.Fatalf("header %v, want %v", rows[0], wantHeader)
+	}
+	wantRow := []string{"meta", "'=SUM(A1:A2)", "'-1 + 1?", "'@0", "''=quoted", "plain", `{"category":"math","level":2}`}
+	if strings.Join(rows[1], "|") != strings.Join(wantRow, "|") {
+		t.Fatalf("row %q, want %q", rows[1], wantRow)
+	}
+}
+
+func TestCSVSafeCell(t *testing.T) {
+	tests := []struct {
+		cell, safe string
+	}{
+		{"", ""},
+		{"plain", "plain"},
+		{"=1+1", "'=1+1"},
+		{"+1", "'+1"},
+		{"-1", "'-1"},
+		{"@SUM(A1)", "'@SUM(A1)"},
+		{"\tx", "'\tx"},
+		{"'=x", "''=x"},
+		{"'plain", "'plain"},
+		{"'", "'"},
+		{"a=b", "a=b"},
+	}
+	for _, tt := range tests {
+		if got := csvSafeCell(tt.cell); got != tt.safe {
+			t.Errorf("csvSafeCell(%q) = %q, want %q", tt.cell, got, tt.safe)
+		}
+		if got := unescapeCSVCell(tt.safe); got != tt.cell {
+			t.Errorf("unescapeCSVCell(%q) = %q, want %q", tt.safe, got, tt.cell)
+		}
+	}
+}
+
+func TestCSVDroppedTurns(t *testing.T) {
+	content := `{"meta":{},"turns":[{"role":"system","text":"be brief"},{"role":"Human","text":"q"},{"role":"Assistant","text":"a"}]}
+{"meta":{},"turns":[{"role":"Human","text":"q"},{"role":"Assistant","text":"a"}]}
+{"meta":{},"turns":[{"role":"System","text":"s1"},{"role":"system","text":"s2"},{"role":"Human","text":"q"},{"role":"Assistant","text":"a"}]}
+`
+	tests := []struct {
+		indices map[int]bool
+		want    int
+	}{
+		{nil, 3},
+		{map[int]bool{0: true}, 1},
+		{map[int]bool{1: true}, 0},
+		{map[int]bool{1: true, 2: true}, 2},
+	}
+	for _, tt := range tests {
+		got, err := CSVDroppedTurns(openString(content), tt.indices)
+		if err != nil || got != tt.want {
+			t.Errorf("CSVDroppedTurns(%v) = %d, %v; want %d", tt.indices, got, err, tt.want)
+		}
+	}
+}
+
+func TestExportFileBase(t *testing.T) {
+	tests := []struct {
+		filename, base string
+	}{
+		{"data.jsonl", "data"},
+		{"my.data.csv", "my.data"},
+		{"../../etc/passwd", "passwd"},
+		{`..\..\evil.jsonl`, "evil"},
+		{"/abs/path.jsonl", "path"},
+		{"..", "data"},
+		{"..jsonl", "data"},
+		{"a..b.jsonl", "a_b"},
+		{"", "data"},
+	}
+	for _, tt := range tests {
+		if got := ExportFileBase(tt.filename); got != tt.base {
+			t.Errorf("ExportFileBase(%q) = %q, want %q", tt.filename, got, tt.base)
+		}
+	}
+}
+
+func TestExportDataBundlePaths(t *testing.T) {
+	initTestBlobStore(t, 0)
+	files := []model.UserData{
+		{ID: 1, Filename: "../../../tmp/evil.jsonl", FileContent: exportTestContent},
+		{ID: 2, Filename: `..\..\win.jsonl`, FileContent: exportTestContent},
+	}
+	var archive bytes.Buffer
+	if err := ExportDataBundle(context.Background(), &archive, "csv", files); err != nil {
+		t.Fatalf("ExportDataBundle: %v", err)
+	}
+
+	reader, err := zip.NewReader(bytes.NewReader(archive.Bytes()), int64(archive.Len()))
+	if err != nil {
+		t.Fatalf("open archive: %v", err)
+	}
+	var names []string
+	for _, file := range reader.File {
+		names = append(names, file.Name)
+	}
+	want := []string{"datasets/1_evil.csv", "datasets/2_win.csv", "manifest.json"}
+	if strings.Join(names, ",") != strings.Join(want, ",") {
+		t.Fatalf("archive holds %v, want %v", names, want)
+	}
+}
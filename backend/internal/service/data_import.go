@@ -0,0 +1,625 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/wzyjerry/llm-judge/internal/model"
+)
+
+// Record formats of imported files. Auto detects the format of each record.
+const (
+	ImportFormatAuto     = "auto"
+	ImportFormatNative   = "native"   // {"meta": {...}, "turns": [{"role", "text"}]}
+	ImportFormatOpenAI   = "openai"   // {"messages": [{"role", "content"}]}
+	ImportFormatShareGPT = "sharegpt" // {"conversations": [{"from", "value"}]}
+	ImportFormatAlpaca   = "alpaca"   // {"instruction", "input", "output"}
+	ImportFormatColumns  = "columns"  // flat records mapped by a DataColumnMapping
+)
+
+// parquetScript prints the rows of a Parquet file as JSONL. Like main.py it is resolved
+// against the working directory.
+const parquetScript = "llm_judge/data_load/parquet_to_jsonl.py"
+
+var (
+	ErrUnsupportedDataFile = errors.New("only .jsonl, .json, .csv and .parquet files are supported")
+	ErrInvalidDataFile     = errors.New("invalid data file")
+	ErrInvalidImport       = errors.New("invalid import options")
+)
+
+// shareGPTRoles maps the lowercased "from" values of ShareGPT conversations to turn roles
+var shareGPTRoles = map[string]string{
+	"human":     "Human",
+	"user":      "Human",
+	"gpt":       "assistant",
+	"chatgpt":   "assistant",
+	"assistant": "assistant",
+}
+
+// ImportDataFile converts the records of an uploaded file into meta/turns items and passes each,
+// serialized as one JSON line, to emit. The file type is taken from the filename: .jsonl holds one
+// record per line, .json an array of records, .csv a header and rows, .parquet rows of a table.
+// Every item is checked by the validator; native JSONL lines are passed on unchanged. Once all
+// records were read, a DataValidationError is returned when any of them is invalid.
+func ImportDataFile(ctx context.Context, filename string, src io.Reader, opts *model.DataImportOptions, validator *DataValidator, emit func(line []byte) error) error {
+	if err := normalizeImportOptions(opts); err != nil {
+		return err
+	}
+
+	imp := &dataImporter{opts: opts, validator: validator, emit: emit}
+	var err error
+	switch strings.ToLower(filepath.Ext(filename)) {
+	case ".jsonl":
+		err = ForEachDataLine(src, imp.importLine)
+	case ".json":
+		err = imp.importJSONArray(src)
+	case ".csv":
+		err = imp.importCSV(src)
+	case ".parquet":
+		err = imp.importParquet(ctx, src)
+	default:
+		return ErrUnsupportedDataFile
+	}
+	if err != nil {
+		return err
+	}
+
+	if !validator.Valid() {
+		return &DataValidationError{Info: validator.Info()}
+	}
+	return nil
+}
+
+// IsImportableFile reports whether ImportDataFile reads files of this name
+func IsImportableFile(filename string) bool {
+	switch strings.ToLower(filepath.Ext(filename)) {
+	case ".jsonl", ".json", ".csv", ".parquet":
+		return true
+	}
+	return false
+}
+
+// normalizeImportOptions defaults the format to auto, or to columns when a mapping is given,
+// and checks that the columns format has a usable mapping
+func normalizeImportOptions(opts *model.DataImportOptions) error {
+	if opts.Format == "" || (opts.Format == ImportFormatAuto && opts.Mapping != nil) {
+		opts.Format = ImportFormatAuto
+		if opts.Mapping != nil {
+			opts.Format = ImportFormatColumns
+		}
+	}
+
+	switch opts.Format {
+	case ImportFormatAuto, ImportFormatNative, ImportFormatOpenAI, ImportFormatShareGPT, ImportFormatAlpaca:
+		return nil
+	case ImportFormatColumns:
+		if opts.Mapping == nil || len(opts.Mapping.Turns) == 0 {
+			return fmt.Errorf("%w: the columns format needs a mapping with at least one turn column", ErrInvalidImport)
+		}
+		for i, turn := range opts.Mapping.Turns {
+			if turn.Column == "" || turn.Role == "" {
+				return fmt.Errorf("%w: turn mapping %d needs a column and a role", ErrInvalidImport, i+1)
+			}
+		}
+		return nil
+	}
+	return fmt.Errorf("%w: unknown format %q", ErrInvalidImport, opts.Format)
+}
+
+// dataImporter converts records one at a time
+type dataImporter struct {
+	opts      *model.DataImportOptions
+	validator *DataValidator
+	emit      func(line []byte) error
+}
+
+// importLine imports one line of a JSONL file
+func (imp *dataImporter) importLine(line int, raw []byte) error {
+	record, err := decodeRecord(raw)
+	if err != nil {
+		imp.validator.Reject(line, "line is not a JSON object")
+		return nil
+	}
+	return imp.importRecord(line, record, raw)
+}
+
+// importJSONArray imports the records of a JSON array, numbering them from 1
+func (imp *dataImporter) importJSONArray(src io.Reader) error {
+	decoder := json.NewDecoder(src)
+	token, err := decoder.Token()
+	if delim, ok := token.(json.Delim); err != nil || !ok || delim != '[' {
+		return fmt.Errorf("%w: a .json file must hold an array of records", ErrInvalidDataFile)
+	}
+
+	for n := 1; decoder.More(); n++ {
+		var raw json.RawMessage
+		if err := decoder.Decode(&raw); err != nil {
+			return fmt.Errorf("%w: record %d: %v", ErrInvalidDataFile, n, err)
+		}
+		record, err := decodeRecord(raw)
+		if err != nil {
+			imp.validator.Reject(n, "record is not a JSON object")
+			continue
+		}
+		if err := imp.importRecord(n, record, nil); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// importCSV imports the rows of a CSV file as records keyed by the header. Without a mapping
+// in auto format, rows use the fixed layout: meta, meta_description, then Human/Assistant pairs.
+func (imp *dataImporter) importCSV(src io.Reader) error {
+	reader := csv.NewReader(src)
+	header, err := reader.Read()
+	if err == io.EOF {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	if mapping := imp.opts.Mapping; mapping != nil {
+		columns := make(map[string]bool, len(header))
+		for _, column := range header {
+			columns[column] = true
+		}
+		for _, column := range mappedColumns(mapping) {
+			if !columns[column] {
+				return fmt.Errorf("%w: column %q not found in the CSV header", ErrInvalidImport, column)
+			}
+		}
+	}
+
+	metaColumn := len(header) > 2 && header[len(header)-1] == csvMetaColumn
+	for {
+		row, err := reader.Read()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		line, _ := reader.FieldPos(0)
+
+		if imp.opts.Format == ImportFormatAuto {
+			if len(row) < 2 || strings.TrimSpace(row[0]) == "" {
+				continue
+			}
+			var extra string
+			if metaColumn && len(row) == len(header) {
+				extra, row = unescapeCSVCell(row[len(row)-1]), row[:len(row)-1]
+			}
+			item := ConvertCSVRow(row)
+			if extra != "" {
+				var fields map[string]interface{}
+				if err := json.Unmarshal([]byte(extra), &fields); err != nil {
+					imp.validator.Reject(line, fmt.Sprintf("%s is not a JSON object", csvMetaColumn))
+					continue
+				}
+				meta := item["meta"].(map[string]interface{})
+				for k, v := range fields {
+					if k != "meta_description" {
+						meta[k] = v
+					}
+				}
+			}
+			if err := imp.acceptItem(line, item, nil); err != nil {
+				return err
+			}
+			continue
+		}
+
+		record := make(map[string]interface{}, len(header))
+		for i, column := range header {
+			if i < len(row) {
+				record[column] = row[i]
+			}
+		}
+		if err := imp.importRecord(line, record, nil); err != nil {
+			return err
+		}
+	}
+}
+
+// importParquet imports the rows of a Parquet file, numbering them from 1.
+// The file is read by a Python script since pyarrow needs a seekable file.
+func (imp *dataImporter) importParquet(ctx context.Context, src io.Reader) error {
+	tmp, err := os.CreateTemp("", "llm-judge-import-*.parquet")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	_, err = io.Copy(tmp, src)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return err
+	}
+
+	pythonCmd, err := findPythonCommand()
+	if err != nil {
+		return err
+	}
+	cmd := exec.CommandContext(ctx, pythonCmd, parquetScript, tmp.Name())
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return err
+	}
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+
+	err = ForEachDataLine(stdout, func(row int, raw []byte) error {
+		record, err := decodeRecord(raw)
+		if err != nil {
+			return fmt.Errorf("%w: unexpected output for row %d", ErrInvalidDataFile, row)
+		}
+		return imp.importRecord(row, record, nil)
+	})
+	if err != nil {
+		cmd.Process.Kill()
+		cmd.Wait()
+		return err
+	}
+
+	if err := cmd.Wait(); err != nil {
+		lines := strings.Split(strings.TrimSpace(stderr.String()), "\n")
+		return fmt.Errorf("%w: failed to read Parquet file: %s", ErrInvalidDataFile, lines[len(lines)-1])
+	}
+	return nil
+}
+
+// importRecord converts a record in the configured or detected format. raw holds the original
+// line of a native record, which is passed on unchanged when given.
+func (imp *dataImporter) importRecord(line int, record map[string]interface{}, raw []byte) error {
+	format := imp.opts.Format
+	if format == ImportFormatAuto {
+		format = detectRecordFormat(record)
+	}
+
+	var item map[string]interface{}
+	var err error
+	switch format {
+	case ImportFormatNative:
+		item = record
+	case ImportFormatOpenAI:
+		item, err = convertOpenAIRecord(record)
+	case ImportFormatShareGPT:
+		item, err = convertShareGPTRecord(record)
+	case ImportFormatAlpaca:
+		item, err = convertAlpacaRecord(record)
+	case ImportFormatColumns:
+		item = convertColumnsRecord(record, imp.opts.Mapping)
+	default:
+		err = errors.New("unrecognized record; expected turns, messages, conversations or instruction/output")
+	}
+	if err != nil {
+		imp.validator.Reject(line, err.Error())
+		return nil
+	}
+
+	if format != ImportFormatNative {
+		raw = nil
+	}
+	return imp.acceptItem(line, item, raw)
+}
+
+// acceptItem validates an item and emits it while every record so far is valid
+func (imp *dataImporter) acceptItem(line int, item map[string]interface{}, raw []byte) error {
+	imp.validator.ValidateItem(line, item)
+	if !imp.validator.Valid() {
+		// The import fails anyway, so only the report is completed
+		return nil
+	}
+
+	if raw == nil {
+		var buf bytes.Buffer
+		encoder := json.NewEncoder(&buf)
+		encoder.SetEscapeHTML(false)
+		if err := encoder.Encode(item); err != nil {
+			return err
+		}
+		raw = bytes.TrimSuffix(buf.Bytes(), []byte("\n"))
+	}
+	return imp.emit(raw)
+}
+
+// decodeRecord parses a JSON object, keeping numbers as written
+func decodeRecord(raw []byte) (map[string]interface{}, error) {
+	decoder := json.NewDecoder(bytes.NewReader(raw))
+	decoder.UseNumber()
+	var record map[string]interface{}
+	if err := decoder.Decode(&record); err != nil {
+		return nil, err
+	}
+	if record == nil {
+		return nil, errors.New("not a JSON object")
+	}
+	return record, nil
+}
+
+// detectRecordFormat guesses the format of a record from its fields
+func detectRecordFormat(record map[string]interface{}) string {
+	has := func(field string) bool {
+		_, exists := record[field]
+		return exists
+	}
+
+	switch {
+	case has("turns"):
+		return ImportFormatNative
+	case has("messages"):
+		return ImportFormatOpenAI
+	case has("conversations"):
+		return ImportFormatShareGPT
+	case has("instruction") && has("output"):
+		return ImportFormatAlpaca
+	}
+	return ""
+}
+
+// mappedColumns lists every column a mapping reads
+func mappedColumns(mapping *model.DataColumnMapping) []string {
+	var columns []string
+	if mapping.System != "" {
+		columns = append(columns, mapping.System)
+	}
+	for _, turn := range mapping.Turns {
+		columns = append(columns, turn.Column)
+	}
+	return append(columns, mapping.Meta...)
+}
+
+// importedItem collects the meta and turns of a converted record
+type importedItem struct {
+	meta  map[string]interface{}
+	turns []interface{}
+}
+
+// newImportedItem starts an item whose meta holds the record's fields other than the converted ones
+func newImportedItem(record map[string]interface{}, converted ...string) *importedItem {
+	item := &importedItem{meta: make(map[string]interface{}), turns: []interface{}{}}
+	skip := make(map[string]bool, len(converted))
+	for _, field := range converted {
+		skip[field] = true
+	}
+
+	for field, value := range record {
+		if skip[field] {
+			continue
+		}
+		if meta, ok := value.(map[string]interface{}); ok && field == "meta" {
+			for k, v := range meta {
+				item.meta[k] = v
+			}
+			continue
+		}
+		item.meta[field] = value
+	}
+	return item
+}
+
+// addSystem appends a system prompt to meta_description
+func (it *importedItem) addSystem(text string) {
+	if existing, _ := it.meta["meta_description"].(string); existing != "" {
+		text = existing + "\n\n" + text
+	}
+	it.meta["meta_description"] = text
+}
+
+func (it *importedItem) addTurn(role, text string) {
+	it.turns = append(it.turns, map[string]interface{}{"role": role, "text": text})
+}
+
+func (it *importedItem) toMap() map[string]interface{} {
+	return map[string]interface{}{"meta": it.meta, "turns": it.turns}
+}
+
+// convertOpenAIRecord converts chat messages; system messages become meta_description
+func convertOpenAIRecord(record map[string]interface{}) (map[string]interface{}, error) {
+	messages, ok := record["messages"].([]interface{})
+	if !ok {
+		return nil, errors.New("messages must be a list")
+	}
+
+	item := newImportedItem(record, "messages")
+	for i, raw := range messages {
+		message, ok := raw.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("message %d must be an object", i+1)
+		}
+		text, err := messageContentText(message["content"])
+		if err != nil {
+			return nil, fmt.Errorf("message %d: %v", i+1, err)
+		}
+
+		role, _ := message["role"].(string)
+		switch strings.ToLower(role) {
+		case "system", "developer":
+			item.addSystem(text)
+		case "user":
+			item.addTurn("Human", text)
+		case "assistant":
+			item.addTurn("assistant", text)
+		default:
+			return nil, fmt.Errorf("message %d has unsupported role %q", i+1, role)
+		}
+	}
+	return item.toMap(), nil
+}
+
+// messageContentText returns the text of a message content, which is a string or a list of parts
+func messageContentText(content interface{}) (string, error) {
+	switch value := content.(type) {
+	case nil:
+		return "", nil
+	case string:
+		return value, nil
+	case []interface{}:
+		var texts []string
+		for _, raw := range value {
+			part, _ := raw.(map[string]interface{})
+			text, ok := part["text"].(string)
+			if !ok || (part["type"] != nil && part["type"] != "text") {
+				return "", errors.New("only text content parts are supported")
+			}
+			texts = append(texts, text)
+		}
+		return strings.Join(texts, "\n"), nil
+	}
+	return "", errors.New("content must be a string or a list of parts")
+}
+
+// convertShareGPTRecord converts a ShareGPT conversation; a system field or turn becomes meta_description
+func convertShareGPTRecord(record map[string]interface{}) (map[string]interface{}, error) {
+	conversations, ok := record["conversations"].([]interface{})
+	if !ok {
+		return nil, errors.New("conversations must be a list")
+	}
+
+	item := newImportedItem(record, "conversations", "system")
+	if system, _ := record["system"].(string); system != "" {
+		item.addSystem(system)
+	}
+	for i, raw := range conversations {
+		turn, ok := raw.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("conversation turn %d must be an object", i+1)
+		}
+		from, _ := turn["from"].(string)
+		value, ok := turn["value"].(string)
+		if !ok {
+			return nil, fmt.Errorf("conversation turn %d value must be a string", i+1)
+		}
+
+		if strings.EqualFold(from, "system") {
+			item.addSystem(value)
+			continue
+		}
+		role, known := shareGPTRoles[strings.ToLower(from)]
+		if !known {
+			return nil, fmt.Errorf("conversation turn %d has unsupported from %q", i+1, from)
+		}
+		item.addTurn(role, value)
+	}
+	return item.toMap(), nil
+}
+
+// convertAlpacaRecord converts an instruction record. The input is appended to the instruction,
+// and history holds earlier [instruction, output] pairs.
+func convertAlpacaRecord(record map[string]interface{}) (map[string]interface{}, error) {
+	instruction, ok := record["instruction"].(string)
+	if !ok {
+		return nil, errors.New("instruction must be a string")
+	}
+	output, ok := record["output"].(string)
+	if !ok {
+		return nil, errors.New("output must be a string")
+	}
+
+	item := newImportedItem(record, "instruction", "input", "output", "system", "history")
+	if system, _ := record["system"].(string); system != "" {
+		item.addSystem(system)
+	}
+	if history, exists := record["history"]; exists && history != nil {
+		pairs, ok := history.([]interface{})
+		if !ok {
+			return nil, errors.New("history must be a list of [instruction, output] pairs")
+		}
+		for i, raw := range pairs {
+			pair, _ := raw.([]interface{})
+			if len(pair) != 2 {
+				return nil, fmt.Errorf("history entry %d must be an [instruction, output] pair", i+1)
+			}
+			question, _ := pair[0].(string)
+			answer, _ := pair[1].(string)
+			item.addTurn("Human", question)
+			item.addTurn("assistant", answer)
+		}
+	}
+
+	prompt := instruction
+	if input, _ := record["input"].(string); strings.TrimSpace(input) != "" {
+		prompt += "\n\n" + input
+	}
+	item.addTurn("Human", prompt)
+	item.addTurn("assistant", output)
+	return item.toMap(), nil
+}
+
+// convertColumnsRecord converts a flat record with a column mapping; empty cells add no turn
+func convertColumnsRecord(record map[string]interface{}, mapping *model.DataColumnMapping) map[string]interface{} {
+	item := &importedItem{meta: make(map[string]interface{}), turns: []interface{}{}}
+	if mapping.System != "" {
+		if text := cellText(record[mapping.System]); text != "" {
+			item.addSystem(text)
+		}
+	}
+	for _, column := range mapping.Meta {
+		if value, exists := record[column]; exists {
+			item.meta[column] = value
+		}
+	}
+	for _, turn := range mapping.Turns {
+		if text := cellText(record[turn.Column]); strings.TrimSpace(text) != "" {
+			item.addTurn(turn.Role, text)
+		}
+	}
+	return item.toMap()
+}
+
+// cellText formats a cell of a flat record as text
+func cellText(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case map[string]interface{}, []interface{}:
+		data, _ := json.Marshal(v)
+		return string(data)
+	}
+	return fmt.Sprint(value)
+}
+
+// csvMetaColumn is the optional last column of the fixed CSV layout holding the meta fields other
+// than meta_description as a JSON object
+const csvMetaColumn = "meta_json"
+
+// ConvertCSVRow converts a row of the fixed CSV layout: meta, meta_description, then pairs of
+// Human and Assistant texts. Pairs with an empty cell are skipped. Cells quoted against formula
+// evaluation on export are restored.
+func ConvertCSVRow(row []string) map[string]interface{} {
+	item := map[string]interface{}{
+		"meta": map[string]interface{}{
+			"meta_description": unescapeCSVCell(row[1]),
+		},
+	}
+
+	turns := []interface{}{}
+	for i := 2; i+1 < len(row); i += 2 {
+		if row[i] != "" && row[i+1] != "" {
+			turns = append(turns,
+				map[string]interface{}{"role": "Human", "text": unescapeCSVCell(row[i])},
+				map[string]interface{}{"role": "Assistant", "text": unescapeCSVCell(row[i+1])},
+			)
+		}
+	}
+	item["turns"] = turns
+
+	return item
+}
@@ -0,0 +1,159 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/wzyjerry/llm-judge/internal/model"
+)
+
+// importString imports content as a file of the given name and returns the emitted lines
+func importString(filename, content string, opts *model.DataImportOptions) ([]string, error) {
+	var lines []string
+	err := ImportDataFile(context.Background(), filename, strings.NewReader(content), opts, NewDataValidator(""), func(line []byte) error {
+		lines = append(lines, string(line))
+		return nil
+	})
+	return lines, err
+}
+
+func TestImportDataFileFormats(t *testing.T) {
+	tests := []struct {
+		name     string
+		filename string
+		content  string
+		opts     model.DataImportOptions
+		want     []string
+	}{
+		{
+			name:     "native lines pass unchanged",
+			filename: "data.jsonl",
+			content:  `{"turns":[{"role":"Human","text":"q"},{"role":"assistant","text":"a"}],"meta":{"n":1.50}}`,
+			want:     []string{`{"turns":[{"role":"Human","text":"q"},{"role":"assistant","text":"a"}],"meta":{"n":1.50}}`},
+		},
+		{
+			name:     "openai",
+			filename: "data.jsonl",
+			content:  `{"id":7,"messages":[{"role":"system","content":"be brief"},{"role":"user","content":[{"type":"text","text":"hi"}]},{"role":"assistant","content":"hello"}]}`,
+			want:     []string{`{"meta":{"id":7,"meta_description":"be brief"},"turns":[{"role":"Human","text":"hi"},{"role":"assistant","text":"hello"}]}`},
+		},
+		{
+			name:     "sharegpt array",
+			filename: "data.json",
+			content:  `[{"system":"s","conversations":[{"from":"human","value":"q"},{"from":"gpt","value":"a"}]}]`,
+			want:     []string{`{"meta":{"meta_description":"s"},"turns":[{"role":"Human","text":"q"},{"role":"assistant","text":"a"}]}`},
+		},
+		{
+			name:     "alpaca with history",
+			filename: "data.jsonl",
+			content:  `{"instruction":"Sum","input":"1 2","output":"3","history":[["hi","hello"]],"source":"x"}`,
+			want: []string{`{"meta":{"source":"x"},"turns":[{"role":"Human","text":"hi"},{"role":"assistant","text":"hello"},` +
+				`{"role":"Human","text":"Sum\n\n1 2"},{"role":"assistant","text":"3"}]}`},
+		},
+		{
+			name:     "forced format",
+			filename: "data.jsonl",
+			content:  `{"instruction":"q","output":"a","turns":"not native"}`,
+			opts:     model.DataImportOptions{Format: ImportFormatAlpaca},
+			want:     []string{`{"meta":{"turns":"not native"},"turns":[{"role":"Human","text":"q"},{"role":"assistant","text":"a"}]}`},
+		},
+		{
+			name:     "csv columns",
+			filename: "data.csv",
+			content:  "system,question,answer,category,unused\n,q1,a1,c1,x\ns,q2,a2,c2,y\n",
+			opts: model.DataImportOptions{Mapping: &model.DataColumnMapping{
+				System: "system",
+				Turns:  []model.DataColumnTurn{{Column: "question", Role: "Human"}, {Column: "answer", Role: "assistant"}},
+				Meta:   []string{"category"},
+			}},
+			want: []string{
+				`{"meta":{"category":"c1"},"turns":[{"role":"Human","text":"q1"},{"role":"assistant","text":"a1"}]}`,
+				`{"meta":{"category":"c2","meta_description":"s"},"turns":[{"role":"Human","text":"q2"},{"role":"assistant","text":"a2"}]}`,
+			},
+		},
+		{
+			name:     "csv fixed layout",
+			filename: "data.CSV",
+			content:  "meta,meta_description,Human,Assistant,meta_json\nmeta,'=sys,q,a,\"{\"\"k\"\":1}\"\n,skipped,q,a,\n",
+			want:     []string{`{"meta":{"k":1,"meta_description":"=sys"},"turns":[{"role":"Human","text":"q"},{"role":"Assistant","text":"a"}]}`},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := importString(tt.filename, tt.content, &tt.opts)
+			if err != nil {
+				var validationErr *DataValidationError
+				if errors.As(err, &validationErr) {
+					t.Fatalf("ImportDataFile: %v: %+v", err, validationErr.Info.InvalidLines)
+				}
+				t.Fatalf("ImportDataFile: %v", err)
+			}
+			if strings.Join(got, "\n") != strings.Join(tt.want, "\n") {
+				t.Errorf("imported\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(tt.want, "\n"))
+			}
+
+			// Imported items are native, so importing them again changes nothing
+			again, err := importString("imported.jsonl", strings.Join(got, "\n"), &model.DataImportOptions{})
+			if err != nil || strings.Join(again, "\n") != strings.Join(got, "\n") {
+				t.Errorf("importing the items again gave\n%s\n%v", strings.Join(again, "\n"), err)
+			}
+		})
+	}
+}
+
+func TestImportDataFileErrors(t *testing.T) {
+	columns := &model.DataColumnMapping{Turns: []model.DataColumnTurn{{Column: "missing", Role: "Human"}}}
+	tests := []struct {
+		name     string
+		filename string
+		content  string
+		opts     model.DataImportOptions
+		want     error
+	}{
+		{"unsupported file", "data.txt", "", model.DataImportOptions{}, ErrUnsupportedDataFile},
+		{"unknown format", "data.jsonl", "", model.DataImportOptions{Format: "yaml"}, ErrInvalidImport},
+		{"columns without mapping", "data.jsonl", "", model.DataImportOptions{Format: ImportFormatColumns}, ErrInvalidImport},
+		{"turn without role", "data.jsonl", "", model.DataImportOptions{Mapping: &model.DataColumnMapping{
+			Turns: []model.DataColumnTurn{{Column: "q"}}}}, ErrInvalidImport},
+		{"mapped column not in header", "data.csv", "q,a\nx,y\n", model.DataImportOptions{Mapping: columns}, ErrInvalidImport},
+		{"json object", "data.json", `{"turns":[]}`, model.DataImportOptions{}, ErrInvalidDataFile},
+	}
+	for _, tt := range tests {
+		if _, err := importString(tt.filename, tt.content, &tt.opts); !errors.Is(err, tt.want) {
+			t.Errorf("%s: ImportDataFile returned %v, want %v", tt.name, err, tt.want)
+		}
+	}
+}
+
+func TestImportDataFileReportsInvalidRecords(t *testing.T) {
+	content := strings.Join([]string{
+		`{"messages":[{"role":"user","content":"q"},{"role":"assistant","content":"a"}]}`,
+		`{"messages":[{"role":"tool","content":"q"}]}`,
+		`[1, 2]`,
+		`{"unknown":true}`,
+		`{"messages":[{"role":"user","content":"q"},{"role":"assistant","content":"a"}]}`,
+	}, "\n")
+
+	lines, err := importString("data.jsonl", content, &model.DataImportOptions{})
+	var validationErr *DataValidationError
+	if !errors.As(err, &validationErr) {
+		t.Fatalf("ImportDataFile returned %v, want a DataValidationError", err)
+	}
+	info := validationErr.Info
+	if info.TotalLines != 5 || info.ValidItems != 2 || info.InvalidCount != 3 {
+		t.Errorf("report counts %d lines, %d valid and %d invalid, want 5, 2 and 3", info.TotalLines, info.ValidItems, info.InvalidCount)
+	}
+	var invalid []int
+	for _, issue := range info.InvalidLines {
+		invalid = append(invalid, issue.Line)
+	}
+	if len(invalid) != 3 || invalid[0] != 2 || invalid[1] != 3 || invalid[2] != 4 {
+		t.Errorf("invalid lines %v, want [2 3 4]", invalid)
+	}
+	// Items after the first invalid record are only validated
+	if len(lines) != 1 {
+		t.Errorf("emitted %d items, want 1", len(lines))
+	}
+}
@@ -0,0 +1,187 @@
+package service
+
+import (
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"math"
+	"math/rand"
+	"sort"
+)
+
+// Dataset operations derive new data files from the items of existing ones
+const (
+	DataOperationSample = "sample"
+	DataOperationSplit  = "split"
+	DataOperationMerge  = "merge"
+	DataOperationDedupe = "dedupe"
+)
+
+// Duplicate detection modes of the dedupe and merge operations
+const (
+	DedupeExact  = "exact"  // identical items
+	DedupePrompt = "prompt" // items with the same meta_description and user turns
+)
+
+// ErrInvalidDataOperation reports invalid parameters of a dataset operation
+var ErrInvalidDataOperation = errors.New("invalid dataset operation")
+
+// SampleDataItems draws size items at random, keeping their order in the file. With stratifyBy, every
+// value of that meta field keeps its share of the items in the sample. The seed makes the sample reproducible.
+func SampleDataItems(items []map[string]interface{}, size int, stratifyBy string, seed int64) ([]map[string]interface{}, error) {
+	if size <= 0 || size > len(items) {
+		return nil, fmt.Errorf("%w: size must be between 1 and the %d items of the data file", ErrInvalidDataOperation, len(items))
+	}
+
+	parts, err := partitionItems(items, []int{size, len(items) - size}, stratifyBy, seed)
+	if err != nil {
+		return nil, err
+	}
+	return parts[0], nil
+}
+
+// SplitDataItems shuffles items into parts sized by the ratios, keeping their order in the file within
+// each part. With stratifyBy, every value of that meta field is split in the same ratios.
+func SplitDataItems(items []map[string]interface{}, ratios []float64, stratifyBy string, seed int64) ([][]map[string]interface{}, error) {
+	sizes := apportion(len(items), ratios)
+	for i, size := range sizes {
+		if size == 0 {
+			return nil, fmt.Errorf("%w: part %d of the split would be empty", ErrInvalidDataOperation, i+1)
+		}
+	}
+	return partitionItems(items, sizes, stratifyBy, seed)
+}
+
+// DedupeDataItems keeps the first item of each group of duplicates, returning the kept items and the number
+// of items dropped. In prompt mode, items without user turns are always kept.
+func DedupeDataItems(items []map[string]interface{}, mode string) ([]map[string]interface{}, int) {
+	key := exactItemKey
+	if mode == DedupePrompt {
+		key = promptItemKey
+	}
+
+	kept := make([]map[string]interface{}, 0, len(items))
+	seen := make(map[[sha256.Size]byte]bool)
+	for _, item := range items {
+		if k, ok := key(item); ok {
+			if seen[k] {
+				continue
+			}
+			seen[k] = true
+		}
+		kept = append(kept, item)
+	}
+	return kept, len(items) - len(kept)
+}
+
+// partitionItems shuffles items into parts of the given sizes. Strata are the values of the stratifyBy
+// meta field, or all items when empty; each part takes about the same share of every stratum.
+func partitionItems(items []map[string]interface{}, sizes []int, stratifyBy string, seed int64) ([][]map[string]interface{}, error) {
+	strata, err := stratifyItems(items, stratifyBy)
+	if err != nil {
+		return nil, err
+	}
+
+	// Fill the parts one after another, dividing each among the strata in proportion to the items
+	// they have left, so every stratum is split in about the same ratios
+	remaining := make([]float64, len(strata))
+	for s, indices := range strata {
+		remaining[s] = float64(len(indices))
+	}
+	counts := make([][]int, len(sizes))
+	for p, size := range sizes {
+		counts[p] = apportion(size, remaining)
+		for s, count := range counts[p] {
+			remaining[s] -= float64(count)
+		}
+	}
+
+	rng := rand.New(rand.NewSource(seed))
+	partIndices := make([][]int, len(sizes))
+	for s, indices := range strata {
+		rng.Shuffle(len(indices), func(i, j int) { indices[i], indices[j] = indices[j], indices[i] })
+		for p := range sizes {
+			partIndices[p] = append(partIndices[p], indices[:counts[p][s]]...)
+			indices = indices[counts[p][s]:]
+		}
+	}
+
+	parts := make([][]map[string]interface{}, len(sizes))
+	for p, indices := range partIndices {
+		sort.Ints(indices)
+		parts[p] = make([]map[string]interface{}, len(indices))
+		for i, index := range indices {
+			parts[p][i] = items[index]
+		}
+	}
+	return parts, nil
+}
+
+// stratifyItems groups the indices of items by the value of a meta field, in order of first appearance.
+// Items without the field form one stratum.
+func stratifyItems(items []map[string]interface{}, field string) ([][]int, error) {
+	if field == "" {
+		all := make([]int, len(items))
+		for i := range all {
+			all[i] = i
+		}
+		return [][]int{all}, nil
+	}
+
+	var strata [][]int
+	positions := make(map[string]int)
+	present := false
+	for i, item := range items {
+		meta, _ := item["meta"].(map[string]interface{})
+		value, ok := meta[field]
+		present = present || ok
+		key := cellText(value)
+		if !ok {
+			// Keep items without the field apart from items with an empty value
+			key = "\x00missing"
+		}
+		position, seen := positions[key]
+		if !seen {
+			position = len(strata)
+			positions[key] = position
+			strata = append(strata, nil)
+		}
+		strata[position] = append(strata[position], i)
+	}
+	if !present {
+		return nil, fmt.Errorf("%w: no item has the meta field %q", ErrInvalidDataOperation, field)
+	}
+	return strata, nil
+}
+
+// apportion divides total into integer shares proportional to weights by the largest remainder method.
+// The shares add up to total; ties go to the earlier weight.
+func apportion(total int, weights []float64) []int {
+	shares := make([]int, len(weights))
+	sum := 0.0
+	for _, w := range weights {
+		sum += w
+	}
+	if total <= 0 || sum <= 0 {
+		return shares
+	}
+
+	remainders := make([]float64, len(weights))
+	assigned := 0
+	for i, w := range weights {
+		quota := float64(total) * w / sum
+		shares[i] = int(math.Floor(quota))
+		remainders[i] = quota - float64(shares[i])
+		assigned += shares[i]
+	}
+
+	order := make([]int, len(weights))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(a, b int) bool { return remainders[order[a]] > remainders[order[b]] })
+	for _, i := range order[:total-assigned] {
+		shares[i]++
+	}
+	return shares
+}
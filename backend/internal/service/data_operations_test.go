@@ -0,0 +1,199 @@
+package service
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+// parseItems decodes one JSON item per line
+func parseItems(t *testing.T, lines ...string) []map[string]interface{} {
+	t.Helper()
+	items := make([]map[string]interface{}, len(lines))
+	for i, line := range lines {
+		if err := json.Unmarshal([]byte(line), &items[i]); err != nil {
+			t.Fatalf("parse %q: %v", line, err)
+		}
+	}
+	return items
+}
+
+// categoryItems returns items numbered in order, with the meta category of each
+func categoryItems(t *testing.T, categories ...string) []map[string]interface{} {
+	t.Helper()
+	lines := make([]string, len(categories))
+	for i, category := range categories {
+		lines[i] = fmt.Sprintf(`{"meta":{"id":%d,"category":%q},"turns":[]}`, i, category)
+	}
+	return parseItems(t, lines...)
+}
+
+// itemIDs returns the meta id of each item
+func itemIDs(items []map[string]interface{}) []int {
+	ids := make([]int, len(items))
+	for i, item := range items {
+		ids[i] = int(item["meta"].(map[string]interface{})["id"].(float64))
+	}
+	return ids
+}
+
+// countCategories counts the items of each meta category
+func countCategories(items []map[string]interface{}) map[string]int {
+	counts := make(map[string]int)
+	for _, item := range items {
+		counts[item["meta"].(map[string]interface{})["category"].(string)]++
+	}
+	return counts
+}
+
+func TestApportion(t *testing.T) {
+	tests := []struct {
+		total   int
+		weights []float64
+		want    []int
+	}{
+		{10, []float64{0.8, 0.1, 0.1}, []int{8, 1, 1}},
+		{10, []float64{1, 1, 1}, []int{4, 3, 3}},
+		{7, []float64{0.5, 0.5}, []int{4, 3}},
+		{1, []float64{1, 1, 1}, []int{1, 0, 0}},
+		{5, []float64{3, 0, 1}, []int{4, 0, 1}},
+		{3, []float64{2, 1}, []int{2, 1}},
+		{0, []float64{1, 2}, []int{0, 0}},
+		{5, []float64{0, 0}, []int{0, 0}},
+	}
+	for _, tt := range tests {
+		if got := apportion(tt.total, tt.weights); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("apportion(%d, %v) = %v, want %v", tt.total, tt.weights, got, tt.want)
+		}
+	}
+}
+
+func TestSplitDataItemsDeterministic(t *testing.T) {
+	items := categoryItems(t, "a", "a", "b", "a", "b", "a", "a", "b", "a", "b")
+
+	tests := []struct {
+		name       string
+		ratios     []float64
+		stratifyBy string
+		wantSizes  []int
+		wantCounts []map[string]int
+		wantIDs    [][]int // parts drawn with seed 42, when pinned
+	}{
+		{"plain", []float64{0.6, 0.4}, "", []int{6, 4}, nil, nil},
+		{"stratified", []float64{0.5, 0.5}, "category", []int{5, 5},
+			[]map[string]int{{"a": 3, "b": 2}, {"a": 3, "b": 2}}, [][]int{{1, 2, 5, 6, 9}, {0, 3, 4, 7, 8}}},
+		{"three parts", []float64{0.8, 0.1, 0.1}, "", []int{8, 1, 1}, nil, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			parts, err := SplitDataItems(items, tt.ratios, tt.stratifyBy, 42)
+			if err != nil {
+				t.Fatalf("SplitDataItems: %v", err)
+			}
+			again, err := SplitDataItems(items, tt.ratios, tt.stratifyBy, 42)
+			if err != nil {
+				t.Fatalf("SplitDataItems: %v", err)
+			}
+
+			seen := make(map[int]bool)
+			for p, part := range parts {
+				ids := itemIDs(part)
+				if len(ids) != tt.wantSizes[p] {
+					t.Errorf("part %d has %d items, want %d", p, len(ids), tt.wantSizes[p])
+				}
+				if !reflect.DeepEqual(ids, itemIDs(again[p])) {
+					t.Errorf("part %d is %v, then %v with the same seed", p, ids, itemIDs(again[p]))
+				}
+				for i, id := range ids {
+					if i > 0 && id <= ids[i-1] {
+						t.Errorf("part %d is not in file order: %v", p, ids)
+					}
+					if seen[id] {
+						t.Errorf("item %d is in more than one part", id)
+					}
+					seen[id] = true
+				}
+				if tt.wantIDs != nil && !reflect.DeepEqual(ids, tt.wantIDs[p]) {
+					t.Errorf("part %d is %v, want %v", p, ids, tt.wantIDs[p])
+				}
+				if tt.wantCounts != nil && !reflect.DeepEqual(countCategories(part), tt.wantCounts[p]) {
+					t.Errorf("part %d has categories %v, want %v", p, countCategories(part), tt.wantCounts[p])
+				}
+			}
+			if len(seen) != len(items) {
+				t.Errorf("parts hold %d of the %d items", len(seen), len(items))
+			}
+		})
+	}
+}
+
+func TestSplitDataItemsRejectsEmptyParts(t *testing.T) {
+	items := categoryItems(t, "a", "a", "a")
+	if _, err := SplitDataItems(items, []float64{0.9, 0.1}, "", 1); !errors.Is(err, ErrInvalidDataOperation) {
+		t.Errorf("split into an empty part returned %v, want ErrInvalidDataOperation", err)
+	}
+	if _, err := SplitDataItems(items, []float64{0.5, 0.5}, "missing", 1); !errors.Is(err, ErrInvalidDataOperation) {
+		t.Errorf("split by a missing meta field returned %v, want ErrInvalidDataOperation", err)
+	}
+}
+
+func TestSampleDataItems(t *testing.T) {
+	items := categoryItems(t, "a", "a", "a", "a", "a", "a", "b", "b", "b", "b")
+
+	sample, err := SampleDataItems(items, 5, "category", 7)
+	if err != nil {
+		t.Fatalf("SampleDataItems: %v", err)
+	}
+	if want := map[string]int{"a": 3, "b": 2}; !reflect.DeepEqual(countCategories(sample), want) {
+		t.Errorf("stratified sample has categories %v, want %v", countCategories(sample), want)
+	}
+	again, _ := SampleDataItems(items, 5, "category", 7)
+	if !reflect.DeepEqual(itemIDs(sample), itemIDs(again)) {
+		t.Errorf("sample is %v, then %v with the same seed", itemIDs(sample), itemIDs(again))
+	}
+
+	for _, size := range []int{0, -1, 11} {
+		if _, err := SampleDataItems(items, size, "", 7); !errors.Is(err, ErrInvalidDataOperation) {
+			t.Errorf("sample of size %d returned %v, want ErrInvalidDataOperation", size, err)
+		}
+	}
+}
+
+func TestDedupeDataItems(t *testing.T) {
+	items := parseItems(t,
+		`{"meta":{"meta_description":"sys"},"turns":[{"role":"Human","text":"Hello  World"},{"role":"Assistant","text":"a"}]}`,
+		`{"meta":{"meta_description":"sys"},"turns":[{"role":"Human","text":"Hello  World"},{"role":"Assistant","text":"a"}]}`,
+		`{"meta":{"meta_description":"sys"},"turns":[{"role":"Human","text":"hello world"},{"role":"Assistant","text":"b"}]}`,
+		`{"meta":{"meta_description":"other"},"turns":[{"role":"Human","text":"hello world"},{"role":"Assistant","text":"a"}]}`,
+		`{"meta":{},"turns":[{"role":"Assistant","text":"no prompt"}]}`,
+		`{"meta":{},"turns":[{"role":"Assistant","text":"no prompt"}]}`,
+	)
+
+	tests := []struct {
+		mode        string
+		wantKept    []int
+		wantDropped int
+	}{
+		// Identical items only
+		{DedupeExact, []int{0, 2, 3, 4}, 2},
+		// Same system prompt and user turns, ignoring case and whitespace; items without user turns are kept
+		{DedupePrompt, []int{0, 3, 4, 5}, 2},
+	}
+	for _, tt := range tests {
+		kept, dropped := DedupeDataItems(items, tt.mode)
+		var keptIndices []int
+		for _, item := range kept {
+			for i := range items {
+				if reflect.ValueOf(item).Pointer() == reflect.ValueOf(items[i]).Pointer() {
+					keptIndices = append(keptIndices, i)
+				}
+			}
+		}
+		if !reflect.DeepEqual(keptIndices, tt.wantKept) || dropped != tt.wantDropped {
+			t.Errorf("DedupeDataItems(%s) kept %v and dropped %d, want %v and %d",
+				tt.mode, keptIndices, dropped, tt.wantKept, tt.wantDropped)
+		}
+	}
+}
@@ -0,0 +1,429 @@
+package service
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"io"
+	"math"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/wzyjerry/llm-judge/internal/model"
+	"github.com/wzyjerry/llm-judge/internal/pkg/stats"
+	"github.com/wzyjerry/llm-judge/internal/repository"
+	"go.uber.org/zap"
+)
+
+const (
+	// lengthHistogramBins is the number of equal-width bins of length and numeric meta value histograms
+	lengthHistogramBins = 10
+	// maxDuplicateGroups limits the duplicate groups listed in data statistics
+	maxDuplicateGroups = 50
+	// englishStopwordShare is the share of common English words above which Latin text counts as English
+	englishStopwordShare = 0.1
+)
+
+// englishStopwords are frequent English words telling English apart from other Latin-script languages
+var englishStopwords = map[string]bool{
+	"the": true, "a": true, "an": true, "and": true, "or": true, "is": true, "are": true, "was": true,
+	"were": true, "be": true, "to": true, "of": true, "in": true, "on": true, "for": true, "with": true,
+	"that": true, "this": true, "it": true, "you": true, "i": true, "what": true, "how": true, "why": true,
+	"do": true, "does": true, "can": true, "please": true, "my": true, "your": true,
+}
+
+// languageScripts are the writing systems detectLanguage tells apart, with the language reported for each
+var languageScripts = []struct {
+	language string
+	table    *unicode.RangeTable
+}{
+	{"zh", unicode.Han},
+	{"ja", unicode.Hiragana},
+	{"ja", unicode.Katakana},
+	{"ko", unicode.Hangul},
+	{"ru", unicode.Cyrillic},
+	{"ar", unicode.Arabic},
+	{"hi", unicode.Devanagari},
+	{"th", unicode.Thai},
+	{"latin", unicode.Latin},
+}
+
+// GetDataStatistics returns the statistics of a version of a data file, computing them from the
+// content returned by open and caching them on first use
+func GetDataStatistics(ctx context.Context, dataID, version int, open func() (io.ReadCloser, error)) (*model.DataStatistics, error) {
+	if version > 0 {
+		cached, err := repository.GetDataStatistics(dataID, version)
+		if err != nil {
+			return nil, err
+		}
+		if cached != nil {
+			return cached, nil
+		}
+	}
+
+	content, err := open()
+	if err != nil {
+		return nil, err
+	}
+	defer content.Close()
+
+	statistics, err := ComputeDataStatistics(content)
+	if err != nil {
+		return nil, err
+	}
+	statistics.DataID = dataID
+	statistics.Version = version
+
+	// Data files created before versioning have no version to cache under
+	if version > 0 {
+		if err := repository.SaveDataStatistics(statistics); err != nil {
+			zap.L().Warn("Failed to cache data statistics", zap.Int("data_id", dataID), zap.Int("version", version), zap.Error(err))
+		}
+	}
+	return statistics, nil
+}
+
+// ComputeDataStatistics profiles JSONL data in one pass: turn counts, text lengths per role,
+// duplicates, languages and meta field values
+func ComputeDataStatistics(r io.Reader) (*model.DataStatistics, error) {
+	p := newDataProfiler()
+	if err := ForEachDataLine(r, p.addLine); err != nil {
+		return nil, err
+	}
+	return p.result(), nil
+}
+
+// dataProfiler collects the values data statistics are computed from
+type dataProfiler struct {
+	statistics   *model.DataStatistics
+	turnCounts   []int
+	roleChars    map[string][]float64
+	roleTokens   map[string][]float64
+	exactItems   *duplicateIndex
+	promptItems  *duplicateIndex
+	metaValues   map[string]map[string]int
+	metaNumbers  map[string][]float64
+	metaNumeric  map[string]bool
+	metaPresence map[string]int
+}
+
+func newDataProfiler() *dataProfiler {
+	return &dataProfiler{
+		statistics: &model.DataStatistics{
+			ComputedAt: time.Now().Format(time.RFC3339),
+			Roles:      make(map[string]model.RoleStatistics),
+			Languages:  make(map[string]int),
+			MetaFields: make(map[string]model.MetaFieldStatistics),
+		},
+		roleChars:    make(map[string][]float64),
+		roleTokens:   make(map[string][]float64),
+		exactItems:   newDuplicateIndex(),
+		promptItems:  newDuplicateIndex(),
+		metaValues:   make(map[string]map[string]int),
+		metaNumbers:  make(map[string][]float64),
+		metaNumeric:  make(map[string]bool),
+		metaPresence: make(map[string]int),
+	}
+}
+
+// addLine profiles the item on one line
+func (p *dataProfiler) addLine(line int, raw []byte) error {
+	item, err := decodeRecord(raw)
+	if err != nil {
+		p.statistics.ParseErrorCount++
+		return nil
+	}
+	index := p.statistics.ItemCount
+	p.statistics.ItemCount++
+
+	if key, ok := exactItemKey(item); ok {
+		p.exactItems.add(key, index)
+	}
+	if key, ok := promptItemKey(item); ok {
+		p.promptItems.add(key, index)
+	}
+
+	turns := exportedTurns(item)
+	p.turnCounts = append(p.turnCounts, len(turns))
+	_, meta := splitExportMeta(item)
+
+	var userTexts, allTexts []string
+	for _, turn := range turns {
+		role := strings.ToLower(turn[0])
+		p.roleChars[role] = append(p.roleChars[role], float64(utf8.RuneCountInString(turn[1])))
+		p.roleTokens[role] = append(p.roleTokens[role], float64(EstimateTokens(turn[1])))
+		allTexts = append(allTexts, turn[1])
+		if turnSide(turn[0]) == turnSideUser {
+			userTexts = append(userTexts, turn[1])
+		}
+	}
+	if len(userTexts) == 0 {
+		userTexts = allTexts
+	}
+	p.statistics.Languages[detectLanguage(strings.Join(userTexts, "\n"))]++
+
+	for field, value := range meta {
+		p.addMetaValue(field, value)
+	}
+	return nil
+}
+
+// exactItemKey identifies identical items. encoding/json sorts map keys, so equal items serialize equally.
+func exactItemKey(item map[string]interface{}) ([sha256.Size]byte, bool) {
+	canonical, err := json.Marshal(item)
+	if err != nil {
+		return [sha256.Size]byte{}, false
+	}
+	return sha256.Sum256(canonical), true
+}
+
+// promptItemKey identifies items with the same prompt: the same meta_description and user turns,
+// ignoring case and whitespace. Items without user turns have no prompt.
+func promptItemKey(item map[string]interface{}) ([sha256.Size]byte, bool) {
+	description, _ := splitExportMeta(item)
+	prompt := []string{description}
+	for _, turn := range exportedTurns(item) {
+		if turnSide(turn[0]) == turnSideUser {
+			prompt = append(prompt, strings.Join(strings.Fields(strings.ToLower(turn[1])), " "))
+		}
+	}
+	if len(prompt) == 1 {
+		return [sha256.Size]byte{}, false
+	}
+	return sha256.Sum256([]byte(strings.Join(prompt, "\x00"))), true
+}
+
+// addMetaValue counts a meta field value, keeping numbers for a histogram while every value is a number
+func (p *dataProfiler) addMetaValue(field string, value interface{}) {
+	if p.metaValues[field] == nil {
+		p.metaValues[field] = make(map[string]int)
+		p.metaNumeric[field] = true
+	}
+	p.metaPresence[field]++
+	p.metaValues[field][cellText(value)]++
+
+	if !p.metaNumeric[field] {
+		return
+	}
+	number, ok := value.(json.Number)
+	if !ok {
+		p.metaNumeric[field] = false
+		p.metaNumbers[field] = nil
+		return
+	}
+	f, err := number.Float64()
+	if err != nil {
+		p.metaNumeric[field] = false
+		p.metaNumbers[field] = nil
+		return
+	}
+	p.metaNumbers[field] = append(p.metaNumbers[field], f)
+}
+
+// result computes the statistics from the collected values
+func (p *dataProfiler) result() *model.DataStatistics {
+	s := p.statistics
+	s.Turns = turnCountStatistics(p.turnCounts)
+
+	for role, chars := range p.roleChars {
+		s.Roles[role] = model.RoleStatistics{
+			TurnCount:  len(chars),
+			Characters: lengthDistribution(chars),
+			Tokens:     lengthDistribution(p.roleTokens[role]),
+		}
+	}
+
+	s.Duplicates.ExactGroups, s.Duplicates.ExactCount, s.Duplicates.ExactGroupCount = p.exactItems.groups()
+	s.Duplicates.PromptGroups, s.Duplicates.PromptCount, s.Duplicates.PromptGroupCount = p.promptItems.groups()
+
+	for field, counts := range p.metaValues {
+		values := make([]model.MetaValueCount, 0, len(counts))
+		for value, count := range counts {
+			values = append(values, model.MetaValueCount{Value: value, Count: count})
+		}
+		sort.Slice(values, func(i, j int) bool {
+			if values[i].Count != values[j].Count {
+				return values[i].Count > values[j].Count
+			}
+			return values[i].Value < values[j].Value
+		})
+		if len(values) > maxMetaGroups {
+			values = values[:maxMetaGroups]
+		}
+
+		fieldStatistics := model.MetaFieldStatistics{
+			Present:       p.metaPresence[field],
+			DistinctCount: len(counts),
+			Values:        values,
+		}
+		if p.metaNumeric[field] {
+			fieldStatistics.Histogram = stats.Histogram(p.metaNumbers[field], lengthHistogramBins)
+		}
+		s.MetaFields[field] = fieldStatistics
+	}
+	return s
+}
+
+// turnCountStatistics describes the distribution of turn counts
+func turnCountStatistics(counts []int) model.TurnCountStatistics {
+	result := model.TurnCountStatistics{Distribution: []model.TurnCountBucket{}}
+	if len(counts) == 0 {
+		return result
+	}
+
+	items := make(map[int]int)
+	sum := 0
+	result.Min, result.Max = counts[0], counts[0]
+	for _, n := range counts {
+		items[n]++
+		sum += n
+		if n < result.Min {
+			result.Min = n
+		}
+		if n > result.Max {
+			result.Max = n
+		}
+	}
+	result.Mean = float64(sum) / float64(len(counts))
+
+	for turns, n := range items {
+		result.Distribution = append(result.Distribution, model.TurnCountBucket{Turns: turns, Items: n})
+	}
+	sort.Slice(result.Distribution, func(i, j int) bool {
+		return result.Distribution[i].Turns < result.Distribution[j].Turns
+	})
+	return result
+}
+
+// lengthDistribution describes the distribution of text lengths
+func lengthDistribution(lengths []float64) model.LengthDistribution {
+	result := model.LengthDistribution{
+		Percentiles: make(map[string]float64),
+		Histogram:   stats.Histogram(lengths, lengthHistogramBins),
+	}
+	if len(lengths) == 0 {
+		return result
+	}
+
+	result.Mean = stats.Mean(lengths)
+	result.Min, result.Max = lengths[0], lengths[0]
+	for _, l := range lengths {
+		result.Min = math.Min(result.Min, l)
+		result.Max = math.Max(result.Max, l)
+	}
+	for _, p := range scorePercentiles {
+		result.Percentiles[p.name] = stats.Percentile(lengths, p.q)
+	}
+	return result
+}
+
+// duplicateIndex groups item indices by a hash, remembering the order hashes were first seen
+type duplicateIndex struct {
+	indices map[[sha256.Size]byte][]int
+	order   [][sha256.Size]byte
+}
+
+func newDuplicateIndex() *duplicateIndex {
+	return &duplicateIndex{indices: make(map[[sha256.Size]byte][]int)}
+}
+
+func (d *duplicateIndex) add(hash [sha256.Size]byte, index int) {
+	if _, seen := d.indices[hash]; !seen {
+		d.order = append(d.order, hash)
+	}
+	d.indices[hash] = append(d.indices[hash], index)
+}
+
+// groups returns the first maxDuplicateGroups groups of more than one item, the number of items
+// repeating an earlier one, and the number of groups
+func (d *duplicateIndex) groups() ([]model.DuplicateGroup, int, int) {
+	groups := []model.DuplicateGroup{}
+	duplicates, groupCount := 0, 0
+	for _, hash := range d.order {
+		indices := d.indices[hash]
+		if len(indices) < 2 {
+			continue
+		}
+		duplicates += len(indices) - 1
+		groupCount++
+		if len(groups) < maxDuplicateGroups {
+			groups = append(groups, model.DuplicateGroup{Indices: indices})
+		}
+	}
+	return groups, duplicates, groupCount
+}
+
+// EstimateTokens approximates the token count of a text without a tokenizer: a CJK character is one
+// token, a word of other letters or digits one token per four characters, and any other symbol one token
+func EstimateTokens(text string) int {
+	tokens, word := 0, 0
+	flush := func() {
+		tokens += (word + 3) / 4
+		word = 0
+	}
+	for _, r := range text {
+		switch {
+		case isCJK(r):
+			flush()
+			tokens++
+		case unicode.IsLetter(r) || unicode.IsDigit(r):
+			word++
+		default:
+			flush()
+			if !unicode.IsSpace(r) {
+				tokens++
+			}
+		}
+	}
+	flush()
+	return tokens
+}
+
+// isCJK reports whether r is a Chinese, Japanese or Korean character
+func isCJK(r rune) bool {
+	return unicode.In(r, unicode.Han, unicode.Hiragana, unicode.Katakana, unicode.Hangul)
+}
+
+// detectLanguage names the language of a text from the writing system most of its letters use.
+// Text with kana is Japanese even when Han characters dominate, and Latin text is English when
+// common English words make up a large enough share of it, "latin" otherwise.
+func detectLanguage(text string) string {
+	counts := make(map[string]int)
+	for _, r := range text {
+		for _, script := range languageScripts {
+			if unicode.Is(script.table, r) {
+				counts[script.language]++
+				break
+			}
+		}
+	}
+
+	language, most := "unknown", 0
+	for _, script := range languageScripts {
+		if n := counts[script.language]; n > most {
+			language, most = script.language, n
+		}
+	}
+
+	switch {
+	case language == "zh" && counts["ja"] > 0:
+		return "ja"
+	case language == "latin":
+		words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+			return !unicode.IsLetter(r) && r != '\''
+		})
+		stopwords := 0
+		for _, word := range words {
+			if englishStopwords[word] {
+				stopwords++
+			}
+		}
+		if len(words) > 0 && float64(stopwords)/float64(len(words)) >= englishStopwordShare {
+			return "en"
+		}
+	}
+	return language
+}
@@ -0,0 +1,110 @@
+package service
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/wzyjerry/llm-judge/internal/model"
+)
+
+func TestEstimateTokens(t *testing.T) {
+	tests := []struct {
+		text string
+		want int
+	}{
+		{"", 0},
+		{"  \n\t", 0},
+		{"hi", 1},
+		{"hello", 2},
+		{"hello world", 4},
+		{"a,b", 3},
+		{"GPT-4", 3},
+		{"你好", 2},
+		{"你好world", 4},
+		{"こんにちは", 5},
+	}
+	for _, tt := range tests {
+		if got := EstimateTokens(tt.text); got != tt.want {
+			t.Errorf("EstimateTokens(%q) = %d, want %d", tt.text, got, tt.want)
+		}
+	}
+}
+
+func TestDetectLanguage(t *testing.T) {
+	tests := []struct {
+		text string
+		want string
+	}{
+		{"What is the capital of France?", "en"},
+		{"Quelle est la capitale de la France ?", "latin"},
+		{"你好，世界", "zh"},
+		{"日本語を勉強する", "ja"},
+		{"안녕하세요", "ko"},
+		{"Привет, мир", "ru"},
+		{"Translate 你好世界 please", "en"},
+		{"请把这句话翻译成英文 ok", "zh"},
+		{"12345 !!!", "unknown"},
+		{"", "unknown"},
+	}
+	for _, tt := range tests {
+		if got := detectLanguage(tt.text); got != tt.want {
+			t.Errorf("detectLanguage(%q) = %q, want %q", tt.text, got, tt.want)
+		}
+	}
+}
+
+func TestComputeDataStatisticsDuplicates(t *testing.T) {
+	content := strings.Join([]string{
+		`{"meta":{"meta_description":"sys"},"turns":[{"role":"Human","text":"Hi there"},{"role":"Assistant","text":"a"}]}`,
+		`{"meta":{"meta_description":"sys"},"turns":[{"role":"Human","text":"hi  THERE"},{"role":"Assistant","text":"b"}]}`,
+		`{"meta":{"meta_description":"sys"},"turns":[{"role":"Human","text":"Hi there"},{"role":"Assistant","text":"a"}]}`,
+		`{"meta":{},"turns":[{"role":"Human","text":"other"},{"role":"Assistant","text":"c"}]}`,
+		`not json`,
+		`{"meta":{},"turns":[{"role":"Human","text":"other"},{"role":"Assistant","text":"c"}]}`,
+	}, "\n")
+
+	statistics, err := ComputeDataStatistics(strings.NewReader(content))
+	if err != nil {
+		t.Fatalf("ComputeDataStatistics: %v", err)
+	}
+	if statistics.ItemCount != 5 || statistics.ParseErrorCount != 1 {
+		t.Errorf("counted %d items and %d parse errors, want 5 and 1", statistics.ItemCount, statistics.ParseErrorCount)
+	}
+
+	// Indices count items, so the line that failed to parse is skipped
+	want := model.DuplicateStatistics{
+		ExactCount:       2,
+		PromptCount:      3,
+		ExactGroups:      []model.DuplicateGroup{{Indices: []int{0, 2}}, {Indices: []int{3, 4}}},
+		PromptGroups:     []model.DuplicateGroup{{Indices: []int{0, 1, 2}}, {Indices: []int{3, 4}}},
+		ExactGroupCount:  2,
+		PromptGroupCount: 2,
+	}
+	if !reflect.DeepEqual(statistics.Duplicates, want) {
+		t.Errorf("duplicates %+v, want %+v", statistics.Duplicates, want)
+	}
+}
+
+func TestDuplicateIndexGroupsLimit(t *testing.T) {
+	index := newDuplicateIndex()
+	pairs := maxDuplicateGroups + 10
+	for i := 0; i < pairs; i++ {
+		hash := sha256.Sum256([]byte(fmt.Sprint(i)))
+		index.add(hash, 2*i)
+		index.add(hash, 2*i+1)
+	}
+	// A hash seen once is no group
+	index.add(sha256.Sum256([]byte("single")), 2*pairs)
+
+	groups, duplicates, groupCount := index.groups()
+	if len(groups) != maxDuplicateGroups || duplicates != pairs || groupCount != pairs {
+		t.Fatalf("listed %d groups of %d with %d duplicates, want %d of %d with %d",
+			len(groups), groupCount, duplicates, maxDuplicateGroups, pairs, pairs)
+	}
+	if !reflect.DeepEqual(groups[0].Indices, []int{0, 1}) || !reflect.DeepEqual(groups[maxDuplicateGroups-1].Indices, []int{98, 99}) {
+		t.Errorf("groups are not in the order first seen: first %v, last %v", groups[0].Indices, groups[maxDuplicateGroups-1].Indices)
+	}
+}
@@ -0,0 +1,226 @@
+package service
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/wzyjerry/llm-judge/internal/model"
+	"github.com/wzyjerry/llm-judge/internal/pkg/blob"
+	"github.com/wzyjerry/llm-judge/internal/repository"
+	"go.uber.org/zap"
+)
+
+// dataBlobPrefix prefixes the blob keys of data file contents, which are addressed by checksum
+// so identical contents are stored once
+const dataBlobPrefix = "datasets/"
+
+// ErrDataTooLarge is returned when a data file exceeds storage.max_upload_mb
+var ErrDataTooLarge = errors.New("data file exceeds the maximum upload size")
+
+// DataLineError reports a JSONL line that is not valid JSON
+type DataLineError struct {
+	Line int // 1-based
+	Err  error
+}
+
+func (e *DataLineError) Error() string {
+	return fmt.Sprintf("line %d: %v", e.Line, e.Err)
+}
+
+func (e *DataLineError) Unwrap() error {
+	return e.Err
+}
+
+// StoreDataContent streams JSONL content into blob storage. Every non-empty line must be valid JSON
+// and the content must not exceed the maximum upload size.
+func StoreDataContent(ctx context.Context, r io.Reader) (*model.DataBlob, error) {
+	return putDataBlob(ctx, r, blob.MaxUploadSize(), true)
+}
+
+// putDataBlob spools content to a temporary file while hashing and counting its lines, then stores
+// it under its checksum. A limit of 0 accepts any size.
+func putDataBlob(ctx context.Context, r io.Reader, limit int64, validate bool) (*model.DataBlob, error) {
+	store, err := blob.GetStore()
+	if err != nil {
+		return nil, err
+	}
+
+	tmp, err := os.CreateTemp("", "llm-judge-data-*")
+	if err != nil {
+		return nil, err
+	}
+	defer os.Remove(tmp.Name())
+	defer tmp.Close()
+
+	if limit > 0 {
+		// One byte more than the limit tells an oversized upload apart from one of exactly the limit
+		r = io.LimitReader(r, limit+1)
+	}
+	hasher := sha256.New()
+	counter := &countingWriter{}
+	tee := io.TeeReader(r, io.MultiWriter(tmp, hasher, counter))
+
+	itemCount := 0
+	err = ForEachDataLine(tee, func(line int, data []byte) error {
+		if validate && !json.Valid(data) {
+			return &DataLineError{Line: line, Err: errors.New("invalid JSON")}
+		}
+		itemCount++
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if limit > 0 && counter.n > limit {
+		return nil, ErrDataTooLarge
+	}
+
+	if _, err := tmp.Seek(0, io.SeekStart); err != nil {
+		return nil, err
+	}
+
+	checksum := hex.EncodeToString(hasher.Sum(nil))
+	dataBlob := &model.DataBlob{
+		Key:       dataBlobPrefix + checksum + ".jsonl",
+		Size:      int(counter.n),
+		Checksum:  checksum,
+		ItemCount: itemCount,
+	}
+	if err := store.Put(ctx, dataBlob.Key, tmp, counter.n); err != nil {
+		return nil, err
+	}
+
+	return dataBlob, nil
+}
+
+// countingWriter counts the bytes written to it
+type countingWriter struct {
+	n int64
+}
+
+func (w *countingWriter) Write(p []byte) (int, error) {
+	w.n += int64(len(p))
+	return len(p), nil
+}
+
+// ForEachDataLine calls fn with the 1-based number and trimmed content of each non-empty JSONL line,
+// reading lines of any length
+func ForEachDataLine(r io.Reader, fn func(line int, data []byte) error) error {
+	reader := bufio.NewReader(r)
+	for line := 1; ; line++ {
+		data, err := reader.ReadBytes('\n')
+		if err != nil && err != io.EOF {
+			return err
+		}
+		if trimmed := bytes.TrimSpace(data); len(trimmed) > 0 {
+			if fnErr := fn(line, trimmed); fnErr != nil {
+				return fnErr
+			}
+		}
+		if err == io.EOF {
+			return nil
+		}
+	}
+}
+
+// OpenUserData opens the current content of a data file
+func OpenUserData(ctx context.Context, data *model.UserData) (io.ReadCloser, error) {
+	return openDataContent(ctx, data.BlobKey, data.FileContent)
+}
+
+// OpenUserDataVersion opens the content of a data file version
+func OpenUserDataVersion(ctx context.Context, v *model.UserDataVersion) (io.ReadCloser, error) {
+	return openDataContent(ctx, v.BlobKey, v.FileContent)
+}
+
+// openDataContent reads content from blob storage, or from the database for contents not yet migrated
+func openDataContent(ctx context.Context, key, inline string) (io.ReadCloser, error) {
+	if key == "" {
+		return io.NopCloser(strings.NewReader(inline)), nil
+	}
+
+	store, err := blob.GetStore()
+	if err != nil {
+		return nil, err
+	}
+	return store.Get(ctx, key)
+}
+
+// dataBlobLock keeps PruneDataBlobs from deleting a blob between a concurrent write storing the same
+// content-addressed key again and committing the row that references it. Writes hold it shared from
+// storing their blobs until the commit, pruning holds it exclusively. Only the web API writes and
+// prunes data blobs, so a process-wide lock suffices.
+var dataBlobLock sync.RWMutex
+
+// WithDataBlobs runs fn, which stores data blobs and commits the data files or versions referencing
+// them, so that PruneDataBlobs cannot delete the blobs in between
+func WithDataBlobs(fn func() error) error {
+	dataBlobLock.RLock()
+	defer dataBlobLock.RUnlock()
+	return fn()
+}
+
+// PruneDataBlobs deletes the blobs that no data file or version references anymore
+func PruneDataBlobs(ctx context.Context, keys []string) {
+	store, err := blob.GetStore()
+	if err != nil {
+		zap.L().Error("Failed to prune data blobs", zap.Error(err))
+		return
+	}
+
+	dataBlobLock.Lock()
+	defer dataBlobLock.Unlock()
+
+	for _, key := range keys {
+		referenced, err := repository.IsDataBlobReferenced(key)
+		if err == nil && !referenced {
+			err = store.Delete(ctx, key)
+		}
+		if err != nil {
+			zap.L().Error("Failed to prune data blob", zap.String("key", key), zap.Error(err))
+		}
+	}
+}
+
+// MigrateDataContentToBlobs moves data file contents still stored in the database to blob storage.
+// Contents are moved as they are, without validation or size limit.
+func MigrateDataContentToBlobs() (int, error) {
+	versions, err := repository.GetInlineDataVersions()
+	if err != nil {
+		return 0, err
+	}
+
+	ctx := context.Background()
+	for _, v := range versions {
+		version, err := repository.GetUserDataVersion(v.DataID, v.Version)
+		if err != nil {
+			return 0, err
+		}
+		if version == nil {
+			continue
+		}
+
+		err = WithDataBlobs(func() error {
+			dataBlob, err := putDataBlob(ctx, strings.NewReader(version.FileContent), 0, false)
+			if err != nil {
+				return fmt.Errorf("failed to store data %d version %d: %w", v.DataID, v.Version, err)
+			}
+			return repository.MoveDataVersionToBlob(v.DataID, v.Version, dataBlob)
+		})
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	return len(versions), nil
+}
@@ -0,0 +1,115 @@
+package service
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/wzyjerry/llm-judge/internal/model"
+	"github.com/wzyjerry/llm-judge/internal/pkg/blob"
+	"github.com/wzyjerry/llm-judge/internal/pkg/config"
+)
+
+// initTestBlobStore points blob storage at a fresh local directory
+func initTestBlobStore(t *testing.T, maxUploadMB int) {
+	t.Helper()
+	cfg := &config.Config{Storage: config.StorageConfig{LocalDir: t.TempDir(), MaxUploadMB: maxUploadMB}}
+	if err := blob.Init(cfg); err != nil {
+		t.Fatalf("blob.Init: %v", err)
+	}
+}
+
+func readAll(t *testing.T, r io.ReadCloser, err error) string {
+	t.Helper()
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	defer r.Close()
+	data, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatalf("read: %v", err)
+	}
+	return string(data)
+}
+
+func TestStoreDataContentRoundTrip(t *testing.T) {
+	initTestBlobStore(t, 0)
+	ctx := context.Background()
+	content := "{\"a\":1}\n\n  {\"b\":[2,3]}  \n{\"c\":\"\\u4f60\"}"
+
+	dataBlob, err := StoreDataContent(ctx, strings.NewReader(content))
+	if err != nil {
+		t.Fatalf("StoreDataContent: %v", err)
+	}
+
+	sum := sha256.Sum256([]byte(content))
+	checksum := hex.EncodeToString(sum[:])
+	if dataBlob.Checksum != checksum || dataBlob.Key != dataBlobPrefix+checksum+".jsonl" {
+		t.Errorf("blob %+v, want checksum %s under %s", dataBlob, checksum, dataBlobPrefix)
+	}
+	if dataBlob.ItemCount != 3 || dataBlob.Size != len(content) {
+		t.Errorf("blob has %d items and %d bytes, want 3 and %d", dataBlob.ItemCount, dataBlob.Size, len(content))
+	}
+
+	data := &model.UserData{BlobKey: dataBlob.Key}
+	r, err := OpenUserData(ctx, data)
+	if got := readAll(t, r, err); got != content {
+		t.Errorf("OpenUserData returned %q, want %q", got, content)
+	}
+
+	version := &model.UserDataVersion{BlobKey: dataBlob.Key}
+	r, err = OpenUserDataVersion(ctx, version)
+	if got := readAll(t, r, err); got != content {
+		t.Errorf("OpenUserDataVersion returned %q, want %q", got, content)
+	}
+
+	// Identical content is stored once under the same key
+	again, err := StoreDataContent(ctx, strings.NewReader(content))
+	if err != nil || again.Key != dataBlob.Key {
+		t.Errorf("storing the same content again gave %+v, %v; want key %s", again, err, dataBlob.Key)
+	}
+}
+
+func TestOpenUserDataInline(t *testing.T) {
+	initTestBlobStore(t, 0)
+	r, err := OpenUserData(context.Background(), &model.UserData{FileContent: "{\"legacy\":true}"})
+	if got := readAll(t, r, err); got != "{\"legacy\":true}" {
+		t.Errorf("OpenUserData returned %q for content not yet in blob storage", got)
+	}
+}
+
+func TestOpenUserDataMissingBlob(t *testing.T) {
+	initTestBlobStore(t, 0)
+	_, err := OpenUserData(context.Background(), &model.UserData{BlobKey: "datasets/missing.jsonl"})
+	if !errors.Is(err, blob.ErrNotFound) {
+		t.Errorf("OpenUserData of a missing blob returned %v, want blob.ErrNotFound", err)
+	}
+}
+
+func TestStoreDataContentRejectsInvalidLines(t *testing.T) {
+	initTestBlobStore(t, 0)
+	_, err := StoreDataContent(context.Background(), strings.NewReader("{\"a\":1}\n\nnot json\n"))
+	var lineErr *DataLineError
+	if !errors.As(err, &lineErr) || lineErr.Line != 3 {
+		t.Fatalf("StoreDataContent returned %v, want a DataLineError on line 3", err)
+	}
+}
+
+func TestStoreDataContentSizeLimit(t *testing.T) {
+	initTestBlobStore(t, 1)
+	line := "{\"x\":\"" + strings.Repeat("a", 1000) + "\"}\n"
+	limit := 1 << 20
+
+	exact := strings.Repeat(line, limit/len(line))
+	exact += strings.Repeat(" ", limit-len(exact))
+	if _, err := StoreDataContent(context.Background(), strings.NewReader(exact)); err != nil {
+		t.Fatalf("content of exactly the limit was rejected: %v", err)
+	}
+	if _, err := StoreDataContent(context.Background(), strings.NewReader(exact+"\n")); !errors.Is(err, ErrDataTooLarge) {
+		t.Fatalf("content over the limit returned %v, want ErrDataTooLarge", err)
+	}
+}
@@ -0,0 +1,209 @@
+package service
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/wzyjerry/llm-judge/internal/model"
+)
+
+const (
+	// DefaultEvaluationRole is the turn role evaluated when a task does not configure one
+	DefaultEvaluationRole = "assistant"
+	// maxReportedIssues limits the invalid lines and warnings listed in a validation report
+	maxReportedIssues = 100
+)
+
+// Sides of a conversation a turn belongs to. User and model turns must alternate.
+const (
+	turnSideSystem = "system"
+	turnSideUser   = "user"
+	turnSideModel  = "model"
+)
+
+// knownTurnRoles maps the lowercased roles prepare_prompt understands to their side
+var knownTurnRoles = map[string]string{
+	"system":    turnSideSystem,
+	"human":     turnSideUser,
+	"user":      turnSideUser,
+	"assistant": turnSideModel,
+}
+
+// DataValidationError is returned when data content does not match the meta/turns format
+type DataValidationError struct {
+	Info *model.DataValidationInfo
+}
+
+func (e *DataValidationError) Error() string {
+	return fmt.Sprintf("%d of %d lines do not match the data format", e.Info.InvalidCount, e.Info.TotalLines)
+}
+
+// DataValidator checks JSONL data line by line and collects a report
+type DataValidator struct {
+	role string
+	info model.DataValidationInfo
+}
+
+// NewDataValidator creates a validator for data evaluated on turns of the given role
+func NewDataValidator(role string) *DataValidator {
+	role = normalizeEvaluationRole(role)
+	return &DataValidator{
+		role: role,
+		info: model.DataValidationInfo{
+			Role:         role,
+			InvalidLines: []model.DataLineIssue{},
+			Warnings:     []model.DataLineIssue{},
+		},
+	}
+}
+
+// ValidateLine checks one non-empty line
+func (v *DataValidator) ValidateLine(line int, data []byte) {
+	v.info.TotalLines++
+
+	var item map[string]interface{}
+	if err := json.Unmarshal(data, &item); err != nil || item == nil {
+		v.reject(line, "line is not a JSON object")
+		return
+	}
+	v.validateItem(line, item)
+}
+
+// ValidateItem checks the item converted from the record on the given line
+func (v *DataValidator) ValidateItem(line int, item map[string]interface{}) {
+	v.info.TotalLines++
+	v.validateItem(line, item)
+}
+
+// Reject records a line that could not be turned into an item
+func (v *DataValidator) Reject(line int, message string) {
+	v.info.TotalLines++
+	v.reject(line, message)
+}
+
+func (v *DataValidator) reject(line int, message string) {
+	v.info.InvalidCount++
+	addLineIssue(&v.info.InvalidLines, line, []string{message})
+}
+
+func (v *DataValidator) validateItem(line int, item map[string]interface{}) {
+	errs, warnings := ValidateDataItem(item, v.role)
+	if len(warnings) > 0 {
+		v.info.WarningCount++
+		addLineIssue(&v.info.Warnings, line, warnings)
+	}
+	if len(errs) > 0 {
+		v.info.InvalidCount++
+		addLineIssue(&v.info.InvalidLines, line, errs)
+		return
+	}
+	v.info.ValidItems++
+}
+
+// Valid reports whether every line checked so far is valid
+func (v *DataValidator) Valid() bool {
+	return v.info.InvalidCount == 0
+}
+
+// Info returns the report of the lines checked so far
+func (v *DataValidator) Info() *model.DataValidationInfo {
+	return &v.info
+}
+
+// addLineIssue lists an issue unless the report already lists maxReportedIssues
+func addLineIssue(issues *[]model.DataLineIssue, line int, messages []string) {
+	if len(*issues) < maxReportedIssues {
+		*issues = append(*issues, model.DataLineIssue{Line: line, Messages: messages})
+	}
+}
+
+// normalizeEvaluationRole lowercases a role the way prepare_prompt compares it
+func normalizeEvaluationRole(role string) string {
+	role = strings.ToLower(strings.TrimSpace(role))
+	if role == "" {
+		return DefaultEvaluationRole
+	}
+	return role
+}
+
+// ValidateDataItem checks an item against the JSONLData model: meta must be an object, and turns a
+// non-empty list of known roles with non-empty texts, where user and model turns alternate and at
+// least one turn has the evaluation role. It returns the errors and warnings found.
+func ValidateDataItem(item map[string]interface{}, role string) ([]string, []string) {
+	role = normalizeEvaluationRole(role)
+	var errs, warnings []string
+
+	if raw, exists := item["meta"]; exists && raw != nil {
+		meta, ok := raw.(map[string]interface{})
+		if !ok {
+			errs = append(errs, "meta must be an object")
+		} else if description, exists := meta["meta_description"]; exists {
+			if _, ok := description.(string); !ok {
+				errs = append(errs, "meta.meta_description must be a string")
+			}
+		}
+	}
+
+	raw, exists := item["turns"]
+	if !exists {
+		return append(errs, "turns is missing"), warnings
+	}
+	turns, ok := raw.([]interface{})
+	if !ok {
+		return append(errs, "turns must be a list"), warnings
+	}
+	if len(turns) == 0 {
+		return append(errs, "turns is empty"), warnings
+	}
+
+	evaluated := false
+	previousSide := ""
+	for i, rawTurn := range turns {
+		n := i + 1
+		turn, ok := rawTurn.(map[string]interface{})
+		if !ok {
+			errs = append(errs, fmt.Sprintf("turn %d must be an object", n))
+			continue
+		}
+
+		turnRole, ok := turn["role"].(string)
+		if !ok || strings.TrimSpace(turnRole) == "" {
+			errs = append(errs, fmt.Sprintf("turn %d has no role", n))
+			continue
+		}
+		if text, ok := turn["text"].(string); !ok {
+			errs = append(errs, fmt.Sprintf("turn %d text must be a string", n))
+		} else if strings.TrimSpace(text) == "" {
+			errs = append(errs, fmt.Sprintf("turn %d has an empty text", n))
+		}
+
+		lower := strings.ToLower(turnRole)
+		side, known := knownTurnRoles[lower]
+		if lower == role {
+			side, known = turnSideModel, true
+			evaluated = true
+		}
+		switch {
+		case !known:
+			errs = append(errs, fmt.Sprintf("turn %d has unknown role %q", n, turnRole))
+		case side == turnSideSystem:
+			if i > 0 {
+				warnings = append(warnings, fmt.Sprintf("turn %d is a system turn after the start of the conversation", n))
+			}
+		case side == previousSide:
+			errs = append(errs, fmt.Sprintf("turn %d follows another %s turn; user and %s turns must alternate", n, side, role))
+		default:
+			if previousSide == "" && side == turnSideModel {
+				warnings = append(warnings, fmt.Sprintf("turn %d (%s) comes before any user turn", n, turnRole))
+			}
+			previousSide = side
+		}
+	}
+
+	if !evaluated {
+		errs = append(errs, fmt.Sprintf("no %q turn to evaluate", role))
+	}
+
+	return errs, warnings
+}
@@ -0,0 +1,150 @@
+package service
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+func TestValidateDataItem(t *testing.T) {
+	tests := []struct {
+		name     string
+		item     string
+		role     string
+		errs     []string
+		warnings []string
+	}{
+		{
+			name: "valid",
+			item: `{"meta":{"meta_description":"sys"},"turns":[{"role":"Human","text":"q"},{"role":"Assistant","text":"a"}]}`,
+		},
+		{
+			name: "leading system turn",
+			item: `{"turns":[{"role":"system","text":"s"},{"role":"user","text":"q"},{"role":"assistant","text":"a"}]}`,
+		},
+		{
+			name:     "late system turn",
+			item:     `{"turns":[{"role":"user","text":"q"},{"role":"system","text":"s"},{"role":"assistant","text":"a"}]}`,
+			warnings: []string{"turn 2 is a system turn after the start of the conversation"},
+		},
+		{
+			name:     "model turn first",
+			item:     `{"turns":[{"role":"assistant","text":"hi"},{"role":"user","text":"q"},{"role":"assistant","text":"a"}]}`,
+			warnings: []string{"turn 1 (assistant) comes before any user turn"},
+		},
+		{
+			name: "custom evaluation role",
+			item: `{"turns":[{"role":"Human","text":"q"},{"role":"Bot","text":"a"}]}`,
+			role: " BOT ",
+		},
+		{
+			name: "custom role replaces assistant",
+			item: `{"turns":[{"role":"Human","text":"q"},{"role":"assistant","text":"a"}]}`,
+			role: "bot",
+			errs: []string{`no "bot" turn to evaluate`},
+		},
+		{
+			name: "bad meta",
+			item: `{"meta":[],"turns":[{"role":"Human","text":"q"},{"role":"assistant","text":"a"}]}`,
+			errs: []string{"meta must be an object"},
+		},
+		{
+			name: "bad meta_description",
+			item: `{"meta":{"meta_description":1},"turns":[{"role":"Human","text":"q"},{"role":"assistant","text":"a"}]}`,
+			errs: []string{"meta.meta_description must be a string"},
+		},
+		{
+			name: "missing turns",
+			item: `{"meta":{}}`,
+			errs: []string{"turns is missing"},
+		},
+		{
+			name: "turns not a list",
+			item: `{"turns":{}}`,
+			errs: []string{"turns must be a list"},
+		},
+		{
+			name: "empty turns",
+			item: `{"turns":[]}`,
+			errs: []string{"turns is empty"},
+		},
+		{
+			name: "bad turns",
+			item: `{"turns":["x",{"text":"q"},{"role":"Human","text":" "},{"role":"tool","text":"t"},{"role":"assistant","text":1}]}`,
+			errs: []string{
+				"turn 1 must be an object",
+				"turn 2 has no role",
+				"turn 3 has an empty text",
+				`turn 4 has unknown role "tool"`,
+				"turn 5 text must be a string",
+			},
+		},
+		{
+			name: "turns not alternating",
+			item: `{"turns":[{"role":"Human","text":"q"},{"role":"user","text":"q"},{"role":"assistant","text":"a"}]}`,
+			errs: []string{"turn 2 follows another user turn; user and assistant turns must alternate"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			items := parseItems(t, tt.item)
+			errs, warnings := ValidateDataItem(items[0], tt.role)
+			if !reflect.DeepEqual(errs, tt.errs) {
+				t.Errorf("errors %q, want %q", errs, tt.errs)
+			}
+			if !reflect.DeepEqual(warnings, tt.warnings) {
+				t.Errorf("warnings %q, want %q", warnings, tt.warnings)
+			}
+		})
+	}
+}
+
+func TestDataValidatorReport(t *testing.T) {
+	validator := NewDataValidator("")
+	lines := []string{
+		`{"turns":[{"role":"Human","text":"q"},{"role":"assistant","text":"a"}]}`,
+		`not json`,
+		`null`,
+		`{"turns":[{"role":"assistant","text":"a"}]}`,
+		`{"turns":[]}`,
+	}
+	for i, line := range lines {
+		validator.ValidateLine(i+1, []byte(line))
+	}
+	validator.Reject(6, "record is not a JSON object")
+
+	info := validator.Info()
+	if validator.Valid() || info.Role != DefaultEvaluationRole {
+		t.Errorf("validator valid %v for role %q", validator.Valid(), info.Role)
+	}
+	if info.TotalLines != 6 || info.ValidItems != 2 || info.InvalidCount != 4 || info.WarningCount != 1 {
+		t.Errorf("report counts %d lines, %d valid, %d invalid and %d with warnings; want 6, 2, 4 and 1",
+			info.TotalLines, info.ValidItems, info.InvalidCount, info.WarningCount)
+	}
+	var invalid []int
+	for _, issue := range info.InvalidLines {
+		invalid = append(invalid, issue.Line)
+	}
+	if !reflect.DeepEqual(invalid, []int{2, 3, 5, 6}) {
+		t.Errorf("invalid lines %v, want [2 3 5 6]", invalid)
+	}
+	if len(info.Warnings) != 1 || info.Warnings[0].Line != 4 {
+		t.Errorf("warnings %+v, want one on line 4", info.Warnings)
+	}
+}
+
+func TestDataValidatorLimitsIssues(t *testing.T) {
+	validator := NewDataValidator("")
+	for i := 1; i <= maxReportedIssues+5; i++ {
+		validator.ValidateLine(i, []byte(`{"turns":[]}`))
+	}
+	info := validator.Info()
+	if info.InvalidCount != maxReportedIssues+5 || len(info.InvalidLines) != maxReportedIssues {
+		t.Errorf("counted %d invalid lines and listed %d, want %d and %d",
+			info.InvalidCount, len(info.InvalidLines), maxReportedIssues+5, maxReportedIssues)
+	}
+	want := fmt.Sprintf("%d of %d lines do not match the data format", maxReportedIssues+5, maxReportedIssues+5)
+	if err := (&DataValidationError{Info: info}); err.Error() != want {
+		t.Errorf("error message %q, want %q", err.Error(), want)
+	}
+}
@@ -20,6 +20,10 @@ import (
 var (
 	runningTasks = make(map[string]*exec.Cmd)
 	tasksMutex   sync.RWMutex
+
+	taskIDMutex sync.Mutex
+	lastTaskID  string
+	taskIDSeq   int
 )
 
 // EvaluationConfig represents evaluation configuration from API
@@ -27,6 +31,7 @@ type EvaluationConfig struct {
 	APIUrls            []interface{} `json:"api_urls"`
 	Model              string        `json:"model"`
 	DataFile           string        `json:"data_file"`
+	DataVersion        int           `json:"data_version"` // version of the data file evaluated, the current one when 0
 	Scoring            string        `json:"scoring"`
 	ScoringModule      string        `json:"scoring_module"`
 	MaxWorkers         int           `json:"max_workers"`
@@ -44,12 +49,36 @@ type EvaluationConfig struct {
 	IsVLLM             bool          `json:"is_vllm"`
 	Temperature        float64       `json:"temperature"`
 	TopP               float64       `json:"top_p"`
+	ModelConfigID      int           `json:"model_config_id"`
+	ModelConfigVersion int           `json:"model_config_version"`
+	ModelAlias         string        `json:"model_alias"` // alias the model was selected by, if any
+	ModelTags          []string      `json:"model_tags"`  // selects every active model carrying all tags when model is empty
+	Models             []string      `json:"models"`      // evaluates several models in one task and compares them
+
+	// LLM-as-judge settings used by judge scoring functions such as llm_judge_with_answer
+	JudgeConfig
+}
+
+// newTaskID returns a timestamp based task ID, suffixed when several tasks start within the same second
+func newTaskID() string {
+	taskIDMutex.Lock()
+	defer taskIDMutex.Unlock()
+
+	base := time.Now().Format("20060102_150405")
+	if base == lastTaskID {
+		taskIDSeq++
+		return fmt.Sprintf("%s_%d", base, taskIDSeq)
+	}
+
+	lastTaskID = base
+	taskIDSeq = 1
+	return base
 }
 
 // StartEvaluation starts an evaluation task
 func StartEvaluation(userID int, config *EvaluationConfig) (string, error) {
 	// Generate task ID
-	taskID := time.Now().Format("20060102_150405")
+	taskID := newTaskID()
 
 	// Generate JWT token for Python evaluation tasks (for internal API authentication)
 	token, err := jwt.GenerateToken(userID, "system_evaluation")
@@ -57,30 +86,60 @@ func StartEvaluation(userID int, config *EvaluationConfig) (string, error) {
 		return "", fmt.Errorf("failed to generate auth token: %w", err)
 	}
 
-	// Convert config map to struct
-	configMap := map[string]interface{}{
-		"api_urls":            config.APIUrls,
-		"model":               config.Model,
-		"data_file":           config.DataFile,
-		"scoring":             config.Scoring,
-		"scoring_module":      config.ScoringModule,
-		"max_workers":         config.MaxWorkers,
-		"badcase_threshold":   config.BadcaseThreshold,
-		"report_format":       config.ReportFormat,
-		"test_mode":           config.TestMode,
-		"sample_size":         config.SampleSize,
-		"checkpoint_path":     config.CheckpointPath,
-		"checkpoint_interval": config.CheckpointInterval,
-		"resume":              config.Resume,
-		"role":                config.Role,
-		"timeout":             config.Timeout,
-		"max_tokens":          config.MaxTokens,
-		"api_key":             config.APIKey,
-		"is_vllm":             config.IsVLLM,
-		"temperature":         config.Temperature,
-		"top_p":               config.TopP,
-		"data_filename":       config.DataFile,
+	// Create task record in database
+	_, err = repository.CreateUserTask(userID, taskID, taskConfigMap(config))
+	if err != nil {
+		return "", fmt.Errorf("failed to create task: %w", err)
+	}
+
+	// Start evaluation in background with auth token
+	go runEvaluationTask(userID, taskID, config, token)
+
+	return taskID, nil
+}
+
+// StartComparativeEvaluation starts one task that evaluates several models on the same data
+// and combines their reports into a comparison report
+func StartComparativeEvaluation(userID int, configs []*EvaluationConfig) (string, error) {
+	if len(configs) < 2 {
+		return "", fmt.Errorf("a comparison needs at least two models")
+	}
+
+	// Generate task ID
+	taskID := newTaskID()
+
+	// Generate JWT token for Python evaluation tasks (for internal API authentication)
+	token, err := jwt.GenerateToken(userID, "system_evaluation")
+	if err != nil {
+		return "", fmt.Errorf("failed to generate auth token: %w", err)
+	}
+
+	// Shared settings come from the first model, per-model settings are listed separately
+	models := make([]string, len(configs))
+	modelConfigs := make([]map[string]interface{}, len(configs))
+	for i, cfg := range configs {
+		models[i] = cfg.Model
+		modelConfigs[i] = map[string]interface{}{
+			"model":                cfg.Model,
+			"model_alias":          cfg.ModelAlias,
+			"model_config_id":      cfg.ModelConfigID,
+			"model_config_version": cfg.ModelConfigVersion,
+			"api_urls":             cfg.APIUrls,
+			"temperature":          cfg.Temperature,
+			"top_p":                cfg.TopP,
+			"max_tokens":           cfg.MaxTokens,
+			"timeout":              cfg.Timeout,
+			"is_vllm":              cfg.IsVLLM,
+		}
+	}
+
+	configMap := taskConfigMap(configs[0])
+	for key := range modelConfigs[0] {
+		delete(configMap, key)
 	}
+	configMap["model"] = strings.Join(models, ", ")
+	configMap["models"] = models
+	configMap["model_configs"] = modelConfigs
 
 	// Create task record in database
 	_, err = repository.CreateUserTask(userID, taskID, configMap)
@@ -89,30 +148,164 @@ func StartEvaluation(userID int, config *EvaluationConfig) (string, error) {
 	}
 
 	// Start evaluation in background with auth token
-	go runEvaluationTask(userID, taskID, config, token)
+	go runComparisonTask(userID, taskID, configs, token)
 
 	return taskID, nil
 }
 
+// taskConfigMap returns the task settings stored with the task record
+func taskConfigMap(config *EvaluationConfig) map[string]interface{} {
+	configMap := map[string]interface{}{
+		"api_urls":             config.APIUrls,
+		"model":                config.Model,
+		"data_file":            config.DataFile,
+		"data_version":         config.DataVersion,
+		"scoring":              config.Scoring,
+		"scoring_module":       config.ScoringModule,
+		"max_workers":          config.MaxWorkers,
+		"badcase_threshold":    config.BadcaseThreshold,
+		"report_format":        config.ReportFormat,
+		"test_mode":            config.TestMode,
+		"sample_size":          config.SampleSize,
+		"checkpoint_path":      config.CheckpointPath,
+		"checkpoint_interval":  config.CheckpointInterval,
+		"resume":               config.Resume,
+		"role":                 config.Role,
+		"timeout":              config.Timeout,
+		"max_tokens":           config.MaxTokens,
+		"is_vllm":              config.IsVLLM,
+		"temperature":          config.Temperature,
+		"top_p":                config.TopP,
+		"data_filename":        config.DataFile,
+		"model_config_id":      config.ModelConfigID,
+		"model_config_version": config.ModelConfigVersion,
+		"model_alias":          config.ModelAlias,
+		"model_tags":           config.ModelTags,
+	}
+
+	config.JudgeConfig.addToTaskConfig(configMap)
+
+	return configMap
+}
+
 // runEvaluationTask runs the Python evaluation script
 func runEvaluationTask(userID int, taskID string, config *EvaluationConfig, authToken string) {
 	zap.L().Info("Starting evaluation task",
 		zap.Int("user_id", userID),
 		zap.String("task_id", taskID))
 
+	// A pending task may be cancelled before its process starts
+	if isTaskCancelled(userID, taskID) {
+		return
+	}
+	if err := runPythonEvaluation(userID, taskID, config, authToken, 0, 100); err != nil {
+		if !isTaskCancelled(userID, taskID) {
+			updateTaskStatus(taskID, "failed", 0, err.Error())
+		}
+		return
+	}
+
+	updateTaskStatus(taskID, "completed", 100, "Evaluation completed successfully")
+}
+
+// runComparisonTask evaluates each model in turn under the same task and builds the comparison report
+func runComparisonTask(userID int, taskID string, configs []*EvaluationConfig, authToken string) {
+	zap.L().Info("Starting comparison task",
+		zap.Int("user_id", userID),
+		zap.String("task_id", taskID),
+		zap.Int("models", len(configs)))
+
+	// Leave the last 5% for building the comparison report
+	span := 95.0 / float64(len(configs))
+	models := make([]string, len(configs))
+	for i, cfg := range configs {
+		models[i] = cfg.Model
+		// A task cancelled between two runs has no process to kill
+		if isTaskCancelled(userID, taskID) {
+			zap.L().Info("Comparison task cancelled", zap.String("task_id", taskID), zap.Int("models_done", i))
+			return
+		}
+		base := span * float64(i)
+		updateTaskStatus(taskID, "running", base, fmt.Sprintf("Evaluating model %d/%d: %s", i+1, len(configs), cfg.Model))
+
+		if err := runPythonEvaluation(userID, taskID, cfg, authToken, base, span); err != nil {
+			if !isTaskCancelled(userID, taskID) {
+				updateTaskStatus(taskID, "failed", base, fmt.Sprintf("Model %s: %v", cfg.Model, err))
+			}
+			return
+		}
+	}
+
+	if isTaskCancelled(userID, taskID) {
+		return
+	}
+	updateTaskStatus(taskID, "running", 95, "Building comparison report")
+	reportID, err := createComparisonReport(userID, taskID, models)
+	if err != nil {
+		updateTaskStatus(taskID, "failed", 95, fmt.Sprintf("Failed to build comparison report: %v", err))
+		return
+	}
+
+	zap.L().Info("Comparison report created",
+		zap.String("task_id", taskID),
+		zap.Int("comparison_id", reportID))
+	updateTaskStatus(taskID, "completed", 100, fmt.Sprintf("Comparison completed, comparison report %d", reportID))
+}
+
+// createComparisonReport combines the latest report of each model produced by a task
+func createComparisonReport(userID int, taskID string, models []string) (int, error) {
+	reports, err := repository.GetUserReportsByTaskID(taskID)
+	if err != nil {
+		return 0, err
+	}
+
+	byModel := make(map[string]*model.UserReport)
+	for i := range reports {
+		byModel[reports[i].Model] = &reports[i]
+	}
+
+	comparison, err := BuildComparison(models, byModel)
+	if err != nil {
+		return 0, err
+	}
+	comparison.UserID = userID
+	comparison.TaskID = taskID
+
+	return repository.CreateComparisonReport(comparison)
+}
+
+// runPythonEvaluation runs main.py for one model and waits for it to finish.
+// Progress reported by the script is mapped onto [progressBase, progressBase+progressSpan].
+func runPythonEvaluation(userID int, taskID string, config *EvaluationConfig, authToken string, progressBase, progressSpan float64) error {
 	// Build command line arguments with auth token
 	args := buildPythonArgs(userID, taskID, config, authToken)
+	args = append(args,
+		"--progress_base", fmt.Sprintf("%.2f", progressBase),
+		"--progress_span", fmt.Sprintf("%.2f", progressSpan))
+
+	args, cleanup, err := config.JudgeConfig.appendPromptTemplate(args)
+	if err != nil {
+		return err
+	}
+	defer cleanup()
 
+	return runMainScript(userID, taskID, args, config.APIKey, progressBase, progressSpan)
+}
+
+// runMainScript runs main.py with the given arguments and waits for it to finish.
+// Progress parsed from its output is mapped onto [progressBase, progressBase+progressSpan].
+func runMainScript(userID int, taskID string, args []string, apiKey string, progressBase, progressSpan float64) error {
 	// Find Python interpreter
 	pythonCmd, err := findPythonCommand()
 	if err != nil {
-		updateTaskStatus(taskID, "failed", 0, err.Error())
-		return
+		return err
 	}
 
 	// Create command
 	cmdArgs := append([]string{"main.py"}, args...)
 	cmd := exec.Command(pythonCmd, cmdArgs...)
+	// Pass the API key through the environment so it does not show up in ps
+	cmd.Env = append(os.Environ(), "LLM_JUDGE_API_KEY="+apiKey)
 	// Use current working directory instead of hardcoded path
 	if cwd, err := os.Getwd(); err == nil {
 		cmd.Dir = cwd
@@ -123,13 +316,11 @@ func runEvaluationTask(userID int, taskID string, config *EvaluationConfig, auth
 	// Setup pipes for stdout and stderr
 	stdout, err := cmd.StdoutPipe()
 	if err != nil {
-		updateTaskStatus(taskID, "failed", 0, fmt.Sprintf("Failed to create stdout pipe: %v", err))
-		return
+		return fmt.Errorf("Failed to create stdout pipe: %v", err)
 	}
 	stderr, err := cmd.StderrPipe()
 	if err != nil {
-		updateTaskStatus(taskID, "failed", 0, fmt.Sprintf("Failed to create stderr pipe: %v", err))
-		return
+		return fmt.Errorf("Failed to create stderr pipe: %v", err)
 	}
 
 	// Store command reference
@@ -139,29 +330,34 @@ func runEvaluationTask(userID int, taskID string, config *EvaluationConfig, auth
 
 	// Start command
 	if err := cmd.Start(); err != nil {
-		updateTaskStatus(taskID, "failed", 0, fmt.Sprintf("Failed to start Python process: %v", err))
-		return
+		tasksMutex.Lock()
+		delete(runningTasks, taskID)
+		tasksMutex.Unlock()
+		return fmt.Errorf("Failed to start Python process: %v", err)
 	}
 
 	// Update status to running
-	updateTaskStatus(taskID, "running", 0, "Evaluation started")
+	updateTaskStatus(taskID, "running", progressBase, "Evaluation started")
 
 	// Read output in goroutine
-	go readOutput(stdout, stderr, userID, taskID)
+	go readOutput(stdout, stderr, userID, taskID, progressBase, progressSpan)
 
 	// Wait for command to finish
-	go func() {
-		err := cmd.Wait()
-		tasksMutex.Lock()
-		delete(runningTasks, taskID)
-		tasksMutex.Unlock()
+	err = cmd.Wait()
+	tasksMutex.Lock()
+	delete(runningTasks, taskID)
+	tasksMutex.Unlock()
 
-		if err != nil {
-			updateTaskStatus(taskID, "failed", 0, fmt.Sprintf("Evaluation failed: %v", err))
-		} else {
-			updateTaskStatus(taskID, "completed", 100, "Evaluation completed successfully")
-		}
-	}()
+	if err != nil {
+		return fmt.Errorf("Evaluation failed: %v", err)
+	}
+	return nil
+}
+
+// isTaskCancelled reports whether the task was cancelled by the user
+func isTaskCancelled(userID int, taskID string) bool {
+	task, err := repository.GetUserTaskByID(userID, taskID)
+	return err == nil && task != nil && task.Status == "cancelled"
 }
 
 // buildPythonArgs builds command line arguments for Python script
@@ -181,6 +377,9 @@ func buildPythonArgs(userID int, taskID string, evalConfig *EvaluationConfig, au
 
 	// Data ID
 	args = append(args, "--data_id", evalConfig.DataFile)
+	if evalConfig.DataVersion > 0 {
+		args = append(args, "--data_version", strconv.Itoa(evalConfig.DataVersion))
+	}
 
 	// Scoring
 	args = append(args, "--scoring", evalConfig.Scoring)
@@ -204,9 +403,6 @@ func buildPythonArgs(userID int, taskID string, evalConfig *EvaluationConfig, au
 	// Max tokens (use --max-tokens)
 	args = append(args, "--max-tokens", strconv.Itoa(evalConfig.MaxTokens))
 
-	// API key
-	args = append(args, "--api_key", evalConfig.APIKey)
-
 	// Temperature - always pass it (even if 0.0)
 	args = append(args, "--temperature", fmt.Sprintf("%.1f", evalConfig.Temperature))
 
@@ -222,14 +418,8 @@ func buildPythonArgs(userID int, taskID string, evalConfig *EvaluationConfig, au
 	// Task ID
 	args = append(args, "--task_id", taskID)
 
-	// Database service URL - get from global config
-	loadedConfig := config.Get()
-	if loadedConfig != nil {
-		args = append(args, "--database_service_url", fmt.Sprintf("http://%s:%d", loadedConfig.DatabaseService.Host, loadedConfig.DatabaseService.Port))
-	} else {
-		// Fallback to default
-		args = append(args, "--database_service_url", "http://localhost:16384")
-	}
+	// Database service URL
+	args = append(args, "--database_service_url", databaseServiceURL())
 
 	// Is vLLM
 	if evalConfig.IsVLLM {
@@ -257,12 +447,26 @@ func buildPythonArgs(userID int, taskID string, evalConfig *EvaluationConfig, au
 		args = append(args, "--resume")
 	}
 
+	// Judge model (calls go through the model call proxy with the judge budget)
+	args = append(args, evalConfig.JudgeConfig.args()...)
+
 	// Auth token (for backend API authentication)
 	args = append(args, "--auth_token", authToken)
 
 	return args
 }
 
+// databaseServiceURL returns the database service URL handed to the Python runner
+func databaseServiceURL() string {
+	// Get from global config
+	loadedConfig := config.Get()
+	if loadedConfig != nil {
+		return fmt.Sprintf("http://%s:%d", loadedConfig.DatabaseService.Host, loadedConfig.DatabaseService.Port)
+	}
+	// Fallback to default
+	return "http://localhost:16384"
+}
+
 // buildAPIUrls converts API URLs from config to command line format
 func buildAPIUrls(apiUrls []interface{}) string {
 	var urls []string
@@ -294,7 +498,7 @@ func findPythonCommand() (string, error) {
 }
 
 // readOutput reads stdout and stderr from the Python process
-func readOutput(stdout, stderr io.Reader, userID int, taskID string) {
+func readOutput(stdout, stderr io.Reader, userID int, taskID string, progressBase, progressSpan float64) {
 	// Read stdout
 	go func() {
 		scanner := NewScanner(stdout)
@@ -305,7 +509,7 @@ func readOutput(stdout, stderr io.Reader, userID int, taskID string) {
 				zap.String("line", line))
 
 			// Parse progress from output
-			parseAndUpdateProgress(line, taskID)
+			parseAndUpdateProgress(line, taskID, progressBase, progressSpan)
 		}
 	}()
 
@@ -321,24 +525,28 @@ func readOutput(stdout, stderr io.Reader, userID int, taskID string) {
 	}()
 }
 
-// parseAndUpdateProgress parses progress information from Python output
-func parseAndUpdateProgress(line, taskID string) {
+// parseAndUpdateProgress parses progress information from Python output. The stage percentages
+// are of a single run and mapped onto [progressBase, progressBase+progressSpan].
+func parseAndUpdateProgress(line, taskID string, progressBase, progressSpan float64) {
+	update := func(percent float64, message string) {
+		updateTaskStatus(taskID, "running", progressBase+percent*progressSpan/100, message)
+	}
 	// Example: "加载完成，共 100 条数据"
 	if strings.Contains(line, "加载完成，共") {
 		// Data loaded
-		updateTaskStatus(taskID, "running", 10, "Data loaded")
+		update(10, "Data loaded")
 	}
 	// Example: "获取模型输出: 50/100 (30.0%)"
 	if strings.Contains(line, "获取模型输出:") {
-		updateTaskStatus(taskID, "running", 50, "Getting model outputs")
+		update(50, "Getting model outputs")
 	}
 	// Example: "评分处理: 80/100 (68.0%)"
 	if strings.Contains(line, "评分处理:") {
-		updateTaskStatus(taskID, "running", 70, "Scoring")
+		update(70, "Scoring")
 	}
 	// Example: "保存报告"
 	if strings.Contains(line, "保存报告") {
-		updateTaskStatus(taskID, "running", 90, "Saving reports")
+		update(90, "Saving reports")
 	}
 }
 
@@ -360,7 +568,14 @@ func CancelTask(userID int, taskID string) error {
 	tasksMutex.RUnlock()
 
 	if !exists {
-		return fmt.Errorf("task not found or not running")
+		// A comparison task between two model runs has no process; marking it cancelled stops
+		// it before the next run
+		task, err := repository.GetUserTaskByID(userID, taskID)
+		if err != nil || task == nil || (task.Status != "running" && task.Status != "pending") {
+			return fmt.Errorf("task not found or not running")
+		}
+		updateTaskStatus(taskID, "cancelled", 0, "Task cancelled by user")
+		return nil
 	}
 
 	// Kill the process
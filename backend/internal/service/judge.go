@@ -0,0 +1,137 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/wzyjerry/llm-judge/internal/repository"
+)
+
+// DefaultJudgeScale matches the 0-2 rubric of the built-in llm_judge_with_answer prompt
+const DefaultJudgeScale = 2
+
+var (
+	// ErrInvalidJudgeConfig is returned for judge settings that cannot be used
+	ErrInvalidJudgeConfig = errors.New("invalid judge settings")
+	// ErrJudgeModelNotFound is returned when no model config matches the judge model
+	ErrJudgeModelNotFound = errors.New("judge model config not found")
+)
+
+// JudgeConfig holds the LLM-as-judge settings of a task
+type JudgeConfig struct {
+	JudgeModel          string `json:"judge_model"`           // model name or alias of the judge model config
+	JudgePromptTemplate string `json:"judge_prompt_template"` // placeholders: {messages}, {reference_output}, {model_output}, {scale}
+	JudgeScale          int    `json:"judge_scale"`           // judge scores range from 0 to judge_scale
+
+	// Filled in from the judge model config
+	JudgeAPIUrls            []string `json:"-"`
+	JudgeIsVLLM             bool     `json:"-"`
+	JudgeMaxTokens          int      `json:"-"`
+	JudgeTimeout            int      `json:"-"`
+	JudgeModelConfigID      int      `json:"-"`
+	JudgeModelConfigVersion int      `json:"-"`
+}
+
+// ResolveJudgeConfig checks the judge settings and fills them in from the config of the judge model
+func ResolveJudgeConfig(judge *JudgeConfig) error {
+	judgeConfig, err := repository.ResolveModelConfig(judge.JudgeModel)
+	if err != nil {
+		return fmt.Errorf("failed to load judge model config: %w", err)
+	}
+	if judgeConfig == nil {
+		return fmt.Errorf("%w for: %s", ErrJudgeModelNotFound, judge.JudgeModel)
+	}
+	if len(judgeConfig.APIUrls) == 0 {
+		return fmt.Errorf("%w: judge model %s has no api_urls", ErrInvalidJudgeConfig, judgeConfig.ModelName)
+	}
+
+	if judge.JudgeScale < 0 {
+		return fmt.Errorf("%w: judge_scale must be positive, or 0 for the default of %d", ErrInvalidJudgeConfig, DefaultJudgeScale)
+	}
+	if judge.JudgeScale == 0 {
+		judge.JudgeScale = DefaultJudgeScale
+	}
+	// The built-in rubric scores 0 to 2, other scales need a custom template
+	if judge.JudgeScale != DefaultJudgeScale && judge.JudgePromptTemplate == "" {
+		return fmt.Errorf("%w: judge_scale other than %d requires judge_prompt_template", ErrInvalidJudgeConfig, DefaultJudgeScale)
+	}
+	if judge.JudgePromptTemplate != "" && !strings.Contains(judge.JudgePromptTemplate, "{model_output}") {
+		return fmt.Errorf("%w: judge_prompt_template must contain {model_output}", ErrInvalidJudgeConfig)
+	}
+
+	judge.JudgeModel = judgeConfig.ModelName
+	judge.JudgeAPIUrls = judgeConfig.APIUrls
+	judge.JudgeIsVLLM = judgeConfig.IsVLLM == 1
+	judge.JudgeMaxTokens = judgeConfig.MaxTokens
+	judge.JudgeTimeout = judgeConfig.Timeout
+	judge.JudgeModelConfigID = judgeConfig.ID
+	judge.JudgeModelConfigVersion = judgeConfig.CurrentVersion
+	return nil
+}
+
+// JudgeErrorStatus returns the HTTP status of an error returned by ResolveJudgeConfig
+func JudgeErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, ErrJudgeModelNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, ErrInvalidJudgeConfig):
+		return http.StatusBadRequest
+	}
+	return http.StatusInternalServerError
+}
+
+// addToTaskConfig records the judge settings in the stored config of a task
+func (judge *JudgeConfig) addToTaskConfig(configMap map[string]interface{}) {
+	if judge.JudgeModel == "" {
+		return
+	}
+	configMap["judge_model"] = judge.JudgeModel
+	configMap["judge_prompt_template"] = judge.JudgePromptTemplate
+	configMap["judge_scale"] = judge.JudgeScale
+	configMap["judge_model_config_id"] = judge.JudgeModelConfigID
+	configMap["judge_model_config_version"] = judge.JudgeModelConfigVersion
+}
+
+// args returns the runner arguments of the judge settings, except the prompt template
+func (judge *JudgeConfig) args() []string {
+	if judge.JudgeModel == "" {
+		return nil
+	}
+	args := []string{"--judge_model", judge.JudgeModel, "--judge_api_urls"}
+	args = append(args, judge.JudgeAPIUrls...)
+	args = append(args,
+		"--judge_scale", strconv.Itoa(judge.JudgeScale),
+		"--judge_max_tokens", strconv.Itoa(judge.JudgeMaxTokens),
+		"--judge_timeout", strconv.Itoa(judge.JudgeTimeout))
+	if judge.JudgeIsVLLM {
+		args = append(args, "--judge_is_vllm")
+	}
+	return args
+}
+
+// appendPromptTemplate hands the judge prompt template to the runner in a file rather than on the
+// command line, where it would be visible in ps and limited by ARG_MAX. cleanup removes the file
+// once the run is over.
+func (judge *JudgeConfig) appendPromptTemplate(args []string) ([]string, func(), error) {
+	if judge.JudgeModel == "" || judge.JudgePromptTemplate == "" {
+		return args, func() {}, nil
+	}
+
+	f, err := os.CreateTemp("", "llm-judge-prompt-*.txt")
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to write judge prompt template: %w", err)
+	}
+	_, err = f.WriteString(judge.JudgePromptTemplate)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(f.Name())
+		return nil, nil, fmt.Errorf("failed to write judge prompt template: %w", err)
+	}
+	return append(args, "--judge_prompt_template_file", f.Name()), func() { os.Remove(f.Name()) }, nil
+}
@@ -0,0 +1,82 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestJudgeConfigArgs(t *testing.T) {
+	if args := (&JudgeConfig{}).args(); args != nil {
+		t.Errorf("args without a judge model = %v, want none", args)
+	}
+
+	judge := &JudgeConfig{
+		JudgeModel:          "judge",
+		JudgePromptTemplate: "secret rubric {model_output}",
+		JudgeScale:          5,
+		JudgeAPIUrls:        []string{"http://a", "http://b"},
+		JudgeMaxTokens:      512,
+		JudgeTimeout:        60,
+		JudgeIsVLLM:         true,
+	}
+	got := strings.Join(judge.args(), " ")
+	want := "--judge_model judge --judge_api_urls http://a http://b --judge_scale 5 --judge_max_tokens 512 --judge_timeout 60 --judge_is_vllm"
+	if got != want {
+		t.Errorf("args = %q, want %q", got, want)
+	}
+}
+
+func TestJudgeConfigPromptTemplateFile(t *testing.T) {
+	judge := &JudgeConfig{JudgeModel: "judge", JudgePromptTemplate: "rate {model_output}"}
+	args, cleanup, err := judge.appendPromptTemplate([]string{"--x"})
+	if err != nil {
+		t.Fatalf("appendPromptTemplate: %v", err)
+	}
+	if len(args) != 3 || args[1] != "--judge_prompt_template_file" {
+		t.Fatalf("args = %v, want the template file appended", args)
+	}
+	for _, arg := range args {
+		if strings.Contains(arg, "rate {model_output}") {
+			t.Fatalf("the template is on the command line: %v", args)
+		}
+	}
+
+	path := args[2]
+	content, err := os.ReadFile(path)
+	if err != nil || string(content) != judge.JudgePromptTemplate {
+		t.Fatalf("template file holds %q, %v; want %q", content, err, judge.JudgePromptTemplate)
+	}
+	if info, _ := os.Stat(path); info.Mode().Perm()&0o077 != 0 {
+		t.Errorf("template file mode %v is readable by others", info.Mode())
+	}
+	cleanup()
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("template file still exists after cleanup")
+	}
+
+	args, cleanup, err = (&JudgeConfig{JudgeModel: "judge"}).appendPromptTemplate([]string{"--x"})
+	cleanup()
+	if err != nil || len(args) != 1 {
+		t.Errorf("without a template got %v, %v; want the arguments unchanged", args, err)
+	}
+}
+
+func TestJudgeErrorStatus(t *testing.T) {
+	tests := []struct {
+		err    error
+		status int
+	}{
+		{fmt.Errorf("%w for: judge", ErrJudgeModelNotFound), http.StatusNotFound},
+		{fmt.Errorf("%w: judge_scale must be positive", ErrInvalidJudgeConfig), http.StatusBadRequest},
+		{errors.New("database is locked"), http.StatusInternalServerError},
+	}
+	for _, tt := range tests {
+		if got := JudgeErrorStatus(tt.err); got != tt.status {
+			t.Errorf("JudgeErrorStatus(%v) = %d, want %d", tt.err, got, tt.status)
+		}
+	}
+}
@@ -0,0 +1,255 @@
+package service
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/wzyjerry/llm-judge/internal/model"
+	"github.com/wzyjerry/llm-judge/internal/repository"
+)
+
+const (
+	// LeaderboardLatest ranks the most recent report of each model on each dataset
+	LeaderboardLatest = "latest"
+	// LeaderboardBest ranks the best report of each model on each dataset
+	LeaderboardBest = "best"
+	// defaultLeaderboardMetric is the summary metric models are ranked by when none is given
+	defaultLeaderboardMetric = "accuracy"
+)
+
+// lowerIsBetterMetrics lists the summary metrics where a smaller value ranks higher
+var lowerIsBetterMetrics = map[string]bool{
+	"average_inference_time": true,
+}
+
+// NormalizeLeaderboardFilter fills in the default mode and metric and validates the filter
+func NormalizeLeaderboardFilter(filter *model.LeaderboardFilter) error {
+	if filter.Mode == "" {
+		filter.Mode = LeaderboardLatest
+	}
+	if filter.Mode != LeaderboardLatest && filter.Mode != LeaderboardBest {
+		return fmt.Errorf("mode must be %s or %s", LeaderboardLatest, LeaderboardBest)
+	}
+	if filter.Metric == "" {
+		filter.Metric = defaultLeaderboardMetric
+	}
+	if _, err := parseLeaderboardTime(filter.From, false); err != nil {
+		return fmt.Errorf("invalid from: %w", err)
+	}
+	if _, err := parseLeaderboardTime(filter.To, true); err != nil {
+		return fmt.Errorf("invalid to: %w", err)
+	}
+	return nil
+}
+
+// parseLeaderboardTime parses an RFC3339 time or a date. A date used as the end of a range
+// covers the whole day. An empty value returns the zero time.
+func parseLeaderboardTime(value string, end bool) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	t, err := time.ParseInLocation("2006-01-02", value, time.Local)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("expected RFC3339 time or YYYY-MM-DD date, got %q", value)
+	}
+	if end {
+		t = t.Add(24*time.Hour - time.Nanosecond)
+	}
+	return t, nil
+}
+
+// leaderboardReports loads the reports of the given users that match the filter and carry the metric, oldest first
+func leaderboardReports(userIDs []int, filter model.LeaderboardFilter, modelName string) ([]model.LeaderboardReport, error) {
+	var models []string
+	if len(filter.Tags) > 0 {
+		configs, err := repository.GetModelConfigsByTags(filter.Tags, true)
+		if err != nil {
+			return nil, err
+		}
+		models = []string{}
+		for _, config := range configs {
+			if modelName == "" || config.ModelName == modelName {
+				models = append(models, config.ModelName)
+			}
+		}
+	} else if modelName != "" {
+		models = []string{modelName}
+	}
+
+	reports, err := repository.GetLeaderboardReports(userIDs, filter.Dataset, models)
+	if err != nil {
+		return nil, err
+	}
+
+	from, _ := parseLeaderboardTime(filter.From, false)
+	to, _ := parseLeaderboardTime(filter.To, true)
+
+	matched := []model.LeaderboardReport{}
+	for _, report := range reports {
+		if filter.Scoring != "" && report.Scoring != filter.Scoring {
+			continue
+		}
+		if _, ok := metricValue(report.Summary, filter.Metric); !ok {
+			continue
+		}
+		if !from.IsZero() || !to.IsZero() {
+			createdAt, err := time.Parse(time.RFC3339, report.CreatedAt)
+			if err != nil || (!from.IsZero() && createdAt.Before(from)) || (!to.IsZero() && createdAt.After(to)) {
+				continue
+			}
+		}
+		matched = append(matched, report)
+	}
+
+	return matched, nil
+}
+
+// metricValue returns a numeric summary metric
+func metricValue(summary map[string]interface{}, metric string) (float64, bool) {
+	value, ok := summary[metric].(float64)
+	return value, ok
+}
+
+// betterThan reports whether value a ranks above value b for the metric
+func betterThan(metric string, a, b float64) bool {
+	if lowerIsBetterMetrics[metric] {
+		return a < b
+	}
+	return a > b
+}
+
+// ComputeLeaderboard ranks the models on each dataset by the latest or best matching report of the given users
+func ComputeLeaderboard(userIDs []int, filter model.LeaderboardFilter) (*model.Leaderboard, error) {
+	reports, err := leaderboardReports(userIDs, filter, "")
+	if err != nil {
+		return nil, err
+	}
+
+	type key struct{ dataset, model string }
+	selected := make(map[key]*model.LeaderboardEntry)
+	var keys []key
+	for _, report := range reports {
+		value, _ := metricValue(report.Summary, filter.Metric)
+		k := key{report.Dataset, report.Model}
+
+		entry := selected[k]
+		if entry == nil {
+			entry = &model.LeaderboardEntry{Dataset: report.Dataset, Model: report.Model}
+			selected[k] = entry
+			keys = append(keys, k)
+		}
+		entry.RunCount++
+
+		// Reports are oldest first, so the last one seen is the latest
+		if entry.RunCount == 1 || filter.Mode == LeaderboardLatest || betterThan(filter.Metric, value, entry.Value) {
+			entry.Value = value
+			entry.ReportID = report.ID
+			entry.TaskID = report.TaskID
+			entry.Username = report.Username
+			entry.Scoring = report.Scoring
+			entry.Summary = report.Summary
+			entry.CreatedAt = report.CreatedAt
+		}
+	}
+
+	leaderboard := &model.Leaderboard{
+		Filter:   filter,
+		Datasets: []string{},
+		Models:   []string{},
+		Best:     make(map[string]string),
+		Entries:  make([]model.LeaderboardEntry, 0, len(keys)),
+	}
+	for _, k := range keys {
+		leaderboard.Entries = append(leaderboard.Entries, *selected[k])
+	}
+
+	// Order by dataset, then best model first
+	sort.SliceStable(leaderboard.Entries, func(i, j int) bool {
+		a, b := leaderboard.Entries[i], leaderboard.Entries[j]
+		if a.Dataset != b.Dataset {
+			return a.Dataset < b.Dataset
+		}
+		if a.Value != b.Value {
+			return betterThan(filter.Metric, a.Value, b.Value)
+		}
+		return a.Model < b.Model
+	})
+
+	seenModels := make(map[string]bool)
+	first := 0 // index of the first entry of the current dataset
+	for i := range leaderboard.Entries {
+		entry := &leaderboard.Entries[i]
+		if i == 0 || entry.Dataset != leaderboard.Entries[i-1].Dataset {
+			first = i
+			leaderboard.Datasets = append(leaderboard.Datasets, entry.Dataset)
+			leaderboard.Best[entry.Dataset] = entry.Model
+		}
+
+		// Ties share a rank
+		entry.Rank = i - first + 1
+		if i > first && entry.Value == leaderboard.Entries[i-1].Value {
+			entry.Rank = leaderboard.Entries[i-1].Rank
+		}
+
+		if !seenModels[entry.Model] {
+			seenModels[entry.Model] = true
+			leaderboard.Models = append(leaderboard.Models, entry.Model)
+		}
+	}
+	sort.Strings(leaderboard.Models)
+
+	return leaderboard, nil
+}
+
+// ComputeLeaderboardHistory returns every matching report of a model on a dataset, oldest first.
+// A point is a regression when it is worse than the previous point scored with the same
+// scoring function by more than the threshold.
+func ComputeLeaderboardHistory(userIDs []int, filter model.LeaderboardFilter, modelName string, threshold float64) (*model.LeaderboardHistory, error) {
+	reports, err := leaderboardReports(userIDs, filter, modelName)
+	if err != nil {
+		return nil, err
+	}
+
+	history := &model.LeaderboardHistory{
+		Model:     modelName,
+		Dataset:   filter.Dataset,
+		Metric:    filter.Metric,
+		Threshold: threshold,
+		Points:    []model.LeaderboardPoint{},
+	}
+
+	previous := make(map[string]float64)
+	for _, report := range reports {
+		value, _ := metricValue(report.Summary, filter.Metric)
+		point := model.LeaderboardPoint{
+			ReportID:  report.ID,
+			TaskID:    report.TaskID,
+			Username:  report.Username,
+			Scoring:   report.Scoring,
+			CreatedAt: report.CreatedAt,
+			Value:     value,
+		}
+
+		if prev, ok := previous[report.Scoring]; ok {
+			delta := value - prev
+			point.Delta = &delta
+			if lowerIsBetterMetrics[filter.Metric] {
+				point.Regression = delta > threshold
+			} else {
+				point.Regression = -delta > threshold
+			}
+			if point.Regression {
+				history.Regressions++
+			}
+		}
+		previous[report.Scoring] = value
+
+		history.Points = append(history.Points, point)
+	}
+
+	return history, nil
+}
@@ -0,0 +1,176 @@
+package service
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/wzyjerry/llm-judge/internal/model"
+	"github.com/wzyjerry/llm-judge/internal/repository"
+	"go.uber.org/zap"
+)
+
+// Defaults for model configs created from discovered models (same as the admin form)
+const (
+	discoveredTemperature    = 0.0
+	discoveredTopP           = 1.0
+	discoveredMaxTokens      = 1024
+	discoveredTimeout        = 10
+	discoveredMaxConcurrency = 10
+)
+
+// DiscoverModels lists the models served by an endpoint and marks those already configured
+func DiscoverModels(apiURL, apiKey string) ([]model.UpstreamModel, error) {
+	models, err := listUpstreamModels(apiURL, apiKey, healthCheckTimeout())
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range models {
+		existing, err := repository.GetModelConfigByName(models[i].ID)
+		if err != nil {
+			return nil, err
+		}
+		if existing != nil {
+			models[i].Exists = true
+			models[i].ConfigID = existing.ID
+		}
+	}
+
+	return models, nil
+}
+
+// ImportDiscoveredModels creates model configs with default settings for the selected models.
+// Models that are not served by the endpoint or already configured are skipped.
+func ImportDiscoveredModels(req *model.ModelDiscoverImportRequest, author string) (created map[string]int, skipped map[string]string, err error) {
+	models, err := DiscoverModels(req.APIUrl, req.APIKey)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	upstream := make(map[string]model.UpstreamModel, len(models))
+	for _, m := range models {
+		upstream[m.ID] = m
+	}
+
+	created = make(map[string]int)
+	skipped = make(map[string]string)
+	for _, name := range req.ModelNames {
+		m, ok := upstream[name]
+		if !ok {
+			skipped[name] = "model not served by endpoint"
+			continue
+		}
+		if m.Exists {
+			skipped[name] = fmt.Sprintf("model config %d already exists", m.ConfigID)
+			continue
+		}
+
+		isVLLM := 0
+		if req.IsVLLM != nil {
+			isVLLM = *req.IsVLLM
+		} else if strings.EqualFold(m.OwnedBy, "vllm") {
+			isVLLM = 1
+		}
+
+		description := req.Description
+		if description == "" {
+			description = fmt.Sprintf("Discovered from %s", req.APIUrl)
+		}
+
+		configID, err := repository.CreateModelConfig(&model.ModelConfigCreate{
+			ModelName:      name,
+			APIUrls:        []string{req.APIUrl},
+			APIKey:         req.APIKey,
+			Temperature:    discoveredTemperature,
+			TopP:           discoveredTopP,
+			MaxTokens:      discoveredMaxTokens,
+			Timeout:        discoveredTimeout,
+			MaxConcurrency: discoveredMaxConcurrency,
+			Description:    description,
+			IsVLLM:         isVLLM,
+		}, author)
+		if err != nil {
+			skipped[name] = err.Error()
+			continue
+		}
+		created[name] = configID
+	}
+
+	return created, skipped, nil
+}
+
+// StartModelSync periodically flags model configs whose model disappeared upstream
+func StartModelSync(interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			SyncUpstreamModels()
+			<-ticker.C
+		}
+	}()
+}
+
+// SyncUpstreamModels checks every model config against the /v1/models list of its endpoints.
+// A config is flagged when no reachable endpoint lists its model; configs whose endpoints
+// are all unreachable are left unchanged.
+func SyncUpstreamModels() {
+	configs, err := repository.GetAllModelConfigs(true)
+	if err != nil {
+		zap.L().Warn("Failed to load model configs for upstream sync", zap.Error(err))
+		return
+	}
+
+	timeout := healthCheckTimeout()
+	cache := make(map[string][]model.UpstreamModel)
+	failed := make(map[string]bool)
+
+	for _, cfg := range configs {
+		reachable := false
+		listed := false
+
+		for _, apiURL := range cfg.APIUrls {
+			cacheKey := apiURL + "\x00" + cfg.APIKey
+			if failed[cacheKey] {
+				continue
+			}
+			models, ok := cache[cacheKey]
+			if !ok {
+				models, err = listUpstreamModels(apiURL, cfg.APIKey, timeout)
+				if err != nil {
+					failed[cacheKey] = true
+					continue
+				}
+				cache[cacheKey] = models
+			}
+
+			reachable = true
+			for _, m := range models {
+				if m.ID == cfg.ModelName {
+					listed = true
+					break
+				}
+			}
+			if listed {
+				break
+			}
+		}
+
+		if !reachable {
+			continue
+		}
+
+		if !listed && cfg.UpstreamMissing == 0 {
+			zap.L().Warn("Model disappeared upstream",
+				zap.String("model", cfg.ModelName),
+				zap.Strings("api_urls", cfg.APIUrls))
+		}
+		if err := repository.SetModelConfigUpstreamStatus(cfg.ID, !listed); err != nil {
+			zap.L().Warn("Failed to update upstream status",
+				zap.String("model", cfg.ModelName),
+				zap.Error(err))
+		}
+	}
+}
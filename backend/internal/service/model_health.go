@@ -0,0 +1,250 @@
+package service
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/wzyjerry/llm-judge/internal/model"
+	"github.com/wzyjerry/llm-judge/internal/pkg/config"
+	"github.com/wzyjerry/llm-judge/internal/repository"
+	"go.uber.org/zap"
+)
+
+// Health status values for endpoints and model configs
+const (
+	HealthUp       = "up"
+	HealthDegraded = "degraded"
+	HealthDown     = "down"
+	HealthUnknown  = "unknown"
+)
+
+// placeholderAPIKey is the dummy key used when no real key is configured
+const placeholderAPIKey = "sk-xxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxx"
+
+// CheckModelConfig probes every api_url of a model config and stores the results
+func CheckModelConfig(modelConfig *model.ModelConfig) ([]model.ModelEndpointHealth, error) {
+	timeout := healthCheckTimeout()
+
+	health := make([]model.ModelEndpointHealth, 0, len(modelConfig.APIUrls))
+	for _, apiURL := range modelConfig.APIUrls {
+		health = append(health, probeEndpoint(modelConfig, apiURL, timeout))
+	}
+
+	if err := repository.ReplaceModelEndpointHealth(modelConfig.ID, health); err != nil {
+		return health, fmt.Errorf("failed to save health results: %w", err)
+	}
+
+	return health, nil
+}
+
+// AggregateHealthStatus summarizes endpoint results into a single status
+func AggregateHealthStatus(health []model.ModelEndpointHealth) string {
+	if len(health) == 0 {
+		return HealthUnknown
+	}
+
+	upCount := 0
+	for _, h := range health {
+		if h.Status == HealthUp {
+			upCount++
+		}
+	}
+
+	switch {
+	case upCount == len(health):
+		return HealthUp
+	case upCount == 0:
+		return HealthDown
+	default:
+		return HealthDegraded
+	}
+}
+
+// StartModelHealthChecker periodically checks all active model configs in the background
+func StartModelHealthChecker(interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			checkAllModelConfigs()
+			<-ticker.C
+		}
+	}()
+}
+
+// checkAllModelConfigs runs a health check on every active model config
+func checkAllModelConfigs() {
+	configs, err := repository.GetAllModelConfigs(false)
+	if err != nil {
+		zap.L().Warn("Failed to load model configs for health check", zap.Error(err))
+		return
+	}
+
+	for i := range configs {
+		health, err := CheckModelConfig(&configs[i])
+		if err != nil {
+			zap.L().Warn("Model health check failed",
+				zap.String("model", configs[i].ModelName),
+				zap.Error(err))
+			continue
+		}
+		zap.L().Debug("Model health checked",
+			zap.String("model", configs[i].ModelName),
+			zap.String("status", AggregateHealthStatus(health)))
+	}
+}
+
+// probeEndpoint checks /v1/models and sends a minimal chat completion to one api_url
+func probeEndpoint(modelConfig *model.ModelConfig, apiURL string, timeout time.Duration) model.ModelEndpointHealth {
+	result := model.ModelEndpointHealth{
+		ConfigID: modelConfig.ID,
+		APIUrl:   apiURL,
+		Status:   HealthDown,
+	}
+
+	var errs []string
+
+	// Check that the model is served by this endpoint
+	models, err := listUpstreamModels(apiURL, modelConfig.APIKey, timeout)
+	if err != nil {
+		errs = append(errs, fmt.Sprintf("list models: %v", err))
+	} else {
+		for _, m := range models {
+			if m.ID == modelConfig.ModelName {
+				result.ModelAvailable = true
+				break
+			}
+		}
+		if !result.ModelAvailable {
+			errs = append(errs, "model not listed by /v1/models")
+		}
+	}
+
+	// Send a probe chat completion
+	start := time.Now()
+	if err := probeChatCompletion(modelConfig, apiURL, timeout); err != nil {
+		errs = append(errs, fmt.Sprintf("chat completion: %v", err))
+	} else {
+		result.Status = HealthUp
+	}
+	result.LatencyMs = time.Since(start).Milliseconds()
+
+	result.Error = strings.Join(errs, "; ")
+	result.CheckedAt = time.Now().Format(time.RFC3339)
+	return result
+}
+
+// listUpstreamModels returns the models served by an OpenAI-compatible endpoint
+func listUpstreamModels(apiURL, apiKey string, timeout time.Duration) ([]model.UpstreamModel, error) {
+	httpReq, err := http.NewRequest("GET", openAIBaseURL(apiURL)+"/models", nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	setAuthorization(httpReq, apiKey)
+
+	client := &http.Client{Timeout: timeout}
+	resp, err := client.Do(httpReq)
+	if err != nil {
+		return nil, fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("API returned status %d: %s", resp.StatusCode, truncate(string(body), 200))
+	}
+
+	var result struct {
+		Data []model.UpstreamModel `json:"data"`
+	}
+	if err := json.Unmarshal(body, &result); err != nil {
+		return nil, fmt.Errorf("failed to parse response: %w", err)
+	}
+
+	return result.Data, nil
+}
+
+// probeChatCompletion sends a one-token chat completion to verify the endpoint serves the model
+func probeChatCompletion(modelConfig *model.ModelConfig, apiURL string, timeout time.Duration) error {
+	payload := map[string]interface{}{
+		"model": modelConfig.ModelName,
+		"messages": []map[string]string{
+			{"role": "user", "content": "ping"},
+		},
+		"max_tokens":  1,
+		"temperature": 0.0,
+		"stream":      false,
+	}
+	if modelConfig.IsVLLM == 1 {
+		payload["chat_template_kwargs"] = map[string]interface{}{
+			"enable_thinking": false,
+		}
+	}
+
+	jsonData, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	httpReq, err := http.NewRequest("POST", openAIBaseURL(apiURL)+"/chat/completions", bytes.NewBuffer(jsonData))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	setAuthorization(httpReq, modelConfig.APIKey)
+
+	client := &http.Client{Timeout: timeout}
+	resp, err := client.Do(httpReq)
+	if err != nil {
+		return fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("API returned status %d: %s", resp.StatusCode, truncate(string(body), 200))
+	}
+
+	return nil
+}
+
+// openAIBaseURL normalizes an api_url the same way the model call proxy does
+func openAIBaseURL(apiURL string) string {
+	baseURL := strings.TrimRight(apiURL, "/")
+	if !strings.HasSuffix(baseURL, "/v1") {
+		baseURL += "/v1"
+	}
+	return baseURL
+}
+
+// setAuthorization sets the bearer token unless the key is empty or the placeholder
+func setAuthorization(httpReq *http.Request, apiKey string) {
+	if apiKey != "" && apiKey != placeholderAPIKey {
+		httpReq.Header.Set("Authorization", "Bearer "+apiKey)
+	}
+}
+
+// healthCheckTimeout returns the per-endpoint probe timeout
+func healthCheckTimeout() time.Duration {
+	if cfg := config.Get(); cfg != nil && cfg.ModelHealth.TimeoutSeconds > 0 {
+		return time.Duration(cfg.ModelHealth.TimeoutSeconds) * time.Second
+	}
+	return 10 * time.Second
+}
+
+// truncate shortens a string to at most n bytes
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	return s[:n] + "..."
+}
@@ -0,0 +1,439 @@
+package service
+
+import (
+	"archive/zip"
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"html/template"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/wzyjerry/llm-judge/internal/model"
+	"github.com/wzyjerry/llm-judge/internal/repository"
+)
+
+// ExportFormat describes a report export format
+type ExportFormat struct {
+	ContentType string
+	Extension   string
+}
+
+// ExportFormats lists the supported report export formats by name
+var ExportFormats = map[string]ExportFormat{
+	"csv":   {"text/csv; charset=utf-8", "csv"},
+	"xlsx":  {"application/vnd.openxmlformats-officedocument.spreadsheetml.sheet", "xlsx"},
+	"html":  {"text/html; charset=utf-8", "html"},
+	"md":    {"text/markdown; charset=utf-8", "md"},
+	"jsonl": {"application/x-ndjson", "jsonl"},
+}
+
+// xlsxMaxCellLength is the largest number of characters an Excel cell can hold
+const xlsxMaxCellLength = 32767
+
+// itemColumns are the per-item table columns shared by all tabular formats
+var itemColumns = []string{
+	"index", "original_index", "expanded_index", "user_input", "model_output", "reference_output",
+	"score", "is_badcase", "inference_time", "error", "details",
+}
+
+// numericItemColumns marks the item columns written as numbers in XLSX
+var numericItemColumns = map[int]bool{0: true, 1: true, 2: true, 6: true, 7: true, 8: true}
+
+// reportExporter writes a report in one format
+type reportExporter interface {
+	begin(detail *model.ReportDetail) error
+	writeItem(r *model.ReportResult) error
+	end() error
+}
+
+// ExportReport writes a materialized report in the given format, streaming items from the per-item store
+func ExportReport(w io.Writer, format string, detail *model.ReportDetail, badcaseOnly bool) error {
+	var exporter reportExporter
+	switch format {
+	case "csv":
+		exporter = &csvExporter{w: w}
+	case "xlsx":
+		exporter = &xlsxExporter{w: w}
+	case "html":
+		exporter = &htmlExporter{w: bufio.NewWriter(w), badcaseOnly: badcaseOnly}
+	case "md":
+		exporter = &markdownExporter{w: bufio.NewWriter(w), badcaseOnly: badcaseOnly}
+	case "jsonl":
+		exporter = &jsonlExporter{w: bufio.NewWriter(w)}
+	default:
+		return fmt.Errorf("unsupported export format: %s", format)
+	}
+
+	if err := exporter.begin(detail); err != nil {
+		return err
+	}
+	filter := model.ReportItemFilter{BadcaseOnly: badcaseOnly}
+	if err := repository.ForEachReportItem(detail.ID, filter, exporter.writeItem); err != nil {
+		return err
+	}
+	return exporter.end()
+}
+
+// reportSummaryRows flattens report metadata, summary and config into name/value rows
+func reportSummaryRows(detail *model.ReportDetail) [][2]string {
+	rows := [][2]string{
+		{"report_id", strconv.Itoa(detail.ID)},
+		{"task_id", detail.TaskID},
+		{"dataset", detail.Dataset},
+		{"model", detail.Model},
+		{"timestamp", detail.Timestamp},
+		{"item_count", strconv.Itoa(detail.ItemCount)},
+		{"badcase_count", strconv.Itoa(detail.BadcaseCount)},
+	}
+	rows = appendFlattened(rows, "summary", detail.Summary)
+	rows = appendFlattened(rows, "config", detail.Config)
+	return rows
+}
+
+// appendFlattened appends the entries of m as rows named prefix.key, recursing into nested objects
+func appendFlattened(rows [][2]string, prefix string, m map[string]interface{}) [][2]string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	for _, k := range keys {
+		name := prefix + "." + k
+		if nested, ok := m[k].(map[string]interface{}); ok {
+			rows = appendFlattened(rows, name, nested)
+			continue
+		}
+		rows = append(rows, [2]string{name, formatExportValue(m[k])})
+	}
+	return rows
+}
+
+// formatExportValue renders a JSON value as cell text
+func formatExportValue(v interface{}) string {
+	switch value := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return value
+	case float64:
+		return strconv.FormatFloat(value, 'f', -1, 64)
+	case bool:
+		return strconv.FormatBool(value)
+	default:
+		// Keep <, > and & readable in exported cells
+		var buf strings.Builder
+		encoder := json.NewEncoder(&buf)
+		encoder.SetEscapeHTML(false)
+		if err := encoder.Encode(value); err != nil {
+			return fmt.Sprint(value)
+		}
+		return strings.TrimRight(buf.String(), "\n")
+	}
+}
+
+// itemRow renders a report item as cells in itemColumns order
+func itemRow(r *model.ReportResult) []string {
+	details := ""
+	if len(r.Details) > 0 {
+		details = formatExportValue(r.Details)
+	}
+	return []string{
+		strconv.Itoa(r.Index),
+		strconv.Itoa(r.OriginalIndex),
+		strconv.Itoa(r.ExpandedIndex),
+		formatExportValue(r.UserInput),
+		r.ModelOutput,
+		formatExportValue(r.ReferenceOutput),
+		strconv.FormatFloat(r.Score, 'f', -1, 64),
+		strconv.Itoa(r.IsBadcase),
+		strconv.FormatFloat(r.InferenceTime, 'f', -1, 64),
+		r.Error,
+		details,
+	}
+}
+
+// csvExporter writes the per-item table as CSV with a UTF-8 BOM so Excel detects the encoding
+type csvExporter struct {
+	w      io.Writer
+	writer *csv.Writer
+}
+
+func (e *csvExporter) begin(detail *model.ReportDetail) error {
+	if _, err := io.WriteString(e.w, "\ufeff"); err != nil {
+		return err
+	}
+	e.writer = csv.NewWriter(e.w)
+	return e.writer.Write(itemColumns)
+}
+
+func (e *csvExporter) writeItem(r *model.ReportResult) error {
+	row := itemRow(r)
+	// Spreadsheets evaluate text cells such as model output starting with = as formulas
+	for i, cell := range row {
+		if !numericItemColumns[i] {
+			row[i] = csvSafeCell(cell)
+		}
+	}
+	return e.writer.Write(row)
+}
+
+func (e *csvExporter) end() error {
+	e.writer.Flush()
+	return e.writer.Error()
+}
+
+// jsonlExporter writes one JSON object per item
+type jsonlExporter struct {
+	w       *bufio.Writer
+	encoder *json.Encoder
+}
+
+func (e *jsonlExporter) begin(detail *model.ReportDetail) error {
+	e.encoder = json.NewEncoder(e.w)
+	e.encoder.SetEscapeHTML(false)
+	return nil
+}
+
+func (e *jsonlExporter) writeItem(r *model.ReportResult) error {
+	return e.encoder.Encode(r)
+}
+
+func (e *jsonlExporter) end() error {
+	return e.w.Flush()
+}
+
+// markdownExporter writes a summary table followed by the per-item table
+type markdownExporter struct {
+	w           *bufio.Writer
+	badcaseOnly bool
+}
+
+// markdownCell escapes text for a Markdown table cell
+func markdownCell(s string) string {
+	s = strings.ReplaceAll(s, `\`, `\\`)
+	s = strings.ReplaceAll(s, "|", `\|`)
+	s = strings.ReplaceAll(s, "&", "&amp;")
+	s = strings.ReplaceAll(s, "<", "&lt;")
+	s = strings.ReplaceAll(s, "\r\n", "\n")
+	return strings.ReplaceAll(s, "\n", "<br>")
+}
+
+func (e *markdownExporter) begin(detail *model.ReportDetail) error {
+	fmt.Fprintf(e.w, "# %s / %s\n\n", markdownCell(detail.Dataset), markdownCell(detail.Model))
+	fmt.Fprintf(e.w, "## Summary\n\n| Name | Value |\n| --- | --- |\n")
+	for _, row := range reportSummaryRows(detail) {
+		fmt.Fprintf(e.w, "| %s | %s |\n", markdownCell(row[0]), markdownCell(row[1]))
+	}
+
+	title := "Results"
+	if e.badcaseOnly {
+		title = "Badcases"
+	}
+	fmt.Fprintf(e.w, "\n## %s\n\n| %s |\n|%s\n", title, strings.Join(itemColumns, " | "), strings.Repeat(" --- |", len(itemColumns)))
+	return nil
+}
+
+func (e *markdownExporter) writeItem(r *model.ReportResult) error {
+	cells := itemRow(r)
+	for i := range cells {
+		cells[i] = markdownCell(cells[i])
+	}
+	_, err := fmt.Fprintf(e.w, "| %s |\n", strings.Join(cells, " | "))
+	return err
+}
+
+func (e *markdownExporter) end() error {
+	return e.w.Flush()
+}
+
+// htmlReportHead opens a self-contained HTML report with the summary table and the results table header
+var htmlReportHead = template.Must(template.New("head").Parse(`<!DOCTYPE html>
+<html lang="zh-CN">
+<head>
+<meta charset="utf-8">
+<title>{{.Detail.Dataset}} / {{.Detail.Model}}</title>
+<style>
+body { font-family: -apple-system, "Segoe UI", "PingFang SC", "Microsoft YaHei", sans-serif; margin: 24px; color: #1f2328; }
+h1 { font-size: 22px; } h2 { font-size: 18px; margin-top: 32px; }
+table { border-collapse: collapse; width: 100%; font-size: 13px; }
+th, td { border: 1px solid #d0d7de; padding: 6px 8px; text-align: left; vertical-align: top; }
+th { background: #f6f8fa; position: sticky; top: 0; }
+td pre { margin: 0; white-space: pre-wrap; word-break: break-word; font-family: inherit; max-height: 320px; overflow: auto; }
+tr.badcase { background: #fff1f0; }
+.summary { width: auto; min-width: 480px; }
+.meta { color: #656d76; font-size: 12px; }
+</style>
+</head>
+<body>
+<h1>{{.Detail.Dataset}} / {{.Detail.Model}}</h1>
+<p class="meta">Exported at {{.ExportedAt}}</p>
+<h2>Summary</h2>
+<table class="summary">
+{{range .Summary}}<tr><th>{{index . 0}}</th><td>{{index . 1}}</td></tr>
+{{end}}</table>
+<h2>{{if .BadcaseOnly}}Badcases{{else}}Results{{end}}</h2>
+<table>
+<tr>{{range .Columns}}<th>{{.}}</th>{{end}}</tr>
+`))
+
+// htmlReportRow renders one results table row
+var htmlReportRow = template.Must(template.New("row").Parse(
+	`<tr{{if .Badcase}} class="badcase"{{end}}>{{range .Cells}}<td><pre>{{.}}</pre></td>{{end}}</tr>
+`))
+
+// htmlExporter writes a self-contained HTML report that needs no account or network access to view
+type htmlExporter struct {
+	w           *bufio.Writer
+	badcaseOnly bool
+}
+
+func (e *htmlExporter) begin(detail *model.ReportDetail) error {
+	return htmlReportHead.Execute(e.w, map[string]interface{}{
+		"Detail":      detail,
+		"ExportedAt":  time.Now().Format(time.RFC3339),
+		"Summary":     reportSummaryRows(detail),
+		"Columns":     itemColumns,
+		"BadcaseOnly": e.badcaseOnly,
+	})
+}
+
+func (e *htmlExporter) writeItem(r *model.ReportResult) error {
+	return htmlReportRow.Execute(e.w, map[string]interface{}{
+		"Badcase": r.IsBadcase != 0,
+		"Cells":   itemRow(r),
+	})
+}
+
+func (e *htmlExporter) end() error {
+	if _, err := e.w.WriteString("</table>\n</body>\n</html>\n"); err != nil {
+		return err
+	}
+	return e.w.Flush()
+}
+
+// xlsxExporter writes an Office Open XML workbook with a Summary and a Results sheet.
+// Cells use inline strings, so no shared string table has to be held in memory.
+type xlsxExporter struct {
+	w     io.Writer
+	zw    *zip.Writer
+	sheet *bufio.Writer
+}
+
+const xlsxContentTypes = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types">
+<Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/>
+<Default Extension="xml" ContentType="application/xml"/>
+<Override PartName="/xl/workbook.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.sheet.main+xml"/>
+<Override PartName="/xl/worksheets/sheet1.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.worksheet+xml"/>
+<Override PartName="/xl/worksheets/sheet2.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.worksheet+xml"/>
+</Types>`
+
+const xlsxRootRels = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">
+<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/officeDocument" Target="xl/workbook.xml"/>
+</Relationships>`
+
+const xlsxWorkbook = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships">
+<sheets>
+<sheet name="Summary" sheetId="1" r:id="rId1"/>
+<sheet name="Results" sheetId="2" r:id="rId2"/>
+</sheets>
+</workbook>`
+
+const xlsxWorkbookRels = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">
+<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="worksheets/sheet1.xml"/>
+<Relationship Id="rId2" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="worksheets/sheet2.xml"/>
+</Relationships>`
+
+const (
+	xlsxSheetHead = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><sheetData>`
+	xlsxSheetTail = `</sheetData></worksheet>`
+)
+
+func (e *xlsxExporter) begin(detail *model.ReportDetail) error {
+	e.zw = zip.NewWriter(e.w)
+
+	parts := []struct{ name, content string }{
+		{"[Content_Types].xml", xlsxContentTypes},
+		{"_rels/.rels", xlsxRootRels},
+		{"xl/workbook.xml", xlsxWorkbook},
+		{"xl/_rels/workbook.xml.rels", xlsxWorkbookRels},
+	}
+	for _, part := range parts {
+		f, err := e.zw.Create(part.name)
+		if err != nil {
+			return err
+		}
+		if _, err := io.WriteString(f, part.content); err != nil {
+			return err
+		}
+	}
+
+	f, err := e.zw.Create("xl/worksheets/sheet1.xml")
+	if err != nil {
+		return err
+	}
+	summary := bufio.NewWriter(f)
+	summary.WriteString(xlsxSheetHead)
+	writeXLSXRow(summary, []string{"name", "value"}, nil)
+	for _, row := range reportSummaryRows(detail) {
+		writeXLSXRow(summary, row[:], nil)
+	}
+	summary.WriteString(xlsxSheetTail)
+	if err := summary.Flush(); err != nil {
+		return err
+	}
+
+	// The results sheet stays open while items are streamed
+	f, err = e.zw.Create("xl/worksheets/sheet2.xml")
+	if err != nil {
+		return err
+	}
+	e.sheet = bufio.NewWriter(f)
+	e.sheet.WriteString(xlsxSheetHead)
+	writeXLSXRow(e.sheet, itemColumns, nil)
+	return nil
+}
+
+func (e *xlsxExporter) writeItem(r *model.ReportResult) error {
+	writeXLSXRow(e.sheet, itemRow(r), numericItemColumns)
+	return nil
+}
+
+func (e *xlsxExporter) end() error {
+	e.sheet.WriteString(xlsxSheetTail)
+	if err := e.sheet.Flush(); err != nil {
+		return err
+	}
+	return e.zw.Close()
+}
+
+// writeXLSXRow writes one sheet row; cells marked numeric are written as numbers, the rest as inline strings
+func writeXLSXRow(w *bufio.Writer, cells []string, numeric map[int]bool) {
+	w.WriteString("<row>")
+	for i, cell := range cells {
+		if numeric[i] && cell != "" {
+			w.WriteString(`<c t="n"><v>`)
+			xml.EscapeText(w, []byte(cell))
+			w.WriteString(`</v></c>`)
+			continue
+		}
+		if runes := []rune(cell); len(runes) > xlsxMaxCellLength {
+			cell = string(runes[:xlsxMaxCellLength])
+		}
+		w.WriteString(`<c t="inlineStr"><is><t xml:space="preserve">`)
+		xml.EscapeText(w, []byte(cell))
+		w.WriteString(`</t></is></c>`)
+	}
+	w.WriteString("</row>")
+}
@@ -0,0 +1,47 @@
+package service
+
+import (
+	"bytes"
+	"encoding/csv"
+	"testing"
+
+	"github.com/wzyjerry/llm-judge/internal/model"
+)
+
+func TestCSVExporterNeutralisesFormulas(t *testing.T) {
+	var out bytes.Buffer
+	exporter := &csvExporter{w: &out}
+	if err := exporter.begin(&model.ReportDetail{}); err != nil {
+		t.Fatalf("begin: %v", err)
+	}
+	result := &model.ReportResult{
+		Index:       1,
+		UserInput:   "+1 or -1?",
+		ModelOutput: `=HYPERLINK("http://evil","x")`,
+		Score:       -0.5,
+		Error:       "@timeout",
+	}
+	if err := exporter.writeItem(result); err != nil {
+		t.Fatalf("writeItem: %v", err)
+	}
+	if err := exporter.end(); err != nil {
+		t.Fatalf("end: %v", err)
+	}
+
+	rows, err := csv.NewReader(bytes.NewReader(bytes.TrimPrefix(out.Bytes(), []byte("\ufeff")))).ReadAll()
+	if err != nil {
+		t.Fatalf("read CSV: %v", err)
+	}
+	row := rows[1]
+	checks := map[int]string{
+		3: "'+1 or -1?",
+		4: `'=HYPERLINK("http://evil","x")`,
+		6: "-0.5", // numeric columns stay numbers
+		9: "'@timeout",
+	}
+	for column, want := range checks {
+		if row[column] != want {
+			t.Errorf("%s cell %q, want %q", itemColumns[column], row[column], want)
+		}
+	}
+}
@@ -0,0 +1,74 @@
+package service
+
+import (
+	"github.com/wzyjerry/llm-judge/internal/model"
+	"github.com/wzyjerry/llm-judge/internal/pkg/stats"
+)
+
+// ComputeReviewAgreement measures how often reviewers agree with the scorer and with each other on badcase flags.
+// Reviews must be ordered by item with the newest review first; autoFlags holds the scorer's flag per item.
+func ComputeReviewAgreement(reviews []model.ReportItemReview, autoFlags map[int]int) model.ReviewAgreement {
+	agreement := model.ReviewAgreement{FailureCategories: make(map[string]int)}
+
+	labels := make(map[int][]bool)
+	var order []int
+	for _, review := range reviews {
+		if review.FailureCategory != "" {
+			agreement.FailureCategories[review.FailureCategory]++
+		}
+		if review.IsBadcase == nil {
+			continue
+		}
+		if _, ok := labels[review.ItemIndex]; !ok {
+			order = append(order, review.ItemIndex)
+		}
+		labels[review.ItemIndex] = append(labels[review.ItemIndex], *review.IsBadcase != 0)
+	}
+
+	// The latest label of each item is compared with the scorer
+	var human, scorer []bool
+	agreeing := 0
+	for _, index := range order {
+		auto, ok := autoFlags[index]
+		if !ok {
+			continue
+		}
+		latest := labels[index][0]
+		human = append(human, latest)
+		scorer = append(scorer, auto != 0)
+		if latest == (auto != 0) {
+			agreeing++
+		}
+	}
+	agreement.LabeledCount = len(human)
+	if len(human) > 0 {
+		agreement.ScorerAgreement = float64(agreeing) / float64(len(human))
+	}
+	if kappa, ok := stats.CohenKappa(human, scorer); ok {
+		agreement.ScorerKappa = &kappa
+	}
+
+	// Every pair of reviewers labeling the same item counts once
+	agreeingPairs := 0
+	for _, index := range order {
+		itemLabels := labels[index]
+		if len(itemLabels) < 2 {
+			continue
+		}
+		agreement.MultiReviewedCount++
+		for a := 0; a < len(itemLabels); a++ {
+			for b := a + 1; b < len(itemLabels); b++ {
+				agreement.ReviewerPairs++
+				if itemLabels[a] == itemLabels[b] {
+					agreeingPairs++
+				}
+			}
+		}
+	}
+	if agreement.ReviewerPairs > 0 {
+		ratio := float64(agreeingPairs) / float64(agreement.ReviewerPairs)
+		agreement.ReviewerAgreement = &ratio
+	}
+
+	return agreement
+}
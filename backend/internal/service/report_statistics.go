@@ -0,0 +1,239 @@
+package service
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/wzyjerry/llm-judge/internal/model"
+	"github.com/wzyjerry/llm-judge/internal/pkg/stats"
+)
+
+const (
+	// scoreHistogramBins is the number of equal-width bins of the score histogram
+	scoreHistogramBins = 10
+	// maxMetaGroups skips meta fields with more distinct values, such as free-text fields
+	maxMetaGroups = 50
+)
+
+// scorePercentiles are the score quantiles reported in the statistics
+var scorePercentiles = []struct {
+	name string
+	q    float64
+}{
+	{"p10", 0.10}, {"p25", 0.25}, {"p50", 0.50}, {"p75", 0.75}, {"p90", 0.90},
+}
+
+// ignoredMetaFields are meta fields never broken down by
+var ignoredMetaFields = map[string]bool{
+	"meta_description": true, // system prompt
+}
+
+// httpStatusPattern matches an HTTP error status code in an error message
+var httpStatusPattern = regexp.MustCompile(`\b[45]\d\d\b`)
+
+// EnrichReportSummary computes the statistics of a report from its results and stores them as summary.statistics.
+// Content that is not a JSON report, as sent by early versions, gets no statistics.
+func EnrichReportSummary(report *model.UserReportCreate) {
+	var content reportContent
+	if err := json.Unmarshal([]byte(report.ReportContent), &content); err != nil {
+		return
+	}
+
+	if report.Summary == nil {
+		report.Summary = make(map[string]interface{})
+	}
+	report.Summary["statistics"] = ComputeReportStatistics(content.Results)
+}
+
+// ComputeReportStatistics computes score, accuracy, latency and error statistics and per meta field breakdowns
+func ComputeReportStatistics(results []model.ReportResult) *model.ReportStatistics {
+	statistics := &model.ReportStatistics{
+		Count: len(results),
+		Errors: model.ErrorStatistics{
+			ByType: make(map[string]int),
+		},
+		MetaBreakdowns: make(map[string][]model.MetaGroup),
+	}
+
+	var scores, latencies, passes []float64
+	for _, r := range results {
+		pass := 0.0
+		if r.IsBadcase == 0 {
+			pass = 1
+		}
+		passes = append(passes, pass)
+
+		if r.Error != "" {
+			statistics.Errors.Count++
+			statistics.Errors.ByType[classifyError(r)]++
+		} else {
+			scores = append(scores, r.Score)
+		}
+		if r.ModelOutput != "" {
+			latencies = append(latencies, r.InferenceTime)
+		}
+	}
+
+	if len(results) > 0 {
+		statistics.Errors.Rate = float64(statistics.Errors.Count) / float64(len(results))
+		statistics.Accuracy.Value = stats.Mean(passes)
+	}
+	statistics.Accuracy.CI = stats.BootstrapMeanCI(passes, bootstrapIterations, bootstrapConfidence, bootstrapSeed)
+	statistics.Score = scoreStatistics(scores)
+	statistics.Latency = latencyStatistics(latencies)
+	statistics.MetaBreakdowns = metaBreakdowns(results)
+
+	return statistics
+}
+
+// scoreStatistics describes the distribution of scores
+func scoreStatistics(scores []float64) model.ScoreStatistics {
+	result := model.ScoreStatistics{
+		Count:       len(scores),
+		Percentiles: make(map[string]float64),
+		Histogram:   stats.Histogram(scores, scoreHistogramBins),
+		MeanCI:      stats.BootstrapMeanCI(scores, bootstrapIterations, bootstrapConfidence, bootstrapSeed),
+	}
+	if len(scores) == 0 {
+		return result
+	}
+
+	result.Mean = stats.Mean(scores)
+	result.Min, result.Max = scores[0], scores[0]
+	variance := 0.0
+	for _, s := range scores {
+		result.Min = math.Min(result.Min, s)
+		result.Max = math.Max(result.Max, s)
+		variance += (s - result.Mean) * (s - result.Mean)
+	}
+	result.StdDev = math.Sqrt(variance / float64(len(scores)))
+
+	for _, p := range scorePercentiles {
+		result.Percentiles[p.name] = stats.Percentile(scores, p.q)
+	}
+	return result
+}
+
+// latencyStatistics describes the distribution of inference times
+func latencyStatistics(latencies []float64) model.LatencyStatistics {
+	result := model.LatencyStatistics{Count: len(latencies)}
+	if len(latencies) == 0 {
+		return result
+	}
+
+	result.Mean = stats.Mean(latencies)
+	result.P50 = stats.Percentile(latencies, 0.50)
+	result.P95 = stats.Percentile(latencies, 0.95)
+	result.P99 = stats.Percentile(latencies, 0.99)
+	result.Max = stats.Percentile(latencies, 1)
+	return result
+}
+
+// classifyError names the kind of failure of an item. Items with a model output failed while scoring,
+// the others while calling the model.
+func classifyError(r model.ReportResult) string {
+	if r.ModelOutput != "" {
+		return "scoring"
+	}
+
+	msg := strings.ToLower(r.Error)
+	switch {
+	case strings.Contains(msg, "timeout") || strings.Contains(msg, "timed out"):
+		return "timeout"
+	case strings.Contains(msg, "429") || strings.Contains(msg, "rate limit") || strings.Contains(msg, "too many requests"):
+		return "rate_limit"
+	case strings.Contains(msg, "connect") || strings.Contains(msg, "refused") || strings.Contains(msg, "unreachable"):
+		return "connection"
+	case httpStatusPattern.MatchString(msg):
+		return "http_error"
+	default:
+		return "other"
+	}
+}
+
+// metaGroupAccumulator sums the metrics of one meta field value
+type metaGroupAccumulator struct {
+	group    model.MetaGroup
+	scoreSum float64
+	scored   int
+}
+
+// metaBreakdowns groups the results by the value of each scalar meta field
+func metaBreakdowns(results []model.ReportResult) map[string][]model.MetaGroup {
+	fields := make(map[string]map[string]*metaGroupAccumulator)
+	for _, r := range results {
+		for field, raw := range r.Meta {
+			if ignoredMetaFields[field] {
+				continue
+			}
+			value, ok := metaValue(raw)
+			if !ok {
+				continue
+			}
+
+			groups := fields[field]
+			if groups == nil {
+				groups = make(map[string]*metaGroupAccumulator)
+				fields[field] = groups
+			}
+			acc := groups[value]
+			if acc == nil {
+				acc = &metaGroupAccumulator{group: model.MetaGroup{Value: value}}
+				groups[value] = acc
+			}
+
+			acc.group.Count++
+			if r.IsBadcase != 0 {
+				acc.group.BadcaseCount++
+			}
+			if r.Error != "" {
+				acc.group.ErrorCount++
+			} else {
+				acc.scoreSum += r.Score
+				acc.scored++
+			}
+		}
+	}
+
+	breakdowns := make(map[string][]model.MetaGroup)
+	for field, groups := range fields {
+		if len(groups) > maxMetaGroups {
+			continue
+		}
+
+		list := make([]model.MetaGroup, 0, len(groups))
+		for _, acc := range groups {
+			group := acc.group
+			group.Accuracy = float64(group.Count-group.BadcaseCount) / float64(group.Count)
+			if acc.scored > 0 {
+				group.AverageScore = acc.scoreSum / float64(acc.scored)
+			}
+			list = append(list, group)
+		}
+		sort.Slice(list, func(i, j int) bool {
+			if list[i].Count != list[j].Count {
+				return list[i].Count > list[j].Count
+			}
+			return list[i].Value < list[j].Value
+		})
+		breakdowns[field] = list
+	}
+
+	return breakdowns
+}
+
+// metaValue formats a scalar meta value as a group key
+func metaValue(raw interface{}) (string, bool) {
+	switch v := raw.(type) {
+	case string:
+		return v, true
+	case float64, bool:
+		return fmt.Sprint(v), true
+	default:
+		return "", false
+	}
+}
@@ -0,0 +1,99 @@
+package service
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/wzyjerry/llm-judge/internal/model"
+	"github.com/wzyjerry/llm-judge/internal/pkg/jwt"
+	"github.com/wzyjerry/llm-judge/internal/repository"
+	"go.uber.org/zap"
+)
+
+// RescoreConfig represents the settings of rescoring an existing report
+type RescoreConfig struct {
+	Scoring          string   `json:"scoring"`
+	ScoringModule    string   `json:"scoring_module"`
+	BadcaseThreshold *float64 `json:"badcase_threshold"` // defaults to the threshold of the parent report
+	MaxWorkers       int      `json:"max_workers"`
+	ReportFormat     string   `json:"report_format"`
+
+	// LLM-as-judge settings of judge scoring functions, defaulting to those of the parent report
+	JudgeConfig
+}
+
+// StartRescore starts a task that scores the stored outputs of a report again with another
+// scoring function or threshold. The result is saved as a new report derived from the parent,
+// no model is called.
+func StartRescore(userID int, parent *model.ReportDetail, config *RescoreConfig) (string, error) {
+	// Generate task ID
+	taskID := newTaskID()
+
+	// Generate JWT token for Python evaluation tasks (for internal API authentication)
+	token, err := jwt.GenerateToken(userID, "system_evaluation")
+	if err != nil {
+		return "", fmt.Errorf("failed to generate auth token: %w", err)
+	}
+
+	configMap := map[string]interface{}{
+		"rescore_report_id": parent.ID,
+		"model":             parent.Model,
+		"data_file":         parent.Dataset,
+		"data_filename":     parent.Dataset,
+		"scoring":           config.Scoring,
+		"scoring_module":    config.ScoringModule,
+		"badcase_threshold": *config.BadcaseThreshold,
+		"max_workers":       config.MaxWorkers,
+		"report_format":     config.ReportFormat,
+	}
+	config.JudgeConfig.addToTaskConfig(configMap)
+
+	// Create task record in database
+	_, err = repository.CreateUserTask(userID, taskID, configMap)
+	if err != nil {
+		return "", fmt.Errorf("failed to create task: %w", err)
+	}
+
+	// Start rescoring in background with auth token
+	go runRescoreTask(userID, taskID, parent.ID, config, token)
+
+	return taskID, nil
+}
+
+// runRescoreTask runs the Python script in rescore mode
+func runRescoreTask(userID int, taskID string, parentReportID int, config *RescoreConfig, authToken string) {
+	zap.L().Info("Starting rescore task",
+		zap.Int("user_id", userID),
+		zap.String("task_id", taskID),
+		zap.Int("parent_report_id", parentReportID))
+
+	args := []string{
+		"--rescore_report_id", strconv.Itoa(parentReportID),
+		"--scoring", config.Scoring,
+		"--scoring_module", config.ScoringModule,
+		"--max_workers", strconv.Itoa(config.MaxWorkers),
+		"--badcase_threshold", strconv.FormatFloat(*config.BadcaseThreshold, 'f', -1, 64),
+		"--report_format", config.ReportFormat,
+		"--output_json",
+		"--user_id", strconv.Itoa(userID),
+		"--task_id", taskID,
+		"--database_service_url", databaseServiceURL(),
+		"--auth_token", authToken,
+	}
+	args = append(args, config.JudgeConfig.args()...)
+	args, cleanup, err := config.JudgeConfig.appendPromptTemplate(args)
+	if err != nil {
+		updateTaskStatus(taskID, "failed", 0, err.Error())
+		return
+	}
+	defer cleanup()
+
+	if err := runMainScript(userID, taskID, args, "", 0, 100); err != nil {
+		if !isTaskCancelled(userID, taskID) {
+			updateTaskStatus(taskID, "failed", 0, err.Error())
+		}
+		return
+	}
+
+	updateTaskStatus(taskID, "completed", 100, "Rescore completed successfully")
+}
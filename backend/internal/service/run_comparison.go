@@ -0,0 +1,155 @@
+package service
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+
+	"github.com/wzyjerry/llm-judge/internal/model"
+	"github.com/wzyjerry/llm-judge/internal/pkg/stats"
+)
+
+const (
+	// bootstrapIterations is the number of resamples for the score difference interval
+	bootstrapIterations = 1000
+	// bootstrapConfidence is the confidence level of the score difference interval
+	bootstrapConfidence = 0.95
+	// bootstrapSeed keeps intervals stable across requests for the same reports
+	bootstrapSeed = 42
+)
+
+// runMetrics are the aggregates computed over the aligned items of a report
+type runMetrics struct {
+	correctCount  float64
+	scoreSum      float64
+	inferenceTime float64
+}
+
+// CompareRuns compares every report after the first one against the first as baseline.
+// Items are aligned by original_index and expanded_index, and only items present
+// in both reports count towards metrics, regressions and significance tests.
+func CompareRuns(reports []*model.UserReport) (*model.RunComparison, error) {
+	if len(reports) < 2 {
+		return nil, fmt.Errorf("at least two reports are required")
+	}
+
+	contents := make([]reportContent, len(reports))
+	for i, report := range reports {
+		if err := json.Unmarshal([]byte(report.ReportContent), &contents[i]); err != nil {
+			return nil, fmt.Errorf("failed to parse report %d: %w", report.ID, err)
+		}
+	}
+
+	baseline := make(map[itemKey]model.ReportResult, len(contents[0].Results))
+	for _, r := range contents[0].Results {
+		baseline[itemKey{r.OriginalIndex, r.ExpandedIndex}] = r
+	}
+
+	comparison := &model.RunComparison{
+		Baseline:    runInfo(reports[0], &contents[0]),
+		Comparisons: make([]model.RunDelta, 0, len(reports)-1),
+	}
+
+	for i := 1; i < len(reports); i++ {
+		delta := model.RunDelta{
+			Run:          runInfo(reports[i], &contents[i]),
+			Regressions:  []model.RunItemChange{},
+			Improvements: []model.RunItemChange{},
+		}
+
+		var base, current runMetrics
+		var scoreDiffs []float64
+		for _, r := range contents[i].Results {
+			b, ok := baseline[itemKey{r.OriginalIndex, r.ExpandedIndex}]
+			if !ok {
+				continue
+			}
+			delta.AlignedCount++
+
+			base.add(b)
+			current.add(r)
+			scoreDiffs = append(scoreDiffs, r.Score-b.Score)
+
+			basePassed := b.IsBadcase == 0
+			passed := r.IsBadcase == 0
+			switch {
+			case basePassed && !passed:
+				delta.Regressions = append(delta.Regressions, itemChange(b, r))
+			case !basePassed && passed:
+				delta.Improvements = append(delta.Improvements, itemChange(b, r))
+			}
+		}
+
+		sortItemChanges(delta.Regressions)
+		sortItemChanges(delta.Improvements)
+
+		n := float64(delta.AlignedCount)
+		delta.Metrics = map[string]model.MetricDelta{
+			"correct_count": metricDelta(base.correctCount, current.correctCount),
+		}
+		if n > 0 {
+			delta.Metrics["accuracy"] = metricDelta(base.correctCount/n, current.correctCount/n)
+			delta.Metrics["average_score"] = metricDelta(base.scoreSum/n, current.scoreSum/n)
+			delta.Metrics["average_inference_time"] = metricDelta(base.inferenceTime/n, current.inferenceTime/n)
+		}
+
+		// Discordant pairs of the baseline (A) and the current run (B)
+		delta.McNemar = stats.McNemar(len(delta.Regressions), len(delta.Improvements))
+		delta.ScoreDiffCI = stats.BootstrapMeanCI(scoreDiffs, bootstrapIterations, bootstrapConfidence, bootstrapSeed)
+
+		comparison.Comparisons = append(comparison.Comparisons, delta)
+	}
+
+	return comparison, nil
+}
+
+// add accumulates one item result
+func (m *runMetrics) add(r model.ReportResult) {
+	if r.IsBadcase == 0 {
+		m.correctCount++
+	}
+	m.scoreSum += r.Score
+	m.inferenceTime += r.InferenceTime
+}
+
+// runInfo describes a compared report
+func runInfo(report *model.UserReport, content *reportContent) model.RunInfo {
+	return model.RunInfo{
+		ReportID:  report.ID,
+		TaskID:    report.TaskID,
+		Dataset:   report.Dataset,
+		Model:     report.Model,
+		Timestamp: report.Timestamp,
+		ItemCount: len(content.Results),
+		Summary:   content.Summary,
+	}
+}
+
+// metricDelta returns a metric of the baseline and the current run with their difference
+func metricDelta(baseline, current float64) model.MetricDelta {
+	return model.MetricDelta{Baseline: baseline, Current: current, Delta: current - baseline}
+}
+
+// itemChange describes an item whose outcome differs between the baseline and the current run
+func itemChange(baseline, current model.ReportResult) model.RunItemChange {
+	return model.RunItemChange{
+		OriginalIndex:   current.OriginalIndex,
+		ExpandedIndex:   current.ExpandedIndex,
+		UserInput:       current.UserInput,
+		ReferenceOutput: current.ReferenceOutput,
+		BaselineOutput:  baseline.ModelOutput,
+		BaselineScore:   baseline.Score,
+		CurrentOutput:   current.ModelOutput,
+		CurrentScore:    current.Score,
+	}
+}
+
+// sortItemChanges orders item changes by dataset position
+func sortItemChanges(changes []model.RunItemChange) {
+	sort.Slice(changes, func(i, j int) bool {
+		if changes[i].OriginalIndex != changes[j].OriginalIndex {
+			return changes[i].OriginalIndex < changes[j].OriginalIndex
+		}
+		return changes[i].ExpandedIndex < changes[j].ExpandedIndex
+	})
+}
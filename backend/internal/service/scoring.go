@@ -0,0 +1,4 @@
+package service
+
+// DefaultScoringModule is the scoring module loaded when a task does not name one
+const DefaultScoringModule = "./function_register/plugin.py"
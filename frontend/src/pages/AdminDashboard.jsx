@@ -12,7 +12,8 @@ import {
     DatabaseOutlined,
     SettingOutlined,
     PlusOutlined,
-    EditOutlined
+    EditOutlined,
+    ApiOutlined
 } from '@ant-design/icons'
 import { useNavigate } from 'react-router-dom'
 import {
@@ -22,10 +23,14 @@ import {
     terminateAdminTask,
     getAdminData,
     deleteAdminData,
+    setAdminDataLibrary,
     getAdminModelConfigs,
     createAdminModelConfig,
     updateAdminModelConfig,
-    deleteAdminModelConfig
+    deleteAdminModelConfig,
+    testAdminModelConfig,
+    discoverAdminModels,
+    importAdminDiscoveredModels
 } from '../services/api'
 import useStore from '../stores'
 import dayjs from 'dayjs'
@@ -50,6 +55,13 @@ export default function AdminDashboard() {
     const [editingConfig, setEditingConfig] = useState(null)
     const [modelConfigForm] = Form.useForm()
 
+    // Model discovery modal state
+    const [discoverModalVisible, setDiscoverModalVisible] = useState(false)
+    const [discoverForm] = Form.useForm()
+    const [discoveredModels, setDiscoveredModels] = useState([])
+    const [selectedModelNames, setSelectedModelNames] = useState([])
+    const [discovering, setDiscovering] = useState(false)
+
     // Fetch data functions
     const fetchUsers = async () => {
         setLoading(true)
@@ -137,6 +149,16 @@ export default function AdminDashboard() {
         }
     }
 
+    const handleToggleLibrary = async (dataId, inLibrary) => {
+        try {
+            await setAdminDataLibrary(dataId, inLibrary)
+            message.success(inLibrary ? 'Added to the benchmark library' : 'Removed from the benchmark library')
+            fetchDataFiles()
+        } catch (err) {
+            message.error('Update failed: ' + err.message)
+        }
+    }
+
     const handleOpenModelConfigModal = (config = null) => {
         setEditingConfig(config)
         if (config) {
@@ -198,6 +220,59 @@ export default function AdminDashboard() {
         }
     }
 
+    const handleTestModelConfig = async (configId) => {
+        try {
+            const res = await testAdminModelConfig(configId)
+            if (res.health_status === 'up') {
+                message.success(`${res.model_name}: all endpoints are up`)
+            } else {
+                const failed = (res.health || []).filter(h => h.status !== 'up')
+                message.warning(`${res.model_name}: ${res.health_status}` + (failed.length ? ` (${failed.map(h => `${h.api_url}: ${h.error}`).join('; ')})` : ''))
+            }
+            fetchModelConfigs()
+        } catch (err) {
+            message.error('Test failed: ' + err.message)
+        }
+    }
+
+    const handleDiscoverModels = async () => {
+        try {
+            const values = await discoverForm.validateFields()
+            setDiscovering(true)
+            const res = await discoverAdminModels(values.api_url.trim(), values.api_key || '')
+            setDiscoveredModels(res.models || [])
+            setSelectedModelNames([])
+        } catch (err) {
+            if (err.errorFields) return
+            message.error('Discover failed: ' + err.message)
+        } finally {
+            setDiscovering(false)
+        }
+    }
+
+    const handleImportDiscoveredModels = async () => {
+        if (selectedModelNames.length === 0) {
+            message.warning('Please select at least one model')
+            return
+        }
+        try {
+            const values = discoverForm.getFieldsValue()
+            const res = await importAdminDiscoveredModels(values.api_url.trim(), values.api_key || '', selectedModelNames)
+            const skipped = Object.entries(res.skipped || {})
+            if (skipped.length > 0) {
+                message.warning(`${res.message}, skipped: ${skipped.map(([name, reason]) => `${name} (${reason})`).join('; ')}`)
+            } else {
+                message.success(res.message)
+            }
+            setDiscoverModalVisible(false)
+            setDiscoveredModels([])
+            discoverForm.resetFields()
+            fetchModelConfigs()
+        } catch (err) {
+            message.error('Import failed: ' + err.message)
+        }
+    }
+
     const handleLogout = () => {
         logout()
         navigate('/login')
@@ -363,6 +438,18 @@ export default function AdminDashboard() {
             key: 'created_at',
             render: (text) => dayjs(text).format('MM-DD HH:mm')
         },
+        {
+            title: 'Library',
+            dataIndex: 'is_public',
+            key: 'is_public',
+            render: (isPublic, record) => (
+                <Switch
+                    size="small"
+                    checked={isPublic}
+                    onChange={(checked) => handleToggleLibrary(record.id, checked)}
+                />
+            )
+        },
         {
             title: 'Action',
             key: 'action',
@@ -391,7 +478,12 @@ export default function AdminDashboard() {
             title: 'Model Name',
             dataIndex: 'model_name',
             key: 'model_name',
-            render: (text) => <Text strong>{text}</Text>
+            render: (text, record) => (
+                <Space>
+                    <Text strong>{text}</Text>
+                    {record.upstream_missing ? <Tag color="error">Missing upstream</Tag> : null}
+                </Space>
+            )
         },
         {
             title: 'API URLs',
@@ -459,12 +551,34 @@ export default function AdminDashboard() {
                 </Tag>
             )
         },
+        {
+            title: 'Health',
+            dataIndex: 'health_status',
+            key: 'health_status',
+            width: 100,
+            render: (status, record) => {
+                const colors = { up: 'success', degraded: 'warning', down: 'error' }
+                const latency = (record.health || []).map(h => `${h.api_url}: ${h.status} (${h.latency_ms}ms)`).join('\n')
+                return (
+                    <Tag color={colors[status] || 'default'} title={latency}>
+                        {status || 'unknown'}
+                    </Tag>
+                )
+            }
+        },
         {
             title: 'Action',
             key: 'action',
-            width: 150,
+            width: 230,
             render: (_, record) => (
                 <Space>
+                    <Button
+                        icon={<ApiOutlined />}
+                        size="small"
+                        onClick={() => handleTestModelConfig(record.id)}
+                    >
+                        Test
+                    </Button>
                     <Button
                         icon={<EditOutlined />}
                         size="small"
@@ -590,6 +704,14 @@ export default function AdminDashboard() {
                                     <Tag>{activeTab === 'users' ? (users?.length || 0) : (activeTab === 'tasks' ? (tasks?.length || 0) : (activeTab === 'data' ? (dataFiles?.length || 0) : (modelConfigs?.length || 0)))} Total</Tag>
                                 </Space>
                                 <Space>
+                                    {activeTab === 'models' && (
+                                        <Button
+                                            icon={<ApiOutlined />}
+                                            onClick={() => setDiscoverModalVisible(true)}
+                                        >
+                                            Discover Models
+                                        </Button>
+                                    )}
                                     {activeTab === 'models' && (
                                         <Button
                                             type="primary"
@@ -626,6 +748,63 @@ export default function AdminDashboard() {
                 </Content>
             </Layout>
 
+            {/* Model Discovery Modal */}
+            <Modal
+                title="Discover Models"
+                open={discoverModalVisible}
+                onOk={handleImportDiscoveredModels}
+                onCancel={() => {
+                    setDiscoverModalVisible(false)
+                    setDiscoveredModels([])
+                    discoverForm.resetFields()
+                }}
+                width={700}
+                okText="Create Selected"
+                cancelText="Cancel"
+                okButtonProps={{ disabled: selectedModelNames.length === 0 }}
+                centered
+            >
+                <Form form={discoverForm} layout="vertical">
+                    <Form.Item
+                        name="api_url"
+                        label="API URL"
+                        rules={[{ required: true, message: 'Please input API URL' }]}
+                    >
+                        <Input placeholder="http://localhost:8000/v1" />
+                    </Form.Item>
+                    <Form.Item name="api_key" label="API Key">
+                        <Input.Password placeholder="Optional" />
+                    </Form.Item>
+                    <Button icon={<ApiOutlined />} onClick={handleDiscoverModels} loading={discovering}>
+                        List Models
+                    </Button>
+                </Form>
+                <Table
+                    style={{ marginTop: 16 }}
+                    size="small"
+                    rowKey="id"
+                    dataSource={discoveredModels}
+                    pagination={false}
+                    rowSelection={{
+                        selectedRowKeys: selectedModelNames,
+                        onChange: (keys) => setSelectedModelNames(keys),
+                        getCheckboxProps: (record) => ({ disabled: record.exists })
+                    }}
+                    columns={[
+                        { title: 'Model', dataIndex: 'id', key: 'id' },
+                        { title: 'Owned By', dataIndex: 'owned_by', key: 'owned_by', width: 100 },
+                        {
+                            title: 'Status',
+                            key: 'exists',
+                            width: 120,
+                            render: (_, record) => record.exists
+                                ? <Tag>Configured</Tag>
+                                : <Tag color="blue">New</Tag>
+                        }
+                    ]}
+                />
+            </Modal>
+
             {/* Model Config Modal */}
             <Modal
                 title={editingConfig ? 'Edit Model Config' : 'Add Model Config'}
@@ -1,14 +1,16 @@
 import { useState, useEffect } from 'react'
 import { useParams, useNavigate } from 'react-router-dom'
-import { Modal, Button, Input, Select, Radio, message, Space, Divider, Checkbox, Upload } from 'antd'
-import { EditOutlined, SaveOutlined, CloseOutlined, DeleteOutlined, PlusOutlined, UploadOutlined, ExclamationCircleOutlined } from '@ant-design/icons'
+import { Modal, Button, Input, Select, Radio, message, Space, Divider, Checkbox, Upload, Pagination, Alert } from 'antd'
+import { EditOutlined, SaveOutlined, CloseOutlined, DeleteOutlined, PlusOutlined, UploadOutlined, DownloadOutlined, BarChartOutlined, ExclamationCircleOutlined } from '@ant-design/icons'
 import useStore from '../stores'
-import { editUserDataContent, editSingleItemComplete, deleteSingleItem, batchDeleteItems, addSingleItem, appendDataFile } from '../services/api'
+import { editUserDataContent, editSingleItemComplete, deleteSingleItem, batchDeleteItems, addSingleItem, appendDataFile, downloadUserData, getDataStats } from '../services/api'
 import './DataDetailPage.css'
 
 const { TextArea } = Input
 const { Option } = Select
 
+const PAGE_SIZE = 50
+
 export default function DataDetailPage() {
   const { dataId } = useParams()
   const navigate = useNavigate()
@@ -16,7 +18,10 @@ export default function DataDetailPage() {
   
   const [expandedRows, setExpandedRows] = useState(new Set())
   const [searchText, setSearchText] = useState('')
-  const [filteredData, setFilteredData] = useState([])
+  const [searchQuery, setSearchQuery] = useState('') // 输入停顿后提交给服务端的搜索词
+  const [roleFilter, setRoleFilter] = useState('')
+  const [page, setPage] = useState(1)
+  const [pageSize, setPageSize] = useState(PAGE_SIZE)
   
   // 编辑相关状态
   const [editingItems, setEditingItems] = useState({}) // {originalIndex: editedJsonString}
@@ -28,6 +33,11 @@ export default function DataDetailPage() {
   
   // 批量选择和删除相关状态
   const [selectedItems, setSelectedItems] = useState(new Set()) // 选中的数据索引集合
+  const [exportFormat, setExportFormat] = useState('jsonl')
+  const [exporting, setExporting] = useState(false)
+  const [stats, setStats] = useState(null)
+  const [statsVisible, setStatsVisible] = useState(false)
+  const [statsLoading, setStatsLoading] = useState(false)
   const [batchDeleting, setBatchDeleting] = useState(false)
   
   // 添加数据相关状态
@@ -38,18 +48,18 @@ export default function DataDetailPage() {
   // 导入追加相关状态
   const [importing, setImporting] = useState(false)
   
-  // 获取所有可用的角色
-  const getAllRoles = () => {
-    if (!currentDataDetail?.data) return []
-    const roles = new Set()
-    currentDataDetail.data.forEach(item => {
-      if (item.turns && Array.isArray(item.turns)) {
-        item.turns.forEach(turn => {
-          if (turn.role) roles.add(turn.role)
-        })
-      }
+  // 当前页的数据及其在文件中的索引
+  const pageData = currentDataDetail?.data || []
+  const pageIndices = currentDataDetail?.indices || []
+
+  // 按当前分页、搜索和角色筛选条件加载数据
+  const loadContent = () => {
+    return fetchDataContent(parseInt(dataId), {
+      offset: (page - 1) * pageSize,
+      limit: pageSize,
+      q: searchQuery || undefined,
+      role: roleFilter || undefined,
     })
-    return Array.from(roles)
   }
 
   useEffect(() => {
@@ -57,28 +67,26 @@ export default function DataDetailPage() {
       navigate('/data')
       return
     }
-    fetchDataContent(parseInt(dataId))
-    
+
     return () => {
       clearDataDetail()
     }
-  }, [dataId, fetchDataContent, clearDataDetail])
+  }, [dataId, clearDataDetail])
 
   useEffect(() => {
-    if (currentDataDetail?.data) {
-      const data = currentDataDetail.data
-      if (searchText.trim()) {
-        const lowerSearch = searchText.toLowerCase()
-        const filtered = data.filter(item => {
-          const itemStr = JSON.stringify(item).toLowerCase()
-          return itemStr.includes(lowerSearch)
-        })
-        setFilteredData(filtered)
-      } else {
-        setFilteredData(data)
-      }
+    if (dataId) {
+      loadContent().catch(() => {})
     }
-  }, [currentDataDetail, searchText])
+  }, [dataId, page, pageSize, searchQuery, roleFilter, fetchDataContent])
+
+  // 输入停顿后再搜索，避免每次按键都请求服务端
+  useEffect(() => {
+    const timer = setTimeout(() => {
+      setSearchQuery(searchText.trim())
+      setPage(1)
+    }, 300)
+    return () => clearTimeout(timer)
+  }, [searchText])
 
   const toggleRowExpand = (index) => {
     const newExpanded = new Set(expandedRows)
@@ -185,7 +193,7 @@ export default function DataDetailPage() {
       return
     }
     
-    const originalItem = currentDataDetail.data[originalIndex]
+    const originalItem = pageData[pageIndices.indexOf(originalIndex)]
     
     // 验证编辑后的JSON
     const validation = validateEditedJson(originalItem, editedJsonString)
@@ -225,7 +233,7 @@ export default function DataDetailPage() {
       setEditingItems(newEditingItems)
       
       // 重新加载数据
-      await fetchDataContent(parseInt(dataId))
+      await loadContent()
     } catch (err) {
       message.error('保存失败: ' + (err.message || '未知错误'))
     }
@@ -276,7 +284,7 @@ export default function DataDetailPage() {
       message.success('批量编辑成功，已更新所有数据')
       
       // 重新加载数据
-      await fetchDataContent(parseInt(dataId))
+      await loadContent()
       setBatchEditModalVisible(false)
       setBatchEditValue('')
     } catch (err) {
@@ -305,7 +313,7 @@ export default function DataDetailPage() {
           newSelected.delete(originalIndex)
           setSelectedItems(newSelected)
           // 重新加载数据
-          await fetchDataContent(parseInt(dataId))
+          await loadContent()
         } catch (err) {
           message.error('删除失败: ' + (err.message || '未知错误'))
         }
@@ -327,10 +335,7 @@ export default function DataDetailPage() {
   // 全选/取消全选
   const handleSelectAll = (checked) => {
     if (checked) {
-      const allIndices = new Set(filteredData.map((item, index) => {
-        return currentDataDetail.data.findIndex(d => d === item)
-      }))
-      setSelectedItems(allIndices)
+      setSelectedItems(new Set(pageIndices))
     } else {
       setSelectedItems(new Set())
     }
@@ -359,7 +364,7 @@ export default function DataDetailPage() {
           message.success(`成功删除 ${itemIndices.length} 条数据`)
           setSelectedItems(new Set())
           // 重新加载数据
-          await fetchDataContent(parseInt(dataId))
+          await loadContent()
         } catch (err) {
           message.error('批量删除失败: ' + (err.message || '未知错误'))
         } finally {
@@ -390,7 +395,7 @@ export default function DataDetailPage() {
       setAddItemModalVisible(false)
       setNewItemJson('')
       // 重新加载数据
-      await fetchDataContent(parseInt(dataId))
+      await loadContent()
     } catch (err) {
       if (err.message.includes('JSON')) {
         message.error('JSON格式错误: ' + err.message)
@@ -402,10 +407,47 @@ export default function DataDetailPage() {
     }
   }
 
+  // 打开数据统计弹窗
+  const handleOpenStats = async () => {
+    setStatsVisible(true)
+    try {
+      setStatsLoading(true)
+      setStats(await getDataStats(parseInt(dataId)))
+    } catch (err) {
+      message.error('获取统计信息失败: ' + (err.message || '未知错误'))
+      setStatsVisible(false)
+    } finally {
+      setStatsLoading(false)
+    }
+  }
+
+  // 按出现次数从多到少排列计数对象
+  const sortedCounts = (counts) => Object.entries(counts || {}).sort((a, b) => b[1] - a[1])
+
+  // 导出数据：有选中数据时只导出选中的数据
+  const handleExport = async () => {
+    try {
+      setExporting(true)
+      const indices = Array.from(selectedItems).sort((a, b) => a - b)
+      const blob = await downloadUserData(parseInt(dataId), exportFormat, indices)
+      const baseName = (currentDataDetail?.filename || `data_${dataId}`).replace(/\.[^/.]+$/, '')
+      const suffix = exportFormat === 'openai' || exportFormat === 'sharegpt' ? `_${exportFormat}` : ''
+      const link = document.createElement('a')
+      link.href = URL.createObjectURL(blob)
+      link.download = `${baseName}${suffix}.${exportFormat === 'csv' ? 'csv' : 'jsonl'}`
+      link.click()
+      URL.revokeObjectURL(link.href)
+    } catch (err) {
+      message.error('导出失败: ' + (err.message || '未知错误'))
+    } finally {
+      setExporting(false)
+    }
+  }
+
   // 处理文件导入追加
   const handleFileImport = async (file) => {
-    if (!file.name.endsWith('.jsonl') && !file.name.endsWith('.csv')) {
-      message.error('只支持 .jsonl 和 .csv 格式的文件')
+    if (!['.jsonl', '.json', '.csv', '.parquet'].some(ext => file.name.toLowerCase().endsWith(ext))) {
+      message.error('只支持 .jsonl、.json、.csv 和 .parquet 格式的文件')
       return false
     }
     
@@ -414,7 +456,7 @@ export default function DataDetailPage() {
       const result = await appendDataFile(parseInt(dataId), file)
       message.success(`成功导入 ${result.added_count} 条数据，当前共 ${result.total_count} 条数据`)
       // 重新加载数据
-      await fetchDataContent(parseInt(dataId))
+      await loadContent()
       return false // 阻止默认上传行为
     } catch (err) {
       message.error('导入失败: ' + (err.message || '未知错误'))
@@ -432,21 +474,7 @@ export default function DataDetailPage() {
     return <div className="error-message">无法加载数据详情</div>
   }
 
-  if (currentDataDetail.error) {
-    return (
-      <div className="data-detail-container">
-        <div className="detail-header">
-          <button onClick={() => navigate('/data')} className="back-button">← 返回</button>
-          <h1>{currentDataDetail.filename}</h1>
-        </div>
-        <div className="error-message" style={{ marginTop: '20px' }}>
-          {currentDataDetail.error}
-        </div>
-      </div>
-    )
-  }
-
-  const allRoles = getAllRoles()
+  const allRoles = currentDataDetail.roles || []
 
   return (
     <div className="data-detail-container">
@@ -465,6 +493,25 @@ export default function DataDetailPage() {
         </div>
       </div>
 
+      {currentDataDetail.error_count > 0 && (
+        <Alert
+          type="warning"
+          showIcon
+          style={{ marginBottom: 16 }}
+          message={`有 ${currentDataDetail.error_count} 行无法解析，已跳过`}
+          description={
+            <div style={{ maxHeight: 160, overflowY: 'auto' }}>
+              {currentDataDetail.errors.map(err => (
+                <div key={err.line}>第 {err.line} 行：{err.error}</div>
+              ))}
+              {currentDataDetail.error_count > currentDataDetail.errors.length && (
+                <div>……</div>
+              )}
+            </div>
+          }
+        />
+      )}
+
       {/* 操作按钮区域 */}
       <div style={{ marginBottom: 16, padding: '12px', background: '#f5f5f5', borderRadius: '4px', display: 'flex', gap: 8, flexWrap: 'wrap', alignItems: 'center' }}>
         <Button
@@ -482,7 +529,7 @@ export default function DataDetailPage() {
           添加数据
         </Button>
         <Upload
-          accept=".jsonl,.csv"
+          accept=".jsonl,.json,.csv,.parquet"
           showUploadList={false}
           beforeUpload={handleFileImport}
           disabled={importing}
@@ -494,6 +541,30 @@ export default function DataDetailPage() {
             导入追加
           </Button>
         </Upload>
+        <Button
+          icon={<BarChartOutlined />}
+          onClick={handleOpenStats}
+        >
+          数据统计
+        </Button>
+        <Select
+          value={exportFormat}
+          onChange={setExportFormat}
+          options={[
+            { value: 'jsonl', label: 'JSONL' },
+            { value: 'csv', label: 'CSV' },
+            { value: 'openai', label: 'OpenAI' },
+            { value: 'sharegpt', label: 'ShareGPT' },
+          ]}
+          style={{ width: 120 }}
+        />
+        <Button
+          icon={<DownloadOutlined />}
+          onClick={handleExport}
+          loading={exporting}
+        >
+          {selectedItems.size > 0 ? `导出选中 (${selectedItems.size})` : '导出全部'}
+        </Button>
         {selectedItems.size > 0 && (
           <Button
             danger
@@ -509,45 +580,58 @@ export default function DataDetailPage() {
       <div className="search-section">
         <div style={{ display: 'flex', alignItems: 'center', gap: 12 }}>
           <Checkbox
-            checked={selectedItems.size > 0 && selectedItems.size === filteredData.length}
-            indeterminate={selectedItems.size > 0 && selectedItems.size < filteredData.length}
+            checked={pageIndices.length > 0 && pageIndices.every(i => selectedItems.has(i))}
+            indeterminate={selectedItems.size > 0 && !pageIndices.every(i => selectedItems.has(i))}
             onChange={(e) => handleSelectAll(e.target.checked)}
           >
             全选
           </Checkbox>
           <input
             type="text"
-            placeholder="搜索数据（支持任意字段）..."
+            placeholder="搜索对话内容..."
             value={searchText}
             onChange={(e) => setSearchText(e.target.value)}
             className="search-input"
             style={{ flex: 1 }}
           />
+          <Select
+            value={roleFilter}
+            onChange={(value) => {
+              setRoleFilter(value)
+              setPage(1)
+            }}
+            style={{ width: 160 }}
+          >
+            <Option value="">全部角色</Option>
+            {allRoles.map(role => (
+              <Option key={role} value={role}>{role}</Option>
+            ))}
+          </Select>
           <span className="search-result">
-            {filteredData.length} / {currentDataDetail.total_count} 条
+            {currentDataDetail.matched_count} / {currentDataDetail.total_count} 条
             {selectedItems.size > 0 && ` (已选 ${selectedItems.size})`}
           </span>
         </div>
       </div>
 
       <div className="data-list-container">
-        {filteredData.length === 0 ? (
+        {pageData.length === 0 ? (
           <div className="empty-state">
             <p>没有匹配的数据</p>
           </div>
         ) : (
           <div className="data-items">
-            {filteredData.map((item, index) => {
-              const originalIndex = currentDataDetail.data.findIndex(d => d === item)
+            {pageData.map((item, index) => {
+              const originalIndex = pageIndices[index]
               const editedJson = editingItems[originalIndex]
               const displayJson = editedJson || JSON.stringify(item, null, 2)
               const isEditing = !!editingItems[originalIndex]
               
               return (
-                <div key={index} className="data-item">
+                <div key={originalIndex} className="data-item">
                   <div 
                     className="item-header"
-                    onClick={() => toggleRowExpand(index)}
+                    onClick={() => toggleRowExpand(originalIndex)}
                   >
                     <Checkbox
                       checked={selectedItems.has(originalIndex)}
@@ -559,7 +643,7 @@ export default function DataDetailPage() {
                       style={{ marginRight: 8 }}
                     />
                     <button className="expand-button">
-                      {expandedRows.has(index) ? '▼' : '▶'}
+                      {expandedRows.has(originalIndex) ? '▼' : '▶'}
                     </button>
                     <span className="item-index">第 {originalIndex + 1} 条</span>
                     <div className="item-preview">
@@ -587,7 +671,7 @@ export default function DataDetailPage() {
                     </Button>
                   </div>
 
-                  {expandedRows.has(index) && (
+                  {expandedRows.has(originalIndex) && (
                     <div className="item-content">
                       <div className="json-container">
                         <div style={{ marginBottom: 8, display: 'flex', gap: 8 }}>
@@ -642,6 +726,21 @@ export default function DataDetailPage() {
             })}
           </div>
         )}
+        {currentDataDetail.matched_count > pageSize && (
+          <Pagination
+            current={page}
+            pageSize={pageSize}
+            total={currentDataDetail.matched_count}
+            onChange={(newPage, newPageSize) => {
+              setPage(newPageSize !== pageSize ? 1 : newPage)
+              setPageSize(newPageSize)
+              setExpandedRows(new Set())
+            }}
+            pageSizeOptions={[20, 50, 100, 200]}
+            showSizeChanger
+            style={{ marginTop: 16, textAlign: 'right' }}
+          />
+        )}
       </div>
 
       {/* 批量编辑模态框 */}
@@ -766,6 +865,74 @@ export default function DataDetailPage() {
           </span>
         </div>
       </Modal>
+      {/* 数据统计弹窗 */}
+      <Modal
+        title="数据统计"
+        open={statsVisible}
+        onCancel={() => setStatsVisible(false)}
+        footer={null}
+        width={720}
+      >
+        {statsLoading || !stats ? (
+          <div>统计中...</div>
+        ) : (
+          <div style={{ maxHeight: 560, overflowY: 'auto' }}>
+            <p>
+              版本 v{stats.version}，共 {stats.item_count} 条数据
+              {stats.parse_error_count > 0 && `，${stats.parse_error_count} 行无法解析`}
+            </p>
+
+            <Divider orientation="left">轮次分布</Divider>
+            <p>平均 {stats.turns.mean.toFixed(2)} 轮（最少 {stats.turns.min}，最多 {stats.turns.max}）</p>
+            <Space wrap>
+              {stats.turns.distribution.map(bucket => (
+                <span key={bucket.turns}>{bucket.turns} 轮: {bucket.items} 条</span>
+              ))}
+            </Space>
+
+            <Divider orientation="left">各角色长度（字符 / 估算 token）</Divider>
+            <table style={{ width: '100%' }}>
+              <thead>
+                <tr><th>角色</th><th>轮次数</th><th>平均</th><th>P50</th><th>P90</th><th>最大</th></tr>
+              </thead>
+              <tbody>
+                {Object.entries(stats.roles).map(([role, roleStats]) => (
+                  <tr key={role}>
+                    <td>{role}</td>
+                    <td>{roleStats.turn_count}</td>
+                    <td>{roleStats.characters.mean.toFixed(1)} / {roleStats.tokens.mean.toFixed(1)}</td>
+                    <td>{roleStats.characters.percentiles.p50.toFixed(0)} / {roleStats.tokens.percentiles.p50.toFixed(0)}</td>
+                    <td>{roleStats.characters.percentiles.p90.toFixed(0)} / {roleStats.tokens.percentiles.p90.toFixed(0)}</td>
+                    <td>{roleStats.characters.max} / {roleStats.tokens.max}</td>
+                  </tr>
+                ))}
+              </tbody>
+            </table>
+
+            <Divider orientation="left">重复数据</Divider>
+            <p>完全重复 {stats.duplicates.exact_count} 条（{stats.duplicates.exact_group_count} 组），提示词重复 {stats.duplicates.prompt_count} 条（{stats.duplicates.prompt_group_count} 组）</p>
+            {stats.duplicates.exact_groups.slice(0, 10).map((group, i) => (
+              <div key={i}>完全重复：#{group.indices.map(index => index + 1).join(', #')}</div>
+            ))}
+
+            <Divider orientation="left">语言分布</Divider>
+            <Space wrap>
+              {sortedCounts(stats.languages).map(([language, count]) => (
+                <span key={language}>{language}: {count}</span>
+              ))}
+            </Space>
+
+            <Divider orientation="left">meta 字段取值</Divider>
+            {Object.entries(stats.meta_fields).map(([field, fieldStats]) => (
+              <div key={field} style={{ marginBottom: 8 }}>
+                <strong>{field}</strong>（{fieldStats.present} 条，{fieldStats.distinct_count} 种取值）：
+                {fieldStats.values.slice(0, 10).map(value => `${value.value} (${value.count})`).join('，')}
+                {fieldStats.distinct_count > 10 && ' ……'}
+              </div>
+            ))}
+          </div>
+        )}
+      </Modal>
     </div>
   )
 }
@@ -1,10 +1,55 @@
 import { useState, useEffect } from 'react'
 import { useNavigate } from 'react-router-dom'
-import { Modal, Input, message } from 'antd'
+import { Modal, Input, InputNumber, Select, Button, Alert, Radio, message } from 'antd'
 import { ExclamationCircleOutlined } from '@ant-design/icons'
 import useStore from '../stores'
+import * as api from '../services/api'
 import './DataManagePage.css'
 
+// 支持导入的文件类型
+const IMPORTABLE_EXTENSIONS = ['.jsonl', '.json', '.csv', '.parquet']
+
+// 导入时可选的记录格式
+const IMPORT_FORMATS = [
+  { value: 'auto', label: '自动识别' },
+  { value: 'native', label: 'meta/turns 原生格式' },
+  { value: 'openai', label: 'OpenAI 对话格式（messages）' },
+  { value: 'sharegpt', label: 'ShareGPT（conversations）' },
+  { value: 'alpaca', label: 'Alpaca（instruction/input/output）' },
+  { value: 'columns', label: '按列映射（CSV / Parquet）' },
+]
+
+// 共享权限
+const SHARE_PERMISSIONS = [
+  { value: 'read', label: '只读' },
+  { value: 'write', label: '可编辑' },
+]
+
+const PERMISSION_LABELS = { read: '只读', write: '可编辑', owner: '所有者' }
+
+// 数据集操作
+const OPERATION_LABELS = { sample: '抽样', split: '切分', merge: '合并', dedupe: '去重' }
+
+// 去重方式
+const DEDUPE_MODES = [
+  { value: 'exact', label: '完全相同' },
+  { value: 'prompt', label: '提示相同（meta_description 与 user 轮次）' },
+]
+
+// 派生数据的来源说明，如 "抽样自 a.jsonl v2（seed 42）"
+const describeProvenance = (provenance) => {
+  const sources = provenance.sources.map(source => `${source.filename} v${source.version}`).join('、')
+  const part = provenance.part ? `，${provenance.part}` : ''
+  const seed = provenance.params?.seed !== undefined ? `，seed ${provenance.params.seed}` : ''
+  return `${OPERATION_LABELS[provenance.operation] || provenance.operation}自 ${sources}${part}${seed}`
+}
+
+const DEFAULT_MAPPING = JSON.stringify({
+  system: 'system',
+  turns: [{ column: 'question', role: 'Human' }, { column: 'answer', role: 'assistant' }],
+  meta: [],
+}, null, 2)
+
 export default function DataManagePage() {
   const navigate = useNavigate()
   const { userDataFiles, fetchUserDataFiles, uploadUserDataFile, deleteUserDataFile, loading, error } = useStore()
@@ -13,17 +58,163 @@ export default function DataManagePage() {
   const [descriptionModalVisible, setDescriptionModalVisible] = useState(false)
   const [description, setDescription] = useState('')
   const [pendingFile, setPendingFile] = useState(null)
+  const [importFormat, setImportFormat] = useState('auto')
+  const [mappingText, setMappingText] = useState(DEFAULT_MAPPING)
+  const [preview, setPreview] = useState(null)
+  const [previewing, setPreviewing] = useState(false)
+  const [exporting, setExporting] = useState(false)
+  const [sharedFiles, setSharedFiles] = useState([])
+  const [libraryFiles, setLibraryFiles] = useState([])
+  const [shareTarget, setShareTarget] = useState(null)
+  const [shares, setShares] = useState([])
+  const [myTeams, setMyTeams] = useState([])
+  const [shareWith, setShareWith] = useState('user')
+  const [shareUsername, setShareUsername] = useState('')
+  const [shareTeamId, setShareTeamId] = useState(null)
+  const [sharePermission, setSharePermission] = useState('read')
+  const [sharing, setSharing] = useState(false)
+  const [operationTarget, setOperationTarget] = useState(null)
+  const [operation, setOperation] = useState('sample')
+  const [sampleSize, setSampleSize] = useState(100)
+  const [trainRatio, setTrainRatio] = useState(0.8)
+  const [stratifyBy, setStratifyBy] = useState('')
+  const [seed, setSeed] = useState(null)
+  const [dedupeMode, setDedupeMode] = useState('exact')
+  const [mergeVisible, setMergeVisible] = useState(false)
+  const [mergeIds, setMergeIds] = useState([])
+  const [operating, setOperating] = useState(false)
 
   useEffect(() => {
     fetchUserDataFiles()
+    fetchSharedFiles()
   }, [])
 
+  // 获取共享给我的数据和公共基准库
+  const fetchSharedFiles = async () => {
+    try {
+      const [shared, library] = await Promise.all([api.getSharedDataFiles(), api.getDataLibrary()])
+      setSharedFiles(shared.data_files || [])
+      setLibraryFiles(library.data_files || [])
+    } catch (err) {
+      message.error(err.message || '获取共享数据失败')
+    }
+  }
+
+  // 打开共享弹窗
+  const handleOpenShare = async (dataFile) => {
+    setShareTarget(dataFile)
+    setShareUsername('')
+    setShareTeamId(null)
+    setSharePermission('read')
+    try {
+      const [shareRes, teamRes] = await Promise.all([api.getDataShares(dataFile.id), api.getMyTeams()])
+      setShares(shareRes.shares || [])
+      setMyTeams(teamRes.teams || [])
+    } catch (err) {
+      message.error(err.message || '获取共享列表失败')
+    }
+  }
+
+  const handleShare = async () => {
+    const share = { permission: sharePermission }
+    if (shareWith === 'user') {
+      if (!shareUsername.trim()) {
+        message.warning('请输入用户名')
+        return
+      }
+      share.username = shareUsername.trim()
+    } else {
+      if (!shareTeamId) {
+        message.warning('请选择团队')
+        return
+      }
+      share.team_id = shareTeamId
+    }
+
+    setSharing(true)
+    try {
+      await api.shareUserData(shareTarget.id, share)
+      message.success('共享成功')
+      setShareUsername('')
+      const res = await api.getDataShares(shareTarget.id)
+      setShares(res.shares || [])
+    } catch (err) {
+      message.error(err.message || '共享失败')
+    } finally {
+      setSharing(false)
+    }
+  }
+
+  const handleRevokeShare = async (shareId) => {
+    try {
+      await api.deleteDataShare(shareTarget.id, shareId)
+      setShares(shares.filter(share => share.id !== shareId))
+    } catch (err) {
+      message.error(err.message || '取消共享失败')
+    }
+  }
+
+  // 打开数据集操作弹窗
+  const handleOpenOperation = (dataFile) => {
+    setOperationTarget(dataFile)
+    setOperation('sample')
+    setSampleSize(Math.min(100, dataFile.item_count || 100))
+    setStratifyBy('')
+    setSeed(null)
+  }
+
+  // 执行抽样、切分或去重，结果保存为新数据文件
+  const handleRunOperation = async () => {
+    const options = { stratify_by: stratifyBy.trim() || undefined, seed: seed ?? undefined }
+    setOperating(true)
+    try {
+      let res
+      if (operation === 'sample') {
+        res = await api.sampleUserData(operationTarget.id, { ...options, size: sampleSize })
+      } else if (operation === 'split') {
+        res = await api.splitUserData(operationTarget.id, {
+          ...options,
+          parts: [{ name: 'train', ratio: trainRatio }, { name: 'test', ratio: 1 - trainRatio }],
+        })
+      } else {
+        res = await api.dedupeUserData(operationTarget.id, { mode: dedupeMode })
+      }
+      message.success(`已生成 ${res.data_files.map(file => `${file.filename}（${file.item_count} 条）`).join('、')}`)
+      setOperationTarget(null)
+      fetchUserDataFiles()
+    } catch (err) {
+      message.error(err.message || `${OPERATION_LABELS[operation]}失败`)
+    } finally {
+      setOperating(false)
+    }
+  }
+
+  // 合并多个数据文件
+  const handleMerge = async () => {
+    if (mergeIds.length < 2) {
+      message.warning('请至少选择两个数据文件')
+      return
+    }
+    setOperating(true)
+    try {
+      const res = await api.mergeUserData({ data_ids: mergeIds, dedupe: dedupeMode || undefined })
+      const merged = res.data_files[0]
+      message.success(`已生成 ${merged.filename}（${merged.item_count} 条）`)
+      setMergeVisible(false)
+      fetchUserDataFiles()
+    } catch (err) {
+      message.error(err.message || '合并失败')
+    } finally {
+      setOperating(false)
+    }
+  }
+
   const handleFileUpload = async (e) => {
     const file = e.target.files?.[0]
     if (!file) return
 
-    if (!file.name.endsWith('.jsonl') && !file.name.endsWith('.csv')) {
-      message.error('只支持 .jsonl 和 .csv 格式的文件')
+    if (!IMPORTABLE_EXTENSIONS.some(ext => file.name.toLowerCase().endsWith(ext))) {
+      message.error('只支持 .jsonl、.json、.csv 和 .parquet 格式的文件')
       return
     }
 
@@ -33,30 +224,121 @@ export default function DataManagePage() {
     e.target.value = '' // 重置文件输入
   }
 
+  // 读取导入选项，列映射格式错误时返回 null
+  const getImportOptions = () => {
+    if (importFormat !== 'columns') {
+      return { format: importFormat }
+    }
+    try {
+      return { format: importFormat, mapping: JSON.parse(mappingText) }
+    } catch {
+      message.error('列映射不是合法的 JSON')
+      return null
+    }
+  }
+
+  // 预览导入：转换文件但不保存
+  const handlePreview = async () => {
+    const options = getImportOptions()
+    if (!pendingFile || !options) return
+
+    setPreviewing(true)
+    try {
+      setPreview(await api.previewDataImport(pendingFile, '', options))
+    } catch (err) {
+      setPreview(null)
+      message.error(err.message || '预览失败')
+    } finally {
+      setPreviewing(false)
+    }
+  }
+
   const handleUploadWithDescription = async () => {
-    if (!pendingFile) return
+    const options = getImportOptions()
+    if (!pendingFile || !options) return
 
     setUploading(true)
     setDescriptionModalVisible(false)
 
     try {
-      await uploadUserDataFile(pendingFile, description || '')
+      await uploadUserDataFile(pendingFile, description || '', options)
       message.success('文件上传成功')
-      setDescription('')
-      setPendingFile(null)
+      resetUploadForm()
     } catch (err) {
-      message.error(err.message || '文件上传失败')
+      const validation = err.data?.validation
+      if (validation) {
+        showValidationReport(validation)
+      } else {
+        message.error(err.message || '文件上传失败')
+      }
     } finally {
       setUploading(false)
     }
   }
 
-  const handleCancelDescription = () => {
-    setDescriptionModalVisible(false)
+  // 展示数据格式校验失败的逐行报告
+  const showValidationReport = (validation) => {
+    Modal.error({
+      title: `数据格式校验失败：${validation.invalid_count} / ${validation.total_lines} 行不符合要求`,
+      width: 640,
+      content: (
+        <div style={{ maxHeight: 360, overflowY: 'auto' }}>
+          {validation.invalid_lines.map(issue => (
+            <div key={issue.line} style={{ marginBottom: 8 }}>
+              <strong>第 {issue.line} 行：</strong>
+              {issue.messages.join('；')}
+            </div>
+          ))}
+          {validation.invalid_count > validation.invalid_lines.length && (
+            <div>仅显示前 {validation.invalid_lines.length} 行</div>
+          )}
+        </div>
+      ),
+    })
+  }
+
+  const resetUploadForm = () => {
     setDescription('')
     setPendingFile(null)
+    setImportFormat('auto')
+    setPreview(null)
+  }
+
+  const handleCancelDescription = () => {
+    setDescriptionModalVisible(false)
+    resetUploadForm()
+  }
+
+
+  // 将所有数据文件打包导出为 zip
+  const handleExportAll = async () => {
+    try {
+      setExporting(true)
+      const blob = await api.exportDataBundle()
+      const link = document.createElement('a')
+      link.href = URL.createObjectURL(blob)
+      link.download = `datasets_${new Date().toISOString().slice(0, 10)}.zip`
+      link.click()
+      URL.revokeObjectURL(link.href)
+    } catch (err) {
+      message.error(err.message || '导出失败')
+    } finally {
+      setExporting(false)
+    }
   }
 
+  const handleDownload = async (dataFile) => {
+    try {
+      const blob = await api.downloadUserData(dataFile.id)
+      const link = document.createElement('a')
+      link.href = URL.createObjectURL(blob)
+      link.download = dataFile.filename
+      link.click()
+      URL.revokeObjectURL(link.href)
+    } catch (err) {
+      message.error(err.message || '下载失败')
+    }
+  }
 
   const handleViewDetail = (dataId) => {
     navigate(`/data-detail/${dataId}`)
@@ -108,12 +390,22 @@ export default function DataManagePage() {
         <input
           id="file-upload"
           type="file"
-          accept=".jsonl,.csv"
+          accept={IMPORTABLE_EXTENSIONS.join(',')}
           onChange={handleFileUpload}
           disabled={uploading}
           style={{ display: 'none' }}
         />
-        <span className="upload-hint">支持 .jsonl 和 .csv 格式文件</span>
+        <span className="upload-hint">支持 .jsonl、.json、.csv 和 .parquet 格式文件，可导入 OpenAI、ShareGPT、Alpaca 等格式</span>
+        <Button
+          onClick={() => { setMergeIds([]); setDedupeMode(''); setMergeVisible(true) }}
+          disabled={!userDataFiles?.length}
+          style={{ marginLeft: 'auto' }}
+        >
+          合并数据
+        </Button>
+        <Button onClick={handleExportAll} loading={exporting} disabled={!userDataFiles?.length} style={{ marginLeft: 8 }}>
+          打包导出全部
+        </Button>
       </div>
 
       {error && <div className="error-message">{error}</div>}
@@ -138,7 +430,12 @@ export default function DataManagePage() {
             <tbody>
               {userDataFiles.map((dataFile) => (
                 <tr key={dataFile.id}>
-                  <td className="filename">{dataFile.filename}</td>
+                  <td className="filename">
+                    {dataFile.filename}
+                    {dataFile.provenance && (
+                      <div className="provenance">{describeProvenance(dataFile.provenance)}</div>
+                    )}
+                  </td>
                   <td className="description">
                     <span>{dataFile.description || '-'}</span>
                   </td>
@@ -155,6 +452,24 @@ export default function DataManagePage() {
                     >
                       查看/编辑
                     </button>
+                    <button
+                      onClick={() => handleDownload(dataFile)}
+                      className="action-btn view-btn"
+                    >
+                      下载
+                    </button>
+                    <button
+                      onClick={() => handleOpenOperation(dataFile)}
+                      className="action-btn view-btn"
+                    >
+                      派生
+                    </button>
+                    <button
+                      onClick={() => handleOpenShare(dataFile)}
+                      className="action-btn view-btn"
+                    >
+                      共享
+                    </button>
                     <button
                       onClick={() => handleDelete(dataFile.id, dataFile.filename)}
                       className="action-btn delete-btn"
@@ -169,6 +484,190 @@ export default function DataManagePage() {
         )}
       </div>
 
+      {[
+        { title: '共享给我的数据', files: sharedFiles },
+        { title: '公共基准库', files: libraryFiles },
+      ].map(section => section.files.length > 0 && (
+        <div className="data-list" key={section.title}>
+          <h2>{section.title}</h2>
+          <table className="data-table">
+            <thead>
+              <tr>
+                <th>文件名</th>
+                <th>描述</th>
+                <th>所有者</th>
+                <th>权限</th>
+                <th>操作</th>
+              </tr>
+            </thead>
+            <tbody>
+              {section.files.map((dataFile) => (
+                <tr key={dataFile.id}>
+                  <td className="filename">{dataFile.filename}</td>
+                  <td className="description">
+                    <span>{dataFile.description || '-'}</span>
+                  </td>
+                  <td>{dataFile.owner}</td>
+                  <td>{PERMISSION_LABELS[dataFile.permission]}</td>
+                  <td className="actions">
+                    <button
+                      onClick={() => handleViewDetail(dataFile.id)}
+                      className="action-btn view-btn"
+                    >
+                      {dataFile.permission === 'read' ? '查看' : '查看/编辑'}
+                    </button>
+                    <button
+                      onClick={() => handleDownload(dataFile)}
+                      className="action-btn view-btn"
+                    >
+                      下载
+                    </button>
+                  </td>
+                </tr>
+              ))}
+            </tbody>
+          </table>
+        </div>
+      ))}
+
+      {/* 共享弹窗 */}
+      <Modal
+        title={`共享 "${shareTarget?.filename || ''}"`}
+        open={!!shareTarget}
+        onCancel={() => setShareTarget(null)}
+        footer={null}
+        centered
+      >
+        <Radio.Group value={shareWith} onChange={(e) => setShareWith(e.target.value)}>
+          <Radio value="user">用户</Radio>
+          <Radio value="team">团队</Radio>
+        </Radio.Group>
+        <div style={{ display: 'flex', gap: 8, marginTop: 12 }}>
+          {shareWith === 'user' ? (
+            <Input
+              placeholder="用户名"
+              value={shareUsername}
+              onChange={(e) => setShareUsername(e.target.value)}
+            />
+          ) : (
+            <Select
+              placeholder="选择团队"
+              value={shareTeamId}
+              onChange={setShareTeamId}
+              options={myTeams.map(team => ({ value: team.id, label: team.name }))}
+              style={{ flex: 1 }}
+            />
+          )}
+          <Select
+            value={sharePermission}
+            onChange={setSharePermission}
+            options={SHARE_PERMISSIONS}
+            style={{ width: 100 }}
+          />
+          <Button type="primary" onClick={handleShare} loading={sharing}>
+            共享
+          </Button>
+        </div>
+        <div style={{ marginTop: 16 }}>
+          {shares.length === 0 ? (
+            <div>尚未共享</div>
+          ) : shares.map(share => (
+            <div key={share.id} style={{ display: 'flex', alignItems: 'center', marginBottom: 8 }}>
+              <span style={{ flex: 1 }}>
+                {share.team_id ? `团队 ${share.team_name}` : `用户 ${share.username}`}：{PERMISSION_LABELS[share.permission]}
+              </span>
+              <Button size="small" danger onClick={() => handleRevokeShare(share.id)}>
+                取消共享
+              </Button>
+            </div>
+          ))}
+        </div>
+      </Modal>
+
+      {/* 数据集操作弹窗 */}
+      <Modal
+        title={`派生 "${operationTarget?.filename || ''}"`}
+        open={!!operationTarget}
+        onOk={handleRunOperation}
+        onCancel={() => setOperationTarget(null)}
+        okText="生成新数据"
+        cancelText="取消"
+        confirmLoading={operating}
+        centered
+      >
+        <Radio.Group value={operation} onChange={(e) => setOperation(e.target.value)}>
+          <Radio value="sample">随机抽样</Radio>
+          <Radio value="split">切分 train/test</Radio>
+          <Radio value="dedupe">去重</Radio>
+        </Radio.Group>
+        <div style={{ display: 'flex', flexDirection: 'column', gap: 12, marginTop: 16 }}>
+          {operation === 'sample' && (
+            <div>
+              <span>样本数量：</span>
+              <InputNumber min={1} max={operationTarget?.item_count} value={sampleSize} onChange={setSampleSize} />
+              <span> / {operationTarget?.item_count} 条</span>
+            </div>
+          )}
+          {operation === 'split' && (
+            <div>
+              <span>train 比例：</span>
+              <InputNumber min={0.05} max={0.95} step={0.05} value={trainRatio} onChange={setTrainRatio} />
+            </div>
+          )}
+          {operation === 'dedupe' ? (
+            <div>
+              <span>去重方式：</span>
+              <Select value={dedupeMode || 'exact'} onChange={setDedupeMode} options={DEDUPE_MODES} style={{ width: 320 }} />
+            </div>
+          ) : (
+            <>
+              <Input
+                addonBefore="按 meta 字段分层"
+                placeholder="如 category，留空不分层"
+                value={stratifyBy}
+                onChange={(e) => setStratifyBy(e.target.value)}
+              />
+              <div>
+                <span>随机种子：</span>
+                <InputNumber value={seed} onChange={setSeed} placeholder="留空随机" style={{ width: 200 }} />
+              </div>
+            </>
+          )}
+        </div>
+      </Modal>
+
+      {/* 合并数据弹窗 */}
+      <Modal
+        title="合并数据"
+        open={mergeVisible}
+        onOk={handleMerge}
+        onCancel={() => setMergeVisible(false)}
+        okText="合并"
+        cancelText="取消"
+        confirmLoading={operating}
+        centered
+      >
+        <Select
+          mode="multiple"
+          placeholder="按顺序选择要合并的数据文件"
+          value={mergeIds}
+          onChange={setMergeIds}
+          options={[...(userDataFiles || []), ...sharedFiles, ...libraryFiles]
+            .filter((file, index, files) => files.findIndex(other => other.id === file.id) === index)
+            .map(file => ({ value: file.id, label: `${file.filename}（${file.item_count} 条）` }))}
+          style={{ width: '100%' }}
+        />
+        <div style={{ marginTop: 12 }}>
+          <span>去重：</span>
+          <Select
+            value={dedupeMode}
+            onChange={setDedupeMode}
+            options={[{ value: '', label: '不去重' }, ...DEDUPE_MODES]}
+            style={{ width: 320 }}
+          />
+        </div>
+      </Modal>
+
       {/* 文件描述输入弹窗 */}
       <Modal
         title="输入文件描述"
@@ -188,6 +687,45 @@ export default function DataManagePage() {
           maxLength={500}
           showCount
         />
+        <div style={{ marginTop: 16 }}>
+          <span>数据格式：</span>
+          <Select
+            value={importFormat}
+            onChange={(value) => { setImportFormat(value); setPreview(null) }}
+            options={IMPORT_FORMATS}
+            style={{ width: 280 }}
+          />
+          <Button onClick={handlePreview} loading={previewing} style={{ marginLeft: 8 }}>
+            预览
+          </Button>
+        </div>
+        {importFormat === 'columns' && (
+          <Input.TextArea
+            value={mappingText}
+            onChange={(e) => { setMappingText(e.target.value); setPreview(null) }}
+            rows={6}
+            style={{ marginTop: 8, fontFamily: 'monospace' }}
+          />
+        )}
+        {preview && (
+          <div style={{ marginTop: 16 }}>
+            <Alert
+              type={preview.success ? 'success' : 'error'}
+              message={preview.success
+                ? `可导入 ${preview.validation.valid_items} 条数据`
+                : `${preview.validation.invalid_count} / ${preview.validation.total_lines} 条数据不符合要求`}
+              description={preview.validation.invalid_lines.slice(0, 5).map(issue => (
+                <div key={issue.line}>第 {issue.line} 条：{issue.messages.join('；')}</div>
+              ))}
+              showIcon
+            />
+            {preview.preview_data.length > 0 && (
+              <pre style={{ maxHeight: 240, overflow: 'auto', marginTop: 8, fontSize: 12 }}>
+                {preview.preview_data.map(item => JSON.stringify(item, null, 2)).join('\n')}
+              </pre>
+            )}
+          </div>
+        )}
       </Modal>
     </div>
   )
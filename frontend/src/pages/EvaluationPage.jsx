@@ -33,6 +33,13 @@ const { Title, Text } = Typography
 const { Option } = Select
 const { TextArea } = Input
 
+// 数据文件来源标注：共享给我的文件和基准库中的文件
+const dataFileSource = (file) => {
+  if (file.type === 'shared') return `（${file.owner} 共享）`
+  if (file.type === 'library') return '（基准库）'
+  return ''
+}
+
 function EvaluationPage() {
   const navigate = useNavigate()
   const [form] = Form.useForm()
@@ -241,7 +248,7 @@ function EvaluationPage() {
                 >
                   {dataFiles.map(file => (
                     <Option key={file.id} value={String(file.id)}>
-                      {file.name}
+                      {file.name + dataFileSource(file)}
                     </Option>
                   ))}
                 </Select>
@@ -402,7 +409,7 @@ function EvaluationPage() {
                     <Form.Item
                       label="样本数量"
                       name="sample_size"
-                      tooltip="0 表示使用全部数据"
+                      tooltip="取前 N 条，0 表示使用全部数据；随机或分层抽样请在数据管理中派生新数据"
                     >
                       <InputNumber min={0} style={{ width: '100%' }} />
                     </Form.Item>
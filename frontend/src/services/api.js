@@ -50,7 +50,10 @@ api.interceptors.response.use(
       window.location.href = '/login'
     }
 
-    return Promise.reject(new Error(errorMsg))
+    // 保留响应体，便于展示校验报告等详细信息
+    const err = new Error(errorMsg)
+    err.data = error.response?.data
+    return Promise.reject(err)
   }
 )
 
@@ -89,14 +92,37 @@ export const getUserDataFiles = () => {
 }
 
 /**
- * 上传用户数据文件
+ * 将导入选项写入表单
+ * format 为记录格式：auto（默认，逐条识别）、native、openai、sharegpt、alpaca、columns
+ * mapping 为列映射（columns 格式使用）：{ system, turns: [{ column, role }], meta: [列名] }
  */
-export const uploadUserDataFile = (file, description) => {
+const appendImportOptions = (formData, { format, mapping } = {}) => {
+  if (format) {
+    formData.append('format', format)
+  }
+  if (mapping) {
+    formData.append('mapping', JSON.stringify(mapping))
+  }
+}
+
+/**
+ * 上传用户数据文件（message为可选的版本说明）
+ * 支持 .jsonl、.json、.csv、.parquet，按 options 中的格式和列映射转换为 meta/turns 格式
+ * role 为评测时使用的角色（默认 assistant），上传时按该角色校验 meta/turns 格式，校验失败时 err.data.validation 为逐行报告
+ */
+export const uploadUserDataFile = (file, description, message, role, options = {}) => {
   const formData = new FormData()
   formData.append('file', file)
+  appendImportOptions(formData, options)
   if (description) {
     formData.append('description', description)
   }
+  if (message) {
+    formData.append('message', message)
+  }
+  if (role) {
+    formData.append('role', role)
+  }
   return api.post('/user/data', formData, {
     headers: { 'Content-Type': 'multipart/form-data' }
   })
@@ -134,10 +160,101 @@ export const deleteUserDataFile = (id) => {
 }
 
 /**
- * 获取用户数据文件的详细内容（JSONL数据）
+ * 分页获取用户数据文件的内容（JSONL数据）
+ * @param {number} dataId - 数据文件ID
+ * @param {Object} params - 查询参数 { offset, limit, q, role }，q 在对话文本中搜索，role 只保留含该角色对话的数据
+ */
+export const getUserDataContent = (dataId, params = {}) => {
+  return api.get(`/user/data/${dataId}/content`, { params })
+}
+
+/**
+ * 获取其他用户共享给我（或我所在团队）的数据文件
+ */
+export const getSharedDataFiles = () => {
+  return api.get('/user/data/shared')
+}
+
+/**
+ * 获取管理员维护的公共基准库
+ */
+export const getDataLibrary = () => {
+  return api.get('/user/data/library')
+}
+
+/**
+ * 获取数据文件的共享列表（仅所有者）
+ */
+export const getDataShares = (dataId) => {
+  return api.get(`/user/data/${dataId}/shares`)
+}
+
+/**
+ * 将数据文件共享给用户或团队，重复共享会覆盖原有权限
+ * @param {number} dataId - 数据ID
+ * @param {Object} share - { username 或 user_id 或 team_id, permission: 'read' | 'write' }
+ */
+export const shareUserData = (dataId, share) => {
+  return api.put(`/user/data/${dataId}/shares`, share)
+}
+
+/**
+ * 取消数据文件的共享
+ */
+export const deleteDataShare = (dataId, shareId) => {
+  return api.delete(`/user/data/${dataId}/shares/${shareId}`)
+}
+
+/**
+ * 获取数据文件的统计信息：轮次分布、各角色长度分布、重复数据、语言分布和 meta 字段取值
+ * 结果按版本缓存
+ * @param {number} dataId - 数据ID
+ * @param {number} version - 版本号（可选，默认当前版本）
+ */
+export const getDataStats = (dataId, version) => {
+  return api.get(`/user/data/${dataId}/stats`, { params: { version } })
+}
+
+/**
+ * 随机抽样生成新数据文件，可按 meta 字段分层，记录来源
+ * @param {number} dataId - 数据ID
+ * @param {Object} params - { size 或 fraction, stratify_by, seed, version, filename, description }
+ */
+export const sampleUserData = (dataId, params) => {
+  return api.post(`/user/data/${dataId}/sample`, params)
+}
+
+/**
+ * 按比例随机切分为多个新数据文件（如 train/test），可按 meta 字段分层
+ * @param {number} dataId - 数据ID
+ * @param {Object} params - { parts: [{ name, ratio }], stratify_by, seed, version }
+ */
+export const splitUserData = (dataId, params) => {
+  return api.post(`/user/data/${dataId}/split`, params)
+}
+
+/**
+ * 去除重复数据生成新数据文件，每组重复保留第一条
+ * @param {number} dataId - 数据ID
+ * @param {Object} params - { mode: 'exact' | 'prompt', version, filename, description }
+ */
+export const dedupeUserData = (dataId, params) => {
+  return api.post(`/user/data/${dataId}/dedupe`, params)
+}
+
+/**
+ * 合并多个数据文件的当前版本为新数据文件，可同时去重
+ * @param {Object} params - { data_ids, dedupe: 'exact' | 'prompt', filename, description }
+ */
+export const mergeUserData = (params) => {
+  return api.post('/user/data/merge', params)
+}
+
+/**
+ * 按评测角色校验数据文件的 meta/turns 格式，返回逐行报告
  */
-export const getUserDataContent = (dataId) => {
-  return api.get(`/user/data/${dataId}/content`)
+export const validateUserData = (dataId, role) => {
+  return api.get(`/user/data/${dataId}/validate`, { params: { role } })
 }
 
 /**
@@ -168,16 +285,85 @@ export const addSingleItem = (dataId, newItem) => {
 }
 
 /**
- * 导入并追加CSV或JSONL数据
+ * 预览导入：转换文件但不保存，返回校验报告和前 5 条转换结果
+ */
+export const previewDataImport = (file, role, options = {}) => {
+  const formData = new FormData()
+  formData.append('file', file)
+  if (role) {
+    formData.append('role', role)
+  }
+  appendImportOptions(formData, options)
+  return api.post('/user/data/import/preview', formData, {
+    headers: { 'Content-Type': 'multipart/form-data' }
+  })
+}
+
+/**
+ * 导入并追加数据（支持 .jsonl、.json、.csv、.parquet）
  */
-export const appendDataFile = (dataId, file) => {
+export const appendDataFile = (dataId, file, options = {}) => {
   const formData = new FormData()
   formData.append('file', file)
+  appendImportOptions(formData, options)
   return api.post(`/user/data/${dataId}/append`, formData, {
     headers: { 'Content-Type': 'multipart/form-data' }
   })
 }
 
+/**
+ * 下载数据文件
+ * @param {number} dataId - 数据ID
+ * @param {string} format - 导出格式：jsonl、csv、openai、sharegpt
+ * @param {number[]} indices - 只导出这些数据索引（可选）
+ */
+export const downloadUserData = (dataId, format = 'jsonl', indices) => {
+  return api.get(`/user/data/${dataId}/download`, {
+    params: { format, indices: indices?.length ? indices.join(',') : undefined },
+    responseType: 'blob'
+  })
+}
+
+/**
+ * 将多个数据文件打包导出为 zip（包含 manifest.json），用于备份或迁移
+ * @param {number[]} ids - 数据ID列表，不传时导出全部数据文件
+ * @param {string} format - 导出格式：jsonl、csv、openai、sharegpt
+ */
+export const exportDataBundle = (ids, format = 'jsonl') => {
+  return api.get('/user/data/export', {
+    params: { format, ids: ids?.length ? ids.join(',') : undefined },
+    responseType: 'blob'
+  })
+}
+
+/**
+ * 获取数据文件的版本历史
+ */
+export const getDataVersions = (dataId) => {
+  return api.get(`/user/data/${dataId}/versions`)
+}
+
+/**
+ * 获取数据文件某个版本的内容
+ */
+export const getDataVersion = (dataId, version) => {
+  return api.get(`/user/data/${dataId}/versions/${version}`)
+}
+
+/**
+ * 比较数据文件的两个版本
+ */
+export const diffDataVersions = (dataId, from, to) => {
+  return api.get(`/user/data/${dataId}/versions/diff`, { params: { from, to } })
+}
+
+/**
+ * 将数据文件回滚到指定版本（生成新版本）
+ */
+export const rollbackDataVersion = (dataId, version) => {
+  return api.post(`/user/data/${dataId}/versions/${version}/rollback`)
+}
+
 // ---------- 评测配置 ----------
 
 /**
@@ -218,6 +404,93 @@ export const deleteReport = (reportId) => {
   return api.delete(`/reports/${reportId}`)
 }
 
+/**
+ * 按ID获取报告元信息（不含逐条结果）
+ */
+export const getReport = (reportId) => {
+  return api.get(`/reports/${reportId}`)
+}
+
+/**
+ * 分页获取报告的逐条结果
+ * @param {number} reportId - 报告ID
+ * @param {Object} params - 查询参数 { offset, limit, badcase_only, min_score, q }
+ */
+export const getReportResults = (reportId, params = {}) => {
+  return api.get(`/reports/${reportId}/results`, { params })
+}
+
+/**
+ * 获取报告结果的聚合统计（支持与逐条结果相同的筛选条件）
+ * @param {number} reportId - 报告ID
+ * @param {Object} params - 筛选参数 { badcase_only, error_only, min_score, max_score, q }
+ */
+export const getReportAggregates = (reportId, params = {}) => {
+  return api.get(`/reports/${reportId}/aggregates`, { params })
+}
+
+/**
+ * 人工复核报告中的单条结果（覆盖分数/badcase标记，填写备注与失败类别）
+ * @param {number} reportId - 报告ID
+ * @param {number} index - 结果序号
+ * @param {Object} review - { score, is_badcase, comment, failure_category }
+ */
+export const reviewReportItem = (reportId, index, review) => {
+  return api.put(`/reports/${reportId}/items/${index}/review`, review)
+}
+
+/**
+ * 删除当前用户对单条结果的复核
+ */
+export const deleteReportItemReview = (reportId, index) => {
+  return api.delete(`/reports/${reportId}/items/${index}/review`)
+}
+
+/**
+ * 获取报告的全部复核记录、复核后汇总及一致性统计
+ */
+export const getReportReviews = (reportId) => {
+  return api.get(`/reports/${reportId}/reviews`)
+}
+
+/**
+ * 导出报告文件
+ * @param {number} reportId - 报告ID
+ * @param {string} format - 导出格式：csv、xlsx、html、md、jsonl
+ * @param {boolean} badcaseOnly - 是否只导出badcase
+ */
+export const exportReport = (reportId, format, badcaseOnly = false) => {
+  return api.get(`/reports/${reportId}/export`, {
+    params: { format, badcase_only: badcaseOnly },
+    responseType: 'blob'
+  })
+}
+
+/**
+ * 使用新的评分函数或阈值重新评分报告，复用已保存的模型输出并生成关联的新报告
+ * @param {number} reportId - 报告ID
+ * @param {Object} data - { scoring, badcase_threshold, scoring_module, max_workers }
+ */
+export const rescoreReport = (reportId, data) => {
+  return api.post(`/reports/${reportId}/rescore`, data)
+}
+
+/**
+ * 获取同一数据项在历次评测中的结果
+ * @param {Object} params - 查询参数 { dataset, original_index, expanded_index, model }
+ */
+export const getReportItemHistory = (params) => {
+  return api.get('/reports/items/history', { params })
+}
+
+/**
+ * 对比同一数据集的多次评测报告（以第一个报告为基线）
+ * @param {number[]} reportIds - 报告ID列表
+ */
+export const compareReports = (reportIds) => {
+  return api.get('/reports/compare', { params: { ids: reportIds.join(',') } })
+}
+
 /**
  * 启动评测任务
  */
@@ -311,6 +584,17 @@ export const deleteAdminData = (userId, dataId) => {
   return api.delete(`/admin/users/${userId}/data/${dataId}`)
 }
 
+/**
+ * 管理员将数据文件加入或移出公共基准库
+ * @param {number} dataId - 数据ID
+ * @param {boolean} inLibrary - 是否加入基准库
+ */
+export const setAdminDataLibrary = (dataId, inLibrary) => {
+  return inLibrary
+    ? api.put(`/admin/data/${dataId}/library`)
+    : api.delete(`/admin/data/${dataId}/library`)
+}
+
 // ---------- 模型配置接口 ----------
 
 /**
@@ -348,4 +632,198 @@ export const deleteAdminModelConfig = (configId) => {
   return api.delete(`/admin/model-configs/${configId}`)
 }
 
+/**
+ * 管理员测试模型配置的连通性
+ */
+export const testAdminModelConfig = (configId) => {
+  return api.post(`/admin/model-configs/${configId}/test`)
+}
+
+/**
+ * 管理员从兼容 OpenAI 的端点发现模型
+ */
+export const discoverAdminModels = (apiUrl, apiKey) => {
+  return api.post('/admin/model-configs/discover', { api_url: apiUrl, api_key: apiKey })
+}
+
+/**
+ * 管理员为发现的模型一键创建模型配置
+ */
+export const importAdminDiscoveredModels = (apiUrl, apiKey, modelNames) => {
+  return api.post('/admin/model-configs/discover/import', {
+    api_url: apiUrl,
+    api_key: apiKey,
+    model_names: modelNames
+  })
+}
+
+/**
+ * 管理员获取模型配置的版本历史
+ */
+export const getAdminModelConfigVersions = (configId) => {
+  return api.get(`/admin/model-configs/${configId}/versions`)
+}
+
+/**
+ * 管理员对比模型配置的两个版本
+ */
+export const diffAdminModelConfigVersions = (configId, from, to) => {
+  return api.get(`/admin/model-configs/${configId}/versions/diff`, { params: { from, to } })
+}
+
+/**
+ * 管理员将模型配置回滚到指定版本
+ */
+export const rollbackAdminModelConfig = (configId, version) => {
+  return api.post(`/admin/model-configs/${configId}/versions/${version}/rollback`)
+}
+
+/**
+ * 获取模型别名列表
+ */
+export const getModelAliases = () => {
+  return api.get('/model-aliases')
+}
+
+/**
+ * 管理员创建或重新指向模型别名
+ */
+export const setAdminModelAlias = (alias, configId) => {
+  return api.put(`/admin/model-aliases/${encodeURIComponent(alias)}`, { config_id: configId })
+}
+
+/**
+ * 管理员删除模型别名
+ */
+export const deleteAdminModelAlias = (alias) => {
+  return api.delete(`/admin/model-aliases/${encodeURIComponent(alias)}`)
+}
+
+/**
+ * 管理员设置模型配置的标签
+ */
+export const setAdminModelConfigTags = (configId, tags) => {
+  return api.put(`/admin/model-configs/${configId}/tags`, { tags })
+}
+
+/**
+ * 获取多模型对比报告列表
+ */
+export const getComparisonReports = () => {
+  return api.get('/reports/comparisons')
+}
+
+/**
+ * 获取多模型对比报告详情
+ */
+export const getComparisonReport = (comparisonId) => {
+  return api.get(`/reports/comparisons/${comparisonId}`)
+}
+
+/**
+ * 删除多模型对比报告
+ */
+export const deleteComparisonReport = (comparisonId) => {
+  return api.delete(`/reports/comparisons/${comparisonId}`)
+}
+
+// ---------- 排行榜接口 ----------
+
+/**
+ * 获取模型×数据集排行榜
+ * @param {Object} params - { mode: latest|best, metric, scoring, from, to, tag: [], dataset, team_id }
+ */
+export const getLeaderboard = (params) => {
+  return api.get('/leaderboard', { params, paramsSerializer: { indexes: null } })
+}
+
+/**
+ * 获取模型在数据集上的得分时间序列，标记回退
+ * @param {Object} params - { model, dataset, metric, scoring, from, to, team_id, regression_threshold }
+ */
+export const getLeaderboardHistory = (params) => {
+  return api.get('/leaderboard/history', { params, paramsSerializer: { indexes: null } })
+}
+
+/**
+ * 获取自己创建及团队共享的排行榜
+ */
+export const getSavedLeaderboards = () => {
+  return api.get('/leaderboards')
+}
+
+/**
+ * 获取已保存排行榜的定义及当前排名
+ */
+export const getSavedLeaderboard = (leaderboardId) => {
+  return api.get(`/leaderboards/${leaderboardId}`)
+}
+
+/**
+ * 保存排行榜，指定team_id时共享给团队
+ * @param {Object} data - { name, team_id, filters }
+ */
+export const createSavedLeaderboard = (data) => {
+  return api.post('/leaderboards', data)
+}
+
+/**
+ * 修改已保存的排行榜
+ */
+export const updateSavedLeaderboard = (leaderboardId, data) => {
+  return api.put(`/leaderboards/${leaderboardId}`, data)
+}
+
+/**
+ * 删除已保存的排行榜
+ */
+export const deleteSavedLeaderboard = (leaderboardId) => {
+  return api.delete(`/leaderboards/${leaderboardId}`)
+}
+
+// ---------- 团队接口 ----------
+
+/**
+ * 获取当前用户所在的团队
+ */
+export const getMyTeams = () => {
+  return api.get('/teams')
+}
+
+/**
+ * 管理员获取所有团队
+ */
+export const getAdminTeams = () => {
+  return api.get('/admin/teams')
+}
+
+/**
+ * 管理员创建团队
+ * @param {Object} data - { name, description, user_ids }
+ */
+export const createAdminTeam = (data) => {
+  return api.post('/admin/teams', data)
+}
+
+/**
+ * 管理员删除团队
+ */
+export const deleteAdminTeam = (teamId) => {
+  return api.delete(`/admin/teams/${teamId}`)
+}
+
+/**
+ * 管理员添加团队成员
+ */
+export const addAdminTeamMember = (teamId, userId) => {
+  return api.post(`/admin/teams/${teamId}/members`, { user_id: userId })
+}
+
+/**
+ * 管理员移除团队成员
+ */
+export const removeAdminTeamMember = (teamId, userId) => {
+  return api.delete(`/admin/teams/${teamId}/members/${userId}`)
+}
+
 export default api
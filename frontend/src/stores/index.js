@@ -202,9 +202,9 @@ const useStore = create((set, get) => ({
     }
   },
 
-  uploadUserDataFile: async (file, description) => {
+  uploadUserDataFile: async (file, description, options = {}) => {
     try {
-      await api.uploadUserDataFile(file, description)
+      await api.uploadUserDataFile(file, description, '', '', options)
       // 重新加载数据文件列表
       await get().fetchUserDataFiles()
       // 同时更新评测配置中的数据文件列表
@@ -233,10 +233,10 @@ const useStore = create((set, get) => ({
     }
   },
 
-  fetchDataContent: async (dataId) => {
+  fetchDataContent: async (dataId, params = {}) => {
     try {
       set({ dataDetailLoading: true })
-      const res = await api.getUserDataContent(dataId)
+      const res = await api.getUserDataContent(dataId, params)
       set({ currentDataDetail: res, dataDetailLoading: false })
       return res
     } catch (err) {
@@ -66,6 +66,7 @@ def evaluate_single_item(item: Dict, api_url: str, item_index: int = 0, test_mod
         'model_output': model_output,
         'reference_output': reference_output,
         'inference_time': inference_time,
+        'meta': item.get('meta', {}),
         'test_mode': test_mode
     }
     return result
@@ -108,7 +109,9 @@ def batch_evaluate(test_data: List[Dict], api_urls: list, scoring_func: Callable
                 'original_index': idx,
                 'expanded_index': i,
                 'messages': messages,
-                'reference_output': messages[-1]['content'] if messages else ''
+                'reference_output': messages[-1]['content'] if messages else '',
+                # 保留数据条目的meta字段（不含系统提示），用于报告按字段分组统计
+                'meta': {k: v for k, v in (item.get('meta') or {}).items() if k != 'meta_description'}
             }
             expanded_data.append(expanded_item)
     
@@ -319,4 +322,65 @@ def batch_evaluate(test_data: List[Dict], api_urls: list, scoring_func: Callable
         print(f"所有结果已评分，无需重新评分")
     
     print(f"评分完成！Badcase数量: {len(badcases)}")
-    return results, badcases
\ No newline at end of file
+    return results, badcases
+
+
+def batch_rescore(stored_results: List[Dict], scoring_func: Callable, max_workers: int = 4,
+                  badcase_threshold: float = 0.5, progress_callback: Callable = None) -> Tuple[List[Dict], List[Dict]]:
+    """
+    使用已保存的模型输出重新评分，不调用模型
+    Args:
+        stored_results: 已有报告中的评估结果
+        scoring_func: 评分函数
+        max_workers: 最大工作线程数
+        badcase_threshold: Badcase判断阈值
+        progress_callback: 进度回调函数
+    Returns:
+        (所有结果列表, badcase结果列表)
+    """
+    results = []
+    scored_indices = []
+    for stored in stored_results:
+        result = {
+            'index': stored['index'],
+            'original_index': stored['original_index'],
+            'expanded_index': stored['expanded_index'],
+            'user_input': stored['user_input'],
+            'model_output': stored['model_output'],
+            'reference_output': stored['reference_output'],
+            'inference_time': stored['inference_time'],
+            'meta': stored.get('meta', {}),
+        }
+        # 获取模型输出失败的数据没有可评分的输出，保留原错误
+        if stored.get('error') and not stored.get('model_output'):
+            result['error'] = stored['error']
+            result['is_badcase'] = 1
+        else:
+            scored_indices.append(len(results))
+        results.append(result)
+
+    print(f"开始重新评分（并行处理），共 {len(scored_indices)} 条结果...")
+    pbar = tqdm(total=len(scored_indices), desc="评分进度", unit="条")
+    with ThreadPoolExecutor(max_workers=max_workers) as executor:
+        future_to_index = {
+            executor.submit(process_score_item, results[i], scoring_func, badcase_threshold): i
+            for i in scored_indices
+        }
+        for j, future in enumerate(as_completed(future_to_index)):
+            i = future_to_index[future]
+            try:
+                results[i], _ = future.result()
+            except Exception as e:
+                print(f"\n处理评分任务时出错: {e}")
+                results[i]['error'] = str(e)
+                results[i]['is_badcase'] = 1
+            pbar.update(1)
+
+            # 报告评分进度（每50条更新一次）
+            if j % 50 == 0 or j == len(future_to_index) - 1:
+                report_progress("评分处理", j + 1, len(scored_indices), progress_callback)
+    pbar.close()
+
+    badcases = [r for r in results if r.get('is_badcase', 0) == 1]
+    print(f"评分完成！Badcase数量: {len(badcases)}")
+    return results, badcases
@@ -46,9 +46,13 @@ def call_model_via_proxy(
     is_vllm: bool = False,
     top_p: float = 1.0,
     auth_token: str = None,
+    purpose: str = None,
 ) -> str:
     """
     通过后端代理调用模型API（带流量控制）
+
+    Args:
+        purpose: 调用用途，"judge" 表示裁判模型调用，使用独立的并发配额
     """
     config = get_backend_config()
     web_config = config.get('web_service', {})
@@ -72,6 +76,8 @@ def call_model_via_proxy(
         "is_vllm": is_vllm,
         "top_p": top_p
     }
+    if purpose:
+        payload["purpose"] = purpose
 
     # 计算请求超时时间：max_wait_time + 实际调用timeout + 缓冲
     redis_config = config.get('redis_service', {})
@@ -150,6 +156,7 @@ def call_model_api(
     top_p: float = 1.0,
     use_proxy: bool = True,
     auth_token: str = None,
+    purpose: str = None,
 ) -> str:
     """
     调用模型API的主入口
@@ -157,6 +164,7 @@ def call_model_api(
     Args:
         use_proxy: 是否使用后端代理（带流量控制），默认为True
         auth_token: JWT token for backend authentication
+        purpose: 调用用途，"judge" 表示裁判模型调用
     """
     if use_proxy:
         # 使用后端代理（带Redis流量控制）
@@ -170,7 +178,8 @@ def call_model_api(
             timeout=timeout,
             is_vllm=is_vllm,
             top_p=top_p,
-            auth_token=auth_token
+            auth_token=auth_token,
+            purpose=purpose
         )
     else:
         # 直接调用（无流量控制）
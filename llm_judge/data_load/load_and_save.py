@@ -5,7 +5,7 @@ from typing import Dict, List, Tuple, Callable, Any, Optional
 
 
 def load_jsonl(file_path: str = None, data_id: int = None, user_id: int = None, 
-               database_service_url: str = None) -> List[Dict]:
+               database_service_url: str = None, data_version: int = None) -> List[Dict]:
     """
     加载JSONL数据
     Args:
@@ -13,6 +13,7 @@ def load_jsonl(file_path: str = None, data_id: int = None, user_id: int = None,
         data_id: 数据库中的数据ID（数据库模式）
         user_id: 用户ID（数据库模式必须）
         database_service_url: 数据库服务URL（数据库模式必须）
+        data_version: 数据文件的版本号（数据库模式可选，默认使用当前版本）
     Returns:
         数据列表
     """
@@ -23,7 +24,9 @@ def load_jsonl(file_path: str = None, data_id: int = None, user_id: int = None,
         try:
             import httpx
             with httpx.Client(base_url=database_service_url, timeout=30.0) as client:
-                response = client.get(f"/api/user-data/{user_id}/{data_id}")
+                # 指定版本时读取任务固定的版本，保证评测数据可复现
+                params = {"version": data_version} if data_version else None
+                response = client.get(f"/api/user-data/{user_id}/{data_id}", params=params)
                 response.raise_for_status()
                 result = response.json()
                 file_content = result.get("file_content", "")
@@ -55,6 +58,28 @@ def load_jsonl(file_path: str = None, data_id: int = None, user_id: int = None,
     exit(1)
 
 
+def load_report_results(report_id: int, user_id: int, database_service_url: str) -> Tuple[Dict, List[Dict]]:
+    """
+    从数据库加载已有报告的元数据和全部评估结果（重新评分使用）
+    Args:
+        report_id: 报告ID
+        user_id: 用户ID
+        database_service_url: 数据库服务URL
+    Returns:
+        (报告元数据, 评估结果列表)
+    """
+    try:
+        import httpx
+        with httpx.Client(base_url=database_service_url, timeout=60.0) as client:
+            response = client.get(f"/api/user-reports/{user_id}/{report_id}/items")
+            response.raise_for_status()
+            result = response.json()
+            return result.get("report", {}), result.get("results", [])
+    except Exception as e:
+        print(f"从数据库加载报告失败: {e}")
+        exit(1)
+
+
 def load_custom_scoring_module(module_path: str) -> None:
     """
     加载自定义评分模块
@@ -0,0 +1,64 @@
+"""
+将 Parquet 文件的每一行以 JSON 对象的形式逐行输出到标准输出（JSONL）
+
+由后端导入数据时调用：python parquet_to_jsonl.py <parquet 文件路径>
+"""
+import base64
+import datetime
+import decimal
+import json
+import sys
+
+# 每批读取的行数，避免一次性把大文件读入内存
+BATCH_SIZE = 1024
+
+
+def _json_default(value):
+    """将 JSON 不支持的 Arrow 类型转换为可序列化的值"""
+    if isinstance(value, (datetime.datetime, datetime.date, datetime.time)):
+        return value.isoformat()
+    if isinstance(value, datetime.timedelta):
+        return value.total_seconds()
+    if isinstance(value, decimal.Decimal):
+        return str(value)
+    if isinstance(value, bytes):
+        try:
+            return value.decode("utf-8")
+        except UnicodeDecodeError:
+            return base64.b64encode(value).decode("ascii")
+    return str(value)
+
+
+def convert(path: str, out=sys.stdout) -> int:
+    """
+    逐批读取 Parquet 文件并写出 JSONL
+    Args:
+        path: Parquet 文件路径
+        out: 输出流
+    Returns:
+        写出的行数
+    """
+    import pyarrow.parquet as pq
+
+    count = 0
+    parquet_file = pq.ParquetFile(path)
+    for batch in parquet_file.iter_batches(batch_size=BATCH_SIZE):
+        for row in batch.to_pylist():
+            out.write(json.dumps(row, ensure_ascii=False, default=_json_default))
+            out.write("\n")
+            count += 1
+    return count
+
+
+if __name__ == "__main__":
+    if len(sys.argv) != 2:
+        print("用法: python parquet_to_jsonl.py <parquet 文件路径>", file=sys.stderr)
+        sys.exit(2)
+    try:
+        convert(sys.argv[1])
+    except ImportError:
+        print("读取 Parquet 文件需要安装 pyarrow", file=sys.stderr)
+        sys.exit(1)
+    except Exception as e:
+        print(f"无法读取 Parquet 文件: {e}", file=sys.stderr)
+        sys.exit(1)
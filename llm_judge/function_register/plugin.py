@@ -2,7 +2,7 @@ import re
 import json
 from typing import Dict, Any
 from rouge_score import rouge_scorer
-from llm_judge.call_model.model_call import call_model_api
+from llm_judge.call_model.model_call import call_model_api, get_backend_config
 
 try:
     from langdetect import detect, DetectorFactory
@@ -38,6 +38,72 @@ def register_scoring_function(name: str):
         return func
     return decorator
 
+# 裁判模型配置，由 main.py 根据任务配置调用 configure_judge 设置；为空时使用 llm_service 配置
+JUDGE_CONFIG = {}
+_judge_call_count = [0]
+
+# 使用自定义裁判提示词模板时的系统提示词
+JUDGE_SYSTEM_PROMPT = """你是一个严格的评测裁判。请按照用户给出的评分标准进行评判，先简要说明理由，
+最后单独一行输出 \\boxed{X}，其中 X 为 0 到 {scale} 之间的分数。"""
+
+def configure_judge(model: str, api_urls: list, prompt_template: str = None, scale: int = 2,
+                    max_tokens: int = 1024, timeout: int = 300, is_vllm: bool = False, auth_token: str = None):
+    """
+    设置裁判模型，LLM-as-judge 评分函数会通过后端代理（purpose=judge）调用该模型
+    Args:
+        prompt_template: 裁判提示词模板，支持 {messages} {reference_output} {model_output} {scale}
+        scale: 评分范围上限，分数取值为 0 到 scale
+    """
+    JUDGE_CONFIG.update({
+        'model': model,
+        'api_urls': api_urls or [],
+        'prompt_template': prompt_template,
+        'scale': scale,
+        'max_tokens': max_tokens,
+        'timeout': timeout,
+        'is_vllm': is_vllm,
+        'auth_token': auth_token,
+    })
+
+def call_judge_model(messages_judge: list):
+    """
+    调用裁判模型
+    Returns:
+        (裁判输出, 裁判模型名称)
+    """
+    if JUDGE_CONFIG.get('api_urls'):
+        # 多个地址时轮询调用
+        api_urls = JUDGE_CONFIG['api_urls']
+        api_url = api_urls[_judge_call_count[0] % len(api_urls)]
+        _judge_call_count[0] += 1
+        # API Key 由后端代理根据模型配置注入
+        output = call_model_api(api_url, "", messages=messages_judge, model=JUDGE_CONFIG['model'],
+                                max_tokens=JUDGE_CONFIG['max_tokens'], timeout=JUDGE_CONFIG['timeout'],
+                                is_vllm=JUDGE_CONFIG['is_vllm'], auth_token=JUDGE_CONFIG['auth_token'],
+                                purpose='judge')
+        return output, JUDGE_CONFIG['model']
+
+    # 使用从配置中获取的API URL
+    config = get_backend_config()
+    api_url = config.get('llm_service', {}).get('api_url', "http://localhost:8000/v1")
+    api_key = config.get('llm_service', {}).get('api_key', "xxx")
+    model_name = config.get('llm_service', {}).get('model', "/data/kaipeng/model/Qwen/Qwen3-235B-A22B-Instruct-2507")
+    output = call_model_api(api_url, api_key, messages=messages_judge, model=model_name, purpose='judge')
+    return output, model_name
+
+def split_judge_output(output: str):
+    """
+    将裁判输出拆分为评分理由和分数（取最后一个 \\boxed{} 中的数值）
+    Returns:
+        (理由, 分数)，无法解析分数时分数为 None
+    """
+    matches = list(re.finditer(r'\\boxed\s*\{\s*(-?\d+(?:\.\d+)?)\s*\}', output))
+    if not matches:
+        return output.strip(), None
+    last = matches[-1]
+    rationale = output[:last.start()].strip() or output[last.end():].strip()
+    return rationale, float(last.group(1))
+
 @register_scoring_function('toolbench_evaluation')
 def evaluate_toolbench(messages: list, model_output: str, reference_output: str) -> Dict[str, Any]:
     """ToolBench数据集的自定义评估函数
@@ -802,12 +868,7 @@ def evaluate_reject_function(messages: list, model_output: str, reference_output
             """ 
                 user_input = user_input.format(messages=messages, reference_output=reference_output, model_output=model_output)
                 messages_judge.append({"role": "user", "content": user_input})
-                # 使用从配置中获取的API URL
-                config = get_backend_config()
-                api_url = config.get('llm_service', {}).get('api_url', "http://localhost:8000/v1")
-                api_key = config.get('llm_service', {}).get('api_key', "xxx")
-                model_name = config.get('llm_service', {}).get('model', "/data/kaipeng/model/Qwen/Qwen3-235B-A22B-Instruct-2507")
-                output = call_model_api(api_url, api_key, messages=messages_judge, model=model_name)
+                output, _ = call_judge_model(messages_judge)
 
                 def extract_boxed_value(text: str) -> str:
                     """提取boxed中的数值"""
@@ -1224,10 +1285,6 @@ def evaluate_llm_judge(messages: list, model_output: str, reference_output: str)
 2. 核心信息部分重叠：\\boxed{1}；
 3. 核心信息完全不重叠：\\boxed{0}；
 简单解释理由(50字以内)并严格按规则输出。"""
-
-    messages_judge = [
-        {"role": "system", "content": system_prompt},
-    ]
     user_input = """
 前序对话内容：
 {messages}
@@ -1238,29 +1295,36 @@ def evaluate_llm_judge(messages: list, model_output: str, reference_output: str)
 模型回答：
 {model_output}
 """ 
-    user_input = user_input.format(messages=messages, reference_output=reference_output, model_output=model_output)
-    messages_judge.append({"role": "user", "content": user_input})
-    # 使用从配置中获取的API URL
-    config = get_backend_config()
-    api_url = config.get('llm_service', {}).get('api_url', "http://localhost:8000/v1")
-    api_key = config.get('llm_service', {}).get('api_key', "xxx")
-    model_name = config.get('llm_service', {}).get('model', "/data/kaipeng/model/Qwen/Qwen3-235B-A22B-Instruct-2507")
-    output = call_model_api(api_url, api_key, messages=messages_judge, model=model_name)
-    def extract_boxed_value(text: str) -> str:
-        """提取boxed中的数值"""
-        match = re.search(r'\\boxed{(\d+)}', text)
-        if match:
-            return match.group(1)
-        return ""
+    scale = 2
+    # 任务配置了裁判提示词模板时使用自定义评分标准
+    if JUDGE_CONFIG.get('prompt_template'):
+        scale = JUDGE_CONFIG.get('scale') or scale
+        system_prompt = JUDGE_SYSTEM_PROMPT.replace('{scale}', str(scale))
+        user_input = JUDGE_CONFIG['prompt_template']
+    # 逐个替换占位符，避免模板中的其他花括号被当作格式化字段
+    for key, value in (('{messages}', messages), ('{reference_output}', reference_output),
+                       ('{model_output}', model_output), ('{scale}', scale)):
+        user_input = user_input.replace(key, str(value))
+
+    messages_judge = [
+        {"role": "system", "content": system_prompt},
+        {"role": "user", "content": user_input},
+    ]
     try:
-        score = extract_boxed_value(output)
-        if score:
-            score = float(score)
-            scores["score"] = score
-        else:
+        output, judge_model = call_judge_model(messages_judge)
+        rationale, score = split_judge_output(output)
+        scores["details"]["content"] = output
+        scores["details"]["rationale"] = rationale
+        scores["details"]["judge_model"] = judge_model
+        scores["details"]["judge_scale"] = scale
+        if score is None:
             scores["error"] = "无法解析box内的内容"
             scores["is_badcase"] = 1
-        scores["details"]["content"] = output
+        elif score < 0 or score > scale:
+            scores["error"] = f"裁判分数 {score} 超出评分范围 0-{scale}"
+            scores["is_badcase"] = 1
+        else:
+            scores["score"] = score
 
     except Exception as e:
         print(f"评估时出错，错误原因是: {e}")
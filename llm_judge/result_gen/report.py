@@ -30,7 +30,7 @@ def generate_report(summary: Dict[str, Any], results: List[Dict], badcases: List
         
         # 过滤敏感路径信息，避免安全风险
         safe_config = {}
-        sensitive_keys = {'report_dir', 'checkpoint_path', 'scoring_module', 'database_service_url'}
+        sensitive_keys = {'report_dir', 'checkpoint_path', 'scoring_module', 'database_service_url', 'api_key', 'auth_token', 'judge_prompt_template_file'}
         for k, v in vars(args).items():
             if k not in sensitive_keys:
                 safe_config[k] = v
@@ -70,7 +70,7 @@ def generate_report(summary: Dict[str, Any], results: List[Dict], badcases: List
             if progress_callback:
                 progress_callback("保存报告", 60, 100, 96.0)
             
-            response = client.post("/api/user-reports", json={
+            report_request = {
                 "user_id": user_id,
                 "task_id": task_id,
                 "dataset": dataset_name,
@@ -78,7 +78,12 @@ def generate_report(summary: Dict[str, Any], results: List[Dict], badcases: List
                 "report_content": report_content_json,
                 "timestamp": time.strftime("%Y-%m-%d %H:%M:%S"),
                 "summary": summary
-            })
+            }
+            # 重新评分生成的报告关联到原报告
+            if getattr(args, 'rescore_report_id', None):
+                report_request["parent_report_id"] = args.rescore_report_id
+                report_request["derivation"] = getattr(args, 'derivation', None)
+            response = client.post("/api/user-reports", json=report_request)
             
             # 请求完成（98%）
             if progress_callback:
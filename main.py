@@ -7,11 +7,11 @@
 import argparse
 import os
 import yaml
-from llm_judge.data_load.load_and_save import load_jsonl, load_custom_scoring_module
-from llm_judge.function_register.plugin import SCORING_FUNCTIONS_plugin, initialize_langdetect_profiles
+from llm_judge.data_load.load_and_save import load_jsonl, load_custom_scoring_module, load_report_results
+from llm_judge.function_register.plugin import SCORING_FUNCTIONS_plugin, initialize_langdetect_profiles, configure_judge
 from llm_judge.result_gen.report import generate_report, aggregate_results
 from llm_judge.score.get_score import get_scoring_function
-from llm_judge.batch.batch_process import batch_evaluate
+from llm_judge.batch.batch_process import batch_evaluate, batch_rescore
 
 def get_config_defaults():
     """
@@ -38,6 +38,7 @@ def parse_args():
     parser.add_argument('--model', default=default_model, help='vllm模型名称')
     parser.add_argument('--data_file', default=None, help='测试数据文件路径')
     parser.add_argument('--data_id', type=int, default=None, help='数据库中的数据ID')
+    parser.add_argument('--data_version', type=int, default=None, help='数据文件的版本号（默认使用当前版本）')
     parser.add_argument('--scoring', default='rouge', help='评分函数名称')
     parser.add_argument('--scoring_module', type=str, default="./function_register/plugin.py", help='自定义评分模块文件路径')
     parser.add_argument('--output', default='evaluation_report.json', help='评分报告输出文件路径')
@@ -57,12 +58,24 @@ def parse_args():
     parser.add_argument('--role', type=str, default="assistant", help='选择指定的测试角色')
     parser.add_argument('--timeout', type=int, default=600, help='API调用超时时间（秒）')
     parser.add_argument('--max-tokens', type=int, default=16384, help='API调用超时时间（秒）')
-    default_api_key = llm_config.get('api_key', "sk-xxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxx")
+    # 优先从环境变量读取API KEY，避免在命令行中暴露
+    default_api_key = os.environ.get('LLM_JUDGE_API_KEY') or llm_config.get('api_key', "sk-xxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxx")
     parser.add_argument('--api_key', type=str, default=default_api_key, help='API KEY')
     parser.add_argument('--is_vllm', action='store_true', default=False, help='是否使用vllm')
     parser.add_argument('--temperature', type=float, default=0.0, help='生成温度')
     parser.add_argument('--top-p', type=float, default=1.0, help='Top P采样参数')
     parser.add_argument('--auth_token', type=str, default=None, help='JWT token for backend API authentication')
+    parser.add_argument('--progress_base', type=float, default=0.0, help='多模型对比任务中本次运行的起始进度')
+    parser.add_argument('--progress_span', type=float, default=100.0, help='多模型对比任务中本次运行占用的进度范围')
+    parser.add_argument('--judge_model', type=str, default=None, help='裁判模型名称（LLM-as-judge评分函数使用）')
+    parser.add_argument('--judge_api_urls', nargs='+', default=None, help='裁判模型的API地址')
+    parser.add_argument('--judge_prompt_template', type=str, default=None, help='裁判提示词模板，支持 {messages} {reference_output} {model_output} {scale}')
+    parser.add_argument('--judge_prompt_template_file', type=str, default=None, help='从文件读取裁判提示词模板，避免模板出现在命令行中')
+    parser.add_argument('--judge_scale', type=int, default=2, help='裁判评分范围上限（0到该值）')
+    parser.add_argument('--judge_max_tokens', type=int, default=1024, help='裁判模型最大生成tokens')
+    parser.add_argument('--judge_timeout', type=int, default=300, help='裁判模型调用超时时间（秒）')
+    parser.add_argument('--judge_is_vllm', action='store_true', default=False, help='裁判模型是否使用vllm')
+    parser.add_argument('--rescore_report_id', type=int, default=None, help='重新评分的报告ID，复用其模型输出，不调用模型')
 
     return parser.parse_args()
 
@@ -78,6 +91,24 @@ def main():
     if args.scoring_module:
         load_custom_scoring_module(args.scoring_module)
     
+    # 配置裁判模型（通过后端代理调用，使用独立的并发配额）
+    if args.judge_prompt_template_file:
+        # 模板随报告配置一起保存
+        with open(args.judge_prompt_template_file, 'r', encoding='utf-8') as f:
+            args.judge_prompt_template = f.read()
+    if args.judge_model:
+        configure_judge(
+            model=args.judge_model,
+            api_urls=args.judge_api_urls,
+            prompt_template=args.judge_prompt_template,
+            scale=args.judge_scale,
+            max_tokens=args.judge_max_tokens,
+            timeout=args.judge_timeout,
+            is_vllm=args.judge_is_vllm,
+            auth_token=args.auth_token,
+        )
+        print(f"裁判模型: {args.judge_model}，评分范围: 0-{args.judge_scale}")
+
     # 确保使用的评分函数存在
     if args.scoring not in SCORING_FUNCTIONS_plugin:
         print(f"错误: 评分函数 '{args.scoring}' 未注册")
@@ -85,8 +116,30 @@ def main():
         exit(1)
     
 
-    # 加载测试数据（支持本地文件或数据库）
-    if args.data_id and args.user_id and args.database_service_url:
+    # 加载测试数据（支持本地文件或数据库，重新评分时加载已有报告的结果）
+    stored_results = None
+    if args.rescore_report_id:
+        if not (args.user_id and args.database_service_url):
+            print("错误: 重新评分需要指定 --user_id 和 --database_service_url")
+            exit(1)
+        print(f"从数据库加载报告结果: report_id={args.rescore_report_id}")
+        parent_report, stored_results = load_report_results(
+            report_id=args.rescore_report_id,
+            user_id=args.user_id,
+            database_service_url=args.database_service_url
+        )
+        parent_config = parent_report.get("config") or {}
+        args.model = parent_report.get("model", "unknown")
+        args.data_filename = parent_report.get("dataset", "unknown")
+        args.derivation = {
+            "type": "rescore",
+            "scoring": args.scoring,
+            "badcase_threshold": args.badcase_threshold,
+            "parent_scoring": parent_config.get("scoring"),
+            "parent_badcase_threshold": parent_config.get("badcase_threshold"),
+        }
+        test_data = stored_results
+    elif args.data_id and args.user_id and args.database_service_url:
         print(f"从数据库加载测试数据: data_id={args.data_id}")
         # 获取数据和文件名
         import httpx
@@ -98,7 +151,8 @@ def main():
         test_data = load_jsonl(
             data_id=args.data_id,
             user_id=args.user_id,
-            database_service_url=args.database_service_url
+            database_service_url=args.database_service_url,
+            data_version=args.data_version
         )
     elif args.data_file:
         print(f"加载测试数据: {args.data_file}")
@@ -128,37 +182,52 @@ def main():
             try:
                 import httpx
                 with httpx.Client(base_url=args.database_service_url, timeout=5.0) as client:
+                    # 多模型对比时将本次进度映射到整个任务的进度范围内
+                    task_pct = args.progress_base + progress_pct * args.progress_span / 100.0
+                    message = f"{phase}: {current}/{total} ({progress_pct:.1f}%)"
+                    if args.progress_span < 100.0:
+                        message = f"[{args.model}] {message}"
                     client.put(f"/api/user-tasks/{args.task_id}", json={
                         "updates": {
-                            "progress": progress_pct,
-                            "message": f"{phase}: {current}/{total} ({progress_pct:.1f}%)"
+                            "progress": task_pct,
+                            "message": message
                         }
                     })
             except Exception:
                 pass  # 保存失败不影响主流程
     
+    # 重新评分：复用已保存的模型输出，不调用模型
+    if stored_results is not None:
+        results, badcases = batch_rescore(
+            stored_results,
+            scoring_func,
+            max_workers=args.max_workers,
+            badcase_threshold=args.badcase_threshold,
+            progress_callback=progress_callback
+        )
     # 批量评估
-    results, badcases = batch_evaluate(
-        test_data,
-        args.api_urls,
-        scoring_func,
-        max_workers=args.max_workers,
-        badcase_threshold=args.badcase_threshold,
-        test_mode=args.test_mode,
-        model=args.model,
-        checkpoint_path=args.checkpoint_path,
-        checkpoint_interval=args.checkpoint_interval,
-        resume=args.resume,
-        role_test=args.role,
-        timeout=args.timeout,
-        max_tokens=args.max_tokens,
-        api_key=args.api_key,
-        is_vllm=args.is_vllm,
-        temperature=getattr(args, 'temperature', 0.0),
-        top_p=getattr(args, 'top_p', 1.0),
-        progress_callback=progress_callback,
-        auth_token=getattr(args, 'auth_token', None)
-    )
+    else:
+        results, badcases = batch_evaluate(
+            test_data,
+            args.api_urls,
+            scoring_func,
+            max_workers=args.max_workers,
+            badcase_threshold=args.badcase_threshold,
+            test_mode=args.test_mode,
+            model=args.model,
+            checkpoint_path=args.checkpoint_path,
+            checkpoint_interval=args.checkpoint_interval,
+            resume=args.resume,
+            role_test=args.role,
+            timeout=args.timeout,
+            max_tokens=args.max_tokens,
+            api_key=args.api_key,
+            is_vllm=args.is_vllm,
+            temperature=getattr(args, 'temperature', 0.0),
+            top_p=getattr(args, 'top_p', 1.0),
+            progress_callback=progress_callback,
+            auth_token=getattr(args, 'auth_token', None)
+        )
 
     # 汇总结果
     summary = aggregate_results(results)